@@ -0,0 +1,206 @@
+// Package ass converts STL files to and from Advanced SubStation Alpha
+// (.ass) scripts, also reading SubStation Alpha (.ssa) v4 scripts.
+//
+// Teletext colors and double height are mapped to script styles, the
+// Justification Code (JC) and Vertical Position (VP) to the alignment and
+// vertical margin of the events, italics and underline to override tags
+// and the titles and translator's name of the GSI block to the
+// [Script Info] section.
+package ass
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/si0ls/subs/stl"
+)
+
+// Options configures an ASS conversion.
+type Options struct {
+	Start     *stl.Timecode // Timecode of the script time 0:00:00.00 (default GSI Time Code: Start-of-Program on export, "Timecode Start" script info on import, else 00:00:00:00)
+	Framerate uint          // Framerate of the STL file created on import, 25 or 30 (default 25)
+	Fallback  stl.Fallback  // Fallback for characters the Character Code Table (CCT) can not represent on import (default replace)
+}
+
+var (
+	ErrNilGSI               = errors.New("nil GSI block")
+	ErrUnsupportedFramerate = errors.New("unsupported framerate")
+	ErrNoEvents             = errors.New("no [Events] section")
+	ErrInvalidTime          = errors.New("invalid time")
+)
+
+// Script info keys written in the [Script Info] section, besides the
+// standard ones.
+const (
+	KeyOriginalTitle = "Original Title"
+	KeyTimecodeStart = "Timecode Start"
+)
+
+const (
+	playResX          = 720
+	teletextRows      = 24 // rows 0..23 of a Teletext page, row 0 being the header
+	defaultStyleColor = stl.TeletextColorWhite
+)
+
+// Alignment is a numpad style alignment of ASS scripts (\an tag).
+type Alignment int
+
+const (
+	AlignmentBottomLeft   Alignment = 1
+	AlignmentBottomCenter Alignment = 2
+	AlignmentBottomRight  Alignment = 3
+	AlignmentMiddleLeft   Alignment = 4
+	AlignmentMiddleCenter Alignment = 5
+	AlignmentMiddleRight  Alignment = 6
+	AlignmentTopLeft      Alignment = 7
+	AlignmentTopCenter    Alignment = 8
+	AlignmentTopRight     Alignment = 9
+)
+
+// alignment returns the alignment of a horizontal position (1 left, 2
+// center, 3 right) and a vertical one (0 bottom, 1 middle, 2 top).
+func alignment(horizontal, vertical int) Alignment {
+	return Alignment(vertical*3 + horizontal)
+}
+
+func (a Alignment) horizontal() int {
+	return (int(a)-1)%3 + 1
+}
+
+func (a Alignment) vertical() int {
+	return (int(a) - 1) / 3
+}
+
+// legacyAlignment converts a SubStation Alpha alignment (\a tag and v4
+// styles) to a numpad alignment.
+func legacyAlignment(a int) Alignment {
+	h := a & 3
+	if h == 0 {
+		h = 2
+	}
+	switch {
+	case a&4 != 0:
+		return alignment(h, 2)
+	case a&8 != 0:
+		return alignment(h, 1)
+	}
+	return alignment(h, 0)
+}
+
+// Style is a script style, as generated from the Teletext attributes of a
+// subtitle row.
+type Style struct {
+	Name         string
+	Color        stl.TeletextColor // Primary color
+	Italic       bool
+	Underline    bool
+	DoubleHeight bool // ScaleY of 200%
+	Alignment    Alignment
+	MarginV      int
+}
+
+// styleName returns the name of the generated style of a Teletext color
+// and height.
+func styleName(c stl.TeletextColor, doubleHeight bool) string {
+	name := c.String()
+	if c == defaultStyleColor {
+		name = "Default"
+	}
+	if doubleHeight {
+		name += " DH"
+	}
+	return name
+}
+
+// colorBGR returns the BBGGRR value of a Teletext color.
+func colorBGR(c stl.TeletextColor) uint32 {
+	var bgr uint32
+	if c&1 != 0 {
+		bgr |= 0x0000FF
+	}
+	if c&2 != 0 {
+		bgr |= 0x00FF00
+	}
+	if c&4 != 0 {
+		bgr |= 0xFF0000
+	}
+	return bgr
+}
+
+// formatColor returns the &HAABBGGRR representation of a Teletext color.
+func formatColor(c stl.TeletextColor, alpha byte) string {
+	return fmt.Sprintf("&H%02X%06X", alpha, colorBGR(c))
+}
+
+// parseColor parses a &HAABBGGRR, &HBBGGRR& or decimal color and returns
+// the nearest Teletext color.
+func parseColor(s string) (stl.TeletextColor, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(s, "&")
+	var v uint64
+	var err error
+	if strings.HasPrefix(s, "&H") || strings.HasPrefix(s, "&h") {
+		v, err = strconv.ParseUint(s[2:], 16, 32)
+	} else {
+		v, err = strconv.ParseUint(s, 10, 32)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid color %q", s)
+	}
+	var c stl.TeletextColor
+	if v&0x80 != 0 {
+		c |= 1
+	}
+	if v&0x8000 != 0 {
+		c |= 2
+	}
+	if v&0x800000 != 0 {
+		c |= 4
+	}
+	return c, nil
+}
+
+// formatTime returns the H:MM:SS.cc representation of a number of frames.
+func formatTime(frames int, framerate uint) string {
+	cs := (frames*100 + int(framerate)/2) / int(framerate)
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
+
+// parseTime parses a H:MM:SS.cc time and returns its number of
+// centiseconds.
+func parseTime(s string) (int, error) {
+	var h, m, sec, cs int
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidTime, s)
+	}
+	secs := strings.SplitN(parts[2], ".", 2)
+	var err error
+	if h, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidTime, s)
+	}
+	if m, err = strconv.Atoi(parts[1]); err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidTime, s)
+	}
+	if sec, err = strconv.Atoi(secs[0]); err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidTime, s)
+	}
+	if len(secs) == 2 {
+		frac := (secs[1] + "00")[:2]
+		if cs, err = strconv.Atoi(frac); err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidTime, s)
+		}
+	}
+	return cs + ((h*60+m)*60+sec)*100, nil
+}
+
+// parseTimecode parses a HH:MM:SS:FF timecode.
+func parseTimecode(s string) (stl.Timecode, error) {
+	var tc stl.Timecode
+	if _, err := fmt.Sscanf(strings.TrimSpace(s), "%d:%d:%d:%d", &tc.Hours, &tc.Minutes, &tc.Seconds, &tc.Frames); err != nil {
+		return tc, fmt.Errorf("invalid timecode %q", s)
+	}
+	return tc, nil
+}
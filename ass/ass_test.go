@@ -0,0 +1,171 @@
+package ass
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/si0ls/subs/internal/stltest"
+	"github.com/si0ls/subs/stl"
+)
+
+func testFile(t *testing.T) *stl.File {
+	t.Helper()
+
+	// double height, colors, italics and a translator comment, which ASS
+	// styles and comment events hold
+	f := stltest.File{Subtitles: []stltest.Subtitle{
+		{TCI: stl.Timecode{Hours: 10, Seconds: 1}, TCO: stl.Timecode{Hours: 10, Seconds: 3, Frames: 12}, VP: 19, JC: stl.JustificationCodeCenteredText,
+			Text: "\x0d\x03\x0b\x0bHello\u008a\u008a\x0d\x03\x0b\x0bWorld"},
+		{TCI: stl.Timecode{Hours: 10, Seconds: 4}, TCO: stl.Timecode{Hours: 10, Seconds: 6}, VP: 2, JC: stl.JustificationCodeLeftJustifiedText,
+			Text: "\x0b\x0b\u0080Ciao\x02amici"},
+		{TCI: stl.Timecode{Hours: 10, Seconds: 6}, TCO: stl.Timecode{Hours: 10, Seconds: 7}, VP: 20, JC: stl.JustificationCodeCenteredText, CF: stl.CommentFlagTranslatorComments,
+			Text: "\x0b\x0bnote"},
+	}}.New(t)
+	f.GSI.OPT = "Original"
+	f.GSI.TPT = "Translated"
+	f.GSI.TN = "Translator"
+	f.GSI.TCP = stl.Timecode{Hours: 10}
+	return f
+}
+
+func TestEncode(t *testing.T) {
+	var buf bytes.Buffer
+	warns, err := Encode(&buf, testFile(t), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) > 0 {
+		t.Errorf("unexpected warnings: %v", warns)
+	}
+
+	for _, want := range []string{
+		"Title: Translated\n",
+		"Original Title: Original\n",
+		"Original Translation: Translator\n",
+		"Timecode Start: 10:00:00:00\n",
+		"Style: Default,Arial,24,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,3,1,0,2,40,40,24,1\n",
+		"Style: Yellow DH,Arial,24,&H0000FFFF,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,200,0,0,3,1,0,2,40,40,24,1\n",
+		"Dialogue: 0,0:00:01.00,0:00:03.48,Yellow DH,,0,0,24,,Hello\\NWorld\n",
+		"Dialogue: 0,0:00:04.00,0:00:06.00,Default,,0,0,48,,{\\an7}{\\i1}Ciao {\\c&H00FF00&}amici\n",
+		"Comment: 0,0:00:06.00,0:00:07.00,Default,,0,0,72,,note\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	f := testFile(t)
+	var buf bytes.Buffer
+	if _, err := Encode(&buf, f, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	got, warns, err := Decode(&buf, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) > 0 {
+		t.Errorf("unexpected warnings: %v", warns)
+	}
+
+	if got.GSI.OPT != f.GSI.OPT || got.GSI.TPT != f.GSI.TPT || got.GSI.TN != f.GSI.TN || got.GSI.TCP != f.GSI.TCP {
+		t.Errorf("GSI = %q %q %q %s, want %q %q %q %s",
+			got.GSI.OPT, got.GSI.TPT, got.GSI.TN, got.GSI.TCP, f.GSI.OPT, f.GSI.TPT, f.GSI.TN, f.GSI.TCP)
+	}
+	if len(got.TTI) != len(f.TTI) {
+		t.Fatalf("got %d subtitles, want %d", len(got.TTI), len(f.TTI))
+	}
+	for i, want := range f.TTI {
+		tti := got.TTI[i]
+		if tti.TCI != want.TCI || tti.TCO != want.TCO {
+			t.Errorf("subtitle %d: times %s-%s, want %s-%s", i, tti.TCI, tti.TCO, want.TCI, want.TCO)
+		}
+		if tti.VP != want.VP || tti.JC != want.JC || tti.CF != want.CF {
+			t.Errorf("subtitle %d: VP %d JC %s CF %d, want VP %d JC %s CF %d", i, tti.VP, tti.JC, tti.CF, want.VP, want.JC, want.CF)
+		}
+		if tti.TF != want.TF {
+			t.Errorf("subtitle %d: TF %q, want %q", i, tti.TF, want.TF)
+		}
+	}
+}
+
+func TestDecodeTags(t *testing.T) {
+	script := strings.Join([]string{
+		"[Script Info]",
+		"ScriptType: v4.00+",
+		"PlayResY: 288",
+		"",
+		"[V4+ Styles]",
+		"Format: Name, Fontname, Fontsize, PrimaryColour, Italic, Alignment, MarginV",
+		"Style: Top,Arial,20,&H0000FFFF,0,8,12",
+		"",
+		"[Events]",
+		"Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text",
+		"Dialogue: 0,0:00:01.00,0:00:02.00,Top,,0,0,0,,{\\u1}a, b{\\u0}\\N{\\r\\an9}c",
+	}, "\n")
+	f, warns, err := Decode(strings.NewReader(script), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) > 0 {
+		t.Errorf("unexpected warnings: %v", warns)
+	}
+	if len(f.TTI) != 1 {
+		t.Fatalf("got %d subtitles, want 1", len(f.TTI))
+	}
+	tti := f.TTI[0]
+	if want := "\x03\x0b\x0b\x82a, b\x8a\x03\x0b\x0b\x83c"; tti.TF != want {
+		t.Errorf("TF = %q, want %q", tti.TF, want)
+	}
+	if tti.JC != stl.JustificationCodeRightJustifiedText || tti.VP != 1 {
+		t.Errorf("JC %s VP %d, want right-justified VP 1", tti.JC, tti.VP)
+	}
+}
+
+func TestDecodeCharacterCodeTable(t *testing.T) {
+	script := strings.Join([]string{
+		"[Events]",
+		"Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text",
+		"Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,Привет мир",
+		"Dialogue: 0,0:00:03.00,0:00:04.00,Default,,0,0,0,,Ёлки ♥",
+	}, "\n")
+	f, warns, err := Decode(strings.NewReader(script), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.GSI.CCT != stl.CharacterCodeTableLatinCyrillic || f.GSI.LC != stl.LanguageCodeRussian {
+		t.Errorf("CCT %s LC %s, want Latin/Cyrillic and Russian", f.GSI.CCT, f.GSI.LC)
+	}
+	if len(f.TTI) != 2 {
+		t.Fatalf("got %d subtitles, want 2", len(f.TTI))
+	}
+	for i, want := range []string{"\x0b\x0bПривет мир", "\x0b\x0bЁлки ?"} {
+		if text, err := f.TTI[i].Text(f.GSI.CCT); err != nil || text != want {
+			t.Errorf("subtitle %d: text %q (%v), want %q", i, text, err, want)
+		}
+	}
+	if len(warns) != 1 || !errors.Is(warns[0], stl.ErrUnmappableRune) || !strings.HasPrefix(warns[0].Error(), "line 4: ") {
+		t.Errorf("warnings %v, want an unmappable rune on line 4", warns)
+	}
+}
+
+func TestEncodeTextOrder(t *testing.T) {
+	f := testFile(t)
+	f.GSI.CCT = stl.CharacterCodeTableLatinHebrew
+	f.TextOrder = stl.TextOrderVisual
+	f.TTI = f.TTI[:1]
+	if err := f.TTI[0].SetTextOrdered("\x0b\x0bשלום עולם", f.GSI.CCT, f.TextOrder); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := Encode(&buf, f, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := ",,שלום עולם\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("missing %q in:\n%s", want, buf.String())
+	}
+}
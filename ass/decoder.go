@@ -0,0 +1,552 @@
+package ass
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/si0ls/subs/stl"
+)
+
+// Decode reads an ASS or SSA script from r and returns its events as a
+// Level-1 Teletext STL file.
+//
+// Dialogue events become subtitles and comment events translator's
+// comments. Each row of an event starts with the double height code if its
+// style or a \fscy override scales it vertically, the alphanumeric color
+// code of its primary color (unless white) and boxing codes. Color changes
+// within a row take the place of the preceding space when there is one,
+// italics and underline are coded with STL control codes. The alignment
+// gives the Justification Code (JC) and, with the vertical margin, the
+// Vertical Position (VP) of the subtitle.
+//
+// The titles and the translator's name of the GSI block are read from the
+// [Script Info] section. The Character Code Table (CCT) is the one
+// representing the text of the events, and the Language Code (LC) is
+// guessed from it; characters it can not represent are handled according
+// to the fallback and returned as warnings. Other override tags, drawings
+// and effects are ignored. Invalid lines are returned as warnings.
+func Decode(r io.Reader, opts Options) (*stl.File, []error, error) {
+	d, err := read(r, opts)
+	if err != nil {
+		return nil, d.warns, err
+	}
+
+	f := stl.NewFile()
+	f.GSI = stl.NewGSIBlock()
+	f.GSI.SetDefaults(d.framerate, stl.DisplayStandardCodeLevel1Teletext)
+	f.GSI.TPT = d.info["Title"]
+	f.GSI.OPT = d.info[KeyOriginalTitle]
+	f.GSI.TN = d.info["Original Translation"]
+	f.GSI.TCP = d.start
+	texts := make([]string, len(d.subtitles))
+	for i, sub := range d.subtitles {
+		texts[i] = sub.text
+	}
+	f.GSI.SetCharacterCodeTable(texts...)
+
+	start := d.start.ToFrames(d.framerate)
+	frames := func(cs int) int {
+		return start + (cs*int(d.framerate)+50)/100
+	}
+	var sn int
+	for _, sub := range d.subtitles {
+		tti := stl.NewTTIBlock()
+		tti.SGN = 0
+		tti.SN = sn
+		tti.EBN = stl.EBNLastBlock
+		tti.CS = stl.CumulativeStatusNone
+		tti.TCI = stl.TimecodeFromFrames(frames(sub.in), d.framerate)
+		tti.TCO = stl.TimecodeFromFrames(frames(sub.out), d.framerate)
+		tti.VP = sub.vp
+		tti.JC = sub.jc
+		tti.CF = stl.CommentFlagSubtitleData
+		if sub.comment {
+			tti.CF = stl.CommentFlagTranslatorComments
+		}
+		unmappables, err := tti.SetTextFallback(sub.text, f.GSI.CCT, opts.Fallback)
+		if err != nil {
+			d.warns = append(d.warns, fmt.Errorf("line %d: %w", sub.line, err))
+			continue
+		}
+		if len(unmappables) > 0 {
+			d.warns = append(d.warns, fmt.Errorf("line %d: %w, fallback %s", sub.line, &stl.UnmappableError{Runes: unmappables}, opts.Fallback))
+		}
+		sn++
+		f.TTI = append(f.TTI, tti.ExtensionBlocks()...)
+	}
+	f.UpdateCounters()
+	return f, d.warns, nil
+}
+
+// read reads the script and the subtitles of its events.
+func read(r io.Reader, opts Options) (*decoder, error) {
+	d := &decoder{
+		framerate: opts.Framerate,
+		info:      map[string]string{},
+		styles:    map[string]Style{},
+	}
+	if d.framerate == 0 {
+		d.framerate = 25
+	}
+	if d.framerate != 25 && d.framerate != 30 {
+		return d, fmt.Errorf("%w: %d", ErrUnsupportedFramerate, d.framerate)
+	}
+	if err := d.parse(r); err != nil {
+		return d, err
+	}
+	if d.eventsFormat == nil {
+		return d, ErrNoEvents
+	}
+
+	if opts.Start != nil {
+		d.start = *opts.Start
+	} else if s, ok := d.info[KeyTimecodeStart]; ok {
+		tc, err := parseTimecode(s)
+		if err != nil {
+			d.warns = append(d.warns, err)
+		} else {
+			d.start = tc
+		}
+	}
+	d.playResY = 288
+	if s, ok := d.info["PlayResY"]; ok {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			d.playResY = v
+		}
+	}
+
+	for _, ev := range d.events {
+		sub, err := d.subtitle(ev)
+		if err != nil {
+			d.warns = append(d.warns, fmt.Errorf("line %d: %w", ev.line, err))
+			continue
+		}
+		if sub.text != "" {
+			d.subtitles = append(d.subtitles, sub)
+		}
+	}
+	sort.SliceStable(d.subtitles, func(i, j int) bool {
+		return d.subtitles[i].in < d.subtitles[j].in
+	})
+	return d, nil
+}
+
+// subtitle is the subtitle of a Dialogue or Comment event, before the
+// encoding of its text.
+type subtitle struct {
+	line    int
+	in, out int // Start and end times, in centiseconds
+	vp      int // Vertical Position (VP)
+	jc      stl.JustificationCode
+	comment bool
+	text    string // Text Field (TF), UTF-8 encoded
+}
+
+type decoder struct {
+	framerate    uint
+	start        stl.Timecode // Timecode of the script time 0:00:00.00
+	playResY     int
+	legacy       bool // SSA v4 styles
+	info         map[string]string
+	styles       map[string]Style
+	stylesFormat []string
+	eventsFormat []string
+	events       []rawEvent
+	subtitles    []subtitle // Subtitles of the events with text, in order of time
+	warns        []error
+}
+
+// rawEvent is a Dialogue or Comment line of the [Events] section.
+type rawEvent struct {
+	line    int
+	comment bool
+	fields  map[string]string
+}
+
+// parse reads the sections of the script.
+func (d *decoder) parse(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	var section string
+	var n int
+	for sc.Scan() {
+		n++
+		line := strings.TrimSpace(sc.Text())
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(line)
+			if section == "[v4 styles]" {
+				d.legacy = true
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			d.warns = append(d.warns, fmt.Errorf("line %d: invalid line %q", n, line))
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch section {
+		case "[script info]":
+			d.info[key] = value
+		case "[v4+ styles]", "[v4 styles]":
+			switch key {
+			case "Format":
+				d.stylesFormat = splitFormat(value)
+			case "Style":
+				if d.stylesFormat == nil {
+					d.warns = append(d.warns, fmt.Errorf("line %d: style before format", n))
+					continue
+				}
+				s, err := d.style(fieldsOf(d.stylesFormat, value))
+				if err != nil {
+					d.warns = append(d.warns, fmt.Errorf("line %d: %w", n, err))
+					continue
+				}
+				d.styles[s.Name] = s
+			}
+		case "[events]":
+			switch key {
+			case "Format":
+				d.eventsFormat = splitFormat(value)
+			case "Dialogue", "Comment":
+				if d.eventsFormat == nil {
+					d.warns = append(d.warns, fmt.Errorf("line %d: event before format", n))
+					continue
+				}
+				d.events = append(d.events, rawEvent{
+					line:    n,
+					comment: key == "Comment",
+					fields:  fieldsOf(d.eventsFormat, value),
+				})
+			}
+		}
+	}
+	return sc.Err()
+}
+
+// splitFormat returns the lowercase field names of a Format line.
+func splitFormat(s string) []string {
+	format := strings.Split(s, ",")
+	for i := range format {
+		format[i] = strings.ToLower(strings.TrimSpace(format[i]))
+	}
+	return format
+}
+
+// fieldsOf maps the values of a line to the field names of its format, the
+// last field (text) taking the rest of the line.
+func fieldsOf(format []string, s string) map[string]string {
+	values := strings.SplitN(s, ",", len(format))
+	fields := make(map[string]string, len(format))
+	for i, v := range values {
+		if format[i] != "text" {
+			v = strings.TrimSpace(v)
+		}
+		fields[format[i]] = v
+	}
+	return fields
+}
+
+func (d *decoder) style(fields map[string]string) (Style, error) {
+	s := Style{
+		Name:      fields["name"],
+		Color:     defaultStyleColor,
+		Alignment: AlignmentBottomCenter,
+	}
+	if v, ok := fields["primarycolour"]; ok {
+		c, err := parseColor(v)
+		if err != nil {
+			return s, fmt.Errorf("style %q: %w", s.Name, err)
+		}
+		s.Color = c
+	}
+	s.Italic = fields["italic"] != "" && fields["italic"] != "0"
+	s.Underline = fields["underline"] != "" && fields["underline"] != "0"
+	if v, err := strconv.ParseFloat(fields["scaley"], 64); err == nil {
+		s.DoubleHeight = v >= 150
+	}
+	if v, err := strconv.Atoi(fields["alignment"]); err == nil {
+		if d.legacy {
+			s.Alignment = legacyAlignment(v)
+		} else if v >= 1 && v <= 9 {
+			s.Alignment = Alignment(v)
+		}
+	}
+	if v, err := strconv.Atoi(fields["marginv"]); err == nil {
+		s.MarginV = v
+	}
+	return s, nil
+}
+
+// subtitle returns the subtitle of an event, without text if it has none.
+func (d *decoder) subtitle(ev rawEvent) (subtitle, error) {
+	in, err := parseTime(ev.fields["start"])
+	if err != nil {
+		return subtitle{}, err
+	}
+	out, err := parseTime(ev.fields["end"])
+	if err != nil {
+		return subtitle{}, err
+	}
+
+	name := strings.TrimPrefix(ev.fields["style"], "*")
+	style, ok := d.styles[name]
+	if !ok {
+		if name != "" && !strings.EqualFold(name, "Default") {
+			d.warns = append(d.warns, fmt.Errorf("line %d: unknown style %q", ev.line, name))
+		}
+		style = Style{Name: name, Color: defaultStyleColor, Alignment: AlignmentBottomCenter}
+	}
+
+	t := newText(style, d.styles)
+	t.parse(ev.fields["text"])
+	rows := t.rows()
+	if len(rows) == 0 {
+		return subtitle{}, nil
+	}
+
+	var tf []rune
+	span := 0
+	for i, row := range rows {
+		if i > 0 {
+			tf = append(tf, rune(stl.ControlCodeLineBreak))
+			if rows[i-1].doubleHeight {
+				tf = append(tf, rune(stl.ControlCodeLineBreak))
+			}
+		}
+		tf = append(tf, row.text...)
+		span++
+		if row.doubleHeight {
+			span++
+		}
+	}
+
+	marginV := style.MarginV
+	if v, err := strconv.Atoi(ev.fields["marginv"]); err == nil && v != 0 {
+		marginV = v
+	}
+	rowHeight := float64(d.playResY) / teletextRows
+	var vp int
+	if t.alignment.vertical() == 0 {
+		vp = int(math.Round(float64(d.playResY-marginV)/rowHeight)) - span
+	} else {
+		vp = int(math.Round(float64(marginV) / rowHeight))
+	}
+	if vp+span-1 > 23 {
+		vp = 23 - span + 1
+	}
+	if vp < 1 {
+		vp = 1
+	}
+
+	e := subtitle{line: ev.line, in: in, out: out, vp: vp, comment: ev.comment, text: string(tf)}
+	switch t.alignment.horizontal() {
+	case 1:
+		e.jc = stl.JustificationCodeLeftJustifiedText
+	case 3:
+		e.jc = stl.JustificationCodeRightJustifiedText
+	default:
+		e.jc = stl.JustificationCodeCenteredText
+	}
+	return e, nil
+}
+
+// textState is the presentation state of the text of an event.
+type textState struct {
+	color        stl.TeletextColor
+	italic       bool
+	underline    bool
+	doubleHeight bool
+}
+
+func styleState(s Style) textState {
+	return textState{
+		color:        s.Color,
+		italic:       s.Italic,
+		underline:    s.Underline,
+		doubleHeight: s.DoubleHeight,
+	}
+}
+
+// textRow is a row of the Text Field (TF) of a subtitle.
+type textRow struct {
+	text         []rune
+	doubleHeight bool
+}
+
+// text converts the text of an event to Text Field (TF) rows.
+type text struct {
+	style     Style
+	styles    map[string]Style
+	alignment Alignment
+	cur       textState // state requested by the override tags
+	out       textState // state coded in the Text Field
+	done      []textRow
+	row       textRow
+	started   bool
+}
+
+func newText(style Style, styles map[string]Style) *text {
+	return &text{
+		style:     style,
+		styles:    styles,
+		alignment: style.Alignment,
+		cur:       styleState(style),
+	}
+}
+
+func (t *text) parse(s string) {
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		switch r := rs[i]; {
+		case r == '{':
+			rest := string(rs[i+1:])
+			end := strings.IndexRune(rest, '}')
+			if end < 0 {
+				t.put(r)
+				continue
+			}
+			t.tags(rest[:end])
+			i += len([]rune(rest[:end])) + 1
+		case r == '\\' && i+1 < len(rs):
+			switch rs[i+1] {
+			case 'N':
+				t.newRow()
+			case 'n', 'h':
+				t.put(' ')
+			default:
+				t.put(r)
+				continue
+			}
+			i++
+		default:
+			t.put(r)
+		}
+	}
+	t.newRow()
+}
+
+// tags applies the override tags of a {...} block.
+func (t *text) tags(block string) {
+	for _, tag := range strings.Split(block, "\\") {
+		tag = strings.TrimSpace(tag)
+		switch {
+		case tag == "":
+		case tag == "i0", tag == "i1":
+			t.cur.italic = tag == "i1"
+		case tag == "i":
+			t.cur.italic = t.style.Italic
+		case tag == "u0", tag == "u1":
+			t.cur.underline = tag == "u1"
+		case tag == "u":
+			t.cur.underline = t.style.Underline
+		case tag == "c", tag == "1c":
+			t.cur.color = t.style.Color
+		case strings.HasPrefix(tag, "c&"), strings.HasPrefix(tag, "1c&"):
+			if c, err := parseColor(tag[strings.IndexRune(tag, '&'):]); err == nil {
+				t.cur.color = c
+			}
+		case strings.HasPrefix(tag, "fscy"):
+			v, err := strconv.ParseFloat(tag[4:], 64)
+			if err != nil {
+				t.cur.doubleHeight = t.style.DoubleHeight
+			} else {
+				t.cur.doubleHeight = v >= 150
+			}
+		case strings.HasPrefix(tag, "an"):
+			if v, err := strconv.Atoi(tag[2:]); err == nil && v >= 1 && v <= 9 {
+				t.alignment = Alignment(v)
+			}
+		case strings.HasPrefix(tag, "a"):
+			if v, err := strconv.Atoi(tag[1:]); err == nil {
+				t.alignment = legacyAlignment(v)
+			}
+		case strings.HasPrefix(tag, "r"):
+			style, ok := t.styles[tag[1:]]
+			if !ok {
+				style = t.style
+			}
+			t.cur = styleState(style)
+		}
+	}
+}
+
+// put appends a character to the current row, preceded by the codes of the
+// state changes.
+func (t *text) put(r rune) {
+	if !t.started {
+		if r == ' ' {
+			return
+		}
+		t.started = true
+		t.row.doubleHeight = t.cur.doubleHeight
+		if t.cur.doubleHeight {
+			t.row.text = append(t.row.text, rune(stl.TeletextControlCodeDoubleHeight))
+		}
+		if t.cur.color != stl.TeletextColorWhite {
+			t.row.text = append(t.row.text, rune(t.cur.color))
+		}
+		t.row.text = append(t.row.text, rune(stl.TeletextControlCodeStartBox), rune(stl.TeletextControlCodeStartBox))
+		t.out.color = t.cur.color
+	}
+
+	if t.cur.color != t.out.color {
+		if n := len(t.row.text); t.row.text[n-1] == ' ' {
+			// the spacing attribute takes the place of the space
+			t.row.text[n-1] = rune(t.cur.color)
+		} else {
+			t.row.text = append(t.row.text, rune(t.cur.color))
+		}
+		t.out.color = t.cur.color
+	}
+	if t.cur.italic != t.out.italic {
+		if t.cur.italic {
+			t.row.text = append(t.row.text, rune(stl.ControlCodeItalicOn))
+		} else {
+			t.row.text = append(t.row.text, rune(stl.ControlCodeItalicOff))
+		}
+		t.out.italic = t.cur.italic
+	}
+	if t.cur.underline != t.out.underline {
+		if t.cur.underline {
+			t.row.text = append(t.row.text, rune(stl.ControlCodeUnderlineOn))
+		} else {
+			t.row.text = append(t.row.text, rune(stl.ControlCodeUnderlineOff))
+		}
+		t.out.underline = t.cur.underline
+	}
+	t.row.text = append(t.row.text, r)
+}
+
+func (t *text) newRow() {
+	for n := len(t.row.text); n > 0 && t.row.text[n-1] == ' '; n-- {
+		t.row.text = t.row.text[:n-1]
+	}
+	t.done = append(t.done, t.row)
+	t.row = textRow{}
+	t.started = false
+}
+
+// rows returns the rows of the text without leading and trailing empty
+// rows.
+func (t *text) rows() []textRow {
+	rows := t.done
+	for len(rows) > 0 && len(rows[0].text) == 0 {
+		rows = rows[1:]
+	}
+	for len(rows) > 0 && len(rows[len(rows)-1].text) == 0 {
+		rows = rows[:len(rows)-1]
+	}
+	return rows
+}
@@ -0,0 +1,138 @@
+package ass
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/si0ls/subs/doc"
+	"github.com/si0ls/subs/stl"
+)
+
+func init() {
+	doc.Register(doc.Format{
+		Name:       "ass",
+		Extensions: []string{".ass", ".ssa"},
+		Decode: func(r io.Reader) (*doc.Document, []error, error) {
+			return DecodeDocument(r, Options{})
+		},
+		Encode: doc.EncodeSTL(func(w io.Writer, f *stl.File) ([]error, error) {
+			return Encode(w, f, Options{})
+		}, doc.Options{AutoCCT: true}),
+	})
+}
+
+// DecodeDocument reads an ASS or SSA script from r and returns its events
+// as a document.
+//
+// Events are read as by Decode, their times being kept to the centisecond
+// rather than rounded to frames and their text in UTF-8. The Vertical
+// Position (VP) and the Justification Code (JC) of the subtitles give the
+// regions of the cues; the framerate of the options only applies to the
+// "Timecode Start" script info, giving the start of the document.
+func DecodeDocument(r io.Reader, opts Options) (*doc.Document, []error, error) {
+	d, err := read(r, opts)
+	if err != nil {
+		return nil, d.warns, err
+	}
+
+	start := d.start.ToDuration(d.framerate)
+	document := &doc.Document{}
+	document.Metadata = doc.Metadata{
+		Title:         d.info["Title"],
+		OriginalTitle: d.info[KeyOriginalTitle],
+		Translator:    d.info["Original Translation"],
+		Start:         start,
+	}
+	regions := map[[2]int]*doc.Region{}
+	for _, sub := range d.subtitles {
+		key := [2]int{sub.vp, int(sub.jc)}
+		region, ok := regions[key]
+		if !ok {
+			region = &doc.Region{
+				ID:  fmt.Sprintf("region%d", len(document.Regions)+1),
+				Top: float64(sub.vp) / teletextRows,
+			}
+			switch sub.jc {
+			case stl.JustificationCodeLeftJustifiedText:
+				region.Align = doc.AlignLeft
+			case stl.JustificationCodeRightJustifiedText:
+				region.Align = doc.AlignRight
+			default:
+				region.Align = doc.AlignCenter
+			}
+			regions[key] = region
+			document.Regions = append(document.Regions, region)
+		}
+		document.Cues = append(document.Cues, &doc.Cue{
+			Start:   start + time.Duration(sub.in)*10*time.Millisecond,
+			End:     start + time.Duration(sub.out)*10*time.Millisecond,
+			Region:  region,
+			Lines:   lines(sub.text),
+			Comment: sub.comment,
+		})
+	}
+	return document, d.warns, nil
+}
+
+// lines returns the lines of the Text Field (TF) of a subtitle, before
+// encoding. Teletext spacing attributes following text are read as spaces,
+// as they are displayed, and the empty rows under double height rows are
+// dropped.
+func lines(tf string) []doc.Line {
+	var lines []doc.Line
+	var italic, underline bool
+	for _, row := range strings.Split(tf, string(rune(stl.ControlCodeLineBreak))) {
+		if row == "" {
+			continue
+		}
+		var l doc.Line
+		style := doc.DefaultStyle
+		put := func(r rune) {
+			s := style
+			s.Italic, s.Underline = italic, underline
+			if n := len(l); n > 0 && l[n-1].Style == s {
+				l[n-1].Text += string(r)
+				return
+			}
+			l = append(l, doc.Span{Text: string(r), Style: s})
+		}
+		for _, r := range row {
+			switch {
+			case r <= rune(stl.TeletextControlCodeAlphaWhite):
+				if len(l) > 0 {
+					put(' ')
+				}
+				style.Color = teletextColor(stl.TeletextColor(r))
+			case r == rune(stl.TeletextControlCodeDoubleHeight):
+				style.DoubleHeight = true
+			case r < 0x20:
+			case r == rune(stl.ControlCodeItalicOn), r == rune(stl.ControlCodeItalicOff):
+				italic = r == rune(stl.ControlCodeItalicOn)
+			case r == rune(stl.ControlCodeUnderlineOn), r == rune(stl.ControlCodeUnderlineOff):
+				underline = r == rune(stl.ControlCodeUnderlineOn)
+			default:
+				put(r)
+			}
+		}
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+// teletextColor returns the RGB color of a Teletext color.
+func teletextColor(c stl.TeletextColor) color.NRGBA {
+	rgb := color.NRGBA{A: 0xFF}
+	if c&1 != 0 {
+		rgb.R = 0xFF
+	}
+	if c&2 != 0 {
+		rgb.G = 0xFF
+	}
+	if c&4 != 0 {
+		rgb.B = 0xFF
+	}
+	return rgb
+}
@@ -0,0 +1,310 @@
+package ass
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/si0ls/subs/stl"
+)
+
+// StylesFormat is the format of the styles written in the [V4+ Styles]
+// section.
+const StylesFormat = "Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding"
+
+// EventsFormat is the format of the events written in the [Events]
+// section.
+const EventsFormat = "Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text"
+
+// Encode writes f as an ASS script to w.
+//
+// Each subtitle is written as a dialogue event, translator's comments as
+// comment events. The script resolution is 720x576 for 25 fps files and
+// 720x480 for 30 fps files, divided in the 24 rows of a Teletext page:
+// events are aligned on the rows of the subtitle (bottom, middle or top of
+// the screen according to its Vertical Position (VP)) and their vertical
+// margin gives back the VP on import.
+//
+// Events use the style of the color and height of their first row, other
+// rows and mid-row color changes are coded with override tags. Teletext
+// boxing is rendered with an opaque box (border style 3) and flash is
+// dropped. Rows are trimmed and empty rows are dropped.
+func Encode(w io.Writer, f *stl.File, opts Options) ([]error, error) {
+	if f.GSI == nil {
+		return nil, ErrNilGSI
+	}
+	framerate := f.GSI.Framerate()
+	if framerate == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFramerate, f.GSI.DFC)
+	}
+	start := f.GSI.TCP
+	if opts.Start != nil {
+		start = *opts.Start
+	}
+	if start.Hours < 0 {
+		start = stl.Timecode{}
+	}
+
+	e := &encoder{
+		gsi:       f.GSI,
+		textOrder: f.TextOrder,
+		framerate: framerate,
+		playResY:  576,
+		styles:    map[string]Style{},
+	}
+	if framerate == 30 {
+		e.playResY = 480
+	}
+	e.style(defaultStyleColor, false)
+
+	var warns []error
+	var events []string
+	for _, tti := range f.Subtitles() {
+		in := tti.TCI.ToFrames(framerate) - start.ToFrames(framerate)
+		out := tti.TCO.ToFrames(framerate) - start.ToFrames(framerate)
+		if in < 0 {
+			warns = append(warns, fmt.Errorf("subtitle %d/%d: starts before %s, dropped", tti.SGN, tti.SN, start))
+			continue
+		}
+		ev, err := e.event(tti)
+		if err != nil {
+			warns = append(warns, fmt.Errorf("subtitle %d/%d: %w", tti.SGN, tti.SN, err))
+			continue
+		}
+		if ev == nil {
+			continue
+		}
+		kind := "Dialogue"
+		if tti.CF == stl.CommentFlagTranslatorComments {
+			kind = "Comment"
+		}
+		events = append(events, fmt.Sprintf("%s: 0,%s,%s,%s,,0,0,%d,,%s",
+			kind, formatTime(in, framerate), formatTime(out, framerate), ev.style, ev.marginV, ev.text))
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, "[Script Info]\n; Converted from EBU STL\n")
+	title := f.GSI.TPT
+	if title == "" {
+		title = f.GSI.OPT
+	}
+	writeInfo(bw, "Title", title)
+	writeInfo(bw, KeyOriginalTitle, f.GSI.OPT)
+	writeInfo(bw, "Original Translation", f.GSI.TN)
+	writeInfo(bw, "ScriptType", "v4.00+")
+	writeInfo(bw, "WrapStyle", "2")
+	writeInfo(bw, "ScaledBorderAndShadow", "yes")
+	writeInfo(bw, "PlayResX", fmt.Sprint(playResX))
+	writeInfo(bw, "PlayResY", fmt.Sprint(e.playResY))
+	writeInfo(bw, KeyTimecodeStart, start.String())
+
+	fmt.Fprintf(bw, "\n[V4+ Styles]\nFormat: %s\n", StylesFormat)
+	rowHeight := e.playResY / teletextRows
+	for _, name := range e.order {
+		s := e.styles[name]
+		scaleY := 100
+		if s.DoubleHeight {
+			scaleY = 200
+		}
+		fmt.Fprintf(bw, "Style: %s,Arial,%d,%s,&H000000FF,%s,&H80000000,0,0,0,0,100,%d,0,0,3,1,0,%d,40,40,%d,1\n",
+			s.Name, rowHeight, formatColor(s.Color, 0), formatColor(stl.TeletextColorBlack, 0), scaleY, s.Alignment, s.MarginV)
+	}
+
+	fmt.Fprintf(bw, "\n[Events]\nFormat: %s\n", EventsFormat)
+	for _, ev := range events {
+		fmt.Fprintf(bw, "%s\n", ev)
+	}
+	return warns, bw.Flush()
+}
+
+func writeInfo(w io.Writer, key, value string) {
+	if value != "" {
+		fmt.Fprintf(w, "%s: %s\n", key, value)
+	}
+}
+
+type encoder struct {
+	gsi       *stl.GSIBlock
+	textOrder stl.TextOrder
+	framerate uint
+	playResY  int
+	styles    map[string]Style
+	order     []string
+}
+
+type event struct {
+	style   string
+	marginV int
+	text    string
+}
+
+// style returns the name of the style of a Teletext color and height,
+// adding it to the script styles.
+func (e *encoder) style(c stl.TeletextColor, doubleHeight bool) string {
+	name := styleName(c, doubleHeight)
+	if _, ok := e.styles[name]; !ok {
+		e.styles[name] = Style{
+			Name:         name,
+			Color:        c,
+			DoubleHeight: doubleHeight,
+			Alignment:    AlignmentBottomCenter,
+			MarginV:      e.playResY / teletextRows,
+		}
+		e.order = append(e.order, name)
+	}
+	return name
+}
+
+// segment is a piece of text of a row sharing the same style.
+type segment struct {
+	text  string
+	style stl.TextStyle
+}
+
+// event returns the event of the subtitle, nil if it has no text.
+func (e *encoder) event(tti *stl.TTIBlock) (*event, error) {
+	textRows, err := tti.RowsOrdered(e.gsi.CCT, e.textOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]segment
+	first, last := -1, -1
+	for i, row := range textRows {
+		segs := rowSegments(row)
+		rows = append(rows, segs)
+		if len(segs) == 0 {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		last = i
+	}
+	if first < 0 {
+		return nil, nil
+	}
+	base := rows[first][0].style
+
+	teletext := e.gsi.DSC == stl.DisplayStandardCodeLevel1Teletext || e.gsi.DSC == stl.DisplayStandardCodeLevel2Teletext
+	vp := tti.VP
+	if !teletext && e.gsi.MNR > 0 {
+		vp = vp * 23 / e.gsi.MNR
+	}
+	top, bottom := vp+first, vp+last
+	if rows[last][0].style.DoubleHeight {
+		bottom++
+	}
+
+	rowHeight := e.playResY / teletextRows
+	vertical, marginV := 0, 0
+	switch mid := (top + bottom) / 2; {
+	case tti.VP == 0:
+		// no position, the style margin applies
+	case mid < 8:
+		vertical, marginV = 2, top*rowHeight
+	case mid < 16:
+		vertical, marginV = 1, top*rowHeight
+	default:
+		marginV = e.playResY - (bottom+1)*rowHeight
+	}
+	if marginV < 0 {
+		marginV = 0
+	}
+	horizontal := 2
+	switch tti.JC {
+	case stl.JustificationCodeLeftJustifiedText:
+		horizontal = 1
+	case stl.JustificationCodeRightJustifiedText:
+		horizontal = 3
+	}
+
+	ev := &event{
+		style:   e.style(base.Foreground, base.DoubleHeight),
+		marginV: marginV,
+	}
+	var sb strings.Builder
+	if an := alignment(horizontal, vertical); an != AlignmentBottomCenter {
+		fmt.Fprintf(&sb, "{\\an%d}", an)
+	}
+	cur := stl.TextStyle{Foreground: base.Foreground, DoubleHeight: base.DoubleHeight}
+	n := 0
+	for _, segs := range rows {
+		if len(segs) == 0 {
+			continue
+		}
+		if n > 0 {
+			sb.WriteString("\\N")
+		}
+		n++
+		var tags string
+		if dh := segs[0].style.DoubleHeight; dh != cur.DoubleHeight {
+			if dh {
+				tags += "\\fscy200"
+			} else {
+				tags += "\\fscy100"
+			}
+			cur.DoubleHeight = dh
+		}
+		for _, seg := range segs {
+			if seg.style.Foreground != cur.Foreground {
+				tags += fmt.Sprintf("\\c&H%06X&", colorBGR(seg.style.Foreground))
+				cur.Foreground = seg.style.Foreground
+			}
+			if seg.style.Italic != cur.Italic {
+				tags += "\\i" + boolTag(seg.style.Italic)
+				cur.Italic = seg.style.Italic
+			}
+			if seg.style.Underline != cur.Underline {
+				tags += "\\u" + boolTag(seg.style.Underline)
+				cur.Underline = seg.style.Underline
+			}
+			if tags != "" {
+				sb.WriteString("{" + tags + "}")
+				tags = ""
+			}
+			sb.WriteString(seg.text)
+		}
+	}
+	ev.text = sb.String()
+	return ev, nil
+}
+
+func boolTag(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// rowSegments returns the segments of a text row without leading and
+// trailing spaces. The character cells of spacing attributes between runs
+// are kept as spaces.
+func rowSegments(row stl.TextRow) []segment {
+	var segs []segment
+	column := -1
+	for _, run := range row {
+		if column >= 0 && run.Column > column && len(segs) > 0 {
+			segs[len(segs)-1].text += strings.Repeat(" ", run.Column-column)
+		}
+		segs = append(segs, segment{text: run.Text, style: run.Style})
+		column = run.Column + utf8.RuneCountInString(run.Text)
+	}
+
+	for len(segs) > 0 {
+		if segs[0].text = strings.TrimLeft(segs[0].text, " "); segs[0].text != "" {
+			break
+		}
+		segs = segs[1:]
+	}
+	for len(segs) > 0 {
+		n := len(segs) - 1
+		if segs[n].text = strings.TrimRight(segs[n].text, " "); segs[n].text != "" {
+			break
+		}
+		segs = segs[:n]
+	}
+	return segs
+}
@@ -0,0 +1,262 @@
+// Package avid reads Avid DS caption files as STL files.
+//
+// An Avid DS caption file is a text file where lines starting with '@' are
+// comments and the captions are enclosed between the "<begin subtitles>"
+// and "<end subtitles>" lines:
+//
+//	@ This file written with the Avid Caption plugin, version 1
+//
+//	<begin subtitles>
+//
+//	10:00:01:05 10:00:03:00
+//	First line
+//	<I>second line</I>
+//
+//	<end subtitles>
+//
+// Each caption is made of the time codes in and out of the caption,
+// followed by its lines up to the next empty line. Time codes use ';' as
+// last separator for drop frame time codes.
+package avid
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/si0ls/subs/stl"
+)
+
+// Markers of the captions of an Avid DS caption file.
+const (
+	BeginSubtitles = "<begin subtitles>"
+	EndSubtitles   = "<end subtitles>"
+)
+
+// Tags of the text of a caption.
+const (
+	TagItalicOn     = "<i>"
+	TagItalicOff    = "</i>"
+	TagUnderlineOn  = "<u>"
+	TagUnderlineOff = "</u>"
+)
+
+const defaultFramerate = 25
+
+var (
+	timecodes = regexp.MustCompile(`^(\d{2}):(\d{2}):(\d{2})([:;.])(\d{2})\s+(\d{2}):(\d{2}):(\d{2})([:;.])(\d{2})$`)
+	tag       = regexp.MustCompile(`<[^<>]*>`)
+)
+
+// Options configures an Avid DS caption import.
+type Options struct {
+	Framerate uint         // Framerate of the time codes and of the STL file, 25 or 30 (default 25)
+	Fallback  stl.Fallback // Fallback for characters the Character Code Table (CCT) can not represent (default replace)
+}
+
+var (
+	ErrUnsupportedFramerate = errors.New("unsupported framerate")
+	ErrNoSubtitles          = errors.New("no subtitles")
+)
+
+// Decode reads an Avid DS caption file from r and returns its captions as
+// a Level-1 Teletext STL file.
+//
+// Time codes are read at the framerate of the options, drop frame time
+// codes being kept as they are in 30 fps files and converted from 29.97 fps
+// in 25 fps files. Captions are
+// centered at the bottom of the screen, each row starting with boxing
+// codes, and italics and underlines are coded with STL control codes.
+// Other tags are removed; they are returned as warnings with the invalid
+// captions. The Character Code Table (CCT) is the one representing the
+// text of the captions, and the Language Code (LC) is guessed from it;
+// characters it can not represent are handled according to the fallback
+// and returned as warnings.
+func Decode(r io.Reader, opts Options) (*stl.File, []error, error) {
+	framerate := opts.Framerate
+	if framerate == 0 {
+		framerate = defaultFramerate
+	}
+	if framerate != 25 && framerate != 30 {
+		return nil, nil, fmt.Errorf("%w: %d", ErrUnsupportedFramerate, framerate)
+	}
+
+	f := stl.NewFile()
+	f.GSI = stl.NewGSIBlock()
+	f.GSI.SetDefaults(framerate, stl.DisplayStandardCodeLevel1Teletext)
+
+	var warns []error
+	var begun, skip bool
+	var n, line int
+	var tti *stl.TTIBlock
+	var rows []string
+	var captions []caption
+	flush := func() {
+		defer func() { tti, rows = nil, nil }()
+		if tti == nil {
+			return
+		}
+		if len(rows) == 0 {
+			warns = append(warns, fmt.Errorf("caption %d: no text", n))
+			return
+		}
+		tti.VP = 23 - len(rows)
+		captions = append(captions, caption{tti: tti, text: strings.Join(rows, string(rune(stl.ControlCodeLineBreak))), n: n})
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		switch {
+		case strings.HasPrefix(text, "@"):
+			continue
+		case strings.EqualFold(strings.TrimSpace(text), BeginSubtitles):
+			begun = true
+			continue
+		case strings.EqualFold(strings.TrimSpace(text), EndSubtitles):
+			begun = false
+			flush()
+			continue
+		case !begun:
+			continue
+		case strings.TrimSpace(text) == "":
+			flush()
+			skip = false
+			continue
+		case skip:
+			continue
+		}
+
+		if tti != nil {
+			row, errs := decodeRow(text)
+			for _, err := range errs {
+				warns = append(warns, fmt.Errorf("caption %d: %w", n, err))
+			}
+			if row != "" {
+				rows = append(rows, row)
+			}
+			continue
+		}
+
+		n++
+		m := timecodes.FindStringSubmatch(strings.TrimSpace(text))
+		if m == nil {
+			warns = append(warns, fmt.Errorf("line %d: invalid time codes %q", line, text))
+			skip = true
+			continue
+		}
+		tci, err := parseTimecode(m[1:6], framerate)
+		if err != nil {
+			warns = append(warns, fmt.Errorf("caption %d: time code in: %w", n, err))
+			skip = true
+			continue
+		}
+		tco, err := parseTimecode(m[6:11], framerate)
+		if err != nil {
+			warns = append(warns, fmt.Errorf("caption %d: time code out: %w", n, err))
+			skip = true
+			continue
+		}
+
+		tti = stl.NewTTIBlock()
+		tti.SGN = 0
+		tti.EBN = stl.EBNLastBlock
+		tti.CS = stl.CumulativeStatusNone
+		tti.TCI = tci
+		tti.TCO = tco
+		tti.JC = stl.JustificationCodeCenteredText
+		tti.CF = stl.CommentFlagSubtitleData
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, warns, err
+	}
+	flush()
+
+	texts := make([]string, len(captions))
+	for i, c := range captions {
+		texts[i] = c.text
+	}
+	f.GSI.SetCharacterCodeTable(texts...)
+	var sn int
+	for _, c := range captions {
+		unmappables, err := c.tti.SetTextFallback(c.text, f.GSI.CCT, opts.Fallback)
+		if err != nil {
+			warns = append(warns, fmt.Errorf("caption %d: %w", c.n, err))
+			continue
+		}
+		if len(unmappables) > 0 {
+			warns = append(warns, fmt.Errorf("caption %d: %w, fallback %s", c.n, &stl.UnmappableError{Runes: unmappables}, opts.Fallback))
+		}
+		c.tti.SN = sn
+		sn++
+		f.TTI = append(f.TTI, c.tti.ExtensionBlocks()...)
+	}
+	if len(f.TTI) == 0 {
+		return nil, warns, ErrNoSubtitles
+	}
+	f.UpdateCounters()
+	return f, warns, nil
+}
+
+// caption is a caption of the file, before the encoding of its text.
+type caption struct {
+	tti  *stl.TTIBlock
+	text string // Text Field (TF), UTF-8 encoded
+	n    int    // Number of the caption in the file
+}
+
+// parseTimecode parses the hours, minutes, separator, seconds and frames of
+// a time code at the framerate.
+func parseTimecode(m []string, framerate uint) (stl.Timecode, error) {
+	var v [4]int
+	for i, s := range []string{m[0], m[1], m[2], m[4]} {
+		v[i], _ = strconv.Atoi(s)
+	}
+	tc := stl.Timecode{Hours: v[0], Minutes: v[1], Seconds: v[2], Frames: v[3]}
+	if m[3] == ";" && framerate != 30 {
+		// 29.97 fps frames to frames at the framerate
+		tc = stl.TimecodeFromFrames((tc.ToDropFrames()*int(framerate)*1001+15000)/30000, framerate)
+	}
+	if err := tc.Validate(framerate); err != nil {
+		return tc, fmt.Errorf("%s: %w", tc, err)
+	}
+	return tc, nil
+}
+
+// decodeRow returns the text of a row with boxing codes and control codes,
+// and the unsupported tags.
+func decodeRow(text string) (string, []error) {
+	var errs []error
+	var sb strings.Builder
+	last := 0
+	for _, loc := range tag.FindAllStringIndex(text, -1) {
+		sb.WriteString(text[last:loc[0]])
+		last = loc[1]
+		switch strings.ToLower(text[loc[0]:loc[1]]) {
+		case TagItalicOn:
+			sb.WriteRune(rune(stl.ControlCodeItalicOn))
+		case TagItalicOff:
+			sb.WriteRune(rune(stl.ControlCodeItalicOff))
+		case TagUnderlineOn:
+			sb.WriteRune(rune(stl.ControlCodeUnderlineOn))
+		case TagUnderlineOff:
+			sb.WriteRune(rune(stl.ControlCodeUnderlineOff))
+		default:
+			errs = append(errs, fmt.Errorf("tag %s not supported, removed", text[loc[0]:loc[1]]))
+		}
+	}
+	sb.WriteString(text[last:])
+	s := strings.TrimSpace(sb.String())
+	if s == "" {
+		return "", errs
+	}
+	return string([]rune{rune(stl.TeletextControlCodeStartBox), rune(stl.TeletextControlCodeStartBox)}) + s, errs
+}
@@ -0,0 +1,112 @@
+package avid
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/si0ls/subs/stl"
+)
+
+const testFile = "\ufeff@ This file written with the Avid Caption plugin, version 1\r\n" +
+	"\r\n" +
+	"<begin subtitles>\r\n" +
+	"\r\n" +
+	"10:00:01:05 10:00:03:00\r\n" +
+	"First line\r\n" +
+	"<I>second</I> <font color=red>line</font>\r\n" +
+	"\r\n" +
+	"10:00:04:30 10:00:05:00\r\n" +
+	"Invalid time code\r\n" +
+	"\r\n" +
+	"10:00:06:00 10:00:07:10\r\n" +
+	"<u>Last</u>\r\n" +
+	"<end subtitles>\r\n"
+
+func TestDecode(t *testing.T) {
+	f, warns, err := Decode(strings.NewReader(testFile), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// invalid time code and two unsupported tags
+	if len(warns) != 3 {
+		t.Errorf("got %d warnings, want 3: %v", len(warns), warns)
+	}
+	if len(f.TTI) != 2 {
+		t.Fatalf("got %d subtitles, want 2", len(f.TTI))
+	}
+
+	for i, tc := range []struct {
+		tci, tco stl.Timecode
+		vp       int
+		text     string
+	}{
+		{stl.Timecode{Hours: 10, Seconds: 1, Frames: 5}, stl.Timecode{Hours: 10, Seconds: 3}, 21,
+			"\x0b\x0bFirst line\u008a\x0b\x0b\u0080second\u0081 line"},
+		{stl.Timecode{Hours: 10, Seconds: 6}, stl.Timecode{Hours: 10, Seconds: 7, Frames: 10}, 22,
+			"\x0b\x0b\u0082Last\u0083"},
+	} {
+		want := stl.NewTTIBlock()
+		if err := want.SetText(tc.text, stl.CharacterCodeTableLatin); err != nil {
+			t.Fatal(err)
+		}
+		got := f.TTI[i]
+		if got.TCI != tc.tci || got.TCO != tc.tco || got.VP != tc.vp || got.JC != stl.JustificationCodeCenteredText || got.TF != want.TF {
+			t.Errorf("subtitle %d: got %s-%s VP %d JC %s TF %q, want %s-%s VP %d TF %q", i,
+				got.TCI, got.TCO, got.VP, got.JC, got.TF, tc.tci, tc.tco, tc.vp, want.TF)
+		}
+	}
+}
+
+func TestDecodeCharacterCodeTable(t *testing.T) {
+	file := "<begin subtitles>\n\n" +
+		"00:00:01:00 00:00:02:00\nПривет мир\n\n" +
+		"00:00:03:00 00:00:04:00\nЁлки ♥\n" +
+		"<end subtitles>\n"
+	f, warns, err := Decode(strings.NewReader(file), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.GSI.CCT != stl.CharacterCodeTableLatinCyrillic || f.GSI.LC != stl.LanguageCodeRussian {
+		t.Errorf("CCT %s LC %s, want Latin/Cyrillic and Russian", f.GSI.CCT, f.GSI.LC)
+	}
+	if len(f.TTI) != 2 {
+		t.Fatalf("got %d subtitles, want 2", len(f.TTI))
+	}
+	for i, want := range []string{"\x0b\x0bПривет мир", "\x0b\x0bЁлки ?"} {
+		if text, err := f.TTI[i].Text(f.GSI.CCT); err != nil || text != want {
+			t.Errorf("subtitle %d: text %q (%v), want %q", i, text, err, want)
+		}
+	}
+	if len(warns) != 1 || !errors.Is(warns[0], stl.ErrUnmappableRune) || !strings.HasPrefix(warns[0].Error(), "caption 2: ") {
+		t.Errorf("warnings %v, want an unmappable rune in caption 2", warns)
+	}
+}
+
+func TestDecodeDropFrame(t *testing.T) {
+	const file = "<begin subtitles>\n00:01:00;02 00:01:01;00\nText\n<end subtitles>\n"
+	for _, tc := range []struct {
+		framerate uint
+		tci       stl.Timecode
+	}{
+		{30, stl.Timecode{Minutes: 1, Frames: 2}},
+		{25, stl.Timecode{Minutes: 1, Frames: 2}},
+	} {
+		f, warns, err := Decode(strings.NewReader(file), Options{Framerate: tc.framerate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(warns) > 0 {
+			t.Errorf("%d fps: unexpected warnings: %v", tc.framerate, warns)
+		}
+		if f.TTI[0].TCI != tc.tci {
+			t.Errorf("%d fps: got TCI %s, want %s", tc.framerate, f.TTI[0].TCI, tc.tci)
+		}
+	}
+}
+
+func TestDecodeNoSubtitles(t *testing.T) {
+	if _, _, err := Decode(strings.NewReader("@ comment\n"), Options{}); err != ErrNoSubtitles {
+		t.Errorf("got %v, want %v", err, ErrNoSubtitles)
+	}
+}
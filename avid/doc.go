@@ -0,0 +1,17 @@
+package avid
+
+import (
+	"io"
+
+	"github.com/si0ls/subs/doc"
+	"github.com/si0ls/subs/stl"
+)
+
+func init() {
+	doc.RegisterSTL("avid", []string{".txt"},
+		func(r io.Reader) (*stl.File, []error, error) {
+			return Decode(r, Options{})
+		},
+		nil,
+	)
+}
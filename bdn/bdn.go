@@ -0,0 +1,259 @@
+// Package bdn exports STL files as Sony BDN XML packages, the image based
+// subtitle format read by Blu-ray and DVD authoring tools.
+// A package is made of one PNG image per subtitle and an index XML file
+// giving the in and out timecodes and the position of each image.
+package bdn
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/si0ls/subs/render"
+	"github.com/si0ls/subs/stl"
+)
+
+// VideoFormat is the BDN video format, defining the graphics canvas size.
+type VideoFormat string
+
+const (
+	VideoFormat1080p VideoFormat = "1080p"
+	VideoFormat1080i VideoFormat = "1080i"
+	VideoFormat720p  VideoFormat = "720p"
+	VideoFormat576i  VideoFormat = "576i"
+	VideoFormat480i  VideoFormat = "480i"
+)
+
+// Size returns the canvas size of the video format.
+// Returns an empty rectangle if the video format is unknown.
+func (vf VideoFormat) Size() image.Rectangle {
+	switch vf {
+	case VideoFormat1080p, VideoFormat1080i:
+		return image.Rect(0, 0, 1920, 1080)
+	case VideoFormat720p:
+		return image.Rect(0, 0, 1280, 720)
+	case VideoFormat576i:
+		return image.Rect(0, 0, 720, 576)
+	case VideoFormat480i:
+		return image.Rect(0, 0, 720, 480)
+	}
+	return image.Rectangle{}
+}
+
+// Options configures a BDN export.
+type Options struct {
+	VideoFormat VideoFormat     // Video format (default 1080p)
+	Name        string          // Title of the package (default GSI Translated Program Title)
+	Language    string          // ISO 639-2 language code (default derived from GSI Language Code)
+	Forced      bool            // Mark all events as forced
+	XMLFile     string          // Name of the index XML file (default "bdn.xml")
+	Render      *render.Options // Rendering options (default render.DefaultOptions), frame size is set from VideoFormat
+}
+
+var (
+	ErrUnsupportedVideoFormat = errors.New("unsupported video format")
+	ErrUnsupportedFramerate   = errors.New("unsupported framerate")
+)
+
+// Export renders the subtitles of f and writes the BDN package to dir:
+// one PNG image per subtitle, cropped to the rendered text, and the index
+// XML file.
+// In and out timecodes are taken from the Time Code In (TCI) and Time Code
+// Out (TCO) of the subtitles, at the frame rate of the GSI block.
+func Export(dir string, f *stl.File, opts Options) error {
+	if f.GSI == nil {
+		return render.ErrNilGSI
+	}
+	if opts.VideoFormat == "" {
+		opts.VideoFormat = VideoFormat1080p
+	}
+	size := opts.VideoFormat.Size()
+	if size.Empty() {
+		return fmt.Errorf("%w: %s", ErrUnsupportedVideoFormat, opts.VideoFormat)
+	}
+	if opts.XMLFile == "" {
+		opts.XMLFile = "bdn.xml"
+	}
+
+	renderOpts := render.DefaultOptions()
+	if opts.Render != nil {
+		renderOpts = *opts.Render
+	}
+	renderOpts.Width, renderOpts.Height = size.Dx(), size.Dy()
+	r, err := render.New(renderOpts)
+	if err != nil {
+		return err
+	}
+
+	stills, err := r.RenderFile(f)
+	if err != nil {
+		return err
+	}
+
+	doc, err := newDocument(f.GSI, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, still := range stills {
+		bounds := render.OpaqueBounds(still.Image)
+		if bounds.Empty() {
+			continue
+		}
+		name := fmt.Sprintf("%04d.png", len(doc.Events)+1)
+		if err := render.WritePNGFile(filepath.Join(dir, name), still.Image.SubImage(bounds)); err != nil {
+			return err
+		}
+		doc.Events = append(doc.Events, Event{
+			InTC:   formatTimecode(still.TTI.TCI),
+			OutTC:  formatTimecode(still.TTI.TCO),
+			Forced: formatBool(opts.Forced),
+			Graphic: Graphic{
+				Width:  bounds.Dx(),
+				Height: bounds.Dy(),
+				X:      bounds.Min.X,
+				Y:      bounds.Min.Y,
+				File:   name,
+			},
+		})
+	}
+	doc.Description.Events.Count = len(doc.Events)
+	if len(doc.Events) > 0 {
+		doc.Description.Events.FirstInTC = doc.Events[0].InTC
+		doc.Description.Events.LastOutTC = doc.Events[len(doc.Events)-1].OutTC
+	}
+
+	file, err := os.Create(filepath.Join(dir, opts.XMLFile))
+	if err != nil {
+		return err
+	}
+	if err := doc.Encode(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func newDocument(gsi *stl.GSIBlock, opts Options) (*Document, error) {
+	var frameRate string
+	switch gsi.Framerate() {
+	case 25:
+		frameRate = "25"
+	case 30:
+		// STL30.01 timecodes count 30 frames per second, non drop-frame
+		frameRate = "30"
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedFramerate, gsi.Framerate())
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = gsi.TPT
+	}
+	if name == "" {
+		name = gsi.OPT
+	}
+	lang := opts.Language
+	if lang == "" {
+		lang = gsi.LC.ISO639_2()
+	}
+
+	doc := &Document{Version: "0.93"}
+	doc.Description.Name = Name{Title: name}
+	doc.Description.Language = Language{Code: lang}
+	doc.Description.Format = Format{
+		VideoFormat: string(opts.VideoFormat),
+		FrameRate:   frameRate,
+		DropFrame:   formatBool(false),
+	}
+	doc.Description.Events.Type = "Graphic"
+	return doc, nil
+}
+
+func formatTimecode(tc stl.Timecode) string {
+	return fmt.Sprintf("%02d:%02d:%02d:%02d", tc.Hours, tc.Minutes, tc.Seconds, tc.Frames)
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "True"
+	}
+	return "False"
+}
+
+// Document is the BDN index XML document.
+type Document struct {
+	XMLName     xml.Name    `xml:"BDN"`
+	Version     string      `xml:"Version,attr"`
+	Description Description `xml:"Description"`
+	Events      []Event     `xml:"Events>Event"`
+}
+
+// Description is the description of a BDN document.
+type Description struct {
+	Name     Name     `xml:"Name"`
+	Language Language `xml:"Language"`
+	Format   Format   `xml:"Format"`
+	Events   Events   `xml:"Events"`
+}
+
+// Name is the title of a BDN document.
+type Name struct {
+	Title   string `xml:"Title,attr"`
+	Content string `xml:"Content,attr"`
+}
+
+// Language is the language of a BDN document.
+type Language struct {
+	Code string `xml:"Code,attr"`
+}
+
+// Format is the video format of a BDN document.
+type Format struct {
+	VideoFormat string `xml:"VideoFormat,attr"`
+	FrameRate   string `xml:"FrameRate,attr"`
+	DropFrame   string `xml:"DropFrame,attr"`
+}
+
+// Events summarizes the events of a BDN document.
+type Events struct {
+	Type      string `xml:"Type,attr"`
+	FirstInTC string `xml:"FirstEventInTC,attr"`
+	LastOutTC string `xml:"LastEventOutTC,attr"`
+	Count     int    `xml:"NumberofEvents,attr"`
+}
+
+// Event is a subtitle of a BDN document.
+type Event struct {
+	InTC    string  `xml:"InTC,attr"`
+	OutTC   string  `xml:"OutTC,attr"`
+	Forced  string  `xml:"Forced,attr"`
+	Graphic Graphic `xml:"Graphic"`
+}
+
+// Graphic is the image of an event, positioned on the canvas.
+type Graphic struct {
+	Width  int    `xml:"Width,attr"`
+	Height int    `xml:"Height,attr"`
+	X      int    `xml:"X,attr"`
+	Y      int    `xml:"Y,attr"`
+	File   string `xml:",chardata"`
+}
+
+// Encode writes the XML encoding of the document to w, with an XML header.
+func (doc *Document) Encode(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,104 @@
+package bdn
+
+import (
+	"encoding/xml"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/si0ls/subs/internal/stltest"
+	"github.com/si0ls/subs/stl"
+)
+
+// testFile returns an open subtitling file whose second subtitle is empty,
+// which is not exported.
+func testFile(t *testing.T) *stl.File {
+	d := stltest.File{}
+	for i, text := range []string{"Bonjour", "", "Au revoir"} {
+		d.Subtitles = append(d.Subtitles, stltest.Subtitle{
+			TCI:  stl.Timecode{Hours: 10, Seconds: 2 * i, Frames: 5},
+			TCO:  stl.Timecode{Hours: 10, Seconds: 2*i + 1, Frames: 20},
+			VP:   20,
+			JC:   stl.JustificationCodeCenteredText,
+			Text: text,
+		})
+	}
+	f := d.New(t)
+	f.GSI.DSC = stl.DisplayStandardCodeOpenSubtitling
+	f.GSI.LC = stl.LanguageCodeFrench
+	f.GSI.TPT = "Programme"
+	return f
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	if err := Export(dir, testFile(t), Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "bdn.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc Document
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Description.Format.FrameRate != "25" || doc.Description.Format.VideoFormat != "1080p" {
+		t.Errorf("unexpected format %+v", doc.Description.Format)
+	}
+	if doc.Description.Language.Code != "fre" || doc.Description.Name.Title != "Programme" {
+		t.Errorf("unexpected description %+v", doc.Description)
+	}
+	// empty subtitle is skipped
+	if len(doc.Events) != 2 || doc.Description.Events.Count != 2 {
+		t.Fatalf("expected 2 events, got %d", len(doc.Events))
+	}
+	if doc.Events[1].InTC != "10:00:04:05" || doc.Events[1].OutTC != "10:00:05:20" {
+		t.Errorf("unexpected timecodes %s-%s", doc.Events[1].InTC, doc.Events[1].OutTC)
+	}
+	if doc.Description.Events.FirstInTC != "10:00:00:05" || doc.Description.Events.LastOutTC != "10:00:05:20" {
+		t.Errorf("unexpected events summary %+v", doc.Description.Events)
+	}
+
+	for _, event := range doc.Events {
+		file, err := os.Open(filepath.Join(dir, event.Graphic.File))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := png.DecodeConfig(file)
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Width != event.Graphic.Width || cfg.Height != event.Graphic.Height {
+			t.Errorf("%s: image size %dx%d, XML size %dx%d", event.Graphic.File, cfg.Width, cfg.Height, event.Graphic.Width, event.Graphic.Height)
+		}
+		if event.Graphic.Y < 1080/2 || event.Graphic.X+event.Graphic.Width > 1920 {
+			t.Errorf("%s: unexpected position %+v", event.Graphic.File, event.Graphic)
+		}
+	}
+}
+
+func TestFrameRate(t *testing.T) {
+	tests := []struct {
+		dfc       stl.DiskFormatCode
+		frameRate string
+	}{
+		{stl.DiskFormatCode25_01, "25"},
+		{stl.DiskFormatCode30_01, "30"},
+	}
+	for _, test := range tests {
+		gsi := testFile(t).GSI
+		gsi.DFC = test.dfc
+		doc, err := newDocument(gsi, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Description.Format.FrameRate != test.frameRate || doc.Description.Format.DropFrame != "False" {
+			t.Errorf("%s: expected frame rate %s non drop-frame but got %+v", test.dfc, test.frameRate, doc.Description.Format)
+		}
+	}
+}
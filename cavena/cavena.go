@@ -0,0 +1,92 @@
+// Package cavena converts STL files to and from Cavena 890 subtitle files.
+//
+// A Cavena 890 file starts with a 455 bytes header, holding the
+// translated program title at offset 40 (28 bytes) and the language
+// identifier, which selects the code page of the text, at offset 146. It
+// is followed by one 128 bytes block per subtitle:
+//
+//	offset  size  content
+//	0       2     subtitle number (big endian)
+//	2       3     time code in, in frames (big endian)
+//	5       3     time code out, in frames (big endian)
+//	16      51    first row
+//	73      51    second row
+//
+// Rows are padded with 0x7F, a single row subtitle uses the second row.
+// Italics start with 0x88 and end with 0x98.
+package cavena
+
+import (
+	"errors"
+
+	"github.com/si0ls/subs/stl"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Sizes of the parts of a Cavena 890 file.
+const (
+	HeaderSize = 455
+	BlockSize  = 128
+	RowSize    = 51
+)
+
+const (
+	titleOffset      = 40
+	titleSize        = 28
+	languageOffset   = 146
+	firstRowOffset   = 16
+	secondRowOffset  = 73
+	padding          = 0x7F
+	italicOn         = 0x88
+	italicOff        = 0x98
+	maxRows          = 2
+	defaultFramerate = 25
+)
+
+// Language identifiers of the header.
+const (
+	LanguageDanish  = 0x07
+	LanguageEnglish = 0x09
+	LanguageArabic  = 0x80
+	LanguageHebrew  = 0x90
+)
+
+// language is a language of a Cavena 890 file, with its code page.
+type language struct {
+	lc       stl.LanguageCode
+	cct      stl.CharacterCodeTable
+	codePage *charmap.Charmap
+}
+
+var languages = map[byte]language{
+	LanguageDanish:  {stl.LanguageCodeDanish, stl.CharacterCodeTableLatin, charmap.ISO8859_1},
+	LanguageEnglish: {stl.LanguageCodeEnglish, stl.CharacterCodeTableLatin, charmap.ISO8859_1},
+	LanguageArabic:  {stl.LanguageCodeArabic, stl.CharacterCodeTableLatinArabic, charmap.ISO8859_6},
+	LanguageHebrew:  {stl.LanguageCodeHebrew, stl.CharacterCodeTableLatinHebrew, charmap.ISO8859_8},
+}
+
+// languageID returns the language identifier of the Language Code (LC)
+// and Character Code Table (CCT) of a GSI block.
+func languageID(gsi *stl.GSIBlock) byte {
+	switch gsi.CCT {
+	case stl.CharacterCodeTableLatinArabic:
+		return LanguageArabic
+	case stl.CharacterCodeTableLatinHebrew:
+		return LanguageHebrew
+	}
+	if gsi.LC == stl.LanguageCodeDanish {
+		return LanguageDanish
+	}
+	return LanguageEnglish
+}
+
+// Options configures a Cavena 890 conversion.
+type Options struct {
+	Framerate uint // Framerate of the STL file created on import, 25 or 30 (default 25)
+}
+
+var (
+	ErrNilGSI               = errors.New("nil GSI block")
+	ErrUnsupportedFramerate = errors.New("unsupported framerate")
+	ErrInvalidHeader        = errors.New("invalid Cavena 890 header")
+)
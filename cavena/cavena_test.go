@@ -0,0 +1,103 @@
+package cavena
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/si0ls/subs/internal/stltest"
+	"github.com/si0ls/subs/stl"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		lc   stl.LanguageCode
+		cct  stl.CharacterCodeTable
+		vp   int
+		text string
+	}{
+		{stl.LanguageCodeEnglish, stl.CharacterCodeTableLatin, 21, "\x0b\x0b\u0080Café\u0081\u008a\x0b\x0b\u0080crème\u0081"},
+		{stl.LanguageCodeHebrew, stl.CharacterCodeTableLatinHebrew, 22, "\x0b\x0bשלום"},
+		{stl.LanguageCodeArabic, stl.CharacterCodeTableLatinArabic, 21, "\x0b\x0bمرحبا\u008a\x0b\x0bبكم"},
+	} {
+		f := stltest.File{CCT: tc.cct, Subtitles: []stltest.Subtitle{{
+			TCI:  stl.Timecode{Hours: 10, Seconds: 2, Frames: 3},
+			TCO:  stl.Timecode{Hours: 10, Seconds: 4, Frames: 24},
+			VP:   tc.vp,
+			JC:   stl.JustificationCodeCenteredText,
+			Text: tc.text,
+		}}}.New(t)
+		f.GSI.LC = tc.lc
+		f.GSI.TPT = "Title"
+		tti := f.TTI[0]
+
+		var buf bytes.Buffer
+		warns, err := Encode(&buf, f, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(warns) > 0 {
+			t.Errorf("%q: unexpected warnings: %v", tc.text, warns)
+		}
+		if buf.Len() != HeaderSize+BlockSize {
+			t.Fatalf("%q: got %d bytes, want %d", tc.text, buf.Len(), HeaderSize+BlockSize)
+		}
+		// 10:00:02:03 is frame 900053
+		if block := buf.Bytes()[HeaderSize:]; !bytes.Equal(block[:5], []byte{0x00, 0x01, 0x0D, 0xBB, 0xD5}) {
+			t.Errorf("%q: block header % X", tc.text, block[:5])
+		}
+
+		got, warns, err := Decode(&buf, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(warns) > 0 {
+			t.Errorf("%q: unexpected warnings: %v", tc.text, warns)
+		}
+		if got.GSI.LC != tc.lc || got.GSI.CCT != tc.cct || got.GSI.TPT != f.GSI.TPT {
+			t.Errorf("%q: GSI LC %s CCT %s TPT %q", tc.text, got.GSI.LC, got.GSI.CCT, got.GSI.TPT)
+		}
+		if len(got.TTI) != 1 {
+			t.Fatalf("%q: got %d subtitles, want 1", tc.text, len(got.TTI))
+		}
+		g := got.TTI[0]
+		if g.TCI != tti.TCI || g.TCO != tti.TCO || g.VP != tti.VP || g.TF != tti.TF {
+			t.Errorf("%q: got %s-%s VP %d TF %q, want %s-%s VP %d TF %q", tc.text,
+				g.TCI, g.TCO, g.VP, g.TF, tti.TCI, tti.TCO, tti.VP, tti.TF)
+		}
+	}
+}
+
+func TestEncodeTextOrder(t *testing.T) {
+	for _, tc := range []struct {
+		lc   stl.LanguageCode
+		cct  stl.CharacterCodeTable
+		text string
+	}{
+		{stl.LanguageCodeHebrew, stl.CharacterCodeTableLatinHebrew, "\x0b\x0bשלום עולם"},
+		{stl.LanguageCodeArabic, stl.CharacterCodeTableLatinArabic, "\x0b\x0bمرحبا بكم"},
+	} {
+		f := stltest.File{CCT: tc.cct, TextOrder: stl.TextOrderVisual, Subtitles: []stltest.Subtitle{{
+			TCI:  stl.Timecode{Seconds: 1},
+			TCO:  stl.Timecode{Seconds: 2},
+			VP:   22,
+			JC:   stl.JustificationCodeCenteredText,
+			Text: tc.text,
+		}}}.New(t)
+		f.GSI.LC = tc.lc
+
+		var buf bytes.Buffer
+		if _, err := Encode(&buf, f, Options{}); err != nil {
+			t.Fatal(err)
+		}
+		got, _, err := Decode(&buf, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.TTI) != 1 {
+			t.Fatalf("%q: got %d subtitles, want 1", tc.text, len(got.TTI))
+		}
+		if text, err := got.TTI[0].TextOrdered(got.GSI.CCT, got.TextOrder); err != nil || text != tc.text {
+			t.Errorf("text %q (%v), want %q", text, err, tc.text)
+		}
+	}
+}
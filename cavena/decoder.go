@@ -0,0 +1,113 @@
+package cavena
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/si0ls/subs/stl"
+)
+
+// Decode reads a Cavena 890 file from r and returns its subtitles as a
+// Level-1 Teletext STL file, with the Language Code (LC) and Character
+// Code Table (CCT) of the language of the file.
+//
+// Subtitles are centered at the bottom of the screen, each row starting
+// with boxing codes, and italics are coded with STL control codes. An
+// unknown language is read as Latin and returned as a warning, as are
+// invalid characters.
+func Decode(r io.Reader, opts Options) (*stl.File, []error, error) {
+	framerate := opts.Framerate
+	if framerate == 0 {
+		framerate = defaultFramerate
+	}
+	if framerate != 25 && framerate != 30 {
+		return nil, nil, fmt.Errorf("%w: %d", ErrUnsupportedFramerate, framerate)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(b) < HeaderSize {
+		return nil, nil, ErrInvalidHeader
+	}
+
+	var warns []error
+	lang, ok := languages[b[languageOffset]]
+	if !ok {
+		warns = append(warns, fmt.Errorf("unknown language identifier 0x%02X, read as Latin", b[languageOffset]))
+		lang = languages[LanguageEnglish]
+		lang.lc = stl.LanguageCodeUnknown
+	}
+
+	f := stl.NewFile()
+	f.GSI = stl.NewGSIBlock()
+	f.GSI.SetDefaults(framerate, stl.DisplayStandardCodeLevel1Teletext)
+	f.GSI.LC = lang.lc
+	f.GSI.CCT = lang.cct
+	f.GSI.TPT = decodeText(lang, bytes.Trim(b[titleOffset:titleOffset+titleSize], "\x7f\x00 "))
+
+	var n int
+	for i := HeaderSize; i+BlockSize <= len(b); i += BlockSize {
+		block := b[i : i+BlockSize]
+		number := int(block[0])<<8 | int(block[1])
+
+		var rows []string
+		for _, offset := range []int{firstRowOffset, secondRowOffset} {
+			row := bytes.Trim(block[offset:offset+RowSize], "\x7f\x00 ")
+			if len(row) > 0 {
+				rows = append(rows, decodeText(lang, row))
+			}
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		for j := range rows {
+			rows[j] = string([]rune{rune(stl.TeletextControlCodeStartBox), rune(stl.TeletextControlCodeStartBox)}) + rows[j]
+		}
+
+		tti := stl.NewTTIBlock()
+		tti.SGN = 0
+		tti.SN = n
+		tti.EBN = stl.EBNLastBlock
+		tti.CS = stl.CumulativeStatusNone
+		tti.TCI = stl.TimecodeFromFrames(int(block[2])<<16|int(block[3])<<8|int(block[4]), framerate)
+		tti.TCO = stl.TimecodeFromFrames(int(block[5])<<16|int(block[6])<<8|int(block[7]), framerate)
+		tti.VP = 23 - len(rows)
+		tti.JC = stl.JustificationCodeCenteredText
+		tti.CF = stl.CommentFlagSubtitleData
+		unmappables, err := tti.SetTextFallback(strings.Join(rows, string(rune(stl.ControlCodeLineBreak))), lang.cct, stl.FallbackReplace)
+		if err != nil {
+			warns = append(warns, fmt.Errorf("subtitle %d: %w", number, err))
+			continue
+		}
+		if len(unmappables) > 0 {
+			warns = append(warns, fmt.Errorf("subtitle %d: %w, fallback %s", number, &stl.UnmappableError{Runes: unmappables}, stl.FallbackReplace))
+		}
+		f.TTI = append(f.TTI, tti.ExtensionBlocks()...)
+		n++
+	}
+	f.UpdateCounters()
+	return f, warns, nil
+}
+
+// decodeText decodes the bytes of a row, italic codes being replaced by
+// STL control codes.
+func decodeText(lang language, b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		switch c {
+		case italicOn:
+			sb.WriteRune(rune(stl.ControlCodeItalicOn))
+		case italicOff:
+			sb.WriteRune(rune(stl.ControlCodeItalicOff))
+		case padding:
+			sb.WriteByte(' ')
+		default:
+			sb.WriteRune(lang.codePage.DecodeByte(c))
+		}
+	}
+	return sb.String()
+}
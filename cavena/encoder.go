@@ -0,0 +1,155 @@
+package cavena
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/si0ls/subs/stl"
+)
+
+// Encode writes f as a Cavena 890 file to w, in the language of the
+// Character Code Table (CCT) and Language Code (LC) of the GSI block.
+//
+// Timecodes are written at the framerate of the GSI block and subtitles
+// keep their first two rows, trimmed, with italics closed at the end of
+// each row. Translator's comments are skipped. Dropped rows, truncated
+// rows and characters missing from the code page, replaced by '?', are
+// returned as warnings.
+func Encode(w io.Writer, f *stl.File, opts Options) ([]error, error) {
+	if f.GSI == nil {
+		return nil, ErrNilGSI
+	}
+	framerate := f.GSI.Framerate()
+	if framerate == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFramerate, f.GSI.DFC)
+	}
+	id := languageID(f.GSI)
+	lang := languages[id]
+
+	var warns []error
+	header := make([]byte, HeaderSize)
+	title, errs := encodeText(lang, f.GSI.TPT)
+	warns = append(warns, errs...)
+	copy(header[titleOffset:titleOffset+titleSize], bytes.Repeat([]byte{' '}, titleSize))
+	copy(header[titleOffset:titleOffset+titleSize], title)
+	header[languageOffset] = id
+
+	bw := bufio.NewWriter(w)
+	bw.Write(header)
+
+	number := 1
+	for _, tti := range f.Subtitles() {
+		if tti.CF == stl.CommentFlagTranslatorComments {
+			continue
+		}
+		textRows, err := tti.RowsOrdered(f.GSI.CCT, f.TextOrder)
+		if err != nil {
+			warns = append(warns, fmt.Errorf("subtitle %d/%d: %w", tti.SGN, tti.SN, err))
+			continue
+		}
+		var rows [][]byte
+		for _, row := range textRows {
+			s := rowText(row)
+			if s == "" {
+				continue
+			}
+			b, errs := encodeText(lang, s)
+			for _, err := range errs {
+				warns = append(warns, fmt.Errorf("subtitle %d/%d: %w", tti.SGN, tti.SN, err))
+			}
+			rows = append(rows, b)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		if len(rows) > maxRows {
+			warns = append(warns, fmt.Errorf("subtitle %d/%d: %d rows, rows after the second one dropped", tti.SGN, tti.SN, len(rows)))
+			rows = rows[:maxRows]
+		}
+
+		block := make([]byte, BlockSize)
+		block[0], block[1] = byte(number>>8), byte(number)
+		in, out := tti.TCI.ToFrames(framerate), tti.TCO.ToFrames(framerate)
+		block[2], block[3], block[4] = byte(in>>16), byte(in>>8), byte(in)
+		block[5], block[6], block[7] = byte(out>>16), byte(out>>8), byte(out)
+		offsets := []int{secondRowOffset}
+		if len(rows) == 2 {
+			offsets = []int{firstRowOffset, secondRowOffset}
+		}
+		for _, offset := range []int{firstRowOffset, secondRowOffset} {
+			copy(block[offset:offset+RowSize], bytes.Repeat([]byte{padding}, RowSize))
+		}
+		for i, row := range rows {
+			if len(row) > RowSize {
+				warns = append(warns, fmt.Errorf("subtitle %d/%d: row %d longer than %d bytes, truncated", tti.SGN, tti.SN, i+1, RowSize))
+			}
+			copy(block[offsets[i]:offsets[i]+RowSize], row)
+		}
+		bw.Write(block)
+		number++
+	}
+	return warns, bw.Flush()
+}
+
+// rowText returns the trimmed text of a row with the italic control codes,
+// italics being closed at the end of the row.
+func rowText(row stl.TextRow) string {
+	var sb strings.Builder
+	var italic bool
+	column := -1
+	for _, run := range row {
+		if column >= 0 && run.Column > column {
+			sb.WriteString(strings.Repeat(" ", run.Column-column))
+		}
+		column = run.Column + utf8.RuneCountInString(run.Text)
+		text := run.Text
+		if sb.Len() == 0 {
+			text = strings.TrimLeft(text, " ")
+			if text == "" {
+				continue
+			}
+		}
+		if run.Style.Italic != italic {
+			if run.Style.Italic {
+				sb.WriteRune(rune(stl.ControlCodeItalicOn))
+			} else {
+				sb.WriteRune(rune(stl.ControlCodeItalicOff))
+			}
+			italic = run.Style.Italic
+		}
+		sb.WriteString(text)
+	}
+	s := strings.TrimRight(sb.String(), " ")
+	if italic {
+		s += string(rune(stl.ControlCodeItalicOff))
+	}
+	return s
+}
+
+// encodeText encodes text in the code page of the language, italic
+// control codes being replaced by the Cavena italic codes.
+func encodeText(lang language, text string) ([]byte, []error) {
+	var b []byte
+	var errs []error
+	for _, r := range text {
+		switch stl.ControlCode(r) {
+		case stl.ControlCodeItalicOn:
+			b = append(b, italicOn)
+			continue
+		case stl.ControlCodeItalicOff:
+			b = append(b, italicOff)
+			continue
+		}
+		c, ok := lang.codePage.EncodeRune(r)
+		if !ok || c == padding || c == italicOn || c == italicOff {
+			errs = append(errs, fmt.Errorf("character %q not in code page, replaced by '?'", r))
+			c = '?'
+		}
+		b = append(b, c)
+	}
+	return b, errs
+}
@@ -0,0 +1,295 @@
+// Package cheetah reads Cheetah CAP caption files as STL files.
+//
+// A Cheetah CAP file starts with a 128 bytes header, made of the 0xEA 0x22
+// magic number and the frame rate code of the time codes, followed by one
+// record per caption:
+//
+//	offset  size  content
+//	0       1     record length
+//	1       1     display mode (0x00 pop-on, 0x01 roll-up, 0x02 paint-on)
+//	2       4     time code in (hours, minutes, seconds, frames)
+//	6       4     time code out (hours, minutes, seconds, frames)
+//	10      1     row of the first line (1..15)
+//	11      1     justification (0x00 left, 0x01 centered, 0x02 right)
+//	12      -     text
+//
+// Lines of the text are separated by 0x00, italics start with 0x0E and end
+// with 0x0F, other bytes below 0x20 are attribute codes. Characters are
+// encoded in Windows-1252. A record length of 0 ends the file.
+package cheetah
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/si0ls/subs/stl"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Layout of a Cheetah CAP file.
+const (
+	HeaderSize       = 128
+	RecordHeaderSize = 12
+)
+
+// Frame rate codes of the header.
+const (
+	FrameRate30     = 0x00
+	FrameRate30Drop = 0x01
+	FrameRate25     = 0x02
+	FrameRate24     = 0x03
+)
+
+// Display modes of a record.
+const (
+	ModePopOn   = 0x00
+	ModeRollUp  = 0x01
+	ModePaintOn = 0x02
+)
+
+// Justifications of a record.
+const (
+	JustifyLeft   = 0x00
+	JustifyCenter = 0x01
+	JustifyRight  = 0x02
+)
+
+// Bytes of the text of a record.
+const (
+	LineBreak = 0x00
+	ItalicOn  = 0x0E
+	ItalicOff = 0x0F
+)
+
+var magic = []byte{0xEA, 0x22}
+
+// Options configures a Cheetah CAP import.
+type Options struct {
+	Framerate uint         // Framerate of the STL file, 25 or 30 (default 25 for 24 and 25 fps files, 30 otherwise)
+	Fallback  stl.Fallback // Fallback for characters the Character Code Table (CCT) can not represent (default replace)
+}
+
+var (
+	ErrInvalidHeader        = errors.New("invalid Cheetah CAP header")
+	ErrUnsupportedFramerate = errors.New("unsupported framerate")
+)
+
+// Decode reads a Cheetah CAP file from r and returns its captions as a
+// Level-1 Teletext STL file.
+//
+// Time codes are read at the frame rate declared in the header: 29.97 fps
+// time codes are kept as they are in 30 fps files, others are converted to
+// the framerate of the STL file. The row of a caption gives the Vertical
+// Position (VP) and the justification the Justification Code (JC). Rows
+// start with boxing codes and italics are coded with STL control codes.
+// Roll-up and paint-on captions are imported as pop-on captions; they are
+// returned as warnings with the other attribute codes and invalid records.
+// The Character Code Table (CCT) is the one representing the text of the
+// captions, and the Language Code (LC) is guessed from it; characters it
+// can not represent are handled according to the fallback and returned as
+// warnings.
+func Decode(r io.Reader, opts Options) (*stl.File, []error, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(b) < HeaderSize || b[0] != magic[0] || b[1] != magic[1] {
+		return nil, nil, ErrInvalidHeader
+	}
+
+	var warns []error
+	var declared uint
+	var dropFrame bool
+	switch b[2] {
+	case FrameRate30:
+		declared = 30
+	case FrameRate30Drop:
+		declared, dropFrame = 30, true
+	case FrameRate25:
+		declared = 25
+	case FrameRate24:
+		declared = 24
+	default:
+		warns = append(warns, fmt.Errorf("unknown frame rate code 0x%02X, read as 30 fps", b[2]))
+		declared = 30
+	}
+	framerate := opts.Framerate
+	if framerate == 0 {
+		framerate = 30
+		if declared <= 25 {
+			framerate = 25
+		}
+	}
+	if framerate != 25 && framerate != 30 {
+		return nil, warns, fmt.Errorf("%w: %d", ErrUnsupportedFramerate, framerate)
+	}
+	// timecode converts a time code of the file to the framerate of the
+	// STL file
+	timecode := func(tc stl.Timecode) stl.Timecode {
+		switch {
+		case dropFrame && framerate == 30, !dropFrame && declared == framerate:
+			return tc
+		case dropFrame:
+			// 29.97 fps frames to frames at the framerate
+			return stl.TimecodeFromFrames((tc.ToDropFrames()*int(framerate)*1001+15000)/30000, framerate)
+		}
+		frames := tc.ToFrames(declared)
+		return stl.TimecodeFromFrames((frames*int(framerate)*2+int(declared))/(2*int(declared)), framerate)
+	}
+
+	f := stl.NewFile()
+	f.GSI = stl.NewGSIBlock()
+	f.GSI.SetDefaults(framerate, stl.DisplayStandardCodeLevel1Teletext)
+
+	var n int
+	var captions []caption
+	for i := HeaderSize; i < len(b); {
+		length := int(b[i])
+		if length == 0 {
+			break
+		}
+		if length < RecordHeaderSize || i+length > len(b) {
+			warns = append(warns, fmt.Errorf("offset %d: invalid record length %d", i, length))
+			break
+		}
+		record := b[i : i+length]
+		i += length
+		n++
+
+		tci := stl.Timecode{Hours: int(record[2]), Minutes: int(record[3]), Seconds: int(record[4]), Frames: int(record[5])}
+		tco := stl.Timecode{Hours: int(record[6]), Minutes: int(record[7]), Seconds: int(record[8]), Frames: int(record[9])}
+		if err := tci.Validate(declared); err != nil {
+			warns = append(warns, fmt.Errorf("caption %d: time code in %s: %w", n, tci, err))
+			continue
+		}
+		if err := tco.Validate(declared); err != nil {
+			warns = append(warns, fmt.Errorf("caption %d: time code out %s: %w", n, tco, err))
+			continue
+		}
+
+		tti := stl.NewTTIBlock()
+		tti.SGN = 0
+		tti.EBN = stl.EBNLastBlock
+		tti.CS = stl.CumulativeStatusNone
+		tti.TCI = timecode(tci)
+		tti.TCO = timecode(tco)
+		tti.CF = stl.CommentFlagSubtitleData
+
+		switch record[1] {
+		case ModePopOn:
+		case ModeRollUp, ModePaintOn:
+			warns = append(warns, fmt.Errorf("caption %d: roll-up and paint-on captions not supported, imported as pop-on", n))
+		default:
+			warns = append(warns, fmt.Errorf("caption %d: unknown display mode 0x%02X", n, record[1]))
+		}
+		switch record[11] {
+		case JustifyLeft:
+			tti.JC = stl.JustificationCodeLeftJustifiedText
+		case JustifyCenter:
+			tti.JC = stl.JustificationCodeCenteredText
+		case JustifyRight:
+			tti.JC = stl.JustificationCodeRightJustifiedText
+		default:
+			warns = append(warns, fmt.Errorf("caption %d: unknown justification 0x%02X, centered", n, record[11]))
+			tti.JC = stl.JustificationCodeCenteredText
+		}
+
+		tf, rows, errs := decodeText(record[RecordHeaderSize:])
+		for _, err := range errs {
+			warns = append(warns, fmt.Errorf("caption %d: %w", n, err))
+		}
+		if rows == 0 {
+			continue
+		}
+
+		row := int(record[10])
+		if row < 1 || row > 15 {
+			warns = append(warns, fmt.Errorf("caption %d: invalid row %d, bottom row used", n, row))
+			row = 15 - rows + 1
+		}
+		tti.VP = (row*23 + 7) / 15
+		if tti.VP+rows-1 > 23 {
+			tti.VP = 23 - rows + 1
+		}
+		captions = append(captions, caption{tti: tti, text: tf, n: n})
+	}
+
+	texts := make([]string, len(captions))
+	for i, c := range captions {
+		texts[i] = c.text
+	}
+	f.GSI.SetCharacterCodeTable(texts...)
+	var sn int
+	for _, c := range captions {
+		unmappables, err := c.tti.SetTextFallback(c.text, f.GSI.CCT, opts.Fallback)
+		if err != nil {
+			warns = append(warns, fmt.Errorf("caption %d: %w", c.n, err))
+			continue
+		}
+		if len(unmappables) > 0 {
+			warns = append(warns, fmt.Errorf("caption %d: %w, fallback %s", c.n, &stl.UnmappableError{Runes: unmappables}, opts.Fallback))
+		}
+		c.tti.SN = sn
+		sn++
+		f.TTI = append(f.TTI, c.tti.ExtensionBlocks()...)
+	}
+	f.UpdateCounters()
+	return f, warns, nil
+}
+
+// caption is a caption of the file, before the encoding of its text.
+type caption struct {
+	tti  *stl.TTIBlock
+	text string // Text Field (TF), UTF-8 encoded
+	n    int    // Number of the caption in the file
+}
+
+// decodeText returns the Text Field (TF) of the text of a record, before
+// encoding, and its number of rows.
+func decodeText(b []byte) (string, int, []error) {
+	var errs []error
+	var lines [][]rune
+	var line []rune
+	started := false
+	flush := func() {
+		if started {
+			lines = append(lines, line)
+		}
+		line, started = nil, false
+	}
+	for _, c := range b {
+		switch {
+		case c == LineBreak:
+			flush()
+			continue
+		case c == ItalicOn:
+			line = append(line, rune(stl.ControlCodeItalicOn))
+			continue
+		case c == ItalicOff:
+			line = append(line, rune(stl.ControlCodeItalicOff))
+			continue
+		case c < 0x20:
+			errs = append(errs, fmt.Errorf("attribute code 0x%02X not supported", c))
+			continue
+		}
+		if !started {
+			if c == ' ' {
+				continue
+			}
+			started = true
+			line = append([]rune{rune(stl.TeletextControlCodeStartBox), rune(stl.TeletextControlCodeStartBox)}, line...)
+		}
+		line = append(line, charmap.Windows1252.DecodeByte(c))
+	}
+	flush()
+
+	var tf []rune
+	for i, l := range lines {
+		if i > 0 {
+			tf = append(tf, rune(stl.ControlCodeLineBreak))
+		}
+		tf = append(tf, l...)
+	}
+	return string(tf), len(lines), errs
+}
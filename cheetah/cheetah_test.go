@@ -0,0 +1,128 @@
+package cheetah
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/si0ls/subs/stl"
+)
+
+func record(mode byte, tci, tco [4]byte, row, justify byte, text []byte) []byte {
+	b := []byte{byte(RecordHeaderSize + len(text)), mode}
+	b = append(b, tci[:]...)
+	b = append(b, tco[:]...)
+	b = append(b, row, justify)
+	return append(b, text...)
+}
+
+func TestDecode(t *testing.T) {
+	header := make([]byte, HeaderSize)
+	copy(header, magic)
+	header[2] = FrameRate25
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	buf.Write(record(ModePopOn, [4]byte{10, 0, 1, 5}, [4]byte{10, 0, 3, 0}, 14, JustifyCenter,
+		[]byte("Caf\xe9\x00\x0eitalic\x0f")))
+	buf.Write(record(ModeRollUp, [4]byte{10, 0, 4, 0}, [4]byte{10, 0, 5, 0}, 1, JustifyLeft,
+		[]byte("\x02top")))
+	buf.Write(record(ModePopOn, [4]byte{10, 0, 6, 30}, [4]byte{10, 0, 7, 0}, 15, JustifyRight,
+		[]byte("invalid")))
+	buf.WriteByte(0)
+
+	f, warns, err := Decode(&buf, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) != 3 {
+		t.Errorf("got %d warnings, want 3: %v", len(warns), warns)
+	}
+	if f.GSI.Framerate() != 25 {
+		t.Errorf("got framerate %d, want 25", f.GSI.Framerate())
+	}
+	if len(f.TTI) != 2 {
+		t.Fatalf("got %d subtitles, want 2", len(f.TTI))
+	}
+
+	first := f.TTI[0]
+	if first.TCI != (stl.Timecode{Hours: 10, Seconds: 1, Frames: 5}) || first.TCO != (stl.Timecode{Hours: 10, Seconds: 3}) {
+		t.Errorf("got %s-%s", first.TCI, first.TCO)
+	}
+	if first.VP != 21 || first.JC != stl.JustificationCodeCenteredText {
+		t.Errorf("got VP %d JC %s, want 21 centered", first.VP, first.JC)
+	}
+	want := stl.NewTTIBlock()
+	if err := want.SetText("\x0b\x0bCafé\u008a\x0b\x0b\u0080italic\u0081", stl.CharacterCodeTableLatin); err != nil {
+		t.Fatal(err)
+	}
+	if first.TF != want.TF {
+		t.Errorf("got TF %q, want %q", first.TF, want.TF)
+	}
+
+	second := f.TTI[1]
+	if second.VP != 2 || second.JC != stl.JustificationCodeLeftJustifiedText {
+		t.Errorf("got VP %d JC %s, want 2 left", second.VP, second.JC)
+	}
+}
+
+func TestDecodeUnmappable(t *testing.T) {
+	header := make([]byte, HeaderSize)
+	copy(header, magic)
+	header[2] = FrameRate25
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	buf.Write(record(ModePopOn, [4]byte{0, 0, 1, 0}, [4]byte{0, 0, 2, 0}, 15, JustifyCenter, []byte("5 \x83")))
+
+	f, warns, err := Decode(&buf, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.GSI.CCT != stl.CharacterCodeTableLatin || len(f.TTI) != 1 {
+		t.Fatalf("got CCT %s and %d subtitles, want Latin and 1", f.GSI.CCT, len(f.TTI))
+	}
+	if text, err := f.TTI[0].Text(f.GSI.CCT); err != nil || text != "\x0b\x0b5 ?" {
+		t.Errorf("got text %q (%v), want %q", text, err, "\x0b\x0b5 ?")
+	}
+	if len(warns) != 1 || !errors.Is(warns[0], stl.ErrUnmappableRune) || !strings.HasPrefix(warns[0].Error(), "caption 1: ") {
+		t.Errorf("warnings %v, want an unmappable rune in caption 1", warns)
+	}
+}
+
+func TestDecodeDropFrame(t *testing.T) {
+	header := make([]byte, HeaderSize)
+	copy(header, magic)
+	header[2] = FrameRate30Drop
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	buf.Write(record(ModePopOn, [4]byte{0, 1, 0, 2}, [4]byte{0, 1, 1, 2}, 15, JustifyCenter, []byte("text")))
+
+	for _, tc := range []struct {
+		framerate uint
+		tci       stl.Timecode
+	}{
+		{0, stl.Timecode{Minutes: 1, Frames: 2}},
+		// 00:01:00;02 is frame 1800 at 29.97 fps, 60.06 seconds
+		{25, stl.Timecode{Minutes: 1, Frames: 2}},
+	} {
+		f, warns, err := Decode(bytes.NewReader(buf.Bytes()), Options{Framerate: tc.framerate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(warns) > 0 {
+			t.Errorf("%d fps: unexpected warnings: %v", tc.framerate, warns)
+		}
+		if len(f.TTI) != 1 || f.TTI[0].TCI != tc.tci {
+			t.Errorf("%d fps: got %v, want TCI %s", tc.framerate, f.TTI, tc.tci)
+		}
+	}
+}
+
+func TestDecodeInvalidHeader(t *testing.T) {
+	if _, _, err := Decode(bytes.NewReader(make([]byte, HeaderSize)), Options{}); err != ErrInvalidHeader {
+		t.Errorf("got %v, want %v", err, ErrInvalidHeader)
+	}
+}
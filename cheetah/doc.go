@@ -0,0 +1,17 @@
+package cheetah
+
+import (
+	"io"
+
+	"github.com/si0ls/subs/doc"
+	"github.com/si0ls/subs/stl"
+)
+
+func init() {
+	doc.RegisterSTL("cheetah", []string{".cap"},
+		func(r io.Reader) (*stl.File, []error, error) {
+			return Decode(r, Options{})
+		},
+		nil,
+	)
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/si0ls/subs"
+	"github.com/si0ls/subs/doc"
+	"github.com/si0ls/subs/stl"
+	"github.com/si0ls/subs/stlxml"
+
+	// Formats registered for conversions
+	_ "github.com/si0ls/subs/ass"
+	_ "github.com/si0ls/subs/avid"
+	_ "github.com/si0ls/subs/cavena"
+	_ "github.com/si0ls/subs/cheetah"
+	_ "github.com/si0ls/subs/mcc"
+	_ "github.com/si0ls/subs/pac"
+	_ "github.com/si0ls/subs/sami"
+	_ "github.com/si0ls/subs/sbv"
+	_ "github.com/si0ls/subs/scc"
+	_ "github.com/si0ls/subs/spruce"
+	_ "github.com/si0ls/subs/subviewer"
+	_ "github.com/si0ls/subs/teletext"
+)
+
+const usage = `Usage: subs <command> [arguments]
+
+Commands:
+  print [-from format] <file>                           print the STL blocks of a file
+  validate [-from format] <file>                        validate a file as an STL file
+  convert [-from format] [-to format] <input> <output>  convert a file to another format
+  diff [-from format] [-json] <old> <new>               compare the subtitles of two files
+  formats                                               list the formats
+
+The format of input files is detected from their content unless given
+with -from, the format of output files is deduced from their extension
+unless given with -to.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "print":
+		err = printCmd(args)
+	case "validate":
+		err = validateCmd(args)
+	case "convert":
+		err = convertCmd(args)
+	case "diff":
+		err = diffCmd(args)
+	case "formats":
+		formatsCmd()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Err]: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func printCmd(args []string) error {
+	fs := flag.NewFlagSet("print", flag.ExitOnError)
+	from := fs.String("from", "", "format of the file (default detected)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("print takes one file")
+	}
+
+	f, warns, err := readSTL(fs.Arg(0), *from)
+	if err != nil {
+		return err
+	}
+	printWarns(warns)
+
+	stl.PrintGSI(f.GSI)
+	for _, tti := range f.TTI {
+		stl.PrintTTI(tti, f.GSI.CCT)
+	}
+	return nil
+}
+
+func validateCmd(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	from := fs.String("from", "", "format of the file (default detected)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("validate takes one file")
+	}
+
+	data, format, err := read(fs.Arg(0), *from, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if format == "stlxml" {
+		return validateSTLXML(data)
+	}
+
+	f, warns, err := decodeSTL(data, format)
+	if err != nil {
+		return err
+	}
+	printWarns(warns)
+
+	warns, err = f.Validate()
+	if err != nil {
+		return err
+	}
+	printWarns(warns)
+	if len(warns) == 0 {
+		fmt.Println("valid")
+	}
+	return nil
+}
+
+func convertCmd(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	from := fs.String("from", "", "format of the input file (default detected)")
+	to := fs.String("to", "", "format of the output file (default from its extension)")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return fmt.Errorf("convert takes an input and an output file")
+	}
+	in, out := fs.Arg(0), fs.Arg(1)
+
+	data, format, err := read(in, *from, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if *to == "" {
+		if *to = formatOfExtension(filepath.Ext(out)); *to == "" {
+			return fmt.Errorf("no format for the extension of %s, use -to", out)
+		}
+	}
+
+	var buf bytes.Buffer
+	warns, err := doc.Convert(&buf, *to, bytes.NewReader(data), format)
+	printWarns(warns)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(out, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("%s (%s) converted to %s (%s)\n", in, format, out, *to)
+	return nil
+}
+
+func diffCmd(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	from := fs.String("from", "", "format of the files (default detected)")
+	jsonOut := fs.Bool("json", false, "print the differences as JSON")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return fmt.Errorf("diff takes an old and a new file")
+	}
+
+	// keep the standard output for the JSON document
+	report, detected := printWarns, io.Writer(os.Stdout)
+	if *jsonOut {
+		detected = os.Stderr
+		report = func(warns []error) {
+			for _, w := range warns {
+				fmt.Fprintf(os.Stderr, "[Err]: %s\n", w)
+			}
+		}
+	}
+	var files [2]*stl.File
+	for i, path := range fs.Args() {
+		data, format, err := read(path, *from, detected)
+		if err != nil {
+			return err
+		}
+		f, warns, err := decodeSTL(data, format)
+		if err != nil {
+			return err
+		}
+		report(warns)
+		files[i] = f
+	}
+
+	d, err := stl.Diff(files[0], files[1])
+	if err != nil {
+		return err
+	}
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	}
+
+	if len(d.GSI) == 0 && len(d.Subtitles) == 0 {
+		fmt.Println("no differences")
+		return nil
+	}
+	for _, c := range d.GSI {
+		fmt.Printf("GSI %s: %q -> %q\n", c.Field, c.Old, c.New)
+	}
+	for _, c := range d.Subtitles {
+		kinds := make([]string, len(c.Kinds))
+		for i, k := range c.Kinds {
+			kinds[i] = string(k)
+		}
+		fmt.Printf("%s\n", strings.Join(kinds, ", "))
+		if c.Old != nil {
+			printDiffSubtitle("-", c.Old)
+		}
+		if c.New != nil {
+			printDiffSubtitle("+", c.New)
+		}
+	}
+	return nil
+}
+
+// printDiffSubtitle prints a subtitle of a file compared by diff.
+func printDiffSubtitle(prefix string, s *stl.DiffSubtitle) {
+	fmt.Printf("%s #%d (%d/%d) %s - %s VP %d %s %s\n", prefix, s.Index, s.SGN, s.SN, s.TCI, s.TCO, s.VP, s.JC, s.CF)
+	for _, row := range strings.Split(s.Text, "\n") {
+		fmt.Printf("%s   %s\n", prefix, row)
+	}
+}
+
+func formatsCmd() {
+	for _, f := range doc.Formats() {
+		var modes []string
+		if f.Decode != nil {
+			modes = append(modes, "read")
+		}
+		if f.Encode != nil {
+			modes = append(modes, "write")
+		}
+		fmt.Printf("%-10s %-12s %s\n", f.Name, strings.Join(modes, ","), strings.Join(f.Extensions, " "))
+	}
+}
+
+// read returns the content of a file and its format, detected if not
+// given. The detected format is reported to w.
+func read(path, format string, w io.Writer) ([]byte, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if format != "" {
+		return data, format, nil
+	}
+	d, err := subs.Detect(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+	fmt.Fprintf(w, "%s detected as %s (confidence %.0f%%)\n", path, d.Format, d.Confidence*100)
+	return data, d.Format, nil
+}
+
+// validateSTLXML validates an STLXML document, errors are reported with
+// their location in the document.
+func validateSTLXML(data []byte) error {
+	x := stlxml.New()
+	if err := x.Decode(bytes.NewReader(data)); err != nil {
+		return err
+	}
+	errs := x.Validate()
+	printWarns(errs)
+	if len(errs) == 0 {
+		fmt.Println("valid")
+	}
+	return nil
+}
+
+// readSTL reads a file as an STL file.
+func readSTL(path, format string) (*stl.File, []error, error) {
+	data, format, err := read(path, format, os.Stdout)
+	if err != nil {
+		return nil, nil, err
+	}
+	return decodeSTL(data, format)
+}
+
+// decodeSTL decodes the content of a file as an STL file. Files in other
+// formats are converted through their document.
+func decodeSTL(data []byte, format string) (*stl.File, []error, error) {
+	if format == "stl" {
+		f := stl.NewFile()
+		warns, err := f.Decode(bytes.NewReader(data))
+		return f, warns, err
+	}
+
+	src, err := doc.Lookup(format)
+	if err != nil {
+		return nil, nil, err
+	}
+	if src.Decode == nil {
+		return nil, nil, fmt.Errorf("%w: %s", doc.ErrNoDecoder, format)
+	}
+	d, warns, err := src.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, warns, err
+	}
+	f, stlWarns, err := doc.ToSTL(d, doc.Options{AutoCCT: true})
+	return f, append(warns, stlWarns...), err
+}
+
+// formatOfExtension returns the name of the registered format of a file
+// extension, preferring the format named after the extension.
+func formatOfExtension(ext string) string {
+	ext = strings.ToLower(ext)
+	var name string
+	for _, f := range doc.Formats() {
+		if f.Encode == nil {
+			continue
+		}
+		for _, e := range f.Extensions {
+			if e != ext {
+				continue
+			}
+			if f.Name == strings.TrimPrefix(ext, ".") || name == "" {
+				name = f.Name
+			}
+		}
+	}
+	return name
+}
+
+func printWarns(warns []error) {
+	if len(warns) == 0 {
+		return
+	}
+	fmt.Println("Warnings:")
+	printErrs(warns...)
+	fmt.Println("====================================")
+}
+
+func printErrs(errs ...error) {
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		fmt.Printf("[Err]: %s\n", err)
+	}
+}
@@ -0,0 +1,230 @@
+// Package cue converts STL files and documents to cues, the timed lines of
+// text shared by the text subtitle exporters (SAMI, SubViewer, YouTube
+// SBV).
+//
+// A cue keeps what text formats can express of a subtitle: its start and
+// end times, rounded to the millisecond, and its rows of text split on line
+// breaks, with italic spans. Positioning and Teletext attributes are left
+// to the exporters able to express them.
+package cue
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/si0ls/subs/doc"
+	"github.com/si0ls/subs/stl"
+)
+
+// Span is a run of text of a line sharing the same style.
+type Span struct {
+	Text   string // UTF-8 text
+	Italic bool   // Italic text
+}
+
+// Line is a row of text of a cue, trimmed.
+type Line []Span
+
+// String returns the text of the line without styling.
+func (l Line) String() string {
+	var sb strings.Builder
+	for _, span := range l {
+		sb.WriteString(span.Text)
+	}
+	return sb.String()
+}
+
+// Italic reports whether some text of the line is in italics.
+func (l Line) Italic() bool {
+	for _, span := range l {
+		if span.Italic {
+			return true
+		}
+	}
+	return false
+}
+
+// Markup returns the text of the line escaped with escape, if not nil, and
+// italics enclosed between on and off. Italics are closed at the end of the
+// line.
+func (l Line) Markup(on, off string, escape func(string) string) string {
+	var sb strings.Builder
+	var italic bool
+	for _, span := range l {
+		if span.Italic != italic {
+			if span.Italic {
+				sb.WriteString(on)
+			} else {
+				sb.WriteString(off)
+			}
+			italic = span.Italic
+		}
+		if escape != nil {
+			sb.WriteString(escape(span.Text))
+		} else {
+			sb.WriteString(span.Text)
+		}
+	}
+	if italic {
+		sb.WriteString(off)
+	}
+	return sb.String()
+}
+
+// Cue is a subtitle of an STL file.
+type Cue struct {
+	SGN   int           // Subtitle Group Number (SGN) of the subtitle
+	SN    int           // Subtitle Number (SN) of the subtitle
+	Start time.Duration // Time Code In (TCI), rounded to the millisecond
+	End   time.Duration // Time Code Out (TCO), rounded to the millisecond
+	Lines []Line        // Non empty rows of the subtitle
+}
+
+// Italic reports whether some text of the cue is in italics.
+func (c Cue) Italic() bool {
+	for _, l := range c.Lines {
+		if l.Italic() {
+			return true
+		}
+	}
+	return false
+}
+
+// Errorf returns an error about the cue, prefixed with the numbers of its
+// subtitle as in the warnings of the exporters.
+func (c Cue) Errorf(format string, a ...any) error {
+	return fmt.Errorf("subtitle %d/%d: %w", c.SGN, c.SN, fmt.Errorf(format, a...))
+}
+
+var (
+	ErrNilGSI               = errors.New("nil GSI block")
+	ErrUnsupportedFramerate = errors.New("unsupported framerate")
+)
+
+// Duration returns the duration of a timecode at the framerate, rounded to
+// the millisecond.
+func Duration(tc stl.Timecode, framerate uint) time.Duration {
+	return tc.ToDuration(framerate).Round(time.Millisecond)
+}
+
+// FromSTL returns the cues of the subtitles of f, in the order of the file.
+//
+// Times are taken from the Time Code In (TCI) and Time Code Out (TCO) at
+// the framerate of the GSI block. Lines are the rows of the Text Field
+// (TF), split on line break control codes, with the gaps left by Teletext
+// spacing attributes as spaces, right-to-left text being read in the order
+// of the file. Translator's comments and subtitles
+// without text are skipped, subtitles which can not be decoded are
+// returned as warnings.
+func FromSTL(f *stl.File) ([]Cue, []error, error) {
+	if f.GSI == nil {
+		return nil, nil, ErrNilGSI
+	}
+	framerate := f.GSI.Framerate()
+	if framerate == 0 {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedFramerate, f.GSI.DFC)
+	}
+
+	var cues []Cue
+	var warns []error
+	for _, tti := range f.Subtitles() {
+		if tti.CF == stl.CommentFlagTranslatorComments {
+			continue
+		}
+		c := Cue{
+			SGN:   tti.SGN,
+			SN:    tti.SN,
+			Start: Duration(tti.TCI, framerate),
+			End:   Duration(tti.TCO, framerate),
+		}
+		rows, err := tti.RowsOrdered(f.GSI.CCT, f.TextOrder)
+		if err != nil {
+			warns = append(warns, c.Errorf("%w", err))
+			continue
+		}
+		for _, row := range rows {
+			if l := line(row); len(l) > 0 {
+				c.Lines = append(c.Lines, l)
+			}
+		}
+		if len(c.Lines) == 0 {
+			continue
+		}
+		cues = append(cues, c)
+	}
+	return cues, warns, nil
+}
+
+// FromDocument returns the cues of d, in the order of the document.
+//
+// Cues are numbered after their position in the document, in group 0, and
+// times are rounded to the millisecond. Lines keep the italics of their
+// spans. Comments and cues without text are skipped.
+func FromDocument(d *doc.Document) []Cue {
+	var cues []Cue
+	for i, dc := range d.Cues {
+		if dc.Comment {
+			continue
+		}
+		c := Cue{
+			SN:    i,
+			Start: dc.Start.Round(time.Millisecond),
+			End:   dc.End.Round(time.Millisecond),
+		}
+		for _, dl := range dc.Lines {
+			var l Line
+			for _, span := range dl {
+				if n := len(l); n > 0 && l[n-1].Italic == span.Style.Italic {
+					l[n-1].Text += span.Text
+					continue
+				}
+				l = append(l, Span{Text: span.Text, Italic: span.Style.Italic})
+			}
+			if l.String() != "" {
+				c.Lines = append(c.Lines, l)
+			}
+		}
+		if len(c.Lines) == 0 {
+			continue
+		}
+		cues = append(cues, c)
+	}
+	return cues
+}
+
+// line returns the trimmed line of a row, merging the runs of the same
+// style.
+func line(row stl.TextRow) Line {
+	var l Line
+	column := -1
+	for _, run := range row {
+		text := run.Text
+		if column >= 0 && run.Column > column {
+			text = strings.Repeat(" ", run.Column-column) + text
+		}
+		column = run.Column + utf8.RuneCountInString(run.Text)
+		if len(l) == 0 {
+			text = strings.TrimLeft(text, " ")
+			if text == "" {
+				continue
+			}
+		}
+		if n := len(l); n > 0 && l[n-1].Italic == run.Style.Italic {
+			l[n-1].Text += text
+			continue
+		}
+		l = append(l, Span{Text: text, Italic: run.Style.Italic})
+	}
+	for len(l) > 0 {
+		n := len(l) - 1
+		l[n].Text = strings.TrimRight(l[n].Text, " ")
+		if l[n].Text != "" {
+			break
+		}
+		l = l[:n]
+	}
+	return l
+}
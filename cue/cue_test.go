@@ -0,0 +1,93 @@
+package cue
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/si0ls/subs/doc"
+	"github.com/si0ls/subs/internal/stltest"
+	"github.com/si0ls/subs/stl"
+)
+
+func TestDuration(t *testing.T) {
+	for _, tc := range []struct {
+		tc        stl.Timecode
+		framerate uint
+		want      time.Duration
+	}{
+		{stl.Timecode{Seconds: 1, Frames: 1}, 25, 1040 * time.Millisecond},
+		{stl.Timecode{Seconds: 1, Frames: 1}, 30, 1033 * time.Millisecond},
+		{stl.Timecode{Seconds: 1, Frames: 2}, 30, 1067 * time.Millisecond},
+	} {
+		if got := Duration(tc.tc, tc.framerate); got != tc.want {
+			t.Errorf("%s at %d fps: got %s, want %s", tc.tc, tc.framerate, got, tc.want)
+		}
+	}
+}
+
+func TestFromSTL(t *testing.T) {
+	d := stltest.File{}
+	for _, text := range []string{
+		"\x0b\x0b  Red\x01 text \u008a\u008a\x0b\x0b\u0080italic\u0081 end ",
+		"\x0b\x0b   ",
+	} {
+		d.Subtitles = append(d.Subtitles, stltest.Subtitle{TCI: stl.Timecode{Seconds: 1}, TCO: stl.Timecode{Seconds: 2, Frames: 12}, Text: text})
+	}
+	f := d.New(t)
+
+	cues, warns, err := FromSTL(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) > 0 {
+		t.Errorf("unexpected warnings: %v", warns)
+	}
+	want := []Cue{{
+		SGN:   0,
+		SN:    0,
+		Start: time.Second,
+		End:   2480 * time.Millisecond,
+		Lines: []Line{
+			{{Text: "Red  text"}},
+			{{Text: "italic", Italic: true}, {Text: " end"}},
+		},
+	}}
+	if !reflect.DeepEqual(cues, want) {
+		t.Errorf("got %+v, want %+v", cues, want)
+	}
+	if got := want[0].Lines[1].Markup("<i>", "</i>", nil); got != "<i>italic</i> end" {
+		t.Errorf("got markup %q", got)
+	}
+}
+
+func TestFromDocument(t *testing.T) {
+	italic := doc.DefaultStyle
+	italic.Italic = true
+	bold := doc.DefaultStyle
+	bold.Bold = true
+	d := &doc.Document{Cues: []*doc.Cue{
+		{Start: 2500 * time.Millisecond, End: 3 * time.Second, Lines: []doc.Line{{{Text: "Привет ", Style: doc.DefaultStyle}, {Text: "мир", Style: bold}}}},
+		{Start: 3 * time.Second, End: 4 * time.Second, Comment: true, Lines: []doc.Line{{{Text: "Note", Style: doc.DefaultStyle}}}},
+		{Start: 4 * time.Second, End: 5 * time.Second},
+		{Start: 5*time.Second + 400*time.Microsecond, End: 6 * time.Second, Lines: []doc.Line{
+			{},
+			{{Text: "italic", Style: italic}, {Text: " end", Style: doc.DefaultStyle}},
+		}},
+	}}
+
+	want := []Cue{{
+		SN:    0,
+		Start: 2500 * time.Millisecond,
+		End:   3 * time.Second,
+		Lines: []Line{{{Text: "Привет мир"}}},
+	}, {
+		SN:    3,
+		Start: 5 * time.Second,
+		End:   6 * time.Second,
+		Lines: []Line{{{Text: "italic", Italic: true}, {Text: " end"}}},
+	}}
+	if cues := FromDocument(d); !reflect.DeepEqual(cues, want) {
+		t.Errorf("got %+v, want %+v", cues, want)
+	}
+}
@@ -0,0 +1,229 @@
+// Package subs identifies subtitle files and gives access to the formats
+// of the subs module.
+//
+// Format packages (stl, ass, scc...) convert their files to and from the
+// documents of package doc, where they register themselves by name.
+// Detect gives the name of the format of a file from its content.
+package subs
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"regexp"
+
+	"github.com/si0ls/subs/spruce"
+)
+
+// SniffSize is the number of bytes read by Detect.
+const SniffSize = 4096
+
+// Detection is a format identified from the content of a file.
+type Detection struct {
+	Format     string  // Name of the format, as registered in package doc for the formats it converts
+	Confidence float64 // Confidence in the detection, from 0 (none) to 1 (certain)
+}
+
+var ErrUnknownFormat = errors.New("unknown format")
+
+var (
+	srtTiming       = regexp.MustCompile(`^\d+:\d{2}:\d{2}[,.]\d{3}\s+-->\s+\d+:\d{2}:\d{2}[,.]\d{3}`)
+	sbvTiming       = regexp.MustCompile(`^\d+:\d{2}:\d{2}\.\d{3},\d+:\d{2}:\d{2}\.\d{3}$`)
+	subviewerTiming = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}\.\d{2},\d{2}:\d{2}:\d{2}\.\d{2}$`)
+	number          = regexp.MustCompile(`^\d+$`)
+)
+
+// Binary EBU STL GSI block layout.
+var (
+	stlCodePages   = [][]byte{[]byte("437"), []byte("850"), []byte("860"), []byte("863"), []byte("865")}
+	stlDiskFormats = [][]byte{[]byte("STL25.01"), []byte("STL30.01")}
+)
+
+// Cavena 890 language identifiers, at offset 146 of the header.
+var cavenaLanguages = []byte{0x07, 0x09, 0x80, 0x90}
+
+// sniffer identifies a format: sniff returns the confidence that b, the
+// first bytes of a file split in non empty lines, are in the format.
+type sniffer struct {
+	format string
+	sniff  func(b []byte, lines []string) float64
+}
+
+// sniffers are the sniffers of the formats, in the order of preference for
+// equal confidences.
+var sniffers = []sniffer{
+	{"stl", sniffSTL},
+	{"stlxml", func(b []byte, _ []string) float64 {
+		return contains(b, "<StlXml", 1)
+	}},
+	{"ttml", func(b []byte, _ []string) float64 {
+		if bytes.Contains(b, []byte("http://www.w3.org/ns/ttml")) {
+			return 1
+		}
+		if bytes.Contains(b, []byte("<tt ")) || bytes.Contains(b, []byte("<tt>")) {
+			return 0.7
+		}
+		return 0
+	}},
+	{"vtt", func(_ []byte, lines []string) float64 {
+		if len(lines) > 0 && (lines[0] == "WEBVTT" || len(lines[0]) > 6 && lines[0][:7] == "WEBVTT ") {
+			return 1
+		}
+		return 0
+	}},
+	{"srt", func(_ []byte, lines []string) float64 {
+		if len(lines) > 1 && number.MatchString(lines[0]) && srtTiming.MatchString(lines[1]) {
+			return 1
+		}
+		for _, l := range lines {
+			if srtTiming.MatchString(l) {
+				return 0.6
+			}
+		}
+		return 0
+	}},
+	{"ass", func(b []byte, lines []string) float64 {
+		if len(lines) > 0 && lines[0] == "[Script Info]" {
+			return 1
+		}
+		if bytes.Contains(b, []byte("[V4+ Styles]")) || bytes.Contains(b, []byte("[V4 Styles]")) {
+			return 0.9
+		}
+		return 0
+	}},
+	{"sami", func(b []byte, _ []string) float64 {
+		return containsFold(b, "<SAMI>", 1)
+	}},
+	{"scc", func(_ []byte, lines []string) float64 {
+		if len(lines) > 0 && lines[0] == "Scenarist_SCC V1.0" {
+			return 1
+		}
+		return 0
+	}},
+	{"mcc", func(b []byte, _ []string) float64 {
+		return contains(b, "File Format=MacCaption_MCC", 1)
+	}},
+	{"avid", func(b []byte, _ []string) float64 {
+		if bytes.Contains(b, []byte("@ This file written with the Avid Caption plugin")) {
+			return 1
+		}
+		return containsFold(b, "<begin subtitles>", 0.9)
+	}},
+	{"subviewer", func(_ []byte, lines []string) float64 {
+		if len(lines) > 0 && lines[0] == "[INFORMATION]" {
+			return 1
+		}
+		if len(lines) > 0 && subviewerTiming.MatchString(lines[0]) {
+			return 0.8
+		}
+		return 0
+	}},
+	{"sbv", func(_ []byte, lines []string) float64 {
+		if len(lines) > 0 && sbvTiming.MatchString(lines[0]) {
+			return 0.9
+		}
+		return 0
+	}},
+	{"spruce", func(b []byte, _ []string) float64 {
+		if spruce.Detect(b) {
+			return 0.7
+		}
+		return 0
+	}},
+	{"cheetah", func(b []byte, _ []string) float64 {
+		if len(b) >= 2 && b[0] == 0xEA && b[1] == 0x22 {
+			return 0.9
+		}
+		return 0
+	}},
+	{"pac", func(b []byte, _ []string) float64 {
+		// header followed by the header of the first block
+		if len(b) >= 40 && b[0] == 0x01 && b[23] == 0x60 && b[24] == 0x00 && b[27] == 0xFF {
+			return 0.8
+		}
+		return 0
+	}},
+	{"cavena", func(b []byte, _ []string) float64 {
+		if len(b) >= 455 && bytes.IndexByte(cavenaLanguages, b[146]) >= 0 {
+			return 0.4
+		}
+		return 0
+	}},
+}
+
+// Detect reads the first SniffSize bytes of r and returns the format they
+// most likely belong to, with the confidence of the detection.
+//
+// Binary EBU STL files are identified by the Code Page Number (CPN) and
+// Disk Format Code (DFC) of their GSI block, XML formats by their root
+// element and text formats by their header or the time codes of their
+// first cue. ErrUnknownFormat is returned if no format matches.
+func Detect(r io.Reader) (Detection, error) {
+	b := make([]byte, SniffSize)
+	n, err := io.ReadFull(r, b)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return Detection{}, err
+	}
+	b = b[:n]
+	lines := textLines(b)
+
+	var best Detection
+	for _, s := range sniffers {
+		if c := s.sniff(b, lines); c > best.Confidence {
+			best = Detection{Format: s.format, Confidence: c}
+		}
+	}
+	if best.Confidence == 0 {
+		return Detection{}, ErrUnknownFormat
+	}
+	return best, nil
+}
+
+// sniffSTL identifies the GSI block of a binary EBU STL file.
+func sniffSTL(b []byte, _ []string) float64 {
+	if len(b) < 14 {
+		return 0
+	}
+	var dfc bool
+	for _, f := range stlDiskFormats {
+		dfc = dfc || bytes.Equal(b[3:11], f)
+	}
+	if !dfc {
+		return 0
+	}
+	for _, cp := range stlCodePages {
+		if bytes.Equal(b[0:3], cp) {
+			return 1
+		}
+	}
+	return 0.8
+}
+
+// textLines returns the non empty lines of b, trimmed, without byte order
+// mark.
+func textLines(b []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))))
+	scanner.Buffer(make([]byte, SniffSize), SniffSize)
+	for scanner.Scan() {
+		if l := bytes.TrimSpace(scanner.Bytes()); len(l) > 0 {
+			lines = append(lines, string(l))
+		}
+	}
+	return lines
+}
+
+// contains returns the confidence if b contains s, 0 otherwise.
+func contains(b []byte, s string, confidence float64) float64 {
+	if bytes.Contains(b, []byte(s)) {
+		return confidence
+	}
+	return 0
+}
+
+// containsFold returns the confidence if b contains s, case insensitively,
+// 0 otherwise.
+func containsFold(b []byte, s string, confidence float64) float64 {
+	return contains(bytes.ToLower(b), string(bytes.ToLower([]byte(s))), confidence)
+}
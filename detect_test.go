@@ -0,0 +1,48 @@
+package subs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	gsi := append([]byte("850STL25.011"), bytes.Repeat([]byte(" "), 1012)...)
+	cheetah := append([]byte{0xEA, 0x22, 0x02}, make([]byte, 200)...)
+	for _, tc := range []struct {
+		input      []byte
+		format     string
+		confidence float64
+	}{
+		{gsi, "stl", 1},
+		{append([]byte("123"), gsi[3:]...), "stl", 0.8},
+		{[]byte(`<?xml version="1.0"?>` + "\n<StlXml><HEAD/></StlXml>"), "stlxml", 1},
+		{[]byte(`<tt xmlns="http://www.w3.org/ns/ttml" xml:lang="en">`), "ttml", 1},
+		{[]byte("\xef\xbb\xbfWEBVTT\n\n00:01.000 --> 00:02.000\nText\n"), "vtt", 1},
+		{[]byte("1\r\n00:00:01,000 --> 00:00:02,000\r\nText\r\n"), "srt", 1},
+		{[]byte("[Script Info]\nTitle: x\n"), "ass", 1},
+		{[]byte("<SAMI>\n<HEAD>"), "sami", 1},
+		{[]byte("Scenarist_SCC V1.0\n\n00:00:00;00\t9420\n"), "scc", 1},
+		{[]byte("File Format=MacCaption_MCC V1.0\n"), "mcc", 1},
+		{[]byte("<begin subtitles>\n10:00:00:00 10:00:01:00\nText\n"), "avid", 0.9},
+		{[]byte("[INFORMATION]\n[TITLE]x\n"), "subviewer", 1},
+		{[]byte("0:00:01.000,0:00:02.000\nText\n"), "sbv", 0.9},
+		{[]byte("$FontName = Arial\n00:00:01:00 , 00:00:02:00 , Text\n"), "spruce", 0.7},
+		{cheetah, "cheetah", 0.9},
+	} {
+		got, err := Detect(bytes.NewReader(tc.input))
+		if err != nil {
+			t.Errorf("%q: %v", tc.input[:10], err)
+			continue
+		}
+		if got.Format != tc.format || got.Confidence != tc.confidence {
+			t.Errorf("%q: got %s (%.1f), want %s (%.1f)", tc.input[:10], got.Format, got.Confidence, tc.format, tc.confidence)
+		}
+	}
+}
+
+func TestDetectUnknown(t *testing.T) {
+	if _, err := Detect(strings.NewReader("plain text\n")); err != ErrUnknownFormat {
+		t.Errorf("got %v, want %v", err, ErrUnknownFormat)
+	}
+}
@@ -0,0 +1,137 @@
+// Package doc is a subtitle document model independent of any subtitle
+// format, used as the hub of conversions between formats.
+//
+// A format package converts its files to and from a Document, and
+// registers itself with Register so that any registered format can be
+// converted to any other with Convert. Formats timed in (milli)seconds and
+// holding Unicode text (ass, sami, sbv, subviewer) convert their files
+// directly, keeping times and characters which STL files can not hold.
+//
+// Formats timed in frames and laid out in Teletext rows (cavena, pac,
+// cheetah, avid, scc, mcc, spruce, t42) are modelled on stl.File, which
+// remains their internal representation: they register with RegisterSTL
+// and are converted through FromSTL and ToSTL.
+package doc
+
+import (
+	"image/color"
+	"strings"
+	"time"
+)
+
+// Document is a subtitle document.
+type Document struct {
+	Metadata  Metadata  // Descriptive information of the document
+	Framerate uint      // Frame rate of the time codes of the source, 0 if unknown
+	Regions   []*Region // Regions referenced by the cues
+	Cues      []*Cue    // Cues in display order
+}
+
+// Metadata is the descriptive information of a document. Fields are empty
+// when unknown.
+type Metadata struct {
+	Title                string        // Program title, translated
+	EpisodeTitle         string        // Episode title, translated
+	OriginalTitle        string        // Original program title
+	OriginalEpisodeTitle string        // Original episode title
+	Language             string        // ISO 639 language code of the subtitles
+	Translator           string        // Translator's name
+	TranslatorContact    string        // Translator's contact details
+	Editor               string        // Editor's name
+	EditorContact        string        // Editor's contact details
+	Publisher            string        // Publisher
+	Country              string        // Country of origin
+	Reference            string        // Subtitle list reference code
+	Created              time.Time     // Creation date
+	Revised              time.Time     // Revision date
+	Revision             int           // Revision number
+	Start                time.Duration // Time code of the start of the program
+}
+
+// Align is the horizontal alignment of the text of a region.
+type Align int
+
+const (
+	AlignDefault Align = iota // Alignment of the target format
+	AlignLeft
+	AlignCenter
+	AlignRight
+)
+
+// String returns the string representation of Align.
+func (a Align) String() string {
+	switch a {
+	case AlignLeft:
+		return "left"
+	case AlignCenter:
+		return "center"
+	case AlignRight:
+		return "right"
+	}
+	return "default"
+}
+
+// Region is an area of the screen where cues are displayed.
+type Region struct {
+	ID    string  // Identifier, unique in the document
+	Top   float64 // Position of the first line, as a fraction of the screen height (0 top, 1 bottom)
+	Align Align   // Horizontal alignment of the text
+}
+
+// Style is the presentation of a span of text.
+type Style struct {
+	Italic       bool
+	Underline    bool
+	Bold         bool
+	Flash        bool
+	DoubleHeight bool
+	Color        color.NRGBA // Text color
+	Background   color.NRGBA // Background color, transparent when the alpha is 0
+}
+
+// Default colors of a style.
+var (
+	White = color.NRGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	Black = color.NRGBA{0x00, 0x00, 0x00, 0xFF}
+)
+
+// DefaultStyle is white text on a black background.
+var DefaultStyle = Style{Color: White, Background: Black}
+
+// Span is a run of text sharing the same style.
+type Span struct {
+	Text  string
+	Style Style
+}
+
+// Line is a line of text of a cue.
+type Line []Span
+
+// String returns the text of the line without styling.
+func (l Line) String() string {
+	var sb strings.Builder
+	for _, span := range l {
+		sb.WriteString(span.Text)
+	}
+	return sb.String()
+}
+
+// Cue is a timed text of a document.
+type Cue struct {
+	ID      string        // Identifier, empty if the source has none
+	Start   time.Duration // Display start time
+	End     time.Duration // Display end time
+	Region  *Region       // Display region, nil for the default region of the target format
+	Lines   []Line        // Lines of text
+	Comment bool          // Comment not meant to be displayed
+}
+
+// Text returns the lines of the cue without styling, separated by line
+// feeds.
+func (c *Cue) Text() string {
+	lines := make([]string, len(c.Lines))
+	for i, l := range c.Lines {
+		lines[i] = l.String()
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,205 @@
+package doc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/si0ls/subs/internal/stltest"
+	"github.com/si0ls/subs/stl"
+)
+
+func testFile(t *testing.T) *stl.File {
+	f := stltest.File{Subtitles: []stltest.Subtitle{
+		{TCI: stl.Timecode{Hours: 10, Frames: 3}, TCO: stl.Timecode{Hours: 10, Seconds: 1, Frames: 24}, VP: 20, JC: stl.JustificationCodeCenteredText,
+			Text: "\x0d\x03\x0b\x0bYellow\u008a\u008a\x0b\x0b\u0080italic\u0081 text"},
+		{TCI: stl.Timecode{Hours: 10, Seconds: 2, Frames: 3}, TCO: stl.Timecode{Hours: 10, Seconds: 3, Frames: 24}, VP: 1, JC: stl.JustificationCodeLeftJustifiedText,
+			Text: "\x0b\x0bTop \x01red"},
+		{TCI: stl.Timecode{Hours: 10, Seconds: 4, Frames: 3}, TCO: stl.Timecode{Hours: 10, Seconds: 5, Frames: 24}, VP: 22, JC: stl.JustificationCodeCenteredText, CF: stl.CommentFlagTranslatorComments,
+			Text: "\x0b\x0bComment"},
+	}}.New(t)
+	f.GSI.LC = stl.LanguageCodeFrench
+	f.GSI.TPT = "Title"
+	return f
+}
+
+func TestFromSTL(t *testing.T) {
+	d, warns, err := FromSTL(testFile(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) > 0 {
+		t.Errorf("unexpected warnings: %v", warns)
+	}
+	if d.Framerate != 25 || d.Metadata.Title != "Title" || d.Metadata.Language != "fr" {
+		t.Errorf("got framerate %d metadata %+v", d.Framerate, d.Metadata)
+	}
+	if len(d.Cues) != 3 || len(d.Regions) != 3 {
+		t.Fatalf("got %d cues and %d regions, want 3 and 3", len(d.Cues), len(d.Regions))
+	}
+
+	c := d.Cues[0]
+	if c.Start != 36000*time.Second+120*time.Millisecond || c.End != 36001*time.Second+960*time.Millisecond {
+		t.Errorf("got times %s-%s", c.Start, c.End)
+	}
+	if c.Text() != "Yellow\nitalic text" || c.Region.Align != AlignCenter || c.Region.Top != 20.0/24 {
+		t.Errorf("got text %q region %+v", c.Text(), c.Region)
+	}
+	if s := c.Lines[0][0].Style; !s.DoubleHeight || s.Color != teletextColors[stl.TeletextColorYellow] {
+		t.Errorf("got first line style %+v", s)
+	}
+	if s := c.Lines[1][0].Style; !s.Italic || s.DoubleHeight {
+		t.Errorf("got second line style %+v", s)
+	}
+	if l := d.Cues[1].Lines[0]; len(l) != 2 || l[0].Text != "Top " || l[1].Text != " red" {
+		t.Errorf("got spans %+v", l)
+	}
+	if !d.Cues[2].Comment {
+		t.Error("third cue is not a comment")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	f := testFile(t)
+	d, _, err := FromSTL(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, warns, err := ToSTL(d, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) > 0 {
+		t.Errorf("unexpected warnings: %v", warns)
+	}
+	if got.GSI.LC != f.GSI.LC || got.GSI.TPT != f.GSI.TPT || got.GSI.TNS != f.GSI.TNS {
+		t.Errorf("got GSI LC %s TPT %q TNS %d", got.GSI.LC, got.GSI.TPT, got.GSI.TNS)
+	}
+	if len(got.TTI) != len(f.TTI) {
+		t.Fatalf("got %d TTI blocks, want %d", len(got.TTI), len(f.TTI))
+	}
+	for i, tti := range got.TTI {
+		want := f.TTI[i]
+		if tti.TCI != want.TCI || tti.TCO != want.TCO || tti.VP != want.VP || tti.JC != want.JC || tti.CF != want.CF || tti.TF != want.TF {
+			t.Errorf("block %d: got %s-%s VP %d JC %s CF %s TF %q, want %s-%s VP %d JC %s CF %s TF %q", i,
+				tti.TCI, tti.TCO, tti.VP, tti.JC, tti.CF, tti.TF,
+				want.TCI, want.TCO, want.VP, want.JC, want.CF, want.TF)
+		}
+	}
+}
+
+func TestToSTLWarnings(t *testing.T) {
+	d := &Document{Cues: []*Cue{
+		{Start: time.Second, End: 2 * time.Second, Lines: []Line{{{Text: "Bold", Style: Style{Bold: true, Color: White}}}}},
+		{Start: -time.Second, End: time.Second, Lines: []Line{{{Text: "Negative"}}}},
+	}}
+	open := stl.DisplayStandardCodeOpenSubtitling
+	f, warns, err := ToSTL(d, Options{Framerate: 30, DSC: &open})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) != 2 {
+		t.Errorf("got %d warnings, want 2: %v", len(warns), warns)
+	}
+	if len(f.TTI) != 1 || f.TTI[0].VP != 22 || f.TTI[0].TCI != (stl.Timecode{Seconds: 1}) {
+		t.Errorf("got %+v", f.TTI)
+	}
+}
+
+func TestToSTLAutoCCT(t *testing.T) {
+	d := &Document{Cues: []*Cue{
+		{Start: time.Second, End: 2 * time.Second, Lines: []Line{{{Text: "Привет, как дела?"}}}},
+		{Start: 3 * time.Second, End: 4 * time.Second, Lines: []Line{{{Text: "Всё хорошо ♥"}}}},
+	}}
+	f, warns, err := ToSTL(d, Options{AutoCCT: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.GSI.CCT != stl.CharacterCodeTableLatinCyrillic || f.GSI.LC != stl.LanguageCodeRussian {
+		t.Errorf("got CCT %s and LC %s, want Latin/Cyrillic and Russian", f.GSI.CCT, f.GSI.LC)
+	}
+	if len(warns) != 1 || !errors.Is(warns[0], stl.ErrUnmappableRune) {
+		t.Errorf("got %v, want one unmappable rune warning", warns)
+	}
+	if text, _ := f.TTI[0].Text(f.GSI.CCT); !strings.Contains(text, "Привет, как дела?") {
+		t.Errorf("got text %q", text)
+	}
+}
+
+func TestVisualOrder(t *testing.T) {
+	d := &Document{Cues: []*Cue{
+		{Start: time.Second, End: 2 * time.Second, Lines: []Line{{{Text: "שלום עולם"}}, {{Text: "מה שלומך?", Style: Style{Italic: true}}}}},
+	}}
+	f, warns, err := ToSTL(d, Options{AutoCCT: true, TextOrder: stl.TextOrderVisual})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) > 0 {
+		t.Errorf("unexpected warnings: %v", warns)
+	}
+	if text, _ := f.TTI[0].Text(f.GSI.CCT); !strings.Contains(text, "םלוע םולש") || !strings.Contains(text, "?ךמולש המ") {
+		t.Errorf("got text %q, want visual order", text)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded := stl.NewFile()
+	if _, err := decoded.Decode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.TextOrder != stl.TextOrderVisual {
+		t.Errorf("got order %s, want visual", decoded.TextOrder)
+	}
+	got, _, err := FromSTL(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := got.Cues[0].Lines; len(l) != 2 || l[0].String() != "שלום עולם" || l[1].String() != "מה שלומך?" || !l[1][0].Style.Italic {
+		t.Errorf("got lines %+v, want logical order", l)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	Register(Format{
+		Name: "lines",
+		Decode: func(r io.Reader) (*Document, []error, error) {
+			b, err := io.ReadAll(r)
+			if err != nil {
+				return nil, nil, err
+			}
+			d := &Document{}
+			for i, s := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+				d.Cues = append(d.Cues, &Cue{
+					Start: time.Duration(i) * time.Second,
+					End:   time.Duration(i+1) * time.Second,
+					Lines: []Line{{{Text: s, Style: DefaultStyle}}},
+				})
+			}
+			return d, nil, nil
+		},
+	})
+
+	var buf bytes.Buffer
+	if _, err := Convert(&buf, "stl", strings.NewReader("one\ntwo\n"), "lines"); err != nil {
+		t.Fatal(err)
+	}
+	f := stl.NewFile()
+	if _, err := f.Decode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.TTI) != 2 || f.TTI[1].TCI != (stl.Timecode{Seconds: 1}) {
+		t.Errorf("got %+v", *f.TTI[1])
+	}
+
+	if _, err := Convert(&buf, "lines", strings.NewReader(""), "stl"); !errors.Is(err, ErrNoEncoder) {
+		t.Errorf("got %v, want %v", err, ErrNoEncoder)
+	}
+	if _, err := Convert(&buf, "stl", strings.NewReader(""), "unknown"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("got %v, want %v", err, ErrUnknownFormat)
+	}
+}
@@ -0,0 +1,153 @@
+package doc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/si0ls/subs/stl"
+)
+
+// Format is a subtitle format registered for conversions.
+type Format struct {
+	Name       string                                          // Name of the format, e.g. "ass"
+	Extensions []string                                        // File extensions, with the leading dot
+	Decode     func(r io.Reader) (*Document, []error, error)   // Reads a document, nil if the format can not be read
+	Encode     func(w io.Writer, d *Document) ([]error, error) // Writes a document, nil if the format can not be written
+}
+
+var (
+	ErrUnknownFormat = errors.New("unknown format")
+	ErrNoDecoder     = errors.New("format can not be read")
+	ErrNoEncoder     = errors.New("format can not be written")
+)
+
+var (
+	formatsMu sync.RWMutex
+	formats   = map[string]Format{}
+)
+
+// Register makes a format available for conversions by its name. Format
+// packages register themselves in their init function.
+// Register panics if the name is empty or already registered.
+func Register(f Format) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	if f.Name == "" {
+		panic("doc: Register with an empty format name")
+	}
+	if _, ok := formats[f.Name]; ok {
+		panic("doc: Register called twice for format " + f.Name)
+	}
+	formats[f.Name] = f
+}
+
+// Lookup returns the registered format of the name.
+func Lookup(name string) (Format, error) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	f, ok := formats[name]
+	if !ok {
+		return Format{}, fmt.Errorf("%w: %s", ErrUnknownFormat, name)
+	}
+	return f, nil
+}
+
+// Formats returns the registered formats, sorted by name.
+func Formats() []Format {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	list := make([]Format, 0, len(formats))
+	for _, f := range formats {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Convert reads a document in the format from from r and writes it in the
+// format to to w. Warnings of the decoding are returned before those of the
+// encoding.
+func Convert(w io.Writer, to string, r io.Reader, from string) ([]error, error) {
+	src, err := Lookup(from)
+	if err != nil {
+		return nil, err
+	}
+	if src.Decode == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoDecoder, from)
+	}
+	dst, err := Lookup(to)
+	if err != nil {
+		return nil, err
+	}
+	if dst.Encode == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoEncoder, to)
+	}
+
+	d, warns, err := src.Decode(r)
+	if err != nil {
+		return warns, err
+	}
+	encodeWarns, err := dst.Encode(w, d)
+	return append(warns, encodeWarns...), err
+}
+
+// RegisterSTL registers a format read and written as STL files, converted
+// to and from a Document with FromSTL and ToSTL. Text is encoded with the
+// character code table fitting the document (AutoCCT). The decode or encode
+// function is nil if the format can not be read or written.
+func RegisterSTL(name string, extensions []string,
+	decode func(r io.Reader) (*stl.File, []error, error),
+	encode func(w io.Writer, f *stl.File) ([]error, error)) {
+	f := Format{Name: name, Extensions: extensions}
+	if decode != nil {
+		f.Decode = DecodeSTL(decode)
+	}
+	if encode != nil {
+		f.Encode = EncodeSTL(encode, Options{AutoCCT: true})
+	}
+	Register(f)
+}
+
+// DecodeSTL adapts a decoder of a format read as STL files to the Format
+// Decode signature, through FromSTL.
+func DecodeSTL(decode func(r io.Reader) (*stl.File, []error, error)) func(io.Reader) (*Document, []error, error) {
+	return func(r io.Reader) (*Document, []error, error) {
+		f, warns, err := decode(r)
+		if err != nil {
+			return nil, warns, err
+		}
+		d, docWarns, err := FromSTL(f)
+		return d, append(warns, docWarns...), err
+	}
+}
+
+// EncodeSTL adapts an encoder of a format written from STL files to the
+// Format Encode signature, through ToSTL with the options.
+func EncodeSTL(encode func(w io.Writer, f *stl.File) ([]error, error), opts Options) func(io.Writer, *Document) ([]error, error) {
+	return func(w io.Writer, d *Document) ([]error, error) {
+		f, warns, err := ToSTL(d, opts)
+		if err != nil {
+			return warns, err
+		}
+		encodeWarns, err := encode(w, f)
+		return append(warns, encodeWarns...), err
+	}
+}
+
+func init() {
+	Register(Format{
+		Name:       "stl",
+		Extensions: []string{".stl"},
+		Decode: DecodeSTL(func(r io.Reader) (*stl.File, []error, error) {
+			f := stl.NewFile()
+			warns, err := f.Decode(r)
+			return f, warns, err
+		}),
+		Encode: EncodeSTL(func(w io.Writer, f *stl.File) ([]error, error) {
+			return nil, f.Encode(w)
+		}, Options{AutoCCT: true}),
+	})
+}
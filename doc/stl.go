@@ -0,0 +1,454 @@
+package doc
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/si0ls/subs/stl"
+)
+
+var (
+	ErrNilGSI               = errors.New("nil GSI block")
+	ErrUnsupportedFramerate = errors.New("unsupported framerate")
+)
+
+// teletextColors are the RGB colors of the teletext colors.
+var teletextColors = map[stl.TeletextColor]color.NRGBA{
+	stl.TeletextColorBlack:   {0x00, 0x00, 0x00, 0xFF},
+	stl.TeletextColorRed:     {0xFF, 0x00, 0x00, 0xFF},
+	stl.TeletextColorGreen:   {0x00, 0xFF, 0x00, 0xFF},
+	stl.TeletextColorYellow:  {0xFF, 0xFF, 0x00, 0xFF},
+	stl.TeletextColorBlue:    {0x00, 0x00, 0xFF, 0xFF},
+	stl.TeletextColorMagenta: {0xFF, 0x00, 0xFF, 0xFF},
+	stl.TeletextColorCyan:    {0x00, 0xFF, 0xFF, 0xFF},
+	stl.TeletextColorWhite:   {0xFF, 0xFF, 0xFF, 0xFF},
+}
+
+// teletextColor returns the teletext color nearest to c.
+func teletextColor(c color.NRGBA) stl.TeletextColor {
+	nearest, min := stl.TeletextColorWhite, -1
+	for tc, rgb := range teletextColors {
+		dr, dg, db := int(c.R)-int(rgb.R), int(c.G)-int(rgb.G), int(c.B)-int(rgb.B)
+		if d := dr*dr + dg*dg + db*db; min < 0 || d < min || d == min && tc < nearest {
+			nearest, min = tc, d
+		}
+	}
+	return nearest
+}
+
+// screenRows returns the number of rows of the screen in which the Vertical
+// Position (VP) of the subtitles of a GSI block is given.
+func screenRows(gsi *stl.GSIBlock) int {
+	if gsi.DSC == stl.DisplayStandardCodeOpenSubtitling && gsi.MNR > 0 {
+		return gsi.MNR + 1
+	}
+	return 24
+}
+
+// FromSTL returns the document of f.
+//
+// Times are taken from the Time Code In (TCI) and Time Code Out (TCO) at
+// the framerate of the GSI block, rounded to the millisecond. Each
+// distinct Vertical Position (VP) and Justification Code (JC) gives a
+// region, and each row of the Text Field (TF) a line, trimmed, with the
+// gaps left by Teletext spacing attributes as spaces. Empty rows are
+// dropped. Right-to-left text is read in the order of the file.
+// Translator's comments are kept as comments; subtitles which can not be
+// decoded are returned as warnings.
+func FromSTL(f *stl.File) (*Document, []error, error) {
+	if f.GSI == nil {
+		return nil, nil, ErrNilGSI
+	}
+	framerate := f.GSI.Framerate()
+	if framerate == 0 {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedFramerate, f.GSI.DFC)
+	}
+
+	d := &Document{Framerate: framerate}
+	d.Metadata = Metadata{
+		Title:                f.GSI.TPT,
+		EpisodeTitle:         f.GSI.TET,
+		OriginalTitle:        f.GSI.OPT,
+		OriginalEpisodeTitle: f.GSI.OET,
+		Translator:           f.GSI.TN,
+		TranslatorContact:    f.GSI.TCD,
+		Editor:               f.GSI.EN,
+		EditorContact:        f.GSI.ECD,
+		Publisher:            f.GSI.PUB,
+		Country:              f.GSI.CO,
+		Reference:            f.GSI.SLR,
+		Created:              f.GSI.CD,
+		Revised:              f.GSI.RD,
+		Revision:             f.GSI.RN,
+	}
+	if lang := f.GSI.LC.ISO639_1(); lang != "" {
+		d.Metadata.Language = lang
+	} else if lang := f.GSI.LC.ISO639_2(); lang != "und" {
+		d.Metadata.Language = lang
+	}
+	if f.GSI.TCP.Validate(framerate) == nil {
+		d.Metadata.Start = duration(f.GSI.TCP, framerate)
+	}
+
+	rows := screenRows(f.GSI)
+	regions := map[[2]int]*Region{}
+	var warns []error
+	for _, tti := range f.Subtitles() {
+		textRows, err := tti.RowsOrdered(f.GSI.CCT, f.TextOrder)
+		if err != nil {
+			warns = append(warns, fmt.Errorf("subtitle %d/%d: %w", tti.SGN, tti.SN, err))
+			continue
+		}
+		c := &Cue{
+			ID:      fmt.Sprintf("%d/%d", tti.SGN, tti.SN),
+			Start:   duration(tti.TCI, framerate),
+			End:     duration(tti.TCO, framerate),
+			Comment: tti.CF == stl.CommentFlagTranslatorComments,
+		}
+		vp := tti.VP
+		for _, row := range textRows {
+			l := line(row)
+			if len(l) == 0 {
+				if len(c.Lines) == 0 {
+					vp++
+				}
+				continue
+			}
+			c.Lines = append(c.Lines, l)
+		}
+		if len(c.Lines) == 0 {
+			continue
+		}
+
+		key := [2]int{vp, int(tti.JC)}
+		r, ok := regions[key]
+		if !ok {
+			r = &Region{
+				ID:  fmt.Sprintf("region%d", len(d.Regions)+1),
+				Top: float64(vp) / float64(rows),
+			}
+			switch tti.JC {
+			case stl.JustificationCodeLeftJustifiedText:
+				r.Align = AlignLeft
+			case stl.JustificationCodeCenteredText:
+				r.Align = AlignCenter
+			case stl.JustificationCodeRightJustifiedText:
+				r.Align = AlignRight
+			}
+			regions[key] = r
+			d.Regions = append(d.Regions, r)
+		}
+		c.Region = r
+		d.Cues = append(d.Cues, c)
+	}
+	return d, warns, nil
+}
+
+// duration returns the duration of a timecode at the framerate, rounded to
+// the millisecond.
+func duration(tc stl.Timecode, framerate uint) time.Duration {
+	return tc.ToDuration(framerate).Round(time.Millisecond)
+}
+
+// line returns the trimmed line of a row, merging the runs of the same
+// style.
+func line(row stl.TextRow) Line {
+	var l Line
+	column := -1
+	for _, run := range row {
+		text := run.Text
+		if column >= 0 && run.Column > column {
+			text = strings.Repeat(" ", run.Column-column) + text
+		}
+		column = run.Column + utf8.RuneCountInString(run.Text)
+		if len(l) == 0 {
+			text = strings.TrimLeft(text, " ")
+			if text == "" {
+				continue
+			}
+		}
+		style := Style{
+			Italic:       run.Style.Italic,
+			Underline:    run.Style.Underline,
+			Flash:        run.Style.Flash,
+			DoubleHeight: run.Style.DoubleHeight,
+			Color:        teletextColors[run.Style.Foreground],
+			Background:   teletextColors[run.Style.Background],
+		}
+		if n := len(l); n > 0 && l[n-1].Style == style {
+			l[n-1].Text += text
+			continue
+		}
+		l = append(l, Span{Text: text, Style: style})
+	}
+	for len(l) > 0 {
+		n := len(l) - 1
+		l[n].Text = strings.TrimRight(l[n].Text, " ")
+		if l[n].Text != "" {
+			break
+		}
+		l = l[:n]
+	}
+	return l
+}
+
+// Options configures the conversion of a document to an STL file.
+type Options struct {
+	Framerate uint                     // Framerate, 25 or 30 (default framerate of the document if supported, 25 otherwise)
+	DSC       *stl.DisplayStandardCode // Display Standard Code (default Level-1 Teletext)
+	CCT       stl.CharacterCodeTable   // Character Code Table (default Latin)
+	Fallback  stl.Fallback             // Fallback for characters which can not be encoded in the CCT (default replace)
+	AutoCCT   bool                     // Select the CCT from the text of the cues, CCT is ignored
+	TextOrder stl.TextOrder            // Order in which right-to-left text is stored (default logical)
+}
+
+// ToSTL returns the STL file of d.
+//
+// Times are converted to time codes at the framerate, rounded to the
+// nearest frame. The top of the region of a cue gives its Vertical
+// Position (VP) and its alignment the Justification Code (JC); cues
+// without region are centered at the bottom of the screen. In Teletext
+// files, rows start with boxing codes and colors, flash and double height
+// are coded with spacing attributes. Italics and underlines are coded with
+// STL control codes. Styles which can not be coded, cues which can not be
+// encoded in the Character Code Table (CCT) and times which do not fit in
+// a time code are returned as warnings. Characters which can not be
+// encoded are handled according to the fallback and returned as warnings.
+// With AutoCCT, the CCT is the one representing the text of the cues, and
+// the language, if unknown, is guessed from it. Right-to-left text is
+// stored in the order of the options.
+func ToSTL(d *Document, opts Options) (*stl.File, []error, error) {
+	framerate := opts.Framerate
+	if framerate == 0 {
+		framerate = 25
+		if d.Framerate == 30 {
+			framerate = 30
+		}
+	}
+	if framerate != 25 && framerate != 30 {
+		return nil, nil, fmt.Errorf("%w: %d", ErrUnsupportedFramerate, framerate)
+	}
+	dsc := stl.DisplayStandardCodeLevel1Teletext
+	if opts.DSC != nil {
+		dsc = *opts.DSC
+	}
+	cct := opts.CCT
+	if cct == stl.CharacterCodeTableInvalid {
+		cct = stl.CharacterCodeTableLatin
+	}
+	var analysis stl.CharacterCodeTableAnalysis
+	if opts.AutoCCT {
+		var texts []string
+		for _, c := range d.Cues {
+			for _, l := range c.Lines {
+				texts = append(texts, l.String())
+			}
+		}
+		analysis = stl.AnalyzeCharacterCodeTable(texts...)
+		cct = analysis.CCT
+	}
+
+	f := stl.NewFile()
+	f.TextOrder = opts.TextOrder
+	f.GSI = stl.NewGSIBlock()
+	f.GSI.SetDefaults(framerate, dsc)
+	f.GSI.CCT = cct
+	m := d.Metadata
+	f.GSI.TPT, f.GSI.TET = m.Title, m.EpisodeTitle
+	f.GSI.OPT, f.GSI.OET = m.OriginalTitle, m.OriginalEpisodeTitle
+	f.GSI.TN, f.GSI.TCD = m.Translator, m.TranslatorContact
+	f.GSI.EN, f.GSI.ECD = m.Editor, m.EditorContact
+	f.GSI.PUB, f.GSI.CO, f.GSI.SLR = m.Publisher, m.Country, m.Reference
+	f.GSI.CD, f.GSI.RD, f.GSI.RN = m.Created, m.Revised, m.Revision
+	if m.Language != "" {
+		f.GSI.LC = stl.LanguageCodeFromISO639(m.Language)
+	} else if opts.AutoCCT && analysis.LC != stl.LanguageCodeUnknown {
+		f.GSI.LC = analysis.LC
+	}
+	f.GSI.TCP = timecode(m.Start, framerate)
+
+	teletext := dsc != stl.DisplayStandardCodeOpenSubtitling
+	rows := screenRows(f.GSI)
+	var warns []error
+	var sn int
+	for i, c := range d.Cues {
+		if len(c.Lines) == 0 {
+			continue
+		}
+		n := i + 1
+		if c.Start < 0 || c.End < 0 || c.End >= 24*time.Hour {
+			warns = append(warns, fmt.Errorf("cue %d: times %s-%s out of range", n, c.Start, c.End))
+			continue
+		}
+
+		tti := stl.NewTTIBlock()
+		tti.SGN = 0
+		tti.SN = sn
+		tti.EBN = stl.EBNLastBlock
+		tti.CS = stl.CumulativeStatusNone
+		tti.TCI = timecode(c.Start, framerate)
+		tti.TCO = timecode(c.End, framerate)
+		tti.CF = stl.CommentFlagSubtitleData
+		if c.Comment {
+			tti.CF = stl.CommentFlagTranslatorComments
+		}
+
+		tf, height, errs := text(c.Lines, teletext)
+		for _, err := range errs {
+			warns = append(warns, fmt.Errorf("cue %d: %w", n, err))
+		}
+		tti.JC = stl.JustificationCodeCenteredText
+		tti.VP = rows - 1 - height
+		if c.Region != nil {
+			switch c.Region.Align {
+			case AlignDefault:
+				tti.JC = stl.JustificationCodeUnchangedPresentation
+			case AlignLeft:
+				tti.JC = stl.JustificationCodeLeftJustifiedText
+			case AlignRight:
+				tti.JC = stl.JustificationCodeRightJustifiedText
+			}
+			tti.VP = int(c.Region.Top*float64(rows) + 0.5)
+		}
+		if tti.VP+height > rows {
+			tti.VP = rows - height
+		}
+		if tti.VP < 1 {
+			tti.VP = 1
+		}
+		tf = stl.ConvertTextOrder(tf, stl.TextOrderLogical, opts.TextOrder)
+		unmappables, err := tti.SetTextFallback(tf, cct, opts.Fallback)
+		if err != nil {
+			warns = append(warns, fmt.Errorf("cue %d: %w", n, err))
+			continue
+		}
+		if len(unmappables) > 0 {
+			warns = append(warns, fmt.Errorf("cue %d: %w, fallback %s", n, &stl.UnmappableError{Runes: unmappables}, opts.Fallback))
+		}
+		f.TTI = append(f.TTI, tti.ExtensionBlocks()...)
+		sn++
+	}
+	f.UpdateCounters()
+	return f, warns, nil
+}
+
+// timecode returns the timecode of a duration at the framerate, rounded to
+// the nearest frame.
+func timecode(d time.Duration, framerate uint) stl.Timecode {
+	return stl.TimecodeFromDuration(d+time.Second/time.Duration(2*framerate), framerate)
+}
+
+// text returns the Text Field (TF) of lines, before encoding, and the
+// number of rows it takes on screen.
+func text(lines []Line, teletext bool) (string, int, []error) {
+	var errs []error
+	warned := map[string]bool{}
+	warn := func(attr string) {
+		if !warned[attr] {
+			errs = append(errs, fmt.Errorf("%s not supported, removed", attr))
+			warned[attr] = true
+		}
+	}
+
+	var sb strings.Builder
+	var height int
+	var italic, underline, doubleHeight bool
+	for i, l := range lines {
+		if i > 0 {
+			sb.WriteRune(rune(stl.ControlCodeLineBreak))
+			if doubleHeight {
+				// the lower half of a double height row is left empty
+				sb.WriteRune(rune(stl.ControlCodeLineBreak))
+			}
+		}
+		style := DefaultStyle
+		if len(l) > 0 {
+			style = l[0].Style
+		}
+		doubleHeight = teletext && style.DoubleHeight
+		height++
+		if doubleHeight {
+			height++
+		}
+		if teletext {
+			// spacing attributes of the row, before the boxing codes
+			if style.DoubleHeight {
+				sb.WriteRune(rune(stl.TeletextControlCodeDoubleHeight))
+			}
+			if fg := teletextColor(style.Color); fg != stl.TeletextColorWhite {
+				sb.WriteRune(rune(fg))
+			}
+			if style.Flash {
+				sb.WriteRune(rune(stl.TeletextControlCodeFlash))
+			}
+			sb.WriteRune(rune(stl.TeletextControlCodeStartBox))
+			sb.WriteRune(rune(stl.TeletextControlCodeStartBox))
+		}
+
+		prev := style
+		for _, span := range l {
+			s := span.Style
+			text := span.Text
+			if s.Bold {
+				warn("bold")
+			}
+			if teletext {
+				// spacing attributes take the place of a leading space
+				var codes []rune
+				if s.Color != prev.Color {
+					codes = append(codes, rune(teletextColor(s.Color)))
+				}
+				if s.Flash != prev.Flash {
+					if s.Flash {
+						codes = append(codes, rune(stl.TeletextControlCodeFlash))
+					} else {
+						codes = append(codes, rune(stl.TeletextControlCodeSteady))
+					}
+				}
+				for _, code := range codes {
+					sb.WriteRune(code)
+					text = strings.TrimPrefix(text, " ")
+				}
+				if s.DoubleHeight != style.DoubleHeight {
+					warn("double height change within a row")
+				}
+				if s.Background.A != 0 && teletextColor(s.Background) != stl.TeletextColorBlack {
+					warn("background color")
+				}
+			} else {
+				if s.Color != White && s.Color != (color.NRGBA{}) {
+					warn("color")
+				}
+				if s.Flash {
+					warn("flash")
+				}
+				if s.DoubleHeight {
+					warn("double height")
+				}
+			}
+			if s.Italic != italic {
+				if s.Italic {
+					sb.WriteRune(rune(stl.ControlCodeItalicOn))
+				} else {
+					sb.WriteRune(rune(stl.ControlCodeItalicOff))
+				}
+				italic = s.Italic
+			}
+			if s.Underline != underline {
+				if s.Underline {
+					sb.WriteRune(rune(stl.ControlCodeUnderlineOn))
+				} else {
+					sb.WriteRune(rune(stl.ControlCodeUnderlineOff))
+				}
+				underline = s.Underline
+			}
+			sb.WriteString(text)
+			prev = s
+		}
+	}
+	return sb.String(), height, errs
+}
@@ -0,0 +1,200 @@
+package dvbsub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/si0ls/subs/internal/stltest"
+	"github.com/si0ls/subs/stl"
+)
+
+type bitReader struct {
+	b    []byte
+	nbit int
+}
+
+func (r *bitReader) read(n int) uint {
+	var v uint
+	for i := 0; i < n; i++ {
+		bit := r.b[r.nbit/8] >> uint(7-r.nbit%8) & 1
+		v = v<<1 | uint(bit)
+		r.nbit++
+	}
+	return v
+}
+
+// decodeCodeString decodes a pixel code string (ETSI EN 300 743 section
+// 7.2.5.2) up to its end of string signal.
+func decodeCodeString(b []byte, depth int) []byte {
+	r := bitReader{b: b}
+	var pixels []byte
+	add := func(c uint, n uint) {
+		for i := uint(0); i < n; i++ {
+			pixels = append(pixels, byte(c))
+		}
+	}
+	for {
+		switch depth {
+		case 2:
+			if c := r.read(2); c != 0 {
+				add(c, 1)
+			} else if r.read(1) == 1 {
+				n := r.read(3) + 3
+				add(r.read(2), n)
+			} else if r.read(1) == 1 {
+				add(0, 1)
+			} else {
+				switch r.read(2) {
+				case 0:
+					return pixels
+				case 1:
+					add(0, 2)
+				case 2:
+					n := r.read(4) + 12
+					add(r.read(2), n)
+				case 3:
+					n := r.read(8) + 29
+					add(r.read(2), n)
+				}
+			}
+		case 4:
+			if c := r.read(4); c != 0 {
+				add(c, 1)
+			} else if r.read(1) == 0 {
+				n := r.read(3)
+				if n == 0 {
+					return pixels
+				}
+				add(0, n+2)
+			} else if r.read(1) == 0 {
+				n := r.read(2) + 4
+				add(r.read(4), n)
+			} else {
+				switch r.read(2) {
+				case 0:
+					add(0, 1)
+				case 1:
+					add(0, 2)
+				case 2:
+					n := r.read(4) + 9
+					add(r.read(4), n)
+				case 3:
+					n := r.read(8) + 25
+					add(r.read(4), n)
+				}
+			}
+		case 8:
+			if c := r.read(8); c != 0 {
+				add(c, 1)
+			} else if r.read(1) == 0 {
+				n := r.read(7)
+				if n == 0 {
+					return pixels
+				}
+				add(0, n)
+			} else {
+				n := r.read(7)
+				add(r.read(8), n)
+			}
+		}
+	}
+}
+
+func TestCodeStrings(t *testing.T) {
+	var pixels []byte
+	for _, run := range []struct {
+		code byte
+		n    int
+	}{
+		{0, 1}, {1, 1}, {0, 2}, {2, 2}, {0, 3}, {3, 3}, {1, 5}, {0, 8}, {2, 8},
+		{1, 11}, {0, 20}, {3, 26}, {0, 30}, {1, 300}, {0, 700}, {2, 1},
+	} {
+		pixels = append(pixels, bytes.Repeat([]byte{run.code}, run.n)...)
+	}
+
+	for _, depth := range []int{2, 4, 8} {
+		var b []byte
+		switch depth {
+		case 2:
+			b = codeString2Bit(pixels)
+		case 4:
+			b = codeString4Bit(pixels)
+		case 8:
+			b = codeString8Bit(pixels)
+		}
+		if got := decodeCodeString(b, depth); !bytes.Equal(got, pixels) {
+			t.Errorf("%d-bit: decoded %d pixels differ from the %d encoded", depth, len(got), len(pixels))
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	f := stltest.File{Subtitles: []stltest.Subtitle{
+		{TCI: stl.Timecode{Hours: 10, Seconds: 1}, TCO: stl.Timecode{Hours: 10, Seconds: 2, Frames: 12}, VP: 22, JC: stl.JustificationCodeCenteredText, Text: "\x0b\x0bHello"},
+	}}.New(t)
+	f.GSI.TCP = stl.Timecode{Hours: 10}
+
+	var buf bytes.Buffer
+	if _, err := Encode(&buf, f, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.Bytes()
+	for i, want := range []struct {
+		pts      int64
+		segments []SegmentType
+	}{
+		{90000, []SegmentType{
+			SegmentTypeDisplayDefinition,
+			SegmentTypePageComposition,
+			SegmentTypeRegionComposition,
+			SegmentTypeCLUTDefinition,
+			SegmentTypeObjectData,
+			SegmentTypeEndOfDisplaySet,
+		}},
+		{90000 * 62 / 25, []SegmentType{
+			SegmentTypePageComposition,
+			SegmentTypeEndOfDisplaySet,
+		}},
+	} {
+		if len(b) < 14 || !bytes.Equal(b[:4], []byte{0x00, 0x00, 0x01, 0xBD}) {
+			t.Fatalf("PES %d: bad start code", i)
+		}
+		length := int(binary.BigEndian.Uint16(b[4:]))
+		pes := b[6 : 6+length]
+		b = b[6+length:]
+
+		ptsBytes := pes[3:8]
+		pts := int64(ptsBytes[0]>>1&0x07)<<30 | int64(ptsBytes[1])<<22 | int64(ptsBytes[2]>>1)<<15 | int64(ptsBytes[3])<<7 | int64(ptsBytes[4]>>1)
+		if pts != want.pts {
+			t.Errorf("PES %d: expected PTS %d, got %d", i, want.pts, pts)
+		}
+
+		data := pes[8:]
+		if data[0] != dataIdentifier || data[1] != subtitleStreamID || data[len(data)-1] != endOfPESDataMarker {
+			t.Fatalf("PES %d: bad PES data field", i)
+		}
+		data = data[2 : len(data)-1]
+		var types []SegmentType
+		for len(data) > 0 {
+			if data[0] != syncByte {
+				t.Fatalf("PES %d: bad segment sync byte", i)
+			}
+			types = append(types, SegmentType(data[1]))
+			data = data[6+int(binary.BigEndian.Uint16(data[4:])):]
+		}
+		if len(types) != len(want.segments) {
+			t.Fatalf("PES %d: expected segments %v, got %v", i, want.segments, types)
+		}
+		for j := range types {
+			if types[j] != want.segments[j] {
+				t.Errorf("PES %d: expected segments %v, got %v", i, want.segments, types)
+				break
+			}
+		}
+	}
+	if len(b) != 0 {
+		t.Errorf("%d unexpected trailing bytes", len(b))
+	}
+}
@@ -0,0 +1,163 @@
+package dvbsub
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/si0ls/subs/render"
+	"github.com/si0ls/subs/stl"
+)
+
+// Options configures a DVB subtitle encoding.
+type Options struct {
+	PageID uint16          // Composition page id (default 1)
+	Depth  int             // Bits per pixel: 2, 4 or 8 (default 4)
+	Start  *stl.Timecode   // Timecode of PTS 0 (default GSI Time Code: Start-of-Programme (TCP))
+	Render *render.Options // Rendering options (default render.DefaultOptions), frame size is set from the GSI frame rate
+}
+
+var (
+	ErrUnsupportedDepth     = errors.New("unsupported pixel depth")
+	ErrUnsupportedFramerate = errors.New("unsupported framerate")
+)
+
+const (
+	regionID = 0
+	clutID   = 0
+	objectID = 0
+)
+
+// DisplaySets renders the subtitles of f and returns the display sets
+// showing each of them at its Time Code In (TCI) and clearing it at its
+// Time Code Out (TCO), unless the next subtitle replaces it.
+//
+// A subtitle is shown as a single region cropped to the rendered text,
+// with its own CLUT of the most frequent colors of the bitmap. The display
+// is 720x576 at 25 fps and 720x480 at 30 fps.
+// Subtitles starting before Start are skipped and returned as warnings.
+func DisplaySets(f *stl.File, opts Options) ([]DisplaySet, []error, error) {
+	if f.GSI == nil {
+		return nil, nil, render.ErrNilGSI
+	}
+	if opts.PageID == 0 {
+		opts.PageID = 1
+	}
+	if opts.Depth == 0 {
+		opts.Depth = 4
+	}
+	if opts.Depth != 2 && opts.Depth != 4 && opts.Depth != 8 {
+		return nil, nil, fmt.Errorf("%w: %d", ErrUnsupportedDepth, opts.Depth)
+	}
+	framerate := f.GSI.Framerate()
+	if framerate == 0 {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedFramerate, f.GSI.DFC)
+	}
+	start := f.GSI.TCP
+	if opts.Start != nil {
+		start = *opts.Start
+	}
+	startFrame := start.ToFrames(framerate)
+
+	renderOpts := render.DefaultOptions()
+	if opts.Render != nil {
+		renderOpts = *opts.Render
+	}
+	renderOpts.Width, renderOpts.Height = 0, 0
+	r, err := render.New(renderOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+	stills, err := r.RenderFile(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pts := func(tc stl.Timecode) int64 {
+		return int64(tc.ToFrames(framerate)-startFrame) * 90000 / int64(framerate)
+	}
+
+	var warns []error
+	var sets []DisplaySet
+	var version int
+	for i, still := range stills {
+		tti := still.TTI
+		if pts(tti.TCI) < 0 {
+			warns = append(warns, fmt.Errorf("subtitle %d/%d: Time Code In %s before start %s, skipped", tti.SGN, tti.SN, tti.TCI, start))
+			continue
+		}
+		bounds := render.OpaqueBounds(still.Image)
+		if bounds.Empty() {
+			continue
+		}
+
+		palette, indexes := Quantize(still.Image, bounds, 1<<opts.Depth)
+		entries := make([]CLUTEntry, len(palette))
+		for i, c := range palette {
+			entries[i] = NewCLUTEntry(c)
+		}
+		var top, bottom []int
+		for line := 0; line < bounds.Dy(); line++ {
+			if line%2 == 0 {
+				top = append(top, line)
+			} else {
+				bottom = append(bottom, line)
+			}
+		}
+
+		size := still.Image.Bounds()
+		timeout := int((pts(tti.TCO)-pts(tti.TCI)+89999)/90000) + 1
+		if timeout > 255 {
+			timeout = 255
+		}
+		sets = append(sets, DisplaySet{
+			PTS: pts(tti.TCI),
+			Segments: []Segment{
+				DisplayDefinition(opts.PageID, version, size.Dx(), size.Dy()),
+				PageComposition(opts.PageID, version, PageStateModeChange, timeout, []PageRegion{
+					{ID: regionID, X: bounds.Min.X, Y: bounds.Min.Y},
+				}),
+				RegionComposition(opts.PageID, regionID, version, bounds.Dx(), bounds.Dy(), opts.Depth, clutID, objectID),
+				CLUTDefinition(opts.PageID, clutID, version, opts.Depth, entries),
+				ObjectData(opts.PageID, objectID, version,
+					PixelData(indexes, bounds.Dx(), top, opts.Depth),
+					PixelData(indexes, bounds.Dx(), bottom, opts.Depth)),
+				EndOfDisplaySet(opts.PageID),
+			},
+		})
+		version++
+
+		if i+1 < len(stills) && pts(stills[i+1].TTI.TCI) <= pts(tti.TCO) {
+			continue
+		}
+		sets = append(sets, DisplaySet{
+			PTS: pts(tti.TCO),
+			Segments: []Segment{
+				PageComposition(opts.PageID, version, PageStateNormalCase, 0, nil),
+				EndOfDisplaySet(opts.PageID),
+			},
+		})
+		version++
+	}
+
+	return sets, warns, nil
+}
+
+// Encode writes the display sets of the subtitles of f to w as a sequence
+// of PES packets. See DisplaySets for the subtitle encoding.
+func Encode(w io.Writer, f *stl.File, opts Options) ([]error, error) {
+	sets, warns, err := DisplaySets(f, opts)
+	if err != nil {
+		return warns, err
+	}
+	for _, ds := range sets {
+		b, err := ds.PES()
+		if err != nil {
+			return warns, err
+		}
+		if _, err := w.Write(b); err != nil {
+			return warns, err
+		}
+	}
+	return warns, nil
+}
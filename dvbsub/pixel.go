@@ -0,0 +1,317 @@
+package dvbsub
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// Pixel data sub-block data types.
+const (
+	dataType2BitCodeString = 0x10
+	dataType4BitCodeString = 0x11
+	dataType8BitCodeString = 0x12
+	dataTypeEndOfLine      = 0xF0
+)
+
+// CLUTEntry is a CLUT entry: luminance, chrominance and transparency (0 is
+// opaque, 255 fully transparent).
+type CLUTEntry struct {
+	Y, Cr, Cb, T byte
+}
+
+// transparentEntry is the fully transparent CLUT entry, a zero luminance
+// means full transparency.
+var transparentEntry = CLUTEntry{Y: 0, Cr: 0x80, Cb: 0x80, T: 0xFF}
+
+// NewCLUTEntry returns the CLUT entry of c, with ITU-R BT.601 studio range
+// luminance and chrominance.
+func NewCLUTEntry(c color.Color) CLUTEntry {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if n.A == 0 {
+		return transparentEntry
+	}
+	y, cb, cr := color.RGBToYCbCr(n.R, n.G, n.B)
+	return CLUTEntry{
+		Y:  byte(16 + int(y)*219/255),
+		Cr: byte(128 + (int(cr)-128)*224/255),
+		Cb: byte(128 + (int(cb)-128)*224/255),
+		T:  0xFF - n.A,
+	}
+}
+
+// Quantize returns a palette of at most n colors for the pixels of img in
+// rect and the palette index of each pixel, row by row.
+// Index 0 is fully transparent, the other entries are the most frequent
+// colors of the image; the other colors are mapped to the nearest entry.
+func Quantize(img image.Image, rect image.Rectangle, n int) ([]color.NRGBA, []byte) {
+	counts := make(map[color.NRGBA]int)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			if c := nrgbaAt(img, x, y); c.A != 0 {
+				counts[c]++
+			}
+		}
+	}
+	colors := make([]color.NRGBA, 0, len(counts))
+	for c := range counts {
+		colors = append(colors, c)
+	}
+	sort.Slice(colors, func(i, j int) bool {
+		ci, cj := colors[i], colors[j]
+		if counts[ci] != counts[cj] {
+			return counts[ci] > counts[cj]
+		}
+		return uint32(ci.R)<<24|uint32(ci.G)<<16|uint32(ci.B)<<8|uint32(ci.A) <
+			uint32(cj.R)<<24|uint32(cj.G)<<16|uint32(cj.B)<<8|uint32(cj.A)
+	})
+	if len(colors) > n-1 {
+		colors = colors[:n-1]
+	}
+	palette := append([]color.NRGBA{{}}, colors...)
+
+	nearest := make(map[color.NRGBA]byte)
+	indexes := make([]byte, 0, rect.Dx()*rect.Dy())
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			c := nrgbaAt(img, x, y)
+			if c.A == 0 {
+				indexes = append(indexes, 0)
+				continue
+			}
+			i, ok := nearest[c]
+			if !ok {
+				i = nearestIndex(palette, c)
+				nearest[c] = i
+			}
+			indexes = append(indexes, i)
+		}
+	}
+	return palette, indexes
+}
+
+func nrgbaAt(img image.Image, x, y int) color.NRGBA {
+	return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+}
+
+func nearestIndex(palette []color.NRGBA, c color.NRGBA) byte {
+	best, bestDist := 0, -1
+	for i, p := range palette {
+		dr, dg, db, da := int(p.R)-int(c.R), int(p.G)-int(c.G), int(p.B)-int(c.B), int(p.A)-int(c.A)
+		if d := dr*dr + dg*dg + db*db + da*da; bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return byte(best)
+}
+
+// bitWriter writes bits MSB first.
+type bitWriter struct {
+	b    []byte
+	nbit int
+}
+
+func (w *bitWriter) write(v uint, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.nbit%8 == 0 {
+			w.b = append(w.b, 0)
+		}
+		if v>>uint(i)&1 != 0 {
+			w.b[len(w.b)-1] |= 0x80 >> uint(w.nbit%8)
+		}
+		w.nbit++
+	}
+}
+
+// PixelData returns the pixel data sub-blocks of the lines of a bitmap of
+// the given width, coded at depth (2, 4 or 8 bits per pixel), each line
+// terminated by an end of object line code.
+func PixelData(indexes []byte, width int, lines []int, depth int) []byte {
+	var b []byte
+	for _, line := range lines {
+		pixels := indexes[line*width : (line+1)*width]
+		switch depth {
+		case 2:
+			b = append(b, dataType2BitCodeString)
+			b = append(b, codeString2Bit(pixels)...)
+		case 8:
+			b = append(b, dataType8BitCodeString)
+			b = append(b, codeString8Bit(pixels)...)
+		default:
+			b = append(b, dataType4BitCodeString)
+			b = append(b, codeString4Bit(pixels)...)
+		}
+		b = append(b, dataTypeEndOfLine)
+	}
+	return b
+}
+
+// runs calls fn for each run of identical pixels.
+func runs(pixels []byte, fn func(code byte, n int)) {
+	for i := 0; i < len(pixels); {
+		j := i + 1
+		for j < len(pixels) && pixels[j] == pixels[i] {
+			j++
+		}
+		fn(pixels[i], j-i)
+		i = j
+	}
+}
+
+// codeString2Bit returns the 2-bit/pixel code string of the pixels.
+func codeString2Bit(pixels []byte) []byte {
+	var w bitWriter
+	runs(pixels, func(code byte, n int) {
+		c := uint(code)
+		for n > 0 {
+			switch {
+			case n >= 29:
+				m := min(n, 284)
+				w.write(0, 2)
+				w.write(0, 1)
+				w.write(0, 1)
+				w.write(3, 2)
+				w.write(uint(m-29), 8)
+				w.write(c, 2)
+				n -= m
+			case n >= 12:
+				m := min(n, 27)
+				w.write(0, 2)
+				w.write(0, 1)
+				w.write(0, 1)
+				w.write(2, 2)
+				w.write(uint(m-12), 4)
+				w.write(c, 2)
+				n -= m
+			case n >= 3:
+				m := min(n, 10)
+				w.write(0, 2)
+				w.write(1, 1)
+				w.write(uint(m-3), 3)
+				w.write(c, 2)
+				n -= m
+			case c != 0:
+				w.write(c, 2)
+				n--
+			case n == 2:
+				w.write(0, 2)
+				w.write(0, 1)
+				w.write(0, 1)
+				w.write(1, 2)
+				n -= 2
+			default:
+				w.write(0, 2)
+				w.write(0, 1)
+				w.write(1, 1)
+				n--
+			}
+		}
+	})
+	// end of string signal
+	w.write(0, 2)
+	w.write(0, 1)
+	w.write(0, 1)
+	w.write(0, 2)
+	return w.b
+}
+
+// codeString4Bit returns the 4-bit/pixel code string of the pixels.
+func codeString4Bit(pixels []byte) []byte {
+	var w bitWriter
+	runs(pixels, func(code byte, n int) {
+		c := uint(code)
+		for n > 0 {
+			switch {
+			case n >= 25:
+				m := min(n, 280)
+				w.write(0, 4)
+				w.write(1, 1)
+				w.write(1, 1)
+				w.write(3, 2)
+				w.write(uint(m-25), 8)
+				w.write(c, 4)
+				n -= m
+			case n >= 9:
+				m := min(n, 24)
+				w.write(0, 4)
+				w.write(1, 1)
+				w.write(1, 1)
+				w.write(2, 2)
+				w.write(uint(m-9), 4)
+				w.write(c, 4)
+				n -= m
+			case c == 0 && n >= 3:
+				w.write(0, 4)
+				w.write(0, 1)
+				w.write(uint(n-2), 3)
+				n = 0
+			case n >= 4:
+				m := min(n, 7)
+				w.write(0, 4)
+				w.write(1, 1)
+				w.write(0, 1)
+				w.write(uint(m-4), 2)
+				w.write(c, 4)
+				n -= m
+			case c != 0:
+				w.write(c, 4)
+				n--
+			case n == 2:
+				w.write(0, 4)
+				w.write(1, 1)
+				w.write(1, 1)
+				w.write(1, 2)
+				n -= 2
+			default:
+				w.write(0, 4)
+				w.write(1, 1)
+				w.write(1, 1)
+				w.write(0, 2)
+				n--
+			}
+		}
+	})
+	// end of string signal
+	w.write(0, 4)
+	w.write(0, 1)
+	w.write(0, 3)
+	return w.b
+}
+
+// codeString8Bit returns the 8-bit/pixel code string of the pixels.
+func codeString8Bit(pixels []byte) []byte {
+	var w bitWriter
+	runs(pixels, func(code byte, n int) {
+		c := uint(code)
+		for n > 0 {
+			m := min(n, 127)
+			switch {
+			case c == 0:
+				w.write(0, 8)
+				w.write(0, 1)
+				w.write(uint(m), 7)
+			case m >= 3:
+				w.write(0, 8)
+				w.write(1, 1)
+				w.write(uint(m), 7)
+				w.write(c, 8)
+			default:
+				m = 1
+				w.write(c, 8)
+			}
+			n -= m
+		}
+	})
+	// end of string signal
+	w.write(0, 8)
+	w.write(0, 1)
+	w.write(0, 7)
+	return w.b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,191 @@
+// Package dvbsub encodes STL files as DVB subtitles (ETSI EN 300 743):
+// each subtitle is rendered to a bitmap and sent as a display set of
+// subtitling segments in a PES packet, ready to be multiplexed in a
+// transport stream.
+package dvbsub
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// SegmentType is the type of a subtitling segment.
+type SegmentType byte
+
+const (
+	SegmentTypePageComposition   SegmentType = 0x10
+	SegmentTypeRegionComposition SegmentType = 0x11
+	SegmentTypeCLUTDefinition    SegmentType = 0x12
+	SegmentTypeObjectData        SegmentType = 0x13
+	SegmentTypeDisplayDefinition SegmentType = 0x14
+	SegmentTypeEndOfDisplaySet   SegmentType = 0x80
+)
+
+// PageState is the state of a page composition.
+type PageState byte
+
+const (
+	PageStateNormalCase       PageState = 0x0
+	PageStateAcquisitionPoint PageState = 0x1
+	PageStateModeChange       PageState = 0x2
+)
+
+const (
+	syncByte               = 0x0F
+	dataIdentifier         = 0x20
+	subtitleStreamID       = 0x00
+	endOfPESDataMarker     = 0xFF
+	privateStream1         = 0xBD
+	maxPESPacketLength     = 0xFFFF
+	objectCodingPixels     = 0x0
+	objectTypeBitmap       = 0x0
+	objectProviderInStream = 0x0
+)
+
+var ErrPESTooLarge = errors.New("display set too large for a PES packet")
+
+// Segment is a subtitling segment.
+type Segment struct {
+	Type   SegmentType
+	PageID uint16
+	Data   []byte
+}
+
+// Bytes returns the binary representation of the segment.
+func (s Segment) Bytes() []byte {
+	b := make([]byte, 6, 6+len(s.Data))
+	b[0] = syncByte
+	b[1] = byte(s.Type)
+	binary.BigEndian.PutUint16(b[2:], s.PageID)
+	binary.BigEndian.PutUint16(b[4:], uint16(len(s.Data)))
+	return append(b, s.Data...)
+}
+
+// DisplayDefinition returns a display definition segment for a display of
+// width by height pixels.
+func DisplayDefinition(pageID uint16, version int, width, height int) Segment {
+	data := make([]byte, 5)
+	data[0] = byte(version&0x0F)<<4 | 0x07 // no display window
+	binary.BigEndian.PutUint16(data[1:], uint16(width-1))
+	binary.BigEndian.PutUint16(data[3:], uint16(height-1))
+	return Segment{Type: SegmentTypeDisplayDefinition, PageID: pageID, Data: data}
+}
+
+// PageRegion is a region displayed by a page composition.
+type PageRegion struct {
+	ID   byte
+	X, Y int
+}
+
+// PageComposition returns a page composition segment showing the regions
+// for timeout seconds at most. A page without regions clears the display.
+func PageComposition(pageID uint16, version int, state PageState, timeout int, regions []PageRegion) Segment {
+	data := []byte{byte(timeout), byte(version&0x0F)<<4 | byte(state&0x03)<<2 | 0x03}
+	for _, r := range regions {
+		data = append(data, r.ID, 0xFF, byte(r.X>>8), byte(r.X), byte(r.Y>>8), byte(r.Y))
+	}
+	return Segment{Type: SegmentTypePageComposition, PageID: pageID, Data: data}
+}
+
+// RegionComposition returns a region composition segment for a region of
+// width by height pixels at the given depth (2, 4 or 8 bits per pixel)
+// holding the object at its top left corner.
+// The region is filled with the transparent CLUT entry 0.
+func RegionComposition(pageID uint16, regionID byte, version int, width, height, depth int, clutID byte, objectID uint16) Segment {
+	level := depthCode(depth)
+	data := make([]byte, 10, 16)
+	data[0] = regionID
+	data[1] = byte(version&0x0F)<<4 | 0x08 | 0x07 // fill flag
+	binary.BigEndian.PutUint16(data[2:], uint16(width))
+	binary.BigEndian.PutUint16(data[4:], uint16(height))
+	data[6] = level<<5 | level<<2 | 0x03
+	data[7] = clutID
+	data[8] = 0x00                     // 8-bit pixel code for fill
+	data[9] = 0x00<<4 | 0x00<<2 | 0x03 // 4-bit and 2-bit pixel codes for fill
+	data = append(data, byte(objectID>>8), byte(objectID))
+	data = append(data, objectTypeBitmap<<6|objectProviderInStream<<4, 0x00) // horizontal position 0
+	data = append(data, 0xF0, 0x00)                                          // vertical position 0
+	return Segment{Type: SegmentTypeRegionComposition, PageID: pageID, Data: data}
+}
+
+// CLUTDefinition returns a CLUT definition segment defining the entries of
+// palette for the given depth, with full range (8 bits) values.
+func CLUTDefinition(pageID uint16, clutID byte, version int, depth int, palette []CLUTEntry) Segment {
+	flag := byte(0x80) >> (depthCode(depth) - 1)
+	data := []byte{clutID, byte(version&0x0F)<<4 | 0x0F}
+	for i, e := range palette {
+		data = append(data, byte(i), flag|0x1E|0x01, e.Y, e.Cr, e.Cb, e.T)
+	}
+	return Segment{Type: SegmentTypeCLUTDefinition, PageID: pageID, Data: data}
+}
+
+// ObjectData returns an object data segment holding the pixel coded top
+// and bottom fields of a bitmap object.
+func ObjectData(pageID uint16, objectID uint16, version int, top, bottom []byte) Segment {
+	data := make([]byte, 7, 8+len(top)+len(bottom))
+	binary.BigEndian.PutUint16(data, objectID)
+	data[2] = byte(version&0x0F)<<4 | objectCodingPixels<<2 | 0x01
+	binary.BigEndian.PutUint16(data[3:], uint16(len(top)))
+	binary.BigEndian.PutUint16(data[5:], uint16(len(bottom)))
+	data = append(data, top...)
+	data = append(data, bottom...)
+	if (len(top)+len(bottom))%2 == 1 {
+		data = append(data, 0x00) // 8 stuff bits
+	}
+	return Segment{Type: SegmentTypeObjectData, PageID: pageID, Data: data}
+}
+
+// EndOfDisplaySet returns an end of display set segment.
+func EndOfDisplaySet(pageID uint16) Segment {
+	return Segment{Type: SegmentTypeEndOfDisplaySet, PageID: pageID}
+}
+
+// depthCode returns the region level of compatibility and depth code of a
+// pixel depth: 1 for 2 bits, 2 for 4 bits, 3 for 8 bits.
+func depthCode(depth int) byte {
+	switch depth {
+	case 2:
+		return 1
+	case 8:
+		return 3
+	}
+	return 2
+}
+
+// DisplaySet is the set of segments displaying (or clearing) a subtitle at
+// a presentation time.
+type DisplaySet struct {
+	PTS      int64 // Presentation time stamp (90 kHz clock)
+	Segments []Segment
+}
+
+// PES returns the PES packet (private stream 1) carrying the display set.
+func (ds DisplaySet) PES() ([]byte, error) {
+	payload := []byte{dataIdentifier, subtitleStreamID}
+	for _, s := range ds.Segments {
+		payload = append(payload, s.Bytes()...)
+	}
+	payload = append(payload, endOfPESDataMarker)
+
+	// 3 bytes of flags and header length, 5 bytes of PTS
+	length := 3 + 5 + len(payload)
+	if length > maxPESPacketLength {
+		return nil, fmt.Errorf("%w: %d bytes", ErrPESTooLarge, length)
+	}
+
+	pts := ds.PTS & (1<<33 - 1)
+	b := []byte{
+		0x00, 0x00, 0x01, privateStream1,
+		byte(length >> 8), byte(length),
+		0x84, // data alignment indicator
+		0x80, // PTS only
+		0x05, // PES header data length
+		0x21 | byte(pts>>29)&0x0E,
+		byte(pts >> 22),
+		0x01 | byte(pts>>14)&0xFE,
+		byte(pts >> 7),
+		0x01 | byte(pts<<1)&0xFE,
+	}
+	return append(b, payload...), nil
+}
@@ -2,4 +2,6 @@ module github.com/si0ls/subs
 
 go 1.19
 
-require golang.org/x/text v0.5.0
+require golang.org/x/text v0.14.0
+
+require golang.org/x/image v0.14.0
@@ -0,0 +1,58 @@
+// Package stltest builds the STL files used by the tests of the format
+// packages.
+package stltest
+
+import (
+	"testing"
+
+	"github.com/si0ls/subs/stl"
+)
+
+// File describes a Level-1 Teletext STL file of a single subtitle group.
+type File struct {
+	Framerate uint                   // Framerate, 25 or 30 (0: 25)
+	CCT       stl.CharacterCodeTable // Character Code Table of the texts
+	TextOrder stl.TextOrder          // Order the right-to-left texts are stored in
+	Subtitles []Subtitle
+}
+
+// Subtitle describes a subtitle of a File.
+type Subtitle struct {
+	TCI, TCO stl.Timecode
+	VP       int
+	JC       stl.JustificationCode
+	CF       stl.CommentFlag
+	Text     string // UTF-8 text in logical order, control codes as runes (e.g. "\u008a")
+}
+
+// New returns the STL file, its GSI block set with GSIBlock.SetDefaults
+// and its counters updated. Subtitles are numbered from 0, each in a single
+// TTI block. The test fails if a text can not be encoded.
+func (d File) New(t testing.TB) *stl.File {
+	t.Helper()
+
+	framerate := d.Framerate
+	if framerate == 0 {
+		framerate = 25
+	}
+	f := stl.NewFile()
+	f.GSI = stl.NewGSIBlock()
+	f.GSI.SetDefaults(framerate, stl.DisplayStandardCodeLevel1Teletext)
+	f.GSI.CCT = d.CCT
+	f.TextOrder = d.TextOrder
+	for i, s := range d.Subtitles {
+		tti := stl.NewTTIBlock()
+		tti.SGN, tti.SN, tti.EBN = 0, i, stl.EBNLastBlock
+		tti.CS = stl.CumulativeStatusNone
+		tti.TCI, tti.TCO = s.TCI, s.TCO
+		tti.VP = s.VP
+		tti.JC = s.JC
+		tti.CF = s.CF
+		if err := tti.SetTextOrdered(s.Text, d.CCT, d.TextOrder); err != nil {
+			t.Fatalf("subtitle %d: %v", i, err)
+		}
+		f.TTI = append(f.TTI, tti)
+	}
+	f.UpdateCounters()
+	return f
+}
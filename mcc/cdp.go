@@ -0,0 +1,105 @@
+package mcc
+
+import "github.com/si0ls/subs/stl"
+
+const (
+	cdpIdentifier1     = 0x96
+	cdpIdentifier2     = 0x69
+	cdpTimeCodeSection = 0x71
+	cdpCCDataSection   = 0x72
+	cdpFooterSection   = 0x74
+
+	cdpFlagTimeCodePresent      = 0x80
+	cdpFlagCCDataPresent        = 0x40
+	cdpFlagCaptionServiceActive = 0x02
+	cdpFlagReserved             = 0x01
+)
+
+// CDP frame rate codes.
+const (
+	frameRate25    = 0x3
+	frameRate29_97 = 0x4
+)
+
+// cc_data triplet markers.
+const (
+	ccNTSCField1  = 0xFC // valid CEA-608 field 1 data
+	ccNTSCField2  = 0xFD // valid CEA-608 field 2 data
+	ccPacketData  = 0xFE // valid DTVCC packet data
+	ccPacketStart = 0xFF // valid DTVCC packet start
+	ccPadding     = 0xFA // invalid DTVCC packet data
+)
+
+// triplet is a cc_data construct: marker, validity and type byte followed by
+// 2 data bytes.
+type triplet [3]byte
+
+var (
+	nullField1 = triplet{ccNTSCField1, 0x80, 0x80}
+	nullField2 = triplet{ccNTSCField2, 0x80, 0x80}
+	padding    = triplet{ccPadding, 0x00, 0x00}
+)
+
+// packetTriplets returns the cc_data triplets carrying a DTVCC packet.
+func packetTriplets(packet []byte) []triplet {
+	var triplets []triplet
+	for i := 0; i+1 < len(packet); i += 2 {
+		t := triplet{ccPacketData, packet[i], packet[i+1]}
+		if i == 0 {
+			t[0] = ccPacketStart
+		}
+		triplets = append(triplets, t)
+	}
+	return triplets
+}
+
+// cdp is a Caption Distribution Packet (SMPTE 334-2).
+type cdp struct {
+	frameRate byte
+	sequence  uint16
+	timecode  stl.Timecode
+	dropFrame bool
+	ccData    []triplet
+}
+
+// bytes returns the binary representation of the CDP, with time code and
+// cc_data sections and footer checksum.
+func (c cdp) bytes() []byte {
+	b := []byte{
+		cdpIdentifier1, cdpIdentifier2,
+		0, // length, set below
+		c.frameRate<<4 | 0x0F,
+		cdpFlagTimeCodePresent | cdpFlagCCDataPresent | cdpFlagCaptionServiceActive | cdpFlagReserved,
+		byte(c.sequence >> 8), byte(c.sequence),
+	}
+
+	tc := c.timecode
+	var df byte
+	if c.dropFrame {
+		df = 0x80
+	}
+	b = append(b, cdpTimeCodeSection,
+		0xC0|byte(tc.Hours/10)<<4|byte(tc.Hours%10),
+		0x80|byte(tc.Minutes/10)<<4|byte(tc.Minutes%10),
+		byte(tc.Seconds/10)<<4|byte(tc.Seconds%10),
+		df|byte(tc.Frames/10)<<4|byte(tc.Frames%10),
+	)
+
+	b = append(b, cdpCCDataSection, 0xE0|byte(len(c.ccData)))
+	for _, t := range c.ccData {
+		b = append(b, t[:]...)
+	}
+
+	b = append(b, cdpFooterSection, byte(c.sequence>>8), byte(c.sequence))
+	b[2] = byte(len(b) + 1)
+	return append(b, checksum(b))
+}
+
+// checksum returns the byte making the sum of all the bytes zero.
+func checksum(b []byte) byte {
+	var sum byte
+	for _, c := range b {
+		sum += c
+	}
+	return -sum
+}
@@ -0,0 +1,17 @@
+package mcc
+
+import (
+	"io"
+
+	"github.com/si0ls/subs/doc"
+	"github.com/si0ls/subs/stl"
+)
+
+func init() {
+	doc.RegisterSTL("mcc", []string{".mcc"},
+		nil,
+		func(w io.Writer, f *stl.File) ([]error, error) {
+			return Encode(w, f, Options{})
+		},
+	)
+}
@@ -0,0 +1,491 @@
+// Package mcc exports STL files as CEA-708 captions in MacCaption (.mcc)
+// files: one Caption Distribution Packet (CDP) per video frame, wrapped in
+// a SMPTE 334 ancillary data packet and written as compressed hexadecimal
+// text indexed by timecode.
+package mcc
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/si0ls/subs/stl"
+)
+
+// Options configures a MCC encoding.
+type Options struct {
+	NonDropFrame bool      // Write non drop frame timecodes for 30 fps files (default drop frame)
+	UUID         string    // File UUID (default derived from the caption data)
+	Created      time.Time // Creation date and time (default none, for reproducible output)
+}
+
+var (
+	ErrNilGSI               = errors.New("nil GSI block")
+	ErrUnsupportedFramerate = errors.New("unsupported framerate")
+)
+
+// FileHeader is the first line of a MCC file.
+const FileHeader = "File Format=MacCaption_MCC V1.0"
+
+const fileDescription = `///////////////////////////////////////////////////////////////////////////////////
+// Computer Prompting and Captioning Company
+// Ancillary Data Packet Transfer File
+//
+// Permission to generate this format is granted provided that
+//   1. This ANC Transfer file format is used on an as-is basis and no warranty is given, and
+//   2. This entire descriptive information text is included in a generated .mcc file.
+//
+// General file format:
+//   HH:MM:SS:FF(tab)[Hexadecimal ANC data in groups of 2 characters]
+//     Hexadecimal data starts with the Ancillary Data Packet DID (Data ID defined in S291M)
+//       and concludes with the Check Sum following the User Data Words.
+//     Each time code line must contain at most one complete ancillary data packet.
+//     To transfer additional ANC Data successive lines may contain identical time code.
+//     Time Code Rate=[24, 25, 30, 30DF, 50, 60]
+//
+//   ANC data bytes may be represented by one ASCII character according to the following schema:
+//     G  FAh 00h 00h
+//     H  2 x (FAh 00h 00h)
+//     I  3 x (FAh 00h 00h)
+//     J  4 x (FAh 00h 00h)
+//     K  5 x (FAh 00h 00h)
+//     L  6 x (FAh 00h 00h)
+//     M  7 x (FAh 00h 00h)
+//     N  8 x (FAh 00h 00h)
+//     O  9 x (FAh 00h 00h)
+//     P  FBh 80h 80h
+//     Q  FCh 80h 80h
+//     R  FDh 80h 80h
+//     S  96h 69h
+//     T  61h 01h
+//     U  E1h 00h 00h 00h
+//     Z  00h
+//
+///////////////////////////////////////////////////////////////////////////////////`
+
+// SMPTE 334 ancillary data packet identifiers of CEA-708 CDPs.
+const (
+	ancDID  = 0x61
+	ancSDID = 0x01
+)
+
+// timebase converts STL timecodes to frame numbers of the MCC file.
+type timebase struct {
+	framerate uint
+	dropFrame bool
+}
+
+func (tb timebase) frame(tc stl.Timecode) int {
+	if tb.dropFrame {
+		return tc.ToDropFrames()
+	}
+	return tc.ToFrames(tb.framerate)
+}
+
+func (tb timebase) timecode(frame int) stl.Timecode {
+	if tb.dropFrame {
+		return stl.TimecodeFromDropFrames(frame)
+	}
+	return stl.TimecodeFromFrames(frame, tb.framerate)
+}
+
+func (tb timebase) format(frame int) string {
+	tc := tb.timecode(frame)
+	sep := ":"
+	if tb.dropFrame {
+		sep = ";"
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", tc.Hours, tc.Minutes, tc.Seconds, sep, tc.Frames)
+}
+
+// rate returns the MCC Time Code Rate, the CDP frame rate code and the
+// number of cc_data triplets per frame.
+func (tb timebase) rate() (string, byte, int) {
+	if tb.framerate == 25 {
+		return "25", frameRate25, 24
+	}
+	if tb.dropFrame {
+		return "30DF", frameRate29_97, 20
+	}
+	return "30", frameRate29_97, 20
+}
+
+// event is a set of commands to send at a frame.
+type event struct {
+	frame    int
+	priority bool // display and erase commands, sent before loading commands of the same frame
+	commands [][]byte
+}
+
+// Encode writes the subtitles of f to w as CEA-708 captions of the primary
+// caption service.
+//
+// Each subtitle is loaded ahead of its Time Code In (TCI) in a hidden
+// window, alternately window 0 and 1, displayed at its TCI while the other
+// window is hidden, and deleted at its Time Code Out (TCO) unless the next
+// subtitle replaces it on that frame. The window is anchored and justified
+// according to the Vertical Position (VP) and Justification Code (JC) of the
+// subtitle, rows longer than 32 columns are wrapped. Italics, underline and
+// Teletext colors are coded with pen attributes and colors, characters
+// missing from the CEA-708 code sets are replaced.
+//
+// CDPs are written for each frame from the first caption data to the last,
+// CEA-608 data is left empty. Timecodes of 30 fps files are kept as 29.97
+// fps timecodes. Losses and caption delays are returned as warnings.
+func Encode(w io.Writer, f *stl.File, opts Options) ([]error, error) {
+	if f.GSI == nil {
+		return nil, ErrNilGSI
+	}
+	tb := timebase{framerate: f.GSI.Framerate()}
+	switch tb.framerate {
+	case 25:
+	case 30:
+		tb.dropFrame = !opts.NonDropFrame
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFramerate, f.GSI.DFC)
+	}
+	rate, frameRate, ccCount := tb.rate()
+	capacity := ccCount - 2 // the first 2 triplets are CEA-608 data
+
+	var subtitles []*stl.TTIBlock
+	for _, tti := range f.Subtitles() {
+		if tti.CF != stl.CommentFlagTranslatorComments {
+			subtitles = append(subtitles, tti)
+		}
+	}
+	sort.SliceStable(subtitles, func(i, j int) bool {
+		return tb.frame(subtitles[i].TCI) < tb.frame(subtitles[j].TCI)
+	})
+
+	var warns []error
+	var events []event
+	for i, tti := range subtitles {
+		id := i % 2
+		commands, errs := caption(f.GSI, f.TextOrder, tti, id)
+		for _, err := range errs {
+			warns = append(warns, fmt.Errorf("subtitle %d/%d: %w", tti.SGN, tti.SN, err))
+		}
+		if commands == nil {
+			continue
+		}
+
+		in, out := tb.frame(tti.TCI), tb.frame(tti.TCO)
+		var size int
+		for _, p := range (&packetizer{}).packets(commands) {
+			size += len(p) / 2
+		}
+		load := in - int(math.Ceil(float64(size)/float64(capacity))) - 1
+		if load < 0 {
+			load = 0
+		}
+		events = append(events,
+			event{frame: load, commands: commands},
+			event{frame: in, priority: true, commands: [][]byte{
+				windowsCommand(cmdHideWindows, 1-id),
+				windowsCommand(cmdDisplayWindows, id),
+			}},
+		)
+
+		if out <= in || (i+1 < len(subtitles) && tb.frame(subtitles[i+1].TCI) <= out) {
+			continue
+		}
+		events = append(events, event{frame: out, priority: true, commands: [][]byte{
+			windowsCommand(cmdDeleteWindows, id),
+		}})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].frame != events[j].frame {
+			return events[i].frame < events[j].frame
+		}
+		return events[i].priority && !events[j].priority
+	})
+
+	var lines []string
+	var p packetizer
+	var queue []triplet
+	var sequence uint16
+	var frame int
+	if len(events) > 0 {
+		frame = events[0].frame
+	}
+	for i := 0; i < len(events) || len(queue) > 0; frame++ {
+		for ; i < len(events) && events[i].frame <= frame; i++ {
+			if events[i].priority && len(queue) > 0 {
+				warns = append(warns, fmt.Errorf("%s: caption data delayed by %d frames", tb.timecode(frame), (len(queue)+capacity-1)/capacity))
+			}
+			for _, packet := range p.packets(events[i].commands) {
+				queue = append(queue, packetTriplets(packet)...)
+			}
+		}
+
+		c := cdp{
+			frameRate: frameRate,
+			sequence:  sequence,
+			timecode:  tb.timecode(frame),
+			dropFrame: tb.dropFrame,
+			ccData:    []triplet{nullField1, nullField2},
+		}
+		n := capacity
+		if n > len(queue) {
+			n = len(queue)
+		}
+		c.ccData = append(c.ccData, queue[:n]...)
+		queue = queue[n:]
+		for len(c.ccData) < ccCount {
+			c.ccData = append(c.ccData, padding)
+		}
+		lines = append(lines, fmt.Sprintf("%s\t%s", tb.format(frame), compress(ancPacket(c.bytes()))))
+		sequence++
+	}
+
+	uuid := opts.UUID
+	if uuid == "" {
+		uuid = contentUUID(lines)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s\r\n\r\n", FileHeader)
+	fmt.Fprintf(bw, "%s\r\n\r\n", strings.ReplaceAll(fileDescription, "\n", "\r\n"))
+	fmt.Fprintf(bw, "UUID=%s\r\n", uuid)
+	fmt.Fprintf(bw, "Creation Program=github.com/si0ls/subs\r\n")
+	if !opts.Created.IsZero() {
+		fmt.Fprintf(bw, "Creation Date=%s\r\n", opts.Created.Format("Monday, January 2, 2006"))
+		fmt.Fprintf(bw, "Creation Time=%s\r\n", opts.Created.Format("15:04:05"))
+	}
+	fmt.Fprintf(bw, "Time Code Rate=%s\r\n\r\n", rate)
+	for _, line := range lines {
+		fmt.Fprintf(bw, "%s\r\n", line)
+	}
+	return warns, bw.Flush()
+}
+
+// ancPacket returns the ancillary data packet carrying the CDP: DID, SDID,
+// data count, CDP and checksum.
+func ancPacket(cdp []byte) []byte {
+	b := append([]byte{ancDID, ancSDID, byte(len(cdp))}, cdp...)
+	return append(b, checksum(b))
+}
+
+// contentUUID returns a name based UUID (version 5) derived from the lines.
+func contentUUID(lines []string) string {
+	h := sha1.Sum([]byte(strings.Join(lines, "\n")))
+	h[6] = h[6]&0x0F | 0x50
+	h[8] = h[8]&0x3F | 0x80
+	return fmt.Sprintf("%X-%X-%X-%X-%X", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
+
+// compressions are the single character representations of byte sequences.
+var compressions = []struct {
+	c   byte
+	seq []byte
+}{
+	{'P', []byte{0xFB, 0x80, 0x80}},
+	{'Q', []byte{0xFC, 0x80, 0x80}},
+	{'R', []byte{0xFD, 0x80, 0x80}},
+	{'S', []byte{0x96, 0x69}},
+	{'T', []byte{0x61, 0x01}},
+	{'U', []byte{0xE1, 0x00, 0x00, 0x00}},
+	{'Z', []byte{0x00}},
+}
+
+// compress returns the MCC hexadecimal representation of b, with runs of
+// padding triplets and common byte sequences replaced by a single
+// character.
+func compress(b []byte) string {
+	var sb strings.Builder
+	pad := []byte{ccPadding, 0x00, 0x00}
+	for len(b) > 0 {
+		n := 0
+		for n < 9 && bytes.HasPrefix(b[3*n:], pad) {
+			n++
+		}
+		if n > 0 {
+			sb.WriteByte('G' + byte(n-1))
+			b = b[3*n:]
+			continue
+		}
+		matched := false
+		for _, cmp := range compressions {
+			if bytes.HasPrefix(b, cmp.seq) {
+				sb.WriteByte(cmp.c)
+				b = b[len(cmp.seq):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fmt.Fprintf(&sb, "%02X", b[0])
+			b = b[1:]
+		}
+	}
+	return sb.String()
+}
+
+// cell is a character of a caption row.
+type cell struct {
+	r         rune
+	color     penColor
+	italic    bool
+	underline bool
+}
+
+var teletextPenColors = map[stl.TeletextColor]penColor{
+	stl.TeletextColorBlack:   penColorWhite, // black text on black background is unreadable
+	stl.TeletextColorRed:     penColorRed,
+	stl.TeletextColorGreen:   penColorGreen,
+	stl.TeletextColorYellow:  penColorYellow,
+	stl.TeletextColorBlue:    penColorBlue,
+	stl.TeletextColorMagenta: penColorMagenta,
+	stl.TeletextColorCyan:    penColorCyan,
+	stl.TeletextColorWhite:   penColorWhite,
+}
+
+// maxColumns is the number of columns of a 4:3 CEA-708 window.
+const maxColumns = 32
+
+// caption returns the commands defining and filling the hidden window id
+// with the subtitle, nil if it has no text.
+func caption(gsi *stl.GSIBlock, order stl.TextOrder, tti *stl.TTIBlock, id int) ([][]byte, []error) {
+	rows, err := tti.RowsOrdered(gsi.CCT, order)
+	if err != nil {
+		return nil, []error{err}
+	}
+	teletext := gsi.DSC == stl.DisplayStandardCodeLevel1Teletext || gsi.DSC == stl.DisplayStandardCodeLevel2Teletext
+	mnr, mnc := 23, 40
+	if !teletext && gsi.MNR > 0 {
+		mnr = gsi.MNR
+	}
+	if !teletext && gsi.MNC > 0 {
+		mnc = gsi.MNC
+	}
+
+	var lines [][]cell
+	first, last, lead := -1, 0, maxColumns
+	for i, row := range rows {
+		cells, n := rowCells(row)
+		if len(cells) == 0 {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		last = i
+		if n < lead {
+			lead = n
+		}
+		lines = append(lines, wrap(cells)...)
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	w := window{id: id, rows: len(lines), anchorV: 90}
+	for _, line := range lines {
+		if len(line) > w.columns {
+			w.columns = len(line)
+		}
+	}
+	if tti.VP > 0 {
+		w.anchorV = (tti.VP + last) * 100 / (mnr + 1)
+	}
+	if w.anchorV > 99 {
+		w.anchorV = 99
+	}
+	switch tti.JC {
+	case stl.JustificationCodeLeftJustifiedText:
+		w.anchor, w.anchorH, w.justify = anchorBottomLeft, 10, justifyLeft
+	case stl.JustificationCodeRightJustifiedText:
+		w.anchor, w.anchorH, w.justify = anchorBottomRight, 90, justifyRight
+	case stl.JustificationCodeCenteredText:
+		w.anchor, w.anchorH, w.justify = anchorBottomCenter, 50, justifyCenter
+	default:
+		w.anchor, w.anchorH, w.justify = anchorBottomLeft, lead*100/mnc, justifyLeft
+	}
+	if w.rows > 15 {
+		w.rows = 15
+		lines = lines[len(lines)-15:]
+	}
+
+	commands := [][]byte{
+		defineWindow(w),
+		windowsCommand(cmdClearWindows, id),
+		setWindowAttributes(w),
+	}
+	var warns []error
+	for i, line := range lines {
+		commands = append(commands, setPenLocation(i, 0))
+		var cur *cell
+		for j := range line {
+			c := line[j]
+			if cur == nil || c.italic != cur.italic || c.underline != cur.underline {
+				commands = append(commands, setPenAttributes(c.italic, c.underline))
+			}
+			if cur == nil || c.color != cur.color {
+				commands = append(commands, setPenColor(c.color))
+			}
+			cur = &line[j]
+			b, ok := encodeRune(c.r)
+			if !ok {
+				warns = append(warns, fmt.Errorf("character %q not available in CEA-708, replaced", c.r))
+			}
+			commands = append(commands, b)
+		}
+	}
+	return commands, warns
+}
+
+// rowCells returns the cells of a text row without leading and trailing
+// spaces and the number of leading spaces (including spacing attributes).
+func rowCells(row stl.TextRow) ([]cell, int) {
+	var cells []cell
+	for _, run := range row {
+		for len(cells) < run.Column {
+			cells = append(cells, cell{r: ' ', color: penColorWhite})
+		}
+		for _, r := range run.Text {
+			cells = append(cells, cell{
+				r:         r,
+				color:     teletextPenColors[run.Style.Foreground],
+				italic:    run.Style.Italic,
+				underline: run.Style.Underline,
+			})
+		}
+	}
+	lead := 0
+	for lead < len(cells) && unicode.IsSpace(cells[lead].r) {
+		lead++
+	}
+	cells = cells[lead:]
+	for len(cells) > 0 && unicode.IsSpace(cells[len(cells)-1].r) {
+		cells = cells[:len(cells)-1]
+	}
+	return cells, lead
+}
+
+// wrap splits cells in lines of at most 32 columns, on spaces if possible.
+func wrap(cells []cell) [][]cell {
+	var lines [][]cell
+	for len(cells) > maxColumns {
+		cut := maxColumns
+		for i := maxColumns; i > 0; i-- {
+			if cells[i].r == ' ' {
+				cut = i
+				break
+			}
+		}
+		lines = append(lines, cells[:cut])
+		cells = cells[cut:]
+		for len(cells) > 0 && cells[0].r == ' ' {
+			cells = cells[1:]
+		}
+	}
+	return append(lines, cells)
+}
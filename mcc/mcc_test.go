@@ -0,0 +1,161 @@
+package mcc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/si0ls/subs/internal/stltest"
+	"github.com/si0ls/subs/stl"
+)
+
+func TestEmptyCDP(t *testing.T) {
+	c := cdp{
+		frameRate: frameRate29_97,
+		dropFrame: true,
+		ccData:    []triplet{nullField1, nullField2},
+	}
+	for len(c.ccData) < 20 {
+		c.ccData = append(c.ccData, padding)
+	}
+
+	// 29.97 fps, time code and cc_data present, sequence 0, 00:00:00;00,
+	// 20 triplets: CEA-608 nulls then DTVCC padding, footer
+	want := "T4ES4E4FC3ZZ71C080Z8072F4QROO74ZZ0950"
+	if got := compress(ancPacket(c.bytes())); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+// decompress is the reverse of compress.
+func decompress(t *testing.T, s string) []byte {
+	t.Helper()
+
+	var b []byte
+	for len(s) > 0 {
+		c := s[0]
+		switch {
+		case c >= 'G' && c <= 'O':
+			for n := 0; n <= int(c-'G'); n++ {
+				b = append(b, ccPadding, 0x00, 0x00)
+			}
+			s = s[1:]
+			continue
+		case c >= 'P' && c <= 'Z':
+			found := false
+			for _, cmp := range compressions {
+				if cmp.c == c {
+					b = append(b, cmp.seq...)
+					found = true
+				}
+			}
+			if !found {
+				t.Fatalf("unexpected character %q", c)
+			}
+			s = s[1:]
+			continue
+		}
+		v, err := hex.DecodeString(s[:2])
+		if err != nil {
+			t.Fatal(err)
+		}
+		b = append(b, v...)
+		s = s[2:]
+	}
+	return b
+}
+
+func TestEncode(t *testing.T) {
+	f := stltest.File{Framerate: 30, Subtitles: []stltest.Subtitle{
+		{TCI: stl.Timecode{Seconds: 1}, TCO: stl.Timecode{Seconds: 2}, VP: 22, JC: stl.JustificationCodeCenteredText,
+			Text: "\x0b\x0bHello \u0080world\u0081"},
+	}}.New(t)
+
+	var buf, again bytes.Buffer
+	if _, err := Encode(&buf, f, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Encode(&again, f, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+		t.Errorf("output is not deterministic")
+	}
+
+	text := buf.String()
+	if !strings.HasPrefix(text, FileHeader+"\r\n") || !strings.Contains(text, "\r\nTime Code Rate=30DF\r\n") {
+		t.Fatalf("unexpected header:\n%s", text)
+	}
+
+	var service []byte
+	var first, last string
+	var sequence uint16
+	for _, line := range strings.Split(text, "\r\n") {
+		tc, data, ok := strings.Cut(line, "\t")
+		if !ok || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if first == "" {
+			first = tc
+		}
+		last = tc
+
+		anc := decompress(t, data)
+		if checksum(anc[:len(anc)-1]) != anc[len(anc)-1] {
+			t.Fatalf("%s: bad ANC checksum", tc)
+		}
+		c := anc[3 : len(anc)-1]
+		if int(anc[2]) != len(c) || int(c[2]) != len(c) || checksum(c[:len(c)-1]) != c[len(c)-1] {
+			t.Fatalf("%s: bad CDP length or checksum", tc)
+		}
+		if seq := uint16(c[5])<<8 | uint16(c[6]); seq != sequence {
+			t.Errorf("%s: expected sequence %d, got %d", tc, sequence, seq)
+		}
+		sequence++
+
+		ccData := c[14 : 14+3*int(c[13]&0x1F)]
+		for i := 0; i < len(ccData); i += 3 {
+			if ccData[i] == ccPacketData || ccData[i] == ccPacketStart {
+				service = append(service, ccData[i+1], ccData[i+2])
+			}
+		}
+	}
+
+	if first >= "00:00:01;00" {
+		t.Errorf("expected caption loading before 00:00:01;00, got %s", first)
+	}
+	if last != "00:00:02;00" {
+		t.Errorf("expected last frame 00:00:02;00, got %s", last)
+	}
+	for _, want := range [][]byte{
+		[]byte("Hello "),
+		append(setPenAttributes(true, false), "world"...),
+		windowsCommand(cmdDisplayWindows, 0),
+		windowsCommand(cmdDeleteWindows, 0),
+	} {
+		if !bytes.Contains(service, want) {
+			t.Errorf("expected service data to contain % X", want)
+		}
+	}
+}
+
+func TestCaptionTextOrder(t *testing.T) {
+	gsi := stl.NewGSIBlock()
+	gsi.SetDefaults(30, stl.DisplayStandardCodeLevel1Teletext)
+	gsi.CCT = stl.CharacterCodeTableLatinHebrew
+	tti := stl.NewTTIBlock()
+	tti.VP = 22
+	tti.JC = stl.JustificationCodeCenteredText
+	if err := tti.SetTextOrdered("\x0b\x0bשלום Next", gsi.CCT, stl.TextOrderVisual); err != nil {
+		t.Fatal(err)
+	}
+
+	commands, warns := caption(gsi, stl.TextOrderVisual, tti, 0)
+	if len(warns) != 4 {
+		t.Errorf("got %d warnings, want 4: %v", len(warns), warns)
+	}
+	if service := bytes.Join(commands, nil); !bytes.Contains(service, []byte("???? Next")) {
+		t.Errorf("expected the text in logical order in % X", service)
+	}
+}
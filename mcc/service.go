@@ -0,0 +1,200 @@
+package mcc
+
+import (
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// CEA-708 C0 and C1 command codes.
+const (
+	cmdExt1               = 0x10
+	cmdCarriageReturn     = 0x0D
+	cmdSetCurrentWindow   = 0x80 // CW0..CW7
+	cmdClearWindows       = 0x88 // CLW
+	cmdDisplayWindows     = 0x89 // DSW
+	cmdHideWindows        = 0x8A // HDW
+	cmdDeleteWindows      = 0x8C // DLW
+	cmdSetPenAttributes   = 0x90 // SPA
+	cmdSetPenColor        = 0x91 // SPC
+	cmdSetPenLocation     = 0x92 // SPL
+	cmdSetWindowAttribute = 0x97 // SWA
+	cmdDefineWindow       = 0x98 // DF0..DF7
+)
+
+const (
+	serviceNumber       = 1  // primary caption service
+	maxServiceBlockSize = 31 // block_size is 5 bits
+	maxPacketDataSize   = 127
+)
+
+// Anchor points of a window.
+const (
+	anchorBottomLeft   = 6
+	anchorBottomCenter = 7
+	anchorBottomRight  = 8
+)
+
+// Window justifications.
+const (
+	justifyLeft   = 0
+	justifyRight  = 1
+	justifyCenter = 2
+)
+
+// penColor is a 2 bits per component RGB color.
+type penColor byte
+
+const (
+	penColorBlack   penColor = 0x00
+	penColorRed     penColor = 0x30
+	penColorGreen   penColor = 0x0C
+	penColorYellow  penColor = 0x3C
+	penColorBlue    penColor = 0x03
+	penColorMagenta penColor = 0x33
+	penColorCyan    penColor = 0x0F
+	penColorWhite   penColor = 0x3F
+)
+
+// window is a CEA-708 window definition.
+type window struct {
+	id      int
+	visible bool
+	anchorV int // relative vertical anchor (0..99)
+	anchorH int // relative horizontal anchor (0..99)
+	anchor  int // anchor point (0..8)
+	rows    int
+	columns int
+	justify int
+}
+
+// defineWindow returns the DefineWindow command of w, with window and pen
+// styles 1.
+func defineWindow(w window) []byte {
+	var visible byte
+	if w.visible {
+		visible = 1
+	}
+	return []byte{
+		cmdDefineWindow + byte(w.id),
+		visible<<5 | 1<<4 | 1<<3, // row and column locked, priority 0
+		0x80 | byte(w.anchorV),   // relative positioning
+		byte(w.anchorH),
+		byte(w.anchor)<<4 | byte(w.rows-1),
+		byte(w.columns - 1),
+		1<<3 | 1,
+	}
+}
+
+// setWindowAttributes returns the SetWindowAttributes command: solid black
+// fill, no border, left to right print, bottom to top scroll and snap
+// display with the justification of w.
+func setWindowAttributes(w window) []byte {
+	return []byte{cmdSetWindowAttribute, 0x00, 0x00, 3<<2 | byte(w.justify), 0x00}
+}
+
+// setPenAttributes returns the SetPenAttributes command for standard size
+// normal offset text in the default font.
+func setPenAttributes(italic, underline bool) []byte {
+	var b byte
+	if italic {
+		b |= 0x80
+	}
+	if underline {
+		b |= 0x40
+	}
+	return []byte{cmdSetPenAttributes, 0x05, b}
+}
+
+// setPenColor returns the SetPenColor command for solid text of color c on a
+// solid black background.
+func setPenColor(c penColor) []byte {
+	return []byte{cmdSetPenColor, byte(c), 0x00, 0x00}
+}
+
+func setPenLocation(row, column int) []byte {
+	return []byte{cmdSetPenLocation, byte(row), byte(column)}
+}
+
+func windowsCommand(cmd byte, ids ...int) []byte {
+	var bitmap byte
+	for _, id := range ids {
+		bitmap |= 1 << id
+	}
+	return []byte{cmd, bitmap}
+}
+
+// g2Chars are the characters of the G2 code set, coded after the EXT1
+// code.
+var g2Chars = map[rune]byte{
+	'…': 0x25, 'Š': 0x2A, 'Œ': 0x2C, '█': 0x30, '‘': 0x31, '’': 0x32,
+	'“': 0x33, '”': 0x34, '•': 0x35, '™': 0x39, 'š': 0x3A, 'œ': 0x3C,
+	'℠': 0x3D, 'Ÿ': 0x3F, '⅛': 0x76, '⅜': 0x77, '⅝': 0x78, '⅞': 0x79,
+}
+
+// encodeRune returns the code of r in the G0 (ASCII), G1 (Latin-1) or G2
+// code sets. Missing characters are replaced by their base letter (without
+// diacritical mark) or '?', ok is then false.
+func encodeRune(r rune) (b []byte, ok bool) {
+	switch {
+	case r == '♪':
+		return []byte{0x7F}, true
+	case r >= 0x20 && r < 0x7F, r >= 0xA0 && r <= 0xFF:
+		return []byte{byte(r)}, true
+	}
+	if c, ok := g2Chars[r]; ok {
+		return []byte{cmdExt1, c}, true
+	}
+	for _, b := range norm.NFD.String(string(r)) {
+		if b >= 0x20 && b < 0x7F && !unicode.Is(unicode.Mn, b) {
+			return []byte{byte(b)}, false
+		}
+	}
+	return []byte{'?'}, false
+}
+
+// packetizer packs commands of the caption service in DTVCC packets.
+type packetizer struct {
+	sequence int
+}
+
+// packets returns the DTVCC packets carrying the commands, commands are
+// never split across service blocks.
+func (p *packetizer) packets(commands [][]byte) [][]byte {
+	var blocks [][]byte
+	var block []byte
+	for _, cmd := range commands {
+		if len(block)+len(cmd) > maxServiceBlockSize {
+			blocks = append(blocks, block)
+			block = nil
+		}
+		block = append(block, cmd...)
+	}
+	if len(block) > 0 {
+		blocks = append(blocks, block)
+	}
+
+	var packets [][]byte
+	var data []byte
+	flush := func() {
+		if len(data) == 0 {
+			return
+		}
+		if len(data)%2 == 0 {
+			data = append(data, 0x00) // null service block
+		}
+		size := (len(data) + 1) / 2
+		packets = append(packets, append([]byte{byte(p.sequence)<<6 | byte(size)&0x3F}, data...))
+		p.sequence = (p.sequence + 1) % 4
+		data = nil
+	}
+	for _, block := range blocks {
+		if len(data)+1+len(block) > maxPacketDataSize {
+			flush()
+		}
+		data = append(data, serviceNumber<<5|byte(len(block)))
+		data = append(data, block...)
+	}
+	flush()
+	return packets
+}
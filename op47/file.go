@@ -0,0 +1,77 @@
+package op47
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/si0ls/subs/stl"
+	"github.com/si0ls/subs/teletext"
+)
+
+// Options configures an OP-47 export.
+type Options struct {
+	Teletext  teletext.Options // Teletext page and stream start, LinesPerFrame is forced to 5
+	FirstLine int              // First VBI line of field 1 used by the packets of a frame (default 12)
+}
+
+// Frame is a video frame carrying a SDP.
+type Frame struct {
+	Timecode stl.Timecode
+	SDP      SDP
+}
+
+// Frames returns the SDPs carrying the Teletext subtitles of f, one per
+// video frame with subtitle packets, numbered by a sequence counter from 0.
+// Packets of a frame are associated with consecutive lines of field 1 from
+// FirstLine. See teletext.Frames for the subtitle encoding.
+func Frames(f *stl.File, opts Options) ([]Frame, []error, error) {
+	if opts.FirstLine == 0 {
+		opts.FirstLine = 12
+	}
+	if opts.FirstLine < 7 || opts.FirstLine+MaxPackets-1 > 22 {
+		return nil, nil, fmt.Errorf("%w: first line %d", ErrInvalidLine, opts.FirstLine)
+	}
+	opts.Teletext.LinesPerFrame = MaxPackets
+
+	ttxFrames, warns, err := teletext.Frames(f, opts.Teletext)
+	if err != nil {
+		return nil, warns, err
+	}
+
+	frames := make([]Frame, 0, len(ttxFrames))
+	for i, ttxFrame := range ttxFrames {
+		sdp := SDP{Sequence: uint16(i)}
+		for j, p := range ttxFrame.Packets {
+			sdp.Packets = append(sdp.Packets, Packet{Field: 1, Line: opts.FirstLine + j, Data: p})
+		}
+		frames = append(frames, Frame{Timecode: ttxFrame.Timecode, SDP: sdp})
+	}
+	return frames, warns, nil
+}
+
+// FileHeader is the first line of an OP-47 SDP file.
+const FileHeader = "File Format=OP47 SDP V1.0"
+
+// Encode writes the SDPs carrying the Teletext subtitles of f to w as a text
+// file modeled after MacCaption (MCC) files: a header giving the timecode
+// rate followed by one line per frame with a SDP, holding the frame
+// timecode and the hexadecimal SDP bytes separated by a tab.
+func Encode(w io.Writer, f *stl.File, opts Options) ([]error, error) {
+	frames, warns, err := Frames(f, opts)
+	if err != nil {
+		return warns, err
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s\r\n\r\n", FileHeader)
+	fmt.Fprintf(bw, "Time Code Rate=%d\r\n\r\n", f.GSI.Framerate())
+	for _, frame := range frames {
+		b, err := frame.SDP.Bytes()
+		if err != nil {
+			return warns, fmt.Errorf("frame %s: %w", frame.Timecode, err)
+		}
+		fmt.Fprintf(bw, "%s\t%X\r\n", frame.Timecode, b)
+	}
+	return warns, bw.Flush()
+}
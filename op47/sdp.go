@@ -0,0 +1,136 @@
+// Package op47 exports Teletext subtitles as OP-47 Subtitling Distribution
+// Packets (SDP, SMPTE RDD 8), the payload of the SMPTE 2031 VANC data
+// packets carrying Teletext over HD SDI.
+package op47
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/si0ls/subs/teletext"
+)
+
+const (
+	sdpIdentifier1 = 0x51
+	sdpIdentifier2 = 0x15
+	sdpFormatCode  = 0x02 // WST Teletext subtitles
+	sdpFooterID    = 0x74
+	sdpDescriptors = 5
+	sdpClockRunIn  = 0x55
+	sdpFramingCode = 0x27
+)
+
+// MaxPackets is the maximum number of Teletext packets carried by a SDP.
+const MaxPackets = sdpDescriptors
+
+var (
+	ErrTooManyPackets = errors.New("too many packets")
+	ErrInvalidLine    = errors.New("invalid VBI line")
+	ErrInvalidSDP     = errors.New("invalid SDP")
+	ErrChecksum       = errors.New("SDP checksum mismatch")
+)
+
+// Packet is a Teletext packet with the VBI line it is associated with.
+type Packet struct {
+	Field int             // Field number (1 or 2)
+	Line  int             // Line number in the field (7..22 in field 1, 320..335 in field 2 are coded as 7..22)
+	Data  teletext.Packet // T42 packet
+}
+
+// SDP is an OP-47 Subtitling Distribution Packet.
+type SDP struct {
+	Sequence uint16   // Sequence counter, incremented for each SDP
+	Packets  []Packet // Teletext packets (up to 5)
+}
+
+// Bytes returns the binary representation of the SDP: identifier, length,
+// format code, the 5 packet descriptors, the packets each preceded by the
+// clock run-in and framing code, and the footer with the sequence counter
+// and checksum.
+func (s SDP) Bytes() ([]byte, error) {
+	if len(s.Packets) > MaxPackets {
+		return nil, fmt.Errorf("%w: %d (max %d)", ErrTooManyPackets, len(s.Packets), MaxPackets)
+	}
+
+	length := 4 + sdpDescriptors + len(s.Packets)*(3+teletext.PacketSize) + 4
+	b := make([]byte, 0, length)
+	b = append(b, sdpIdentifier1, sdpIdentifier2, byte(length), sdpFormatCode)
+	for i := 0; i < sdpDescriptors; i++ {
+		var desc byte
+		if i < len(s.Packets) {
+			p := s.Packets[i]
+			if p.Line < 7 || p.Line > 22 || (p.Field != 1 && p.Field != 2) {
+				return nil, fmt.Errorf("%w: field %d line %d", ErrInvalidLine, p.Field, p.Line)
+			}
+			desc = byte(p.Line)
+			if p.Field == 1 {
+				desc |= 0x80
+			}
+		}
+		b = append(b, desc)
+	}
+	for _, p := range s.Packets {
+		b = append(b, sdpClockRunIn, sdpClockRunIn, sdpFramingCode)
+		b = append(b, p.Data[:]...)
+	}
+	b = append(b, sdpFooterID, byte(s.Sequence>>8), byte(s.Sequence))
+	return append(b, checksum(b)), nil
+}
+
+// ParseSDP decodes the binary representation of a SDP, verifying its
+// length and checksum.
+func ParseSDP(b []byte) (SDP, error) {
+	var s SDP
+	if len(b) < 4+sdpDescriptors+4 || b[0] != sdpIdentifier1 || b[1] != sdpIdentifier2 {
+		return s, fmt.Errorf("%w: bad identifier", ErrInvalidSDP)
+	}
+	if int(b[2]) != len(b) {
+		return s, fmt.Errorf("%w: length %d, got %d bytes", ErrInvalidSDP, b[2], len(b))
+	}
+	if b[3] != sdpFormatCode {
+		return s, fmt.Errorf("%w: unsupported format code %#02x", ErrInvalidSDP, b[3])
+	}
+	if checksum(b[:len(b)-1]) != b[len(b)-1] {
+		return s, ErrChecksum
+	}
+
+	footer := b[len(b)-4:]
+	if footer[0] != sdpFooterID {
+		return s, fmt.Errorf("%w: bad footer identifier", ErrInvalidSDP)
+	}
+	s.Sequence = uint16(footer[1])<<8 | uint16(footer[2])
+
+	data := b[4+sdpDescriptors : len(b)-4]
+	for _, desc := range b[4 : 4+sdpDescriptors] {
+		if desc == 0 {
+			continue
+		}
+		if len(data) < 3+teletext.PacketSize {
+			return s, fmt.Errorf("%w: missing packet data", ErrInvalidSDP)
+		}
+		if data[0] != sdpClockRunIn || data[1] != sdpClockRunIn || data[2] != sdpFramingCode {
+			return s, fmt.Errorf("%w: bad packet run-in", ErrInvalidSDP)
+		}
+		p := Packet{Field: 2, Line: int(desc & 0x1F)}
+		if desc&0x80 != 0 {
+			p.Field = 1
+		}
+		copy(p.Data[:], data[3:])
+		s.Packets = append(s.Packets, p)
+		data = data[3+teletext.PacketSize:]
+	}
+	if len(data) != 0 {
+		return s, fmt.Errorf("%w: unexpected packet data", ErrInvalidSDP)
+	}
+	return s, nil
+}
+
+// checksum returns the byte making the sum of all the bytes of the SDP
+// zero.
+func checksum(b []byte) byte {
+	var sum byte
+	for _, c := range b {
+		sum += c
+	}
+	return -sum
+}
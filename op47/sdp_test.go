@@ -0,0 +1,101 @@
+package op47
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/si0ls/subs/internal/stltest"
+	"github.com/si0ls/subs/stl"
+	"github.com/si0ls/subs/teletext"
+)
+
+// fillerSDP is a SDP with sequence number 0x0102 carrying a page 8FF
+// header (suppress header and inhibit display set) on line 7 of field 1.
+var fillerSDP = append(append([]byte{
+	0x51, 0x15, 0x3A, 0x02, // identifier, length, format code
+	0x87, 0x00, 0x00, 0x00, 0x00, // descriptors
+	0x55, 0x55, 0x27, // clock run-in, framing code
+	0x15, 0x15, 0xEA, 0xEA, 0x15, 0x15, 0x15, 0x15, 0xC7, 0x15, // MRAG, page FF, subcode, control bits
+}, bytes.Repeat([]byte{0x20}, 32)...),
+	0x74, 0x01, 0x02, 0x61, // footer, sequence counter, checksum
+)
+
+func TestSDPBytes(t *testing.T) {
+	filler := teletext.Header{
+		Magazine: 8,
+		Page:     0xFF,
+		Control:  teletext.ControlSuppressHeader | teletext.ControlInhibitDisplay,
+	}.Packet()
+	sdp := SDP{
+		Sequence: 0x0102,
+		Packets:  []Packet{{Field: 1, Line: 7, Data: filler}},
+	}
+
+	b, err := sdp.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, fillerSDP) {
+		t.Errorf("expected\n%X\ngot\n%X", fillerSDP, b)
+	}
+
+	got, err := ParseSDP(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Sequence != sdp.Sequence || len(got.Packets) != 1 || got.Packets[0] != sdp.Packets[0] {
+		t.Errorf("expected %+v, got %+v", sdp, got)
+	}
+
+	b[20]++
+	if _, err := ParseSDP(b); err != ErrChecksum {
+		t.Errorf("expected ErrChecksum, got %v", err)
+	}
+
+	sdp.Packets = make([]Packet, MaxPackets+1)
+	if _, err := sdp.Bytes(); err == nil {
+		t.Errorf("expected error for %d packets", len(sdp.Packets))
+	}
+}
+
+func TestEncode(t *testing.T) {
+	f := stltest.File{Subtitles: []stltest.Subtitle{
+		{TCI: stl.Timecode{Hours: 10, Seconds: 1}, TCO: stl.Timecode{Hours: 10, Seconds: 2}, VP: 22, JC: stl.JustificationCodeCenteredText, Text: "Hello"},
+	}}.New(t)
+	f.GSI.TCP = stl.Timecode{Hours: 10}
+
+	var buf bytes.Buffer
+	if _, err := Encode(&buf, f, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\r\n")
+	// header, blank, rate, blank, subtitle frame, erase frame
+	if len(lines) != 6 || lines[0] != FileHeader || lines[2] != "Time Code Rate=25" {
+		t.Fatalf("unexpected file:\n%s", buf.String())
+	}
+	for i, want := range []struct {
+		timecode string
+		packets  int
+	}{
+		{"10:00:01:00", 3}, // header, row, filler
+		{"10:00:02:00", 2}, // erase header, filler
+	} {
+		tc, data, _ := strings.Cut(lines[4+i], "\t")
+		if tc != want.timecode {
+			t.Errorf("frame %d: expected timecode %s, got %s", i, want.timecode, tc)
+		}
+		b, err := hex.DecodeString(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sdp, err := ParseSDP(b)
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		if int(sdp.Sequence) != i || len(sdp.Packets) != want.packets {
+			t.Errorf("frame %d: expected sequence %d with %d packets, got %d with %d", i, i, want.packets, sdp.Sequence, len(sdp.Packets))
+		}
+	}
+}
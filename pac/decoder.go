@@ -0,0 +1,131 @@
+package pac
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/si0ls/subs/stl"
+)
+
+// Decode reads a PAC file from r and returns its subtitles as a Level-1
+// Teletext STL file, with the Character Code Table (CCT) of the code page
+// of the options.
+//
+// Each row starts with boxing codes, italics are coded with STL control
+// codes. The alignment of the first row gives the Justification Code (JC)
+// and the vertical position the Vertical Position (VP). Invalid time codes
+// and characters are returned as warnings.
+func Decode(r io.Reader, opts Options) (*stl.File, []error, error) {
+	framerate := opts.Framerate
+	if framerate == 0 {
+		framerate = 25
+	}
+	if framerate != 25 && framerate != 30 {
+		return nil, nil, fmt.Errorf("%w: %d", ErrUnsupportedFramerate, framerate)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(b) < HeaderSize || b[0] != 0x01 {
+		return nil, nil, ErrInvalidHeader
+	}
+
+	cct := opts.CodePage.CharacterCodeTable()
+	cm := opts.CodePage.charmap()
+	var warns []error
+	var subtitles []*stl.TTIBlock
+	for i := HeaderSize; i < len(b); {
+		if i+BlockHeaderSize > len(b) {
+			warns = append(warns, fmt.Errorf("offset %d: %w: truncated block", i, ErrInvalidBlock))
+			break
+		}
+		block := b[i : i+BlockHeaderSize]
+		length := int(block[14]) | int(block[15])<<8
+		if block[3] != 0xFF || i+BlockHeaderSize+length > len(b) {
+			warns = append(warns, fmt.Errorf("offset %d: %w", i, ErrInvalidBlock))
+			break
+		}
+		number := int(block[1]) | int(block[2])<<8
+		text := b[i+BlockHeaderSize : i+BlockHeaderSize+length]
+		i += BlockHeaderSize + length
+
+		tti := stl.NewTTIBlock()
+		tti.SGN = 0
+		tti.SN = len(subtitles)
+		tti.EBN = stl.EBNLastBlock
+		tti.CS = stl.CumulativeStatusNone
+		tti.TCI = decodeTimecode(block[5:9])
+		tti.TCO = decodeTimecode(block[9:13])
+		tti.CF = stl.CommentFlagSubtitleData
+		for _, tc := range []stl.Timecode{tti.TCI, tti.TCO} {
+			if err := tc.Validate(framerate); err != nil {
+				warns = append(warns, fmt.Errorf("subtitle %d: time code %s: %w", number, tc, err))
+			}
+		}
+
+		var tf []rune
+		var rows int
+		tti.JC = stl.JustificationCodeCenteredText
+		for j := 0; j < len(text); j++ {
+			c := text[j]
+			switch {
+			case c == RowStart:
+				if rows > 0 {
+					tf = append(tf, rune(stl.ControlCodeLineBreak))
+				}
+				if j+1 < len(text) && rows == 0 {
+					switch text[j+1] {
+					case AlignLeft:
+						tti.JC = stl.JustificationCodeLeftJustifiedText
+					case AlignRight:
+						tti.JC = stl.JustificationCodeRightJustifiedText
+					}
+				}
+				rows++
+				tf = append(tf, rune(stl.TeletextControlCodeStartBox), rune(stl.TeletextControlCodeStartBox))
+				j += 2
+			case c == ItalicOn:
+				tf = append(tf, rune(stl.ControlCodeItalicOn))
+			case c == ItalicOff:
+				tf = append(tf, rune(stl.ControlCodeItalicOff))
+			case c < 0x20:
+				warns = append(warns, fmt.Errorf("subtitle %d: invalid character 0x%02X", number, c))
+			default:
+				tf = append(tf, cm.DecodeByte(c))
+			}
+		}
+		if rows == 0 {
+			continue
+		}
+
+		switch block[13] {
+		case VerticalTop:
+			tti.VP = 1
+		case VerticalMiddle:
+			tti.VP = (23-rows)/2 + 1
+		default:
+			tti.VP = 23 - rows
+		}
+		unmappables, err := tti.SetTextFallback(string(tf), cct, stl.FallbackReplace)
+		if err != nil {
+			warns = append(warns, fmt.Errorf("subtitle %d: %w", number, err))
+			continue
+		}
+		if len(unmappables) > 0 {
+			warns = append(warns, fmt.Errorf("subtitle %d: %w, fallback %s", number, &stl.UnmappableError{Runes: unmappables}, stl.FallbackReplace))
+		}
+		subtitles = append(subtitles, tti)
+	}
+
+	f := stl.NewFile()
+	f.GSI = stl.NewGSIBlock()
+	f.GSI.SetDefaults(framerate, stl.DisplayStandardCodeLevel1Teletext)
+	f.GSI.CCT = cct
+	for _, tti := range subtitles {
+		f.TTI = append(f.TTI, tti.ExtensionBlocks()...)
+	}
+	f.UpdateCounters()
+	return f, warns, nil
+}
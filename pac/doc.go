@@ -0,0 +1,19 @@
+package pac
+
+import (
+	"io"
+
+	"github.com/si0ls/subs/doc"
+	"github.com/si0ls/subs/stl"
+)
+
+func init() {
+	doc.RegisterSTL("pac", []string{".pac"},
+		func(r io.Reader) (*stl.File, []error, error) {
+			return Decode(r, Options{})
+		},
+		func(w io.Writer, f *stl.File) ([]error, error) {
+			return Encode(w, f, Options{})
+		},
+	)
+}
@@ -0,0 +1,139 @@
+package pac
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/si0ls/subs/stl"
+)
+
+// Encode writes f as a PAC file to w, with the text encoded in the code
+// page of the options.
+//
+// Timecodes are written at the framerate of the GSI block. The
+// Justification Code (JC) gives the alignment of the rows and the Vertical
+// Position (VP) the vertical position of the subtitle, italics are
+// enclosed in '<' and '>'. Translator's comments are skipped, rows are
+// trimmed and characters missing from the code page are replaced by '?'
+// and returned as warnings.
+func Encode(w io.Writer, f *stl.File, opts Options) ([]error, error) {
+	if f.GSI == nil {
+		return nil, ErrNilGSI
+	}
+	if f.GSI.Framerate() == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFramerate, f.GSI.DFC)
+	}
+	cm := opts.CodePage.charmap()
+	teletext := f.GSI.DSC == stl.DisplayStandardCodeLevel1Teletext || f.GSI.DSC == stl.DisplayStandardCodeLevel2Teletext
+
+	bw := bufio.NewWriter(w)
+	bw.Write(header())
+
+	var warns []error
+	number := 0
+	for _, tti := range f.Subtitles() {
+		if tti.CF == stl.CommentFlagTranslatorComments {
+			continue
+		}
+		textRows, err := tti.RowsOrdered(f.GSI.CCT, f.TextOrder)
+		if err != nil {
+			warns = append(warns, fmt.Errorf("subtitle %d/%d: %w", tti.SGN, tti.SN, err))
+			continue
+		}
+
+		align := byte(AlignCenter)
+		switch tti.JC {
+		case stl.JustificationCodeLeftJustifiedText:
+			align = AlignLeft
+		case stl.JustificationCodeRightJustifiedText:
+			align = AlignRight
+		}
+
+		var text []byte
+		var italic bool
+		first, last := -1, -1
+		for i, row := range textRows {
+			s, rowItalic := rowText(row, italic)
+			if s == "" {
+				continue
+			}
+			if first < 0 {
+				first = i
+			}
+			last = i
+			italic = rowItalic
+			text = append(text, RowStart, align, RowText)
+			for _, r := range s {
+				b, ok := cm.EncodeRune(r)
+				if !ok {
+					warns = append(warns, fmt.Errorf("subtitle %d/%d: character %q not in code page, replaced by '?'", tti.SGN, tti.SN, r))
+					b = '?'
+				}
+				text = append(text, b)
+			}
+		}
+		if first < 0 {
+			continue
+		}
+		if italic {
+			text = append(text, ItalicOff)
+		}
+
+		vertical := byte(VerticalBottom)
+		if tti.VP > 0 {
+			vp := tti.VP
+			if !teletext && f.GSI.MNR > 0 {
+				vp = vp * 23 / f.GSI.MNR
+			}
+			switch mid := (2*vp + first + last) / 2; {
+			case mid < 8:
+				vertical = VerticalTop
+			case mid < 16:
+				vertical = VerticalMiddle
+			}
+		}
+
+		block := []byte{0x00, byte(number), byte(number >> 8), 0xFF, 0x00}
+		block = append(block, encodeTimecode(tti.TCI)...)
+		block = append(block, encodeTimecode(tti.TCO)...)
+		block = append(block, vertical, byte(len(text)), byte(len(text)>>8))
+		bw.Write(block)
+		bw.Write(text)
+		number++
+	}
+	return warns, bw.Flush()
+}
+
+// rowText returns the trimmed text of a row with the italic markers, given
+// the italic state at the start of the row, and the italic state at the
+// end of the row.
+func rowText(row stl.TextRow, italic bool) (string, bool) {
+	var sb strings.Builder
+	column := -1
+	for _, run := range row {
+		if column >= 0 && run.Column > column {
+			sb.WriteString(strings.Repeat(" ", run.Column-column))
+		}
+		column = run.Column + utf8.RuneCountInString(run.Text)
+		text := run.Text
+		if sb.Len() == 0 {
+			text = strings.TrimLeft(text, " ")
+			if text == "" {
+				continue
+			}
+		}
+		if run.Style.Italic != italic {
+			if run.Style.Italic {
+				sb.WriteByte(ItalicOn)
+			} else {
+				sb.WriteByte(ItalicOff)
+			}
+			italic = run.Style.Italic
+		}
+		sb.WriteString(text)
+	}
+	return strings.TrimRight(sb.String(), " "), italic
+}
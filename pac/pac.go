@@ -0,0 +1,125 @@
+// Package pac converts STL files to and from Screen Electronics PAC
+// subtitle files.
+//
+// A PAC file starts with a 24 bytes header followed by one block per
+// subtitle:
+//
+//	offset  size  content
+//	0       1     0x00
+//	1       2     subtitle number (little endian)
+//	3       1     0xFF
+//	4       1     0x00
+//	5       4     time code in
+//	9       4     time code out
+//	13      1     vertical position (0x00 bottom, 0x04 middle, 0x08 top)
+//	14      2     text length (little endian)
+//	16      -     text
+//
+// Time codes are two little endian words holding the decimal values
+// HHMM and SSFF. Each row of the text starts with 0xFE, an alignment byte
+// (0x00 right, 0x01 left, 0x02 centered) and 0x03, italics are enclosed in
+// '<' and '>'. The code page of the text is not stored in the file.
+package pac
+
+import (
+	"errors"
+
+	"github.com/si0ls/subs/stl"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Sizes of the parts of a PAC file.
+const (
+	HeaderSize      = 24
+	BlockHeaderSize = 16
+)
+
+// Bytes of the text of a block.
+const (
+	RowStart    = 0xFE
+	RowText     = 0x03
+	ItalicOn    = '<'
+	ItalicOff   = '>'
+	AlignRight  = 0x00
+	AlignLeft   = 0x01
+	AlignCenter = 0x02
+)
+
+// Vertical positions of a block.
+const (
+	VerticalBottom = 0x00
+	VerticalMiddle = 0x04
+	VerticalTop    = 0x08
+)
+
+// CodePage is the code page of the text of a PAC file.
+type CodePage int
+
+const (
+	CodePageLatin CodePage = iota
+	CodePageArabic
+	CodePageHebrew
+)
+
+// charmap returns the character map of the code page.
+func (cp CodePage) charmap() *charmap.Charmap {
+	switch cp {
+	case CodePageArabic:
+		return charmap.ISO8859_6
+	case CodePageHebrew:
+		return charmap.ISO8859_8
+	}
+	return charmap.ISO8859_1
+}
+
+// CharacterCodeTable returns the STL Character Code Table (CCT) of the
+// code page.
+func (cp CodePage) CharacterCodeTable() stl.CharacterCodeTable {
+	switch cp {
+	case CodePageArabic:
+		return stl.CharacterCodeTableLatinArabic
+	case CodePageHebrew:
+		return stl.CharacterCodeTableLatinHebrew
+	}
+	return stl.CharacterCodeTableLatin
+}
+
+// Options configures a PAC conversion.
+type Options struct {
+	Framerate uint     // Framerate of the STL file created on import, 25 or 30 (default 25)
+	CodePage  CodePage // Code page of the PAC text (default Latin)
+}
+
+var (
+	ErrNilGSI               = errors.New("nil GSI block")
+	ErrUnsupportedFramerate = errors.New("unsupported framerate")
+	ErrInvalidHeader        = errors.New("invalid PAC header")
+	ErrInvalidBlock         = errors.New("invalid PAC block")
+)
+
+// header returns the file header.
+func header() []byte {
+	h := make([]byte, HeaderSize)
+	h[0] = 0x01
+	h[HeaderSize-1] = 0x60
+	return h
+}
+
+// encodeTimecode returns the 4 bytes of a time code.
+func encodeTimecode(tc stl.Timecode) []byte {
+	high := tc.Hours*100 + tc.Minutes
+	low := tc.Seconds*100 + tc.Frames
+	return []byte{byte(high), byte(high >> 8), byte(low), byte(low >> 8)}
+}
+
+// decodeTimecode returns the time code of 4 bytes.
+func decodeTimecode(b []byte) stl.Timecode {
+	high := int(b[0]) | int(b[1])<<8
+	low := int(b[2]) | int(b[3])<<8
+	return stl.Timecode{
+		Hours:   high / 100,
+		Minutes: high % 100,
+		Seconds: low / 100,
+		Frames:  low % 100,
+	}
+}
@@ -0,0 +1,96 @@
+package pac
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/si0ls/subs/internal/stltest"
+	"github.com/si0ls/subs/stl"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		codePage CodePage
+		vp       int
+		jc       stl.JustificationCode
+		text     string
+	}{
+		{CodePageLatin, 21, stl.JustificationCodeCenteredText, "\x0b\x0b\u0080Café\u008a\x0b\x0bcrème\u0081"},
+		{CodePageLatin, 1, stl.JustificationCodeLeftJustifiedText, "\x0b\x0bTop"},
+		{CodePageHebrew, 11, stl.JustificationCodeRightJustifiedText, "\x0b\x0bשלום\u008a\x0b\x0bעולם"},
+		{CodePageArabic, 22, stl.JustificationCodeCenteredText, "\x0b\x0bمرحبا"},
+	} {
+		cct := tc.codePage.CharacterCodeTable()
+		f := stltest.File{CCT: cct, Subtitles: []stltest.Subtitle{{
+			TCI:  stl.Timecode{Hours: 10, Minutes: 1, Seconds: 2, Frames: 3},
+			TCO:  stl.Timecode{Hours: 10, Minutes: 1, Seconds: 4, Frames: 24},
+			VP:   tc.vp,
+			JC:   tc.jc,
+			Text: tc.text,
+		}}}.New(t)
+		tti := f.TTI[0]
+
+		var buf bytes.Buffer
+		warns, err := Encode(&buf, f, Options{CodePage: tc.codePage})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(warns) > 0 {
+			t.Errorf("%q: unexpected warnings: %v", tc.text, warns)
+		}
+		block := buf.Bytes()[HeaderSize:]
+		if want := []byte{0x00, 0x00, 0x00, 0xFF, 0x00, 0xE9, 0x03, 0xCB, 0x00, 0xE9, 0x03, 0xA8, 0x01}; !bytes.Equal(block[:13], want) {
+			t.Errorf("%q: block header % X, want % X", tc.text, block[:13], want)
+		}
+
+		got, warns, err := Decode(&buf, Options{CodePage: tc.codePage})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(warns) > 0 {
+			t.Errorf("%q: unexpected warnings: %v", tc.text, warns)
+		}
+		if len(got.TTI) != 1 {
+			t.Fatalf("%q: got %d subtitles, want 1", tc.text, len(got.TTI))
+		}
+		g := got.TTI[0]
+		if g.TCI != tti.TCI || g.TCO != tti.TCO || g.VP != tti.VP || g.JC != tti.JC || g.TF != tti.TF {
+			t.Errorf("%q: got %s-%s VP %d JC %s TF %q, want %s-%s VP %d JC %s TF %q", tc.text,
+				g.TCI, g.TCO, g.VP, g.JC, g.TF, tti.TCI, tti.TCO, tti.VP, tti.JC, tti.TF)
+		}
+	}
+}
+
+func TestEncodeTextOrder(t *testing.T) {
+	for _, tc := range []struct {
+		codePage CodePage
+		text     string
+	}{
+		{CodePageHebrew, "\x0b\x0bשלום עולם"},
+		{CodePageArabic, "\x0b\x0bمرحبا بكم"},
+	} {
+		cct := tc.codePage.CharacterCodeTable()
+		f := stltest.File{CCT: cct, TextOrder: stl.TextOrderVisual, Subtitles: []stltest.Subtitle{{
+			TCI:  stl.Timecode{Seconds: 1},
+			TCO:  stl.Timecode{Seconds: 2},
+			VP:   22,
+			JC:   stl.JustificationCodeCenteredText,
+			Text: tc.text,
+		}}}.New(t)
+
+		var buf bytes.Buffer
+		if _, err := Encode(&buf, f, Options{CodePage: tc.codePage}); err != nil {
+			t.Fatal(err)
+		}
+		got, _, err := Decode(&buf, Options{CodePage: tc.codePage})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.TTI) != 1 {
+			t.Fatalf("%q: got %d subtitles, want 1", tc.text, len(got.TTI))
+		}
+		if text, err := got.TTI[0].TextOrdered(got.GSI.CCT, got.TextOrder); err != nil || text != tc.text {
+			t.Errorf("text %q (%v), want %q", text, err, tc.text)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+// Package render draws STL subtitles into images, for open subtitling
+// previews and burn-in.
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	"github.com/si0ls/subs/stl"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Options configures a Renderer.
+// Zero frame and grid sizes are derived from the GSI block of the rendered
+// subtitles and nil fonts are replaced by the bundled Go fonts.
+// DefaultOptions returns sensible values for the other options.
+type Options struct {
+	Width      int            // Frame width in pixels (0: 720)
+	Height     int            // Frame height in pixels (0: 576 at 25 fps, 480 at 30 fps)
+	Rows       int            // Number of rows of the display grid (0: 24 for teletext, MNR+1 for open subtitling)
+	Columns    int            // Number of columns of the display grid (0: 40 for teletext, MNC for open subtitling)
+	SafeArea   float64        // Fraction of the frame kept free on each side
+	Regular    *opentype.Font // Regular font (nil: Go Regular)
+	Italic     *opentype.Font // Italic font (nil: Go Italic)
+	Background color.Color    // Frame background (nil: transparent)
+	BoxColor   color.Color    // Box color for open subtitling boxing (nil: teletext background color)
+	Outline    color.Color    // Outline of unboxed text (nil: no outline)
+	TextOrder  stl.TextOrder  // Order of the right-to-left text of the Text Fields (TF) drawn by RenderTTI, files being drawn in their own order
+}
+
+// Renderer draws TTI blocks into images.
+// A Renderer is not safe for concurrent use: it caches the font faces it
+// draws with, which are not safe for concurrent use either. Use a Renderer
+// per goroutine.
+type Renderer struct {
+	opts  Options
+	faces map[faceKey]font.Face
+}
+
+type faceKey struct {
+	italic bool
+	size   float64
+}
+
+// DefaultOptions returns the default options.
+func DefaultOptions() Options {
+	return Options{
+		SafeArea: 0.1,
+		BoxColor: color.NRGBA{0, 0, 0, 0xC0},
+		Outline:  color.Black,
+	}
+}
+
+// New returns a new Renderer.
+// It returns an error if the default fonts cannot be parsed.
+func New(opts Options) (*Renderer, error) {
+	if opts.Regular == nil {
+		f, err := opentype.Parse(goregular.TTF)
+		if err != nil {
+			return nil, err
+		}
+		opts.Regular = f
+	}
+	if opts.Italic == nil {
+		f, err := opentype.Parse(goitalic.TTF)
+		if err != nil {
+			return nil, err
+		}
+		opts.Italic = f
+	}
+	if opts.SafeArea < 0 || opts.SafeArea >= 0.5 {
+		return nil, fmt.Errorf("invalid safe area %f: must be in range [0;0.5)", opts.SafeArea)
+	}
+	return &Renderer{opts: opts, faces: make(map[faceKey]font.Face)}, nil
+}
+
+// ErrNilGSI is returned when rendering without a GSI block.
+var ErrNilGSI = errors.New("GSI block is nil")
+
+// teletextColors maps teletext colors to RGB colors.
+var teletextColors = map[stl.TeletextColor]color.NRGBA{
+	stl.TeletextColorBlack:   {0x00, 0x00, 0x00, 0xFF},
+	stl.TeletextColorRed:     {0xFF, 0x00, 0x00, 0xFF},
+	stl.TeletextColorGreen:   {0x00, 0xFF, 0x00, 0xFF},
+	stl.TeletextColorYellow:  {0xFF, 0xFF, 0x00, 0xFF},
+	stl.TeletextColorBlue:    {0x00, 0x00, 0xFF, 0xFF},
+	stl.TeletextColorMagenta: {0xFF, 0x00, 0xFF, 0xFF},
+	stl.TeletextColorCyan:    {0x00, 0xFF, 0xFF, 0xFF},
+	stl.TeletextColorWhite:   {0xFF, 0xFF, 0xFF, 0xFF},
+}
+
+// TeletextColor returns the RGB color of a teletext color.
+func TeletextColor(c stl.TeletextColor) color.NRGBA {
+	if rgb, ok := teletextColors[c]; ok {
+		return rgb
+	}
+	return teletextColors[stl.TeletextColorWhite]
+}
+
+// layout is the display grid of a frame, derived from options and GSI.
+type layout struct {
+	bounds    image.Rectangle // frame bounds
+	safe      image.Rectangle // safe area, rows and columns are laid out in it
+	rows      int
+	columns   int
+	rowHeight int
+	teletext  bool
+}
+
+func (r *Renderer) layout(gsi *stl.GSIBlock) layout {
+	width, height := r.opts.Width, r.opts.Height
+	if width <= 0 {
+		width = 720
+	}
+	if height <= 0 {
+		height = 576
+		if gsi.Framerate() == 30 {
+			height = 480
+		}
+	}
+
+	l := layout{
+		bounds:   image.Rect(0, 0, width, height),
+		teletext: gsi.DSC != stl.DisplayStandardCodeOpenSubtitling,
+	}
+
+	l.rows, l.columns = r.opts.Rows, r.opts.Columns
+	if l.rows <= 0 {
+		l.rows = 24
+		if !l.teletext && gsi.MNR > 0 {
+			l.rows = gsi.MNR + 1
+		}
+	}
+	if l.columns <= 0 {
+		l.columns = 40
+		if !l.teletext && gsi.MNC > 0 {
+			l.columns = gsi.MNC
+		}
+	}
+
+	mx := int(float64(width) * r.opts.SafeArea)
+	my := int(float64(height) * r.opts.SafeArea)
+	l.safe = image.Rect(mx, my, width-mx, height-my)
+	l.rowHeight = l.safe.Dy() / l.rows
+	if l.rowHeight < 1 {
+		l.rowHeight = 1
+	}
+	return l
+}
+
+// RenderTTI draws the Text Field (TF) of tti in a new frame laid out from
+// gsi: rows start at the Vertical Position (VP) of the block and are
+// aligned according to its Justification Code (JC).
+// Italic, underline and boxing (open subtitling) as well as teletext
+// colors, boxes and double height are rendered. Right-to-left text, stored
+// in the TextOrder of the options, is drawn in visual order with Arabic
+// letters shaped.
+func (r *Renderer) RenderTTI(tti *stl.TTIBlock, gsi *stl.GSIBlock) (*image.RGBA, error) {
+	return r.render(tti, gsi, r.opts.TextOrder)
+}
+
+// render draws tti as RenderTTI, its right-to-left text being stored in
+// the order.
+func (r *Renderer) render(tti *stl.TTIBlock, gsi *stl.GSIBlock, order stl.TextOrder) (*image.RGBA, error) {
+	if gsi == nil {
+		return nil, ErrNilGSI
+	}
+
+	l := r.layout(gsi)
+	img := image.NewRGBA(l.bounds)
+	if r.opts.Background != nil {
+		draw.Draw(img, img.Bounds(), image.NewUniform(r.opts.Background), image.Point{}, draw.Src)
+	}
+
+	rows, err := tti.Rows(gsi.CCT)
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		rows[i] = row.Reorder(order, stl.TextOrderVisualShaped)
+	}
+
+	vp := tti.VP
+	if vp < 0 {
+		vp = 0
+	}
+	offsets, span := rowOffsets(rows)
+	if l.teletext && vp == 0 {
+		// teletext subtitles without vertical position are bottom aligned
+		vp = l.rows - span
+	}
+
+	for i, textRow := range rows {
+		row := vp + offsets[i]
+		if offsets[i] < 0 || row >= l.rows || strings.TrimSpace(textRow.String()) == "" {
+			continue
+		}
+		height := 1
+		if rowDoubleHeight(textRow) {
+			height = 2
+		}
+		if err := r.drawRow(img, l, textRow, row, height, tti.JC); err != nil {
+			return nil, err
+		}
+	}
+
+	return img, nil
+}
+
+// rowOffsets returns the grid row offset of each row and the number of grid
+// rows used by all rows.
+// Double height rows use two grid rows, the empty row following a double
+// height row in teletext subtitles is its lower half and gets offset -1.
+func rowOffsets(rows []stl.TextRow) ([]int, int) {
+	offsets := make([]int, len(rows))
+	n := 0
+	lowerHalf := false
+	for i, row := range rows {
+		if lowerHalf && strings.TrimSpace(row.String()) == "" {
+			offsets[i] = -1
+			lowerHalf = false
+			continue
+		}
+		offsets[i] = n
+		lowerHalf = rowDoubleHeight(row)
+		if lowerHalf {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return offsets, n
+}
+
+func rowDoubleHeight(row stl.TextRow) bool {
+	for _, run := range row {
+		if run.Style.DoubleHeight && strings.TrimSpace(run.Text) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// face returns the font face for the given style and size.
+func (r *Renderer) face(italic bool, size float64) (font.Face, error) {
+	key := faceKey{italic: italic, size: size}
+	if f, ok := r.faces[key]; ok {
+		return f, nil
+	}
+	fnt := r.opts.Regular
+	if italic {
+		fnt = r.opts.Italic
+	}
+	f, err := opentype.NewFace(fnt, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+	r.faces[key] = f
+	return f, nil
+}
+
+// drawRow draws a row of text at the given grid row.
+// Double height rows are drawn at normal height then stretched vertically.
+// An error is returned if a font face can not be loaded.
+func (r *Renderer) drawRow(dst *image.RGBA, l layout, row stl.TextRow, gridRow, span int, jc stl.JustificationCode) error {
+	runs := trimRow(row, jc)
+	if len(runs) == 0 {
+		return nil
+	}
+
+	size := float64(l.rowHeight) * 0.8
+	padding := l.rowHeight / 8
+	cellWidth := l.safe.Dx() / l.columns
+
+	// measure runs
+	faces := make([]font.Face, len(runs))
+	widths := make([]int, len(runs))
+	total := 0
+	for i, run := range runs {
+		face, err := r.face(run.Style.Italic, size)
+		if err != nil {
+			return err
+		}
+		faces[i] = face
+		widths[i] = font.MeasureString(face, run.Text).Ceil()
+		total += widths[i]
+	}
+
+	var x int
+	switch jc {
+	case stl.JustificationCodeLeftJustifiedText:
+		x = l.safe.Min.X
+	case stl.JustificationCodeRightJustifiedText:
+		x = l.safe.Max.X - total
+	case stl.JustificationCodeCenteredText:
+		x = l.safe.Min.X + (l.safe.Dx()-total)/2
+	default: // unchanged presentation: position from the character cells
+		x = l.safe.Min.X + runs[0].Column*cellWidth
+	}
+
+	// draw the row in a normal height strip, stretched afterwards if needed
+	strip := image.NewRGBA(image.Rect(0, 0, l.bounds.Dx(), l.rowHeight))
+	ascent := fixed.I(l.rowHeight - padding - l.rowHeight/5)
+	for i, run := range runs {
+		face := faces[i]
+		rect := image.Rect(x-padding, 0, x+widths[i]+padding, l.rowHeight)
+		if run.Style.Boxing {
+			box := r.opts.BoxColor
+			if l.teletext || box == nil {
+				box = TeletextColor(run.Style.Background)
+			}
+			draw.Draw(strip, rect, image.NewUniform(box), image.Point{}, draw.Over)
+		}
+
+		fg := TeletextColor(run.Style.Foreground)
+		dot := fixed.Point26_6{X: fixed.I(x), Y: ascent}
+		if !run.Style.Boxing && r.opts.Outline != nil {
+			r.drawOutline(strip, face, run.Text, dot, r.opts.Outline, l.rowHeight/24+1)
+		}
+		d := font.Drawer{Dst: strip, Src: image.NewUniform(fg), Face: face, Dot: dot}
+		d.DrawString(run.Text)
+
+		if run.Style.Underline {
+			thickness := l.rowHeight/16 + 1
+			y := ascent.Ceil() + face.Metrics().Descent.Ceil()/2
+			draw.Draw(strip, image.Rect(x, y, x+widths[i], y+thickness), image.NewUniform(fg), image.Point{}, draw.Over)
+		}
+		x += widths[i]
+	}
+
+	top := l.safe.Min.Y + gridRow*l.rowHeight
+	target := image.Rect(0, top, l.bounds.Dx(), top+span*l.rowHeight)
+	if span == 1 {
+		draw.Draw(dst, target, strip, image.Point{}, draw.Over)
+		return nil
+	}
+	xdraw.ApproxBiLinear.Scale(dst, target, strip, strip.Bounds(), xdraw.Over, nil)
+	return nil
+}
+
+// drawOutline draws text shifted in all directions to outline it.
+func (r *Renderer) drawOutline(dst draw.Image, face font.Face, text string, dot fixed.Point26_6, c color.Color, width int) {
+	d := font.Drawer{Dst: dst, Src: image.NewUniform(c), Face: face}
+	for dx := -width; dx <= width; dx++ {
+		for dy := -width; dy <= width; dy++ {
+			if dx == 0 && dy == 0 {
+				continue
+			}
+			d.Dot = fixed.Point26_6{X: dot.X + fixed.I(dx), Y: dot.Y + fixed.I(dy)}
+			d.DrawString(text)
+		}
+	}
+}
+
+// trimRow removes the runs without text and, unless the presentation is
+// unchanged, the leading and trailing spaces of the row.
+func trimRow(row stl.TextRow, jc stl.JustificationCode) stl.TextRow {
+	var runs stl.TextRow
+	for _, run := range row {
+		if run.Text != "" {
+			runs = append(runs, run)
+		}
+	}
+	if jc == stl.JustificationCodeUnchangedPresentation {
+		return runs
+	}
+	for len(runs) > 0 {
+		trimmed := strings.TrimLeft(runs[0].Text, "  ")
+		if trimmed != "" {
+			runs[0].Text = trimmed
+			break
+		}
+		runs = runs[1:]
+	}
+	for len(runs) > 0 {
+		n := len(runs) - 1
+		trimmed := strings.TrimRight(runs[n].Text, "  ")
+		if trimmed != "" {
+			runs[n].Text = trimmed
+			break
+		}
+		runs = runs[:n]
+	}
+	return runs
+}
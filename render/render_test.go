@@ -0,0 +1,138 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/si0ls/subs/stl"
+)
+
+func testGSI(dsc stl.DisplayStandardCode) *stl.GSIBlock {
+	gsi := stl.NewGSIBlock()
+	gsi.DFC = stl.DiskFormatCode25_01
+	gsi.DSC = dsc
+	gsi.CCT = stl.CharacterCodeTableLatin
+	gsi.MNC = 40
+	gsi.MNR = 23
+	return gsi
+}
+
+type renderTTITest struct {
+	tf string
+	vp int
+	jc stl.JustificationCode
+}
+
+var renderTTITests = []renderTTITest{
+	{"Hello", 20, stl.JustificationCodeCenteredText},
+	{"Hello", 20, stl.JustificationCodeLeftJustifiedText},
+	{"Hello", 20, stl.JustificationCodeRightJustifiedText},
+	{"\x80Hello\x81\x8A\x82world\x83", 2, stl.JustificationCodeCenteredText},
+	{"\x84Hello\x85", 10, stl.JustificationCodeCenteredText},
+}
+
+func TestRenderTTI(t *testing.T) {
+	r, err := New(DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gsi := testGSI(stl.DisplayStandardCodeOpenSubtitling)
+	l := r.layout(gsi)
+
+	for _, test := range renderTTITests {
+		tti := stl.NewTTIBlock()
+		tti.TF = test.tf
+		tti.VP = test.vp
+		tti.JC = test.jc
+
+		img, err := r.RenderTTI(tti, gsi)
+		if err != nil {
+			t.Errorf("RenderTTI(%q) unexpected error: %s", test.tf, err)
+			continue
+		}
+		if img.Bounds() != image.Rect(0, 0, 720, 576) {
+			t.Errorf("RenderTTI(%q) bounds = %v", test.tf, img.Bounds())
+		}
+
+		bounds := OpaqueBounds(img)
+		top := l.safe.Min.Y + test.vp*l.rowHeight
+		if bounds.Empty() || bounds.Min.Y < top-l.rowHeight/4 || bounds.Min.Y > top+l.rowHeight {
+			t.Errorf("RenderTTI(%q) text bounds %v, want starting at row %d (y=%d)", test.tf, bounds, test.vp, top)
+		}
+
+		center := (bounds.Min.X + bounds.Max.X) / 2
+		switch test.jc {
+		case stl.JustificationCodeLeftJustifiedText:
+			if center >= 360 {
+				t.Errorf("RenderTTI(%q) text bounds %v, want left justified", test.tf, bounds)
+			}
+		case stl.JustificationCodeRightJustifiedText:
+			if center <= 360 {
+				t.Errorf("RenderTTI(%q) text bounds %v, want right justified", test.tf, bounds)
+			}
+		case stl.JustificationCodeCenteredText:
+			if center < 340 || center > 380 {
+				t.Errorf("RenderTTI(%q) text bounds %v, want centered", test.tf, bounds)
+			}
+		}
+	}
+}
+
+func TestRenderTTIDoubleHeight(t *testing.T) {
+	r, err := New(DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gsi := testGSI(stl.DisplayStandardCodeLevel1Teletext)
+	l := r.layout(gsi)
+
+	tti := stl.NewTTIBlock()
+	tti.TF = "\x0D\x0B\x0BHello\x0A\x0A\x8A\x8A\x0D\x0B\x0Bworld\x0A\x0A"
+	tti.VP = 20
+	tti.JC = stl.JustificationCodeCenteredText
+
+	img, err := r.RenderTTI(tti, gsi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bounds := OpaqueBounds(img)
+	want := image.Rect(0, l.safe.Min.Y+20*l.rowHeight, 720, l.safe.Min.Y+24*l.rowHeight)
+	if bounds.Min.Y != want.Min.Y || bounds.Max.Y != want.Max.Y {
+		t.Errorf("text bounds %v, want rows 20 to 23 %v", bounds, want)
+	}
+}
+
+func TestRenderFileTextOrder(t *testing.T) {
+	r, err := New(DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gsi := testGSI(stl.DisplayStandardCodeOpenSubtitling)
+	gsi.CCT = stl.CharacterCodeTableLatinHebrew
+
+	var stills [2][]Still
+	for i, order := range []stl.TextOrder{stl.TextOrderLogical, stl.TextOrderVisual} {
+		tti := stl.NewTTIBlock()
+		tti.SGN, tti.SN, tti.EBN = 0, 0, stl.EBNLastBlock
+		tti.VP = 20
+		tti.JC = stl.JustificationCodeLeftJustifiedText
+		tti.CF = stl.CommentFlagSubtitleData
+		if err := tti.SetTextOrdered("שלום -12", gsi.CCT, order); err != nil {
+			t.Fatal(err)
+		}
+		f := stl.NewFile()
+		f.GSI = gsi
+		f.TTI = []*stl.TTIBlock{tti}
+		f.TextOrder = order
+		if stills[i], err = r.RenderFile(f); err != nil {
+			t.Fatal(err)
+		}
+		if len(stills[i]) != 1 {
+			t.Fatalf("%s: got %d stills, want 1", order, len(stills[i]))
+		}
+	}
+	if !bytes.Equal(stills[0][0].Image.Pix, stills[1][0].Image.Pix) {
+		t.Error("expected the file stored in visual order to be drawn as the file stored in logical order")
+	}
+}
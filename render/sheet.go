@@ -0,0 +1,161 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/si0ls/subs/stl"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Still is a rendered subtitle.
+type Still struct {
+	TTI   *stl.TTIBlock // Rendered subtitle (extension blocks merged)
+	Image *image.RGBA   // Rendered frame
+}
+
+// RenderFile renders each subtitle of f in its own frame, its
+// right-to-left text being stored in the TextOrder of f.
+// Extension blocks are merged and translator's comments are skipped.
+func (r *Renderer) RenderFile(f *stl.File) ([]Still, error) {
+	if f.GSI == nil {
+		return nil, ErrNilGSI
+	}
+
+	var stills []Still
+	for _, tti := range f.Subtitles() {
+		if tti.CF == stl.CommentFlagTranslatorComments {
+			continue
+		}
+		img, err := r.render(tti, f.GSI, f.TextOrder)
+		if err != nil {
+			return stills, fmt.Errorf("subtitle %d/%d: %w", tti.SGN, tti.SN, err)
+		}
+		stills = append(stills, Still{TTI: tti, Image: img})
+	}
+	return stills, nil
+}
+
+// WritePNG encodes img as PNG to w.
+func WritePNG(w io.Writer, img image.Image) error {
+	return png.Encode(w, img)
+}
+
+// WriteStills renders each subtitle of f as a PNG file in dir.
+// Files are named after the subtitle position in the file (0001.png,
+// 0002.png...). It returns the paths of the written files.
+func (r *Renderer) WriteStills(dir string, f *stl.File) ([]string, error) {
+	stills, err := r.RenderFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(stills))
+	for i, still := range stills {
+		path := filepath.Join(dir, fmt.Sprintf("%04d.png", i+1))
+		if err := WritePNGFile(path, still.Image); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// WritePNGFile encodes img as PNG to the file at path, created or truncated.
+func WritePNGFile(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WritePNG(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// SheetOptions configures a contact sheet.
+type SheetOptions struct {
+	Columns    int         // Number of thumbnails per row (0: 4)
+	ThumbWidth int         // Thumbnail width in pixels (0: 240)
+	Background color.Color // Sheet background (nil: dark grey)
+}
+
+// ContactSheet renders all subtitles of f as thumbnails laid out in a grid,
+// each captioned with its Time Code In (TCI) and Time Code Out (TCO).
+func (r *Renderer) ContactSheet(f *stl.File, opts SheetOptions) (*image.RGBA, error) {
+	stills, err := r.RenderFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Columns <= 0 {
+		opts.Columns = 4
+	}
+	if opts.ThumbWidth <= 0 {
+		opts.ThumbWidth = 240
+	}
+	if opts.Background == nil {
+		opts.Background = color.NRGBA{0x40, 0x40, 0x40, 0xFF}
+	}
+
+	layout := r.layout(f.GSI)
+	thumbHeight := opts.ThumbWidth * layout.bounds.Dy() / layout.bounds.Dx()
+	captionHeight := 16
+	gap := 8
+	cellWidth := opts.ThumbWidth + gap
+	cellHeight := thumbHeight + captionHeight + gap
+
+	rows := (len(stills) + opts.Columns - 1) / opts.Columns
+	if rows == 0 {
+		rows = 1
+	}
+	sheet := image.NewRGBA(image.Rect(0, 0, opts.Columns*cellWidth+gap, rows*cellHeight+gap))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(opts.Background), image.Point{}, draw.Src)
+
+	caption, err := r.face(false, float64(captionHeight)*0.75)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, still := range stills {
+		x := gap + (i%opts.Columns)*cellWidth
+		y := gap + (i/opts.Columns)*cellHeight
+		thumb := image.Rect(x, y, x+opts.ThumbWidth, y+thumbHeight)
+		draw.Draw(sheet, thumb, image.NewUniform(color.Black), image.Point{}, draw.Src)
+		xdraw.ApproxBiLinear.Scale(sheet, thumb, still.Image, still.Image.Bounds(), xdraw.Over, nil)
+
+		d := font.Drawer{
+			Dst:  sheet,
+			Src:  image.NewUniform(color.White),
+			Face: caption,
+			Dot:  fixed.P(x, y+thumbHeight+captionHeight-3),
+		}
+		d.DrawString(fmt.Sprintf("%s - %s", still.TTI.TCI, still.TTI.TCO))
+	}
+
+	return sheet, nil
+}
+
+// OpaqueBounds returns the smallest rectangle containing all the non fully
+// transparent pixels of img. It is empty if img is fully transparent.
+func OpaqueBounds(img *image.RGBA) image.Rectangle {
+	var r image.Rectangle
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if img.RGBAAt(x, y).A != 0 {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
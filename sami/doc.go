@@ -0,0 +1,31 @@
+package sami
+
+import (
+	"io"
+
+	"github.com/si0ls/subs/cue"
+	"github.com/si0ls/subs/doc"
+	"github.com/si0ls/subs/stl"
+)
+
+func init() {
+	doc.Register(doc.Format{
+		Name:       "sami",
+		Extensions: []string{".smi", ".sami"},
+		Encode: func(w io.Writer, d *doc.Document) ([]error, error) {
+			return EncodeDocument(w, d, Options{})
+		},
+	})
+}
+
+// EncodeDocument writes d as a SAMI file with a single language class to w,
+// as Encode does with the cues of the document. The language of the
+// document gives the class, name and language tag of the track, and its
+// title the default title of the SAMI file.
+func EncodeDocument(w io.Writer, d *doc.Document, opts Options) ([]error, error) {
+	if opts.Title == "" {
+		opts.Title = d.Metadata.Title
+	}
+	t := Track{Lang: d.Metadata.Language}.defaults(stl.LanguageCodeFromISO639(d.Metadata.Language))
+	return encode(w, []Track{t}, [][]cue.Cue{cue.FromDocument(d)}, opts.Title, nil)
+}
@@ -0,0 +1,168 @@
+// Package sami exports STL files as SAMI (Synchronized Accessible Media
+// Interchange) caption files.
+//
+// A SAMI file is an HTML like document whose style sheet declares one
+// class per language. Captions are SYNC elements, giving their start time
+// in milliseconds, holding one P element per language class; a
+// non-breaking space clears the captions of a language:
+//
+//	<SYNC Start=1200>
+//	  <P Class=ENCC>First line<br>second line</P>
+//	<SYNC Start=3000>
+//	  <P Class=ENCC>&nbsp;</P>
+package sami
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/si0ls/subs/cue"
+	"github.com/si0ls/subs/stl"
+)
+
+// Track is a language block of a SAMI file.
+type Track struct {
+	File  *stl.File // Subtitles of the language
+	Class string    // Class name (default ISO 639 code of the GSI Language Code in upper case followed by "CC")
+	Name  string    // Name of the language (default name of the GSI Language Code)
+	Lang  string    // Language tag (default ISO 639 code of the GSI Language Code)
+}
+
+// Options configures a SAMI export.
+type Options struct {
+	Title string // Title of the document (default GSI Translated Program Title of the first track)
+}
+
+var ErrNoTracks = errors.New("no tracks")
+
+const nbsp = "&nbsp;"
+
+var header = template.Must(template.New("header").Parse(`<SAMI>
+<HEAD>
+<TITLE>{{.Title}}</TITLE>
+<STYLE TYPE="text/css">
+<!--
+P { margin-left: 8pt; margin-right: 8pt; margin-bottom: 2pt; margin-top: 2pt;
+    text-align: center; font-size: 20pt; font-family: Arial, sans-serif;
+    font-weight: normal; color: white; background-color: black; }
+{{- range .Tracks}}
+.{{.Class}} { Name: {{.Name}}; lang: {{.Lang}}; SAMIType: CC; }
+{{- end}}
+-->
+</STYLE>
+</HEAD>
+<BODY>
+`))
+
+// Encode writes f as a SAMI file with a single language class to w.
+// See EncodeTracks.
+func Encode(w io.Writer, f *stl.File, opts Options) ([]error, error) {
+	return EncodeTracks(w, []Track{{File: f}}, opts)
+}
+
+// EncodeTracks writes the tracks as a SAMI file to w, one language class
+// per track.
+//
+// Times and lines are those of the cues of the subtitles, lines being
+// separated by <br> and italics enclosed in <i> elements. The captions of
+// a track are cleared at their end time, unless the next caption of the
+// track starts by then. Subtitles which can not be decoded are
+// returned as warnings, prefixed with the class of their track.
+func EncodeTracks(w io.Writer, tracks []Track, opts Options) ([]error, error) {
+	if len(tracks) == 0 {
+		return nil, ErrNoTracks
+	}
+
+	tracks = append([]Track(nil), tracks...)
+	cues := make([][]cue.Cue, len(tracks))
+	var warns []error
+	for i, t := range tracks {
+		if t.File == nil || t.File.GSI == nil {
+			return warns, fmt.Errorf("track %d: %w", i+1, cue.ErrNilGSI)
+		}
+		tracks[i] = t.defaults(t.File.GSI.LC)
+		if opts.Title == "" {
+			opts.Title = t.File.GSI.TPT
+		}
+
+		var errs []error
+		var err error
+		cues[i], errs, err = cue.FromSTL(t.File)
+		for _, err := range errs {
+			warns = append(warns, fmt.Errorf("%s: %w", tracks[i].Class, err))
+		}
+		if err != nil {
+			return warns, fmt.Errorf("%s: %w", tracks[i].Class, err)
+		}
+	}
+	return encode(w, tracks, cues, opts.Title, warns)
+}
+
+// defaults returns the track with its empty class, name and language tag
+// set from the Language Code.
+func (t Track) defaults(lc stl.LanguageCode) Track {
+	if t.Lang == "" {
+		if t.Lang = lc.ISO639_1(); t.Lang == "" {
+			t.Lang = lc.ISO639_2()
+		}
+	}
+	if t.Class == "" {
+		t.Class = strings.ToUpper(t.Lang) + "CC"
+	}
+	if t.Name == "" {
+		t.Name = lc.String()
+	}
+	return t
+}
+
+// encode writes the cues of the tracks as a SAMI file to w, returning the
+// warnings with the styles removed.
+func encode(w io.Writer, tracks []Track, cues [][]cue.Cue, title string, warns []error) ([]error, error) {
+	type paragraph struct {
+		class string
+		text  string
+	}
+	syncs := map[time.Duration][]paragraph{}
+	for i, t := range tracks {
+		for j, c := range cues[i] {
+			lines := make([]string, len(c.Lines))
+			for k, l := range c.Lines {
+				lines[k] = l.Markup("<i>", "</i>", html.EscapeString)
+			}
+			syncs[c.Start] = append(syncs[c.Start], paragraph{t.Class, strings.Join(lines, "<br>")})
+			if j+1 < len(cues[i]) && cues[i][j+1].Start <= c.End {
+				continue
+			}
+			syncs[c.End] = append(syncs[c.End], paragraph{t.Class, nbsp})
+		}
+	}
+
+	times := make([]time.Duration, 0, len(syncs))
+	for t := range syncs {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	bw := bufio.NewWriter(w)
+	if err := header.Execute(bw, struct {
+		Title  string
+		Tracks []Track
+	}{html.EscapeString(title), tracks}); err != nil {
+		return warns, err
+	}
+	for _, t := range times {
+		fmt.Fprintf(bw, "<SYNC Start=%d>\n", t.Milliseconds())
+		for _, p := range syncs[t] {
+			fmt.Fprintf(bw, "  <P Class=%s>%s</P>\n", p.class, p.text)
+		}
+	}
+	bw.WriteString("</BODY>\n</SAMI>\n")
+	return warns, bw.Flush()
+}
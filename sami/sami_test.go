@@ -0,0 +1,57 @@
+package sami
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/si0ls/subs/internal/stltest"
+	"github.com/si0ls/subs/stl"
+)
+
+func newFile(t *testing.T, lc stl.LanguageCode, texts ...string) *stl.File {
+	d := stltest.File{}
+	for i, text := range texts {
+		d.Subtitles = append(d.Subtitles, stltest.Subtitle{
+			TCI:  stl.Timecode{Seconds: 1 + 2*i, Frames: 5},
+			TCO:  stl.Timecode{Seconds: 3 + 2*i, Frames: 5},
+			Text: text,
+		})
+	}
+	f := d.New(t)
+	f.GSI.LC = lc
+	f.GSI.TPT = "Title & co"
+	return f
+}
+
+func TestEncodeTracks(t *testing.T) {
+	en := newFile(t, stl.LanguageCodeEnglish, "\x0b\x0bFirst <line>\u008a\x0b\x0b\u0080second\u0081 line", "\x0b\x0bNext")
+	fr := newFile(t, stl.LanguageCodeFrench, "\x0b\x0bPremière")
+
+	var buf bytes.Buffer
+	warns, err := EncodeTracks(&buf, []Track{{File: en}, {File: fr, Class: "FRFRCC"}}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) > 0 {
+		t.Errorf("unexpected warnings: %v", warns)
+	}
+	for _, want := range []string{
+		"<TITLE>Title &amp; co</TITLE>",
+		".ENCC { Name: English; lang: en; SAMIType: CC; }",
+		".FRFRCC { Name: French; lang: fr; SAMIType: CC; }",
+		"<SYNC Start=1200>\n  <P Class=ENCC>First &lt;line&gt;<br><i>second</i> line</P>\n  <P Class=FRFRCC>Première</P>\n",
+		"<SYNC Start=3200>\n  <P Class=ENCC>Next</P>\n  <P Class=FRFRCC>&nbsp;</P>\n",
+		"<SYNC Start=5200>\n  <P Class=ENCC>&nbsp;</P>\n</BODY>\n</SAMI>\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing %q in\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestEncodeNoTracks(t *testing.T) {
+	if _, err := EncodeTracks(&bytes.Buffer{}, nil, Options{}); err != ErrNoTracks {
+		t.Errorf("got %v, want %v", err, ErrNoTracks)
+	}
+}
@@ -0,0 +1,22 @@
+package sbv
+
+import (
+	"io"
+
+	"github.com/si0ls/subs/cue"
+	"github.com/si0ls/subs/doc"
+)
+
+func init() {
+	doc.Register(doc.Format{
+		Name:       "sbv",
+		Extensions: []string{".sbv"},
+		Encode:     EncodeDocument,
+	})
+}
+
+// EncodeDocument writes d as a YouTube SBV file to w, as Encode does with
+// the cues of the document.
+func EncodeDocument(w io.Writer, d *doc.Document) ([]error, error) {
+	return encode(w, cue.FromDocument(d), nil)
+}
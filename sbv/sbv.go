@@ -0,0 +1,58 @@
+// Package sbv exports STL files as YouTube SBV (SubViewer) caption files.
+//
+// An SBV file is made of one block per caption, separated by empty lines:
+// the start and end times of the caption, as H:MM:SS.mmm separated by a
+// comma, followed by the lines of the caption.
+//
+//	0:00:01.200,0:00:03.000
+//	First line
+//	second line
+package sbv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/si0ls/subs/cue"
+	"github.com/si0ls/subs/stl"
+)
+
+// Encode writes f as a YouTube SBV file to w.
+//
+// Times and lines are those of the cues of the subtitles. SBV files have
+// no styling: italics are removed and returned as warnings, with the
+// subtitles which can not be decoded.
+func Encode(w io.Writer, f *stl.File) ([]error, error) {
+	cues, warns, err := cue.FromSTL(f)
+	if err != nil {
+		return warns, err
+	}
+	return encode(w, cues, warns)
+}
+
+// encode writes the cues as a YouTube SBV file to w, returning the
+// warnings with the styles removed.
+func encode(w io.Writer, cues []cue.Cue, warns []error) ([]error, error) {
+	bw := bufio.NewWriter(w)
+	for i, c := range cues {
+		if c.Italic() {
+			warns = append(warns, c.Errorf("italics not supported, removed"))
+		}
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		fmt.Fprintf(bw, "%s,%s\n", formatTime(c.Start), formatTime(c.End))
+		for _, l := range c.Lines {
+			bw.WriteString(l.String() + "\n")
+		}
+	}
+	return warns, bw.Flush()
+}
+
+// formatTime formats a duration as H:MM:SS.mmm.
+func formatTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,55 @@
+package sbv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	_ "github.com/si0ls/subs/ass"
+	"github.com/si0ls/subs/doc"
+	"github.com/si0ls/subs/internal/stltest"
+	"github.com/si0ls/subs/stl"
+)
+
+func TestEncode(t *testing.T) {
+	f := stltest.File{Framerate: 30, Subtitles: []stltest.Subtitle{
+		{TCI: stl.Timecode{Seconds: 1, Frames: 1}, TCO: stl.Timecode{Seconds: 3, Frames: 2},
+			Text: "\x0b\x0bFirst  line\u008a\x0b\x0b\u0080second\u0081 line"},
+		{TCI: stl.Timecode{Hours: 1, Minutes: 2, Seconds: 3, Frames: 29}, TCO: stl.Timecode{Hours: 1, Minutes: 2, Seconds: 5},
+			Text: "\x0b\x0bLast"},
+	}}.New(t)
+
+	var buf bytes.Buffer
+	warns, err := Encode(&buf, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) != 1 {
+		t.Errorf("got %d warnings, want 1: %v", len(warns), warns)
+	}
+	// 1 frame at 30 fps is 33.333 ms, 29 frames 966.667 ms
+	want := "0:00:01.033,0:00:03.067\nFirst  line\nsecond line\n\n1:02:03.967,1:02:05.000\nLast\n"
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestConvertASS(t *testing.T) {
+	script := strings.Join([]string{
+		"[Events]",
+		"Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text",
+		"Dialogue: 0,0:00:01.00,0:00:02.50,Default,,0,0,0,,Привет\\N{\\i1}мир{\\i0} ♥",
+	}, "\n")
+	var buf bytes.Buffer
+	warns, err := doc.Convert(&buf, "sbv", strings.NewReader(script), "ass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) != 1 {
+		t.Errorf("got %d warnings, want 1: %v", len(warns), warns)
+	}
+	want := "0:00:01.000,0:00:02.500\nПривет\nмир ♥\n"
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
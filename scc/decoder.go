@@ -0,0 +1,380 @@
+package scc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/si0ls/subs/stl"
+)
+
+// Decode reads a SCC file from r and returns the pop-on captions of
+// channel 1 as a 30 fps Level-1 Teletext STL file, keeping the SCC
+// timecodes.
+//
+// A caption is displayed from its End Of Caption (EOC) command to the next
+// EOC or Erase Displayed Memory (EDM) command. Rows are converted to
+// Vertical Position (VP) and spacing, colors to Teletext alphanumeric
+// color codes and italics and underline to STL control codes.
+// Roll-up, paint-on and text mode captions are not supported and returned
+// as warnings, as are invalid words.
+func Decode(r io.Reader) (*stl.File, []error, error) {
+	d := &decoder{mode: modePopOn, lastControl: -1, channel1: true}
+	sc := bufio.NewScanner(r)
+
+	var header bool
+	var n int
+	for sc.Scan() {
+		n++
+		line := strings.TrimSpace(sc.Text())
+		if !header {
+			if line != Header {
+				return nil, nil, ErrInvalidHeader
+			}
+			header = true
+			continue
+		}
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		tc, dropFrame, err := parseTimecode(fields[0])
+		if err != nil {
+			d.warns = append(d.warns, fmt.Errorf("line %d: %w", n, err))
+			continue
+		}
+		d.dropFrame = d.dropFrame || dropFrame
+		frame := frameNumber(tc, dropFrame)
+		for i, word := range fields[1:] {
+			v, err := strconv.ParseUint(word, 16, 16)
+			if err != nil || len(word) != 4 {
+				d.warns = append(d.warns, fmt.Errorf("line %d: invalid word %q", n, word))
+				continue
+			}
+			d.word(frame+i, byte(v>>8), byte(v))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, d.warns, err
+	}
+	if !header {
+		return nil, nil, ErrInvalidHeader
+	}
+	d.erase(d.frame + 1)
+
+	f := stl.NewFile()
+	f.GSI = stl.NewGSIBlock()
+	f.GSI.SetDefaults(30, stl.DisplayStandardCodeLevel1Teletext)
+	for _, tti := range d.subtitles {
+		f.TTI = append(f.TTI, tti.ExtensionBlocks()...)
+	}
+	f.UpdateCounters()
+	return f, d.warns, nil
+}
+
+func parseTimecode(s string) (stl.Timecode, bool, error) {
+	var tc stl.Timecode
+	dropFrame := strings.ContainsAny(s, ";.,")
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ':' || r == ';' || r == '.' || r == ','
+	})
+	if len(parts) != 4 {
+		return tc, false, fmt.Errorf("invalid timecode %q", s)
+	}
+	var values [4]int
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return tc, false, fmt.Errorf("invalid timecode %q", s)
+		}
+		values[i] = v
+	}
+	tc = stl.Timecode{Hours: values[0], Minutes: values[1], Seconds: values[2], Frames: values[3]}
+	return tc, dropFrame, tc.Validate(30)
+}
+
+type mode int
+
+const (
+	modePopOn mode = iota
+	modeUnsupported
+)
+
+// memory is a caption memory of 15 rows of 32 columns.
+type memory [16][maxColumns]cell
+
+type decoder struct {
+	warns     []error
+	dropFrame bool
+	frame     int
+
+	mode        mode
+	lastControl int // last control code, to skip its repetition
+	channel1    bool
+	displayed   memory
+	loading     memory
+	shownAt     int // frame of the EOC of the displayed caption
+	row, column int
+	style       style
+	subtitles   []*stl.TTIBlock
+}
+
+func (d *decoder) word(frame int, b1, b2 byte) {
+	d.frame = frame
+	c1, err1 := unParity(b1)
+	c2, err2 := unParity(b2)
+	if err1 != nil || err2 != nil {
+		d.warns = append(d.warns, fmt.Errorf("%s: parity error", timecodeFromFrame(frame, d.dropFrame)))
+		return
+	}
+
+	if c1 >= 0x10 && c1 <= 0x1F {
+		code := int(c1)<<8 | int(c2)
+		if code == d.lastControl {
+			d.lastControl = -1
+			return
+		}
+		d.lastControl = code
+		d.control(frame, c1, c2)
+		return
+	}
+	d.lastControl = -1
+	if c1 < 0x10 && c1 != 0 {
+		return // XDS data
+	}
+	if !d.channel1 {
+		return
+	}
+	for _, c := range []byte{c1, c2} {
+		if c >= 0x20 {
+			d.put(basicChars[c])
+		}
+	}
+}
+
+func (d *decoder) control(frame int, c1, c2 byte) {
+	// channel 2 codes have bit 3 of the first byte set
+	d.channel1 = c1&0x08 == 0
+	if !d.channel1 {
+		return
+	}
+	c1 &^= 0x08
+
+	switch {
+	case c1 == 0x14 && c2 >= 0x20 && c2 <= 0x2F:
+		d.miscControl(frame, c2)
+	case c1 == 0x17 && c2 >= 0x21 && c2 <= 0x23: // tab offsets
+		d.column += int(c2 - 0x20)
+		if d.column >= maxColumns {
+			d.column = maxColumns - 1
+		}
+	case c1 == 0x11 && c2 >= 0x20 && c2 <= 0x2F: // mid-row codes
+		if code := (c2 - 0x20) >> 1; code == 7 {
+			d.style.italic = true
+		} else {
+			d.style = style{color: Color(code)}
+		}
+		d.style.underline = c2&0x01 != 0
+		d.put(' ')
+	case c1 == 0x11 && c2 >= 0x30 && c2 <= 0x3F: // special characters
+		d.put(specialChars[c2-0x30])
+	case (c1 == 0x12 || c1 == 0x13) && c2 >= 0x20 && c2 <= 0x3F: // extended characters
+		if d.column > 0 {
+			d.column--
+		}
+		d.put(extendedChars[c1-0x12][c2-0x20])
+	case c2 >= 0x40: // preamble address codes
+		d.preambleAddress(c1, c2)
+	}
+}
+
+func (d *decoder) miscControl(frame int, code byte) {
+	switch code {
+	case ccResumeCaptionLoading:
+		d.mode = modePopOn
+	case ccRollUp2, ccRollUp3, ccRollUp4, ccResumeDirectCaptioning, ccTextRestart, ccResumeTextDisplay:
+		if d.mode != modeUnsupported {
+			d.warns = append(d.warns, fmt.Errorf("%s: only pop-on captions are supported", timecodeFromFrame(frame, d.dropFrame)))
+		}
+		d.mode = modeUnsupported
+	case ccBackspace:
+		if d.mode == modePopOn && d.column > 0 {
+			d.column--
+			d.loading[d.row][d.column] = cell{}
+		}
+	case ccDeleteToEndOfRow:
+		if d.mode == modePopOn {
+			for c := d.column; c < maxColumns; c++ {
+				d.loading[d.row][c] = cell{}
+			}
+		}
+	case ccEraseNonDisplayedMemory:
+		d.loading = memory{}
+	case ccEraseDisplayedMemory:
+		d.erase(frame)
+	case ccEndOfCaption:
+		d.erase(frame)
+		d.displayed, d.loading = d.loading, d.displayed
+		d.shownAt = frame
+	}
+}
+
+func (d *decoder) preambleAddress(c1, c2 byte) {
+	for row := 1; row <= 15; row++ {
+		if pacRows[row][0] == c1 && c2&0x60 == pacRows[row][1] {
+			d.row = row
+			break
+		}
+	}
+	d.column = 0
+	d.style = style{underline: c2&0x01 != 0}
+	switch code := (c2 & 0x1E) >> 1; {
+	case code == 7:
+		d.style.italic = true
+	case code < 7:
+		d.style.color = Color(code)
+	default:
+		d.column = int(code-8) * 4
+	}
+}
+
+func (d *decoder) put(r rune) {
+	if d.mode != modePopOn || d.row == 0 {
+		return
+	}
+	d.loading[d.row][d.column] = cell{r: r, style: d.style}
+	if d.column < maxColumns-1 {
+		d.column++
+	}
+}
+
+// erase ends the display of the displayed caption at frame.
+func (d *decoder) erase(frame int) {
+	displayed := d.displayed
+	d.displayed = memory{}
+
+	first, last := 0, 0
+	for row := 1; row <= 15; row++ {
+		for _, c := range displayed[row] {
+			if c.r != 0 && c.r != ' ' {
+				if first == 0 {
+					first = row
+				}
+				last = row
+				break
+			}
+		}
+	}
+	if first == 0 || frame <= d.shownAt {
+		return
+	}
+
+	var text []rune
+	for row := first; row <= last; row++ {
+		if row > first {
+			text = append(text, rune(stl.ControlCodeLineBreak))
+		}
+		text = append(text, rowText(displayed[row])...)
+	}
+
+	tti := stl.NewTTIBlock()
+	tti.SGN = 0
+	tti.SN = len(d.subtitles)
+	tti.EBN = stl.EBNLastBlock
+	tti.CS = stl.CumulativeStatusNone
+	tti.TCI = timecodeFromFrame(d.shownAt, d.dropFrame)
+	tti.TCO = timecodeFromFrame(frame, d.dropFrame)
+	tti.VP = vpFromRow(first)
+	tti.JC = stl.JustificationCodeUnchangedPresentation
+	tti.CF = stl.CommentFlagSubtitleData
+	unmappables, err := tti.SetTextFallback(string(text), stl.CharacterCodeTableLatin, stl.FallbackReplace)
+	if err != nil {
+		d.warns = append(d.warns, fmt.Errorf("%s: %w", tti.TCI, err))
+		return
+	}
+	if len(unmappables) > 0 {
+		d.warns = append(d.warns, fmt.Errorf("%s: %w, fallback %s", tti.TCI, &stl.UnmappableError{Runes: unmappables}, stl.FallbackReplace))
+	}
+	d.subtitles = append(d.subtitles, tti)
+}
+
+// rowText returns the text of a caption row with STL control codes: the
+// 32 columns are mapped to the 40 Teletext columns, starting with a start
+// box and with style changes coded on the spaces displayed by the mid-row
+// codes.
+func rowText(cells [maxColumns]cell) []rune {
+	start, end := -1, 0
+	for i, c := range cells {
+		if c.r != 0 && c.r != ' ' {
+			if start < 0 {
+				start = i
+			}
+			end = i + 1
+		}
+	}
+
+	lead := start * 40 / maxColumns
+	text := []rune(strings.Repeat(" ", lead))
+	text = append(text, rune(stl.TeletextControlCodeStartBox), rune(stl.TeletextControlCodeStartBox))
+	var cur style
+	for _, c := range cells[start:end] {
+		r := c.r
+		if r == 0 {
+			r = ' '
+		}
+		if c.style != cur {
+			if c.style.color != cur.color {
+				text = append(text, rune(colorTeletext[c.style.color]))
+				if r == ' ' {
+					// the spacing attribute takes the place of the
+					// space displayed by the mid-row code
+					r = 0
+				}
+			}
+			if c.style.italic != cur.italic {
+				text = append(text, italicCode(c.style.italic))
+			}
+			if c.style.underline != cur.underline {
+				text = append(text, underlineCode(c.style.underline))
+			}
+			cur = c.style
+		}
+		if r != 0 {
+			text = append(text, r)
+		}
+	}
+	if cur.italic {
+		text = append(text, rune(stl.ControlCodeItalicOff))
+	}
+	if cur.underline {
+		text = append(text, rune(stl.ControlCodeUnderlineOff))
+	}
+	return text
+}
+
+func italicCode(on bool) rune {
+	if on {
+		return rune(stl.ControlCodeItalicOn)
+	}
+	return rune(stl.ControlCodeItalicOff)
+}
+
+func underlineCode(on bool) rune {
+	if on {
+		return rune(stl.ControlCodeUnderlineOn)
+	}
+	return rune(stl.ControlCodeUnderlineOff)
+}
+
+var errParity = errors.New("parity error")
+
+func unParity(b byte) (byte, error) {
+	if parity(b) != b {
+		return b & 0x7F, errParity
+	}
+	return b & 0x7F, nil
+}
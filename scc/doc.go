@@ -0,0 +1,19 @@
+package scc
+
+import (
+	"io"
+
+	"github.com/si0ls/subs/doc"
+	"github.com/si0ls/subs/stl"
+)
+
+func init() {
+	doc.RegisterSTL("scc", []string{".scc"},
+		func(r io.Reader) (*stl.File, []error, error) {
+			return Decode(r)
+		},
+		func(w io.Writer, f *stl.File) ([]error, error) {
+			return Encode(w, f, Options{})
+		},
+	)
+}
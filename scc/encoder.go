@@ -0,0 +1,425 @@
+package scc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/si0ls/subs/stl"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Options configures a SCC encoding.
+type Options struct {
+	NonDropFrame bool // Write non drop frame timecodes (default drop frame)
+}
+
+// maxColumns is the number of columns of a CEA-608 caption row.
+const maxColumns = 32
+
+// token is a character or a control code of a caption.
+type token struct {
+	control bool
+	b       [2]byte
+	width   int // number of columns taken on screen
+}
+
+func charToken(c byte) token {
+	return token{b: [2]byte{c}, width: 1}
+}
+
+func controlToken(b1, b2 byte, width int) token {
+	return token{control: true, b: [2]byte{b1, b2}, width: width}
+}
+
+func miscControl(code byte) token {
+	return controlToken(0x14, code, 0)
+}
+
+// block is a sequence of words sent from a frame on.
+type block struct {
+	frame int
+	words []string
+}
+
+// Encode writes the subtitles of f to w as pop-on captions.
+//
+// Each subtitle is loaded in non-displayed memory ahead of its Time Code In
+// (TCI) so that its End Of Caption (EOC) command is sent on the TCI frame,
+// and erased at its Time Code Out (TCO) unless the next subtitle replaces
+// it on that frame. Control codes are sent twice.
+//
+// Rows are placed from the row matching the Vertical Position (VP) of the
+// subtitle and positioned according to its Justification Code (JC), with
+// rows longer than 32 columns wrapped. Italics, underline and Teletext
+// colors are coded with mid-row codes, characters missing from the CEA-608
+// character sets are replaced.
+//
+// Timecodes of 30 fps files are kept as 29.97 fps timecodes, those of 25
+// fps files are converted. Losses and caption delays are returned as
+// warnings.
+func Encode(w io.Writer, f *stl.File, opts Options) ([]error, error) {
+	if f.GSI == nil {
+		return nil, ErrNilGSI
+	}
+	framerate := f.GSI.Framerate()
+	if framerate == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFramerate, f.GSI.DFC)
+	}
+	e := &encoder{gsi: f.GSI, textOrder: f.TextOrder, framerate: framerate, dropFrame: !opts.NonDropFrame}
+
+	var subtitles []*stl.TTIBlock
+	for _, tti := range f.Subtitles() {
+		if tti.CF != stl.CommentFlagTranslatorComments {
+			subtitles = append(subtitles, tti)
+		}
+	}
+	sort.SliceStable(subtitles, func(i, j int) bool {
+		return e.frame(subtitles[i].TCI) < e.frame(subtitles[j].TCI)
+	})
+
+	var warns []error
+	var blocks []block
+	for i, tti := range subtitles {
+		toks, errs := e.caption(tti)
+		for _, err := range errs {
+			warns = append(warns, fmt.Errorf("subtitle %d/%d: %w", tti.SGN, tti.SN, err))
+		}
+		if toks == nil {
+			continue
+		}
+		words := encodeWords(toks)
+		in, out := e.frame(tti.TCI), e.frame(tti.TCO)
+		// the first EOC is the last but one word
+		blocks = append(blocks, block{frame: in - (len(words) - 2), words: words})
+
+		if out <= in {
+			continue
+		}
+		if i+1 < len(subtitles) && e.frame(subtitles[i+1].TCI) <= out {
+			continue
+		}
+		blocks = append(blocks, block{frame: out, words: encodeWords([]token{
+			miscControl(ccEraseDisplayedMemory),
+		})})
+	}
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].frame < blocks[j].frame
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s\r\n\r\n", Header)
+	next := 0
+	for _, b := range blocks {
+		if b.frame < next {
+			warns = append(warns, fmt.Errorf("%s: caption data delayed by %d frames", timecodeFromFrame(b.frame, e.dropFrame), next-b.frame))
+			b.frame = next
+		}
+		fmt.Fprintf(bw, "%s\t%s\r\n\r\n", e.formatTimecode(b.frame), strings.Join(b.words, " "))
+		next = b.frame + len(b.words)
+	}
+	return warns, bw.Flush()
+}
+
+type encoder struct {
+	gsi       *stl.GSIBlock
+	textOrder stl.TextOrder
+	framerate uint
+	dropFrame bool
+}
+
+// frame returns the 29.97 fps frame number of the STL timecode.
+func (e *encoder) frame(tc stl.Timecode) int {
+	if e.framerate == 30 {
+		return frameNumber(tc, e.dropFrame)
+	}
+	return int(math.Round(float64(tc.ToFrames(e.framerate)) * 30000 / 1001 / float64(e.framerate)))
+}
+
+func (e *encoder) formatTimecode(frame int) string {
+	tc := timecodeFromFrame(frame, e.dropFrame)
+	sep := ":"
+	if e.dropFrame {
+		sep = ";"
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", tc.Hours, tc.Minutes, tc.Seconds, sep, tc.Frames)
+}
+
+// encodeWords returns the hexadecimal words of the tokens with parity,
+// control codes doubled and aligned on words.
+func encodeWords(toks []token) []string {
+	var words []string
+	word := func(b1, b2 byte) {
+		words = append(words, fmt.Sprintf("%02x%02x", parity(b1), parity(b2)))
+	}
+	var pending []byte
+	for _, tok := range toks {
+		if tok.control {
+			if len(pending) > 0 {
+				word(pending[0], 0x00)
+				pending = nil
+			}
+			word(tok.b[0], tok.b[1])
+			word(tok.b[0], tok.b[1])
+			continue
+		}
+		if len(pending) > 0 {
+			word(pending[0], tok.b[0])
+			pending = nil
+		} else {
+			pending = []byte{tok.b[0]}
+		}
+	}
+	if len(pending) > 0 {
+		word(pending[0], 0x00)
+	}
+	return words
+}
+
+// cell is a character of a caption row.
+type cell struct {
+	r     rune
+	style style
+}
+
+// caption returns the tokens loading and displaying the subtitle, nil if it
+// has no text.
+func (e *encoder) caption(tti *stl.TTIBlock) ([]token, []error) {
+	textRows, err := tti.RowsOrdered(e.gsi.CCT, e.textOrder)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	teletext := e.gsi.DSC == stl.DisplayStandardCodeLevel1Teletext || e.gsi.DSC == stl.DisplayStandardCodeLevel2Teletext
+	columns := 40
+	if !teletext && e.gsi.MNC > 0 {
+		columns = e.gsi.MNC
+	}
+
+	var warns []error
+	var lines [][]cell
+	var columnsFromLeft []int
+	first := -1
+	for i, row := range textRows {
+		cells, lead := rowCells(row)
+		if len(cells) == 0 {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		for _, line := range wrap(cells) {
+			lines = append(lines, line)
+			columnsFromLeft = append(columnsFromLeft, lead*maxColumns/columns)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	vp := tti.VP + first
+	if !teletext && e.gsi.MNR > 0 {
+		vp = vp * 23 / e.gsi.MNR
+	}
+	row := rowFromVP(vp)
+	if tti.VP == 0 || row+len(lines)-1 > 15 {
+		row = 15 - len(lines) + 1
+	}
+	if row < 1 {
+		warns = append(warns, fmt.Errorf("%d rows do not fit on screen, truncated", len(lines)))
+		lines = lines[1-row:]
+		row = 1
+	}
+
+	toks := []token{
+		miscControl(ccResumeCaptionLoading),
+		miscControl(ccEraseNonDisplayedMemory),
+	}
+	for i, line := range lines {
+		lineToks, errs := encodeLine(line)
+		warns = append(warns, errs...)
+		width := 0
+		for _, tok := range lineToks {
+			width += tok.width
+		}
+		if width > maxColumns {
+			warns = append(warns, fmt.Errorf("row %d longer than %d columns, truncated", i+1, maxColumns))
+			for width > maxColumns {
+				width -= lineToks[len(lineToks)-1].width
+				lineToks = lineToks[:len(lineToks)-1]
+			}
+		}
+
+		var column int
+		switch tti.JC {
+		case stl.JustificationCodeLeftJustifiedText:
+			column = 0
+		case stl.JustificationCodeCenteredText:
+			column = (maxColumns - width) / 2
+		case stl.JustificationCodeRightJustifiedText:
+			column = maxColumns - width
+		default:
+			column = columnsFromLeft[i]
+			if column > maxColumns-width {
+				column = maxColumns - width
+			}
+		}
+
+		toks = append(toks, preambleAddress(row+i, column-column%4))
+		if column%4 > 0 {
+			toks = append(toks, controlToken(0x17, 0x20+byte(column%4), 0))
+		}
+		toks = append(toks, lineToks...)
+	}
+	return append(toks, miscControl(ccEndOfCaption)), warns
+}
+
+// rowCells returns the cells of a text row without leading and trailing
+// spaces and the number of leading spaces (including spacing attributes).
+func rowCells(row stl.TextRow) ([]cell, int) {
+	var cells []cell
+	for _, run := range row {
+		for len(cells) < run.Column {
+			cells = append(cells, cell{r: ' '})
+		}
+		s := style{
+			color:     teletextColors[run.Style.Foreground],
+			italic:    run.Style.Italic,
+			underline: run.Style.Underline,
+		}
+		for _, r := range run.Text {
+			cells = append(cells, cell{r: r, style: s})
+		}
+	}
+	lead := 0
+	for lead < len(cells) && unicode.IsSpace(cells[lead].r) {
+		lead++
+	}
+	cells = cells[lead:]
+	for len(cells) > 0 && unicode.IsSpace(cells[len(cells)-1].r) {
+		cells = cells[:len(cells)-1]
+	}
+	return cells, lead
+}
+
+// wrap splits cells in lines of at most 32 columns, on spaces if possible.
+func wrap(cells []cell) [][]cell {
+	var lines [][]cell
+	for len(cells) > maxColumns {
+		cut := maxColumns
+		for i := maxColumns; i > 0; i-- {
+			if cells[i].r == ' ' {
+				cut = i
+				break
+			}
+		}
+		lines = append(lines, cells[:cut])
+		cells = cells[cut:]
+		for len(cells) > 0 && cells[0].r == ' ' {
+			cells = cells[1:]
+		}
+	}
+	return append(lines, cells)
+}
+
+// encodeLine returns the tokens of a row of cells, style changes coded as
+// mid-row codes replacing the preceding space if any.
+func encodeLine(cells []cell) ([]token, []error) {
+	var warns []error
+	var toks []token
+	var cur style
+	for _, c := range cells {
+		// spaces only show the underline
+		if c.r == ' ' && c.style.underline == cur.underline {
+			c.style = cur
+		}
+		if c.style != cur {
+			if n := len(toks); n > 0 && !toks[n-1].control && toks[n-1].b[0] == ' ' {
+				toks = toks[:n-1]
+			}
+			toks = append(toks, midRowCodes(c.style)...)
+			cur = c.style
+		}
+		charToks, ok := encodeRune(c.r)
+		if !ok {
+			warns = append(warns, fmt.Errorf("character %q not available in CEA-608, replaced", c.r))
+		}
+		toks = append(toks, charToks...)
+	}
+	return toks, warns
+}
+
+// preambleAddress returns the preamble address code moving the cursor to
+// row (1..15) and column (multiple of 4) with white non underlined text.
+func preambleAddress(row, column int) token {
+	return controlToken(pacRows[row][0], pacRows[row][1]|byte(8+column/4)<<1, 0)
+}
+
+// midRowCodes returns the mid-row codes switching to the style. Color codes
+// turn italics off.
+func midRowCodes(s style) []token {
+	var u byte
+	if s.underline {
+		u = 1
+	}
+	var toks []token
+	if !s.italic || s.color != ColorWhite {
+		toks = append(toks, controlToken(0x11, 0x20|byte(s.color)<<1|u, 1))
+	}
+	if s.italic {
+		toks = append(toks, controlToken(0x11, 0x2E|u, 1))
+	}
+	return toks
+}
+
+var (
+	basicCodes    = make(map[rune]byte)
+	specialCodes  = make(map[rune]byte)
+	extendedCodes = make(map[rune][2]byte)
+)
+
+func init() {
+	for c := 0x20; c < 0x80; c++ {
+		basicCodes[basicChars[c]] = byte(c)
+	}
+	for i, r := range specialChars {
+		if i != 0x09 { // transparent space
+			specialCodes[r] = 0x30 + byte(i)
+		}
+	}
+	for set, chars := range extendedChars {
+		for i, r := range chars {
+			if _, ok := basicCodes[r]; !ok {
+				extendedCodes[r] = [2]byte{0x12 + byte(set), 0x20 + byte(i)}
+			}
+		}
+	}
+}
+
+// encodeRune returns the tokens of the character r. Extended characters
+// are preceded by a basic character for decoders without the extended
+// character sets. Missing characters are replaced by their base letter
+// (without diacritical mark) or '?', ok is then false.
+func encodeRune(r rune) (toks []token, ok bool) {
+	if c, ok := basicCodes[r]; ok {
+		return []token{charToken(c)}, true
+	}
+	if c, ok := specialCodes[r]; ok {
+		return []token{controlToken(0x11, c, 1)}, true
+	}
+	base := byte('?')
+	for _, b := range norm.NFD.String(string(r)) {
+		if c, ok := basicCodes[b]; ok && !unicode.Is(unicode.Mn, b) {
+			base = c
+			break
+		}
+	}
+	if code, ok := extendedCodes[r]; ok {
+		return []token{charToken(base), controlToken(code[0], code[1], 0)}, true
+	}
+	return []token{charToken(base)}, false
+}
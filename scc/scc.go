@@ -0,0 +1,167 @@
+// Package scc converts STL files to and from Scenarist Closed Caption
+// (.scc) files, carrying CEA-608 caption data for channel 1 (CC1) at
+// 29.97 frames per second.
+package scc
+
+import (
+	"errors"
+
+	"github.com/si0ls/subs/stl"
+)
+
+// Header is the first line of a SCC file.
+const Header = "Scenarist_SCC V1.0"
+
+var (
+	ErrNilGSI               = errors.New("nil GSI block")
+	ErrUnsupportedFramerate = errors.New("unsupported framerate")
+	ErrInvalidHeader        = errors.New("invalid SCC header")
+)
+
+// Miscellaneous control codes of channel 1 (second byte, first byte is
+// 0x14).
+const (
+	ccResumeCaptionLoading    = 0x20 // RCL
+	ccBackspace               = 0x21 // BS
+	ccDeleteToEndOfRow        = 0x24 // DER
+	ccRollUp2                 = 0x25 // RU2
+	ccRollUp3                 = 0x26 // RU3
+	ccRollUp4                 = 0x27 // RU4
+	ccResumeDirectCaptioning  = 0x29 // RDC
+	ccTextRestart             = 0x2A // TR
+	ccResumeTextDisplay       = 0x2B // RTD
+	ccEraseDisplayedMemory    = 0x2C // EDM
+	ccCarriageReturn          = 0x2D // CR
+	ccEraseNonDisplayedMemory = 0x2E // ENM
+	ccEndOfCaption            = 0x2F // EOC
+)
+
+// Color is a CEA-608 foreground color.
+type Color byte
+
+const (
+	ColorWhite Color = iota
+	ColorGreen
+	ColorBlue
+	ColorCyan
+	ColorRed
+	ColorYellow
+	ColorMagenta
+)
+
+// teletextColors maps Teletext colors to CEA-608 colors, black text is not
+// available and rendered white.
+var teletextColors = map[stl.TeletextColor]Color{
+	stl.TeletextColorBlack:   ColorWhite,
+	stl.TeletextColorRed:     ColorRed,
+	stl.TeletextColorGreen:   ColorGreen,
+	stl.TeletextColorYellow:  ColorYellow,
+	stl.TeletextColorBlue:    ColorBlue,
+	stl.TeletextColorMagenta: ColorMagenta,
+	stl.TeletextColorCyan:    ColorCyan,
+	stl.TeletextColorWhite:   ColorWhite,
+}
+
+// colorTeletext is the reverse of teletextColors.
+var colorTeletext = map[Color]stl.TeletextColor{
+	ColorWhite:   stl.TeletextColorWhite,
+	ColorGreen:   stl.TeletextColorGreen,
+	ColorBlue:    stl.TeletextColorBlue,
+	ColorCyan:    stl.TeletextColorCyan,
+	ColorRed:     stl.TeletextColorRed,
+	ColorYellow:  stl.TeletextColorYellow,
+	ColorMagenta: stl.TeletextColorMagenta,
+}
+
+// style is the style of a caption character.
+type style struct {
+	color     Color
+	italic    bool
+	underline bool
+}
+
+// pacRows is the first byte and second byte base of the preamble address
+// codes of rows 1..15 for channel 1.
+var pacRows = [16][2]byte{
+	{},
+	{0x11, 0x40}, {0x11, 0x60}, {0x12, 0x40}, {0x12, 0x60},
+	{0x15, 0x40}, {0x15, 0x60}, {0x16, 0x40}, {0x16, 0x60},
+	{0x17, 0x40}, {0x17, 0x60}, {0x10, 0x40}, {0x13, 0x40},
+	{0x13, 0x60}, {0x14, 0x40}, {0x14, 0x60},
+}
+
+// basicChars is the CEA-608 basic character set (0x20..0x7F), ASCII with
+// a few substitutions.
+var basicChars = func() (chars [128]rune) {
+	for c := 0x20; c < 0x80; c++ {
+		chars[c] = rune(c)
+	}
+	for c, r := range map[byte]rune{
+		0x2A: 'á', 0x5C: 'é', 0x5E: 'í', 0x5F: 'ó', 0x60: 'ú',
+		0x7B: 'ç', 0x7C: '÷', 0x7D: 'Ñ', 0x7E: 'ñ', 0x7F: '█',
+	} {
+		chars[c] = r
+	}
+	return
+}()
+
+// specialChars is the special character set (0x11 0x30..0x3F), 0x39 is a
+// transparent space.
+var specialChars = [16]rune{'®', '°', '½', '¿', '™', '¢', '£', '♪', 'à', ' ', 'è', 'â', 'ê', 'î', 'ô', 'û'}
+
+// extendedChars are the extended character sets (0x12 and 0x13
+// 0x20..0x3F), each replacing the previous character.
+var extendedChars = [2][32]rune{
+	{'Á', 'É', 'Ó', 'Ú', 'Ü', 'ü', '‘', '¡', '*', '’', '—', '©', '℠', '•', '“', '”',
+		'À', 'Â', 'Ç', 'È', 'Ê', 'Ë', 'ë', 'Î', 'Ï', 'ï', 'Ô', 'Ù', 'ù', 'Û', '«', '»'},
+	{'Ã', 'ã', 'Í', 'Ì', 'ì', 'Ò', 'ò', 'Õ', 'õ', '{', '}', '\\', '^', '_', '|', '~',
+		'Ä', 'ä', 'Ö', 'ö', 'ß', '¥', '¤', '¦', 'Å', 'å', 'Ø', 'ø', '┌', '┐', '└', '┘'},
+}
+
+// parity returns c (7 bits) with its bit 8 set to give odd parity.
+func parity(c byte) byte {
+	c &= 0x7F
+	n := 0
+	for b := c; b != 0; b &= b - 1 {
+		n++
+	}
+	if n%2 == 0 {
+		c |= 0x80
+	}
+	return c
+}
+
+// Vertical position conversions between STL rows (1..23) and CEA-608 rows
+// (1..15), each one the inverse of the other for CEA-608 rows.
+
+func rowFromVP(vp int) int {
+	row := (vp*15 + 11) / 23
+	if row < 1 {
+		return 1
+	}
+	if row > 15 {
+		return 15
+	}
+	return row
+}
+
+func vpFromRow(row int) int {
+	return (row*23 + 7) / 15
+}
+
+// frameNumber returns the number of frames since 00:00:00:00 of a 29.97 fps
+// timecode.
+func frameNumber(tc stl.Timecode, dropFrame bool) int {
+	if dropFrame {
+		return tc.ToDropFrames()
+	}
+	return tc.ToFrames(30)
+}
+
+// timecodeFromFrame returns the 29.97 fps timecode of a frame number.
+func timecodeFromFrame(n int, dropFrame bool) stl.Timecode {
+	if dropFrame {
+		return stl.TimecodeFromDropFrames(n)
+	}
+	return stl.TimecodeFromFrames(n, 30)
+}
@@ -0,0 +1,147 @@
+package scc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/si0ls/subs/internal/stltest"
+	"github.com/si0ls/subs/stl"
+)
+
+func TestDropFrame(t *testing.T) {
+	for _, tc := range []struct {
+		timecode stl.Timecode
+		frame    int
+	}{
+		{stl.Timecode{}, 0},
+		{stl.Timecode{Minutes: 1, Frames: 2}, 1800},
+		{stl.Timecode{Minutes: 10}, 17982},
+		{stl.Timecode{Hours: 1}, 107892},
+	} {
+		if got := frameNumber(tc.timecode, true); got != tc.frame {
+			t.Errorf("frameNumber(%s) = %d, want %d", tc.timecode, got, tc.frame)
+		}
+		if got := timecodeFromFrame(tc.frame, true); got != tc.timecode {
+			t.Errorf("timecodeFromFrame(%d) = %s, want %s", tc.frame, got, tc.timecode)
+		}
+	}
+}
+
+func testFile(t *testing.T) *stl.File {
+	t.Helper()
+
+	// 30 fps, special and extended characters, mid-row italics and color,
+	// and a subtitle starting when the previous one ends
+	return stltest.File{Framerate: 30, Subtitles: []stltest.Subtitle{
+		{TCI: stl.Timecode{Seconds: 1}, TCO: stl.Timecode{Seconds: 3}, VP: 20, JC: stl.JustificationCodeCenteredText,
+			Text: "\x0b\x0bCafé °\u008a\x0b\x0b\u0080italic\u0081 \x02green"},
+		{TCI: stl.Timecode{Seconds: 3}, TCO: stl.Timecode{Seconds: 5}, VP: 23, JC: stl.JustificationCodeCenteredText,
+			Text: "\x0b\x0bNext"},
+	}}.New(t)
+}
+
+func TestEncode(t *testing.T) {
+	var buf bytes.Buffer
+	warns, err := Encode(&buf, testFile(t), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) > 0 {
+		t.Errorf("unexpected warnings: %v", warns)
+	}
+
+	want := strings.Join([]string{
+		Header,
+		"",
+		// RCL, ENM, PAC row 13 indent 12, TO1, "Café", " ", "°" (special),
+		// PAC row 14 indent 8, TO1, italics, "italic", " ", green, "green", EOC
+		"00:00:00;02\t9420 9420 94ae 94ae 1376 1376 97a1 97a1 4361 e6dc 2080 9131 9131 9454 9454 97a1 97a1 91ae 91ae e9f4 61ec e9e3 2080 91a2 91a2 67f2 e5e5 6e80 942f 942f",
+		"",
+		// RCL, ENM, PAC row 15 indent 12, TO2, "Next", EOC
+		"00:00:02;20\t9420 9420 94ae 94ae 9476 9476 97a2 97a2 cee5 f8f4 942f 942f",
+		"",
+		// EDM
+		"00:00:05;00\t942c 942c",
+		"",
+		"",
+	}, "\r\n")
+	if got := buf.String(); got != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	f := testFile(t)
+	var buf bytes.Buffer
+	if _, err := Encode(&buf, f, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, warns, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) > 0 {
+		t.Errorf("unexpected warnings: %v", warns)
+	}
+	if len(got.TTI) != len(f.TTI) {
+		t.Fatalf("expected %d subtitles, got %d", len(f.TTI), len(got.TTI))
+	}
+	for i, tti := range got.TTI {
+		want := f.TTI[i]
+		if tti.TCI != want.TCI || tti.TCO != want.TCO {
+			t.Errorf("subtitle %d: expected %s-%s, got %s-%s", i, want.TCI, want.TCO, tti.TCI, tti.TCO)
+		}
+		wantRows, _ := want.Rows(stl.CharacterCodeTableLatin)
+		gotRows, _ := tti.Rows(stl.CharacterCodeTableLatin)
+		if len(gotRows) != len(wantRows) {
+			t.Fatalf("subtitle %d: expected %d rows, got %d", i, len(wantRows), len(gotRows))
+		}
+		for j := range gotRows {
+			if w, g := strings.TrimSpace(wantRows[j].String()), strings.TrimSpace(gotRows[j].String()); w != g {
+				t.Errorf("subtitle %d row %d: expected %q, got %q", i, j, w, g)
+			}
+		}
+	}
+	rows, _ := got.TTI[0].Rows(stl.CharacterCodeTableLatin)
+	for _, run := range rows[1] {
+		switch run.Text {
+		case "italic":
+			if !run.Style.Italic {
+				t.Errorf("expected italic text, got %+v", run)
+			}
+		case "green":
+			if run.Style.Italic || run.Style.Foreground != stl.TeletextColorGreen {
+				t.Errorf("expected green text, got %+v", run)
+			}
+		}
+	}
+}
+
+func TestEncodeTextOrder(t *testing.T) {
+	f := testFile(t)
+	f.GSI.CCT = stl.CharacterCodeTableLatinHebrew
+	f.TextOrder = stl.TextOrderVisual
+	f.TTI = f.TTI[1:]
+	if err := f.TTI[0].SetTextOrdered("\x0b\x0bשלום Next", f.GSI.CCT, f.TextOrder); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := Encode(&buf, f, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.TTI) != 1 {
+		t.Fatalf("expected 1 subtitle, got %d", len(got.TTI))
+	}
+	// Hebrew letters are not available in CEA-608
+	rows, _ := got.TTI[0].Rows(got.GSI.CCT)
+	if len(rows) != 1 || strings.TrimSpace(rows[0].String()) != "???? Next" {
+		t.Errorf("expected the text in logical order, got %+v", rows)
+	}
+}
@@ -0,0 +1,271 @@
+package spruce
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/si0ls/subs/stl"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Decode reads a Spruce STL file from r and returns its subtitles as a
+// Level-1 Teletext STL file at the framerate of the options.
+//
+// Files are read as UTF-8, or Windows-1252 when they are not valid UTF-8.
+// Each row of a subtitle starts with the alphanumeric color code of the
+// $ColorIndex1 palette color (unless white) and boxing codes, italics and
+// underline are coded with STL control codes. $HorzAlign gives the
+// Justification Code (JC) and $VertAlign the Vertical Position (VP).
+// The Character Code Table (CCT) is the one representing the text of the
+// subtitles, and the Language Code (LC) is guessed from it; characters it
+// can not represent are handled according to the fallback and returned as
+// warnings. Bold text and invalid lines are returned as warnings, other
+// directives are ignored.
+func Decode(r io.Reader, opts Options) (*stl.File, []error, error) {
+	framerate := opts.Framerate
+	if framerate == 0 {
+		framerate = 25
+	}
+	if framerate != 25 && framerate != 30 {
+		return nil, nil, fmt.Errorf("%w: %d", ErrUnsupportedFramerate, framerate)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !utf8.Valid(b) {
+		if b, err = charmap.Windows1252.NewDecoder().Bytes(b); err != nil {
+			return nil, nil, err
+		}
+	}
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+
+	d := &decoder{
+		framerate: framerate,
+		palette:   opts.palette(),
+		horz:      AlignCenter,
+		vert:      AlignBottom,
+		color:     stl.TeletextColorWhite,
+	}
+	if len(d.palette) > 0 {
+		d.color = d.palette[0]
+	}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	var n int
+	for sc.Scan() {
+		n++
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "", strings.HasPrefix(line, "//"):
+		case strings.HasPrefix(line, "$"):
+			if err := d.directive(line[1:]); err != nil {
+				d.warns = append(d.warns, fmt.Errorf("line %d: %w", n, err))
+			}
+		default:
+			if err := d.subtitle(line, n); err != nil {
+				d.warns = append(d.warns, fmt.Errorf("line %d: %w", n, err))
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, d.warns, err
+	}
+
+	f := stl.NewFile()
+	f.GSI = stl.NewGSIBlock()
+	f.GSI.SetDefaults(framerate, stl.DisplayStandardCodeLevel1Teletext)
+	texts := make([]string, len(d.subtitles))
+	for i, sub := range d.subtitles {
+		texts[i] = sub.text
+	}
+	f.GSI.SetCharacterCodeTable(texts...)
+	var sn int
+	for _, sub := range d.subtitles {
+		unmappables, err := sub.tti.SetTextFallback(sub.text, f.GSI.CCT, opts.Fallback)
+		if err != nil {
+			d.warns = append(d.warns, fmt.Errorf("line %d: %w", sub.line, err))
+			continue
+		}
+		if len(unmappables) > 0 {
+			d.warns = append(d.warns, fmt.Errorf("line %d: %w, fallback %s", sub.line, &stl.UnmappableError{Runes: unmappables}, opts.Fallback))
+		}
+		sub.tti.SN = sn
+		sn++
+		f.TTI = append(f.TTI, sub.tti.ExtensionBlocks()...)
+	}
+	f.UpdateCounters()
+	return f, d.warns, nil
+}
+
+type decoder struct {
+	framerate uint
+	palette   []stl.TeletextColor
+	italic    bool
+	underline bool
+	bold      bool
+	horz      string
+	vert      string
+	color     stl.TeletextColor
+	boldWarn  bool
+	subtitles []subtitle
+	warns     []error
+}
+
+// subtitle is a subtitle of the file, before the encoding of its text.
+type subtitle struct {
+	tti  *stl.TTIBlock
+	text string // Text Field (TF), UTF-8 encoded
+	line int    // Line of the subtitle
+}
+
+// directive applies a Key = Value directive.
+func (d *decoder) directive(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("invalid directive %q", s)
+	}
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	switch strings.ToLower(key) {
+	case "italic":
+		d.italic = strings.EqualFold(value, "TRUE")
+	case "underlined":
+		d.underline = strings.EqualFold(value, "TRUE")
+	case "bold":
+		d.bold = strings.EqualFold(value, "TRUE")
+	case "horzalign":
+		d.horz = value
+	case "vertalign":
+		d.vert = value
+	case "colorindex1":
+		i, err := strconv.Atoi(value)
+		if err != nil || i < 0 || i >= len(d.palette) {
+			return fmt.Errorf("invalid color index %q", value)
+		}
+		d.color = d.palette[i]
+	}
+	return nil
+}
+
+// subtitle decodes the subtitle line n.
+func (d *decoder) subtitle(line string, n int) error {
+	m := subtitleLine.FindStringSubmatch(line)
+	if m == nil {
+		return fmt.Errorf("invalid line %q", line)
+	}
+	tci, err := parseTimecode(m[1], d.framerate)
+	if err != nil {
+		return err
+	}
+	tco, err := parseTimecode(m[2], d.framerate)
+	if err != nil {
+		return err
+	}
+
+	italic, underline, bold := d.italic, d.underline, d.bold
+	var outItalic, outUnderline bool
+	rows := strings.Split(strings.TrimSpace(m[3]), RowSeparator)
+	var tf []rune
+	for i, row := range rows {
+		if i > 0 {
+			tf = append(tf, rune(stl.ControlCodeLineBreak))
+		}
+		started := false
+		for len(row) > 0 {
+			switch {
+			case strings.HasPrefix(row, ToggleItalic):
+				italic = !italic
+				row = row[len(ToggleItalic):]
+				continue
+			case strings.HasPrefix(row, ToggleUnderline):
+				underline = !underline
+				row = row[len(ToggleUnderline):]
+				continue
+			case strings.HasPrefix(row, ToggleBold):
+				bold = !bold
+				row = row[len(ToggleBold):]
+				continue
+			}
+			r, size := utf8.DecodeRuneInString(row)
+			row = row[size:]
+			if !started {
+				if r == ' ' {
+					continue
+				}
+				started = true
+				if d.color != stl.TeletextColorWhite {
+					tf = append(tf, rune(d.color))
+				}
+				tf = append(tf, rune(stl.TeletextControlCodeStartBox), rune(stl.TeletextControlCodeStartBox))
+			}
+			if italic != outItalic {
+				tf = append(tf, italicCode(italic))
+				outItalic = italic
+			}
+			if underline != outUnderline {
+				tf = append(tf, underlineCode(underline))
+				outUnderline = underline
+			}
+			if bold && !d.boldWarn {
+				d.warns = append(d.warns, errBold)
+				d.boldWarn = true
+			}
+			tf = append(tf, r)
+		}
+	}
+
+	if len(tf) == len(rows)-1 {
+		// no text
+		return nil
+	}
+
+	tti := stl.NewTTIBlock()
+	tti.SGN = 0
+	tti.SN = len(d.subtitles)
+	tti.EBN = stl.EBNLastBlock
+	tti.CS = stl.CumulativeStatusNone
+	tti.TCI = tci
+	tti.TCO = tco
+	tti.VP = verticalPosition(d.vert, len(rows))
+	switch d.horz {
+	case AlignLeft:
+		tti.JC = stl.JustificationCodeLeftJustifiedText
+	case AlignRight:
+		tti.JC = stl.JustificationCodeRightJustifiedText
+	default:
+		tti.JC = stl.JustificationCodeCenteredText
+	}
+	tti.CF = stl.CommentFlagSubtitleData
+	d.subtitles = append(d.subtitles, subtitle{tti: tti, text: string(tf), line: n})
+	return nil
+}
+
+func parseTimecode(s string, framerate uint) (stl.Timecode, error) {
+	var tc stl.Timecode
+	if _, err := fmt.Sscanf(s, "%d:%d:%d:%d", &tc.Hours, &tc.Minutes, &tc.Seconds, &tc.Frames); err != nil {
+		return tc, fmt.Errorf("invalid timecode %q", s)
+	}
+	if err := tc.Validate(framerate); err != nil {
+		return tc, fmt.Errorf("invalid timecode %q: %w", s, err)
+	}
+	return tc, nil
+}
+
+func italicCode(on bool) rune {
+	if on {
+		return rune(stl.ControlCodeItalicOn)
+	}
+	return rune(stl.ControlCodeItalicOff)
+}
+
+func underlineCode(on bool) rune {
+	if on {
+		return rune(stl.ControlCodeUnderlineOn)
+	}
+	return rune(stl.ControlCodeUnderlineOff)
+}
@@ -0,0 +1,19 @@
+package spruce
+
+import (
+	"io"
+
+	"github.com/si0ls/subs/doc"
+	"github.com/si0ls/subs/stl"
+)
+
+func init() {
+	doc.RegisterSTL("spruce", []string{".stl"},
+		func(r io.Reader) (*stl.File, []error, error) {
+			return Decode(r, Options{})
+		},
+		func(w io.Writer, f *stl.File) ([]error, error) {
+			return Encode(w, f, Options{})
+		},
+	)
+}
@@ -0,0 +1,253 @@
+package spruce
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/si0ls/subs/stl"
+)
+
+// header is written at the start of the file, before the subtitles.
+const header = `//Font select and font size
+$FontName       = Arial
+$FontSize       = 30
+
+//Character attributes (global)
+$Bold           = FALSE
+$UnderLined     = FALSE
+$Italic         = FALSE
+
+//Position Control
+$HorzAlign      = Center
+$VertAlign      = Bottom
+$XOffset        = 0
+$YOffset        = 0
+
+//Contrast Control
+$TextContrast           = 15
+$Outline1Contrast       = 15
+$Outline2Contrast       = 13
+$BackgroundContrast     = 0
+
+//Effects Control
+$ForceDisplay   = FALSE
+$FadeIn         = 0
+$FadeOut        = 0
+
+//Other Controls
+$TapeOffset     = FALSE
+
+//Colors
+$ColorIndex1    = %d
+$ColorIndex2    = %d
+$ColorIndex3    = %d
+$ColorIndex4    = %d
+
+//Subtitles
+`
+
+// Encode writes f as a Spruce STL file to w.
+//
+// Timecodes are written at the framerate of the GSI block. The
+// Justification Code (JC), the Vertical Position (VP) and the color of the
+// first row of a subtitle are written as $HorzAlign, $VertAlign and
+// $ColorIndex1 directives when they change. Italics and underline are
+// coded with toggles, translator's comments as comment lines. Other color
+// changes are returned as warnings and rows are trimmed.
+func Encode(w io.Writer, f *stl.File, opts Options) ([]error, error) {
+	if f.GSI == nil {
+		return nil, ErrNilGSI
+	}
+	framerate := f.GSI.Framerate()
+	if framerate == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFramerate, f.GSI.DFC)
+	}
+	e := &encoder{
+		gsi:       f.GSI,
+		textOrder: f.TextOrder,
+		palette:   opts.palette(),
+		horz:      AlignCenter,
+		vert:      AlignBottom,
+	}
+	e.color = e.colorIndex(stl.TeletextColorWhite)
+	black := e.colorIndex(stl.TeletextColorBlack)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, strings.ReplaceAll(header, "\n", "\r\n"), e.color, black, black, black)
+
+	var warns []error
+	for _, tti := range f.Subtitles() {
+		errs := e.subtitle(bw, tti)
+		for _, err := range errs {
+			warns = append(warns, fmt.Errorf("subtitle %d/%d: %w", tti.SGN, tti.SN, err))
+		}
+	}
+	return warns, bw.Flush()
+}
+
+type encoder struct {
+	gsi       *stl.GSIBlock
+	textOrder stl.TextOrder
+	palette   []stl.TeletextColor
+	horz      string
+	vert      string
+	color     int
+}
+
+// colorIndex returns the first palette index of a Teletext color, -1 if
+// the color is not in the palette.
+func (e *encoder) colorIndex(c stl.TeletextColor) int {
+	for i, pc := range e.palette {
+		if pc == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// segment is a piece of text of a row sharing the same style.
+type segment struct {
+	text  string
+	style stl.TextStyle
+}
+
+// subtitle writes the directives and the line of a subtitle.
+func (e *encoder) subtitle(w io.Writer, tti *stl.TTIBlock) []error {
+	textRows, err := tti.RowsOrdered(e.gsi.CCT, e.textOrder)
+	if err != nil {
+		return []error{err}
+	}
+
+	var rows [][]segment
+	first, last := -1, -1
+	for _, row := range textRows {
+		segs := rowSegments(row)
+		if len(segs) > 0 {
+			if first < 0 {
+				first = len(rows)
+			}
+			last = len(rows)
+		}
+		rows = append(rows, segs)
+	}
+	if first < 0 {
+		return nil
+	}
+
+	if tti.CF == stl.CommentFlagTranslatorComments {
+		var texts []string
+		for _, segs := range rows[first : last+1] {
+			var s string
+			for _, seg := range segs {
+				s += seg.text
+			}
+			texts = append(texts, s)
+		}
+		fmt.Fprintf(w, "//%s\r\n", strings.Join(texts, " "+RowSeparator+" "))
+		return nil
+	}
+
+	var warns []error
+	base := rows[first][0].style
+	horz := AlignCenter
+	switch tti.JC {
+	case stl.JustificationCodeLeftJustifiedText:
+		horz = AlignLeft
+	case stl.JustificationCodeRightJustifiedText:
+		horz = AlignRight
+	}
+	vert := AlignBottom
+	if tti.VP > 0 {
+		vp := tti.VP
+		teletext := e.gsi.DSC == stl.DisplayStandardCodeLevel1Teletext || e.gsi.DSC == stl.DisplayStandardCodeLevel2Teletext
+		if !teletext && e.gsi.MNR > 0 {
+			vp = vp * 23 / e.gsi.MNR
+		}
+		vert = verticalAlign(vp+first, vp+last)
+	}
+	color := e.colorIndex(base.Foreground)
+	if color < 0 {
+		warns = append(warns, fmt.Errorf("color %s not in palette", base.Foreground))
+		color = e.color
+	}
+
+	if horz != e.horz {
+		fmt.Fprintf(w, "$HorzAlign      = %s\r\n", horz)
+		e.horz = horz
+	}
+	if vert != e.vert {
+		fmt.Fprintf(w, "$VertAlign      = %s\r\n", vert)
+		e.vert = vert
+	}
+	if color != e.color {
+		fmt.Fprintf(w, "$ColorIndex1    = %d\r\n", color)
+		e.color = color
+	}
+
+	var sb strings.Builder
+	var italic, underline, colorChange bool
+	for i, segs := range rows[first : last+1] {
+		if i > 0 {
+			sb.WriteString(RowSeparator)
+		}
+		for _, seg := range segs {
+			if seg.style.Foreground != base.Foreground && strings.TrimSpace(seg.text) != "" {
+				colorChange = true
+			}
+			if seg.style.Italic != italic {
+				sb.WriteString(ToggleItalic)
+				italic = seg.style.Italic
+			}
+			if seg.style.Underline != underline {
+				sb.WriteString(ToggleUnderline)
+				underline = seg.style.Underline
+			}
+			sb.WriteString(seg.text)
+		}
+	}
+	if italic {
+		sb.WriteString(ToggleItalic)
+	}
+	if underline {
+		sb.WriteString(ToggleUnderline)
+	}
+	if colorChange {
+		warns = append(warns, errColorChanges)
+	}
+
+	fmt.Fprintf(w, "%s , %s , %s\r\n", tti.TCI, tti.TCO, sb.String())
+	return warns
+}
+
+// rowSegments returns the segments of a text row without leading and
+// trailing spaces. The character cells of spacing attributes between runs
+// are kept as spaces.
+func rowSegments(row stl.TextRow) []segment {
+	var segs []segment
+	column := -1
+	for _, run := range row {
+		if column >= 0 && run.Column > column && len(segs) > 0 {
+			segs[len(segs)-1].text += strings.Repeat(" ", run.Column-column)
+		}
+		segs = append(segs, segment{text: run.Text, style: run.Style})
+		column = run.Column + utf8.RuneCountInString(run.Text)
+	}
+
+	for len(segs) > 0 {
+		if segs[0].text = strings.TrimLeft(segs[0].text, " "); segs[0].text != "" {
+			break
+		}
+		segs = segs[1:]
+	}
+	for len(segs) > 0 {
+		n := len(segs) - 1
+		if segs[n].text = strings.TrimRight(segs[n].text, " "); segs[n].text != "" {
+			break
+		}
+		segs = segs[:n]
+	}
+	return segs
+}
@@ -0,0 +1,135 @@
+// Package spruce converts STL files to and from Spruce STL subtitle files,
+// the text format of Spruce Technologies and DVD Studio Pro which shares
+// its extension with EBU STL files.
+//
+// A Spruce STL file is made of $Key = Value directives, // comments and
+// subtitle lines giving the in and out timecodes and the text of the
+// subtitle, rows separated by a pipe and attributes toggled with ^I
+// (italics), ^U (underline) and ^B (bold). Directives apply to the
+// subtitles following them.
+package spruce
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+
+	"github.com/si0ls/subs/stl"
+)
+
+// Options configures a Spruce STL conversion.
+type Options struct {
+	Framerate uint                // Framerate of the STL file created on import, 25 or 30 (default 25)
+	Palette   []stl.TeletextColor // Colors of the $ColorIndex1 palette indices (default DefaultPalette)
+	Fallback  stl.Fallback        // Fallback for characters the Character Code Table (CCT) can not represent on import (default replace)
+}
+
+// DefaultPalette maps the palette indices of the $ColorIndex directives to
+// Teletext colors. The palette is defined by the DVD authoring project and
+// not by the file, the default one uses the order white, black, red, green,
+// blue, yellow, magenta and cyan for the first eight indices, the others
+// being white.
+var DefaultPalette = []stl.TeletextColor{
+	stl.TeletextColorWhite,
+	stl.TeletextColorBlack,
+	stl.TeletextColorRed,
+	stl.TeletextColorGreen,
+	stl.TeletextColorBlue,
+	stl.TeletextColorYellow,
+	stl.TeletextColorMagenta,
+	stl.TeletextColorCyan,
+	stl.TeletextColorWhite,
+	stl.TeletextColorWhite,
+	stl.TeletextColorWhite,
+	stl.TeletextColorWhite,
+	stl.TeletextColorWhite,
+	stl.TeletextColorWhite,
+	stl.TeletextColorWhite,
+	stl.TeletextColorWhite,
+}
+
+var (
+	ErrNilGSI               = errors.New("nil GSI block")
+	ErrUnsupportedFramerate = errors.New("unsupported framerate")
+)
+
+// Attribute toggles of the subtitle text.
+const (
+	ToggleItalic    = "^I"
+	ToggleUnderline = "^U"
+	ToggleBold      = "^B"
+	RowSeparator    = "|"
+)
+
+// Horizontal and vertical alignments of the $HorzAlign and $VertAlign
+// directives.
+const (
+	AlignLeft   = "Left"
+	AlignCenter = "Center"
+	AlignRight  = "Right"
+	AlignTop    = "Top"
+	AlignBottom = "Bottom"
+)
+
+var subtitleLine = regexp.MustCompile(`^(\d{1,2}:\d{2}:\d{2}:\d{2})\s*,\s*(\d{1,2}:\d{2}:\d{2}:\d{2})\s*,(.*)$`)
+
+// Detect reports whether b, the start of a file, is a Spruce STL file.
+// EBU STL files are told apart by the Disk Format Code (DFC) of their GSI
+// block, Spruce STL files by their first line being a directive, a comment
+// or a subtitle line.
+func Detect(b []byte) bool {
+	if len(b) >= 11 && (string(b[3:11]) == string(stl.DiskFormatCode25_01) || string(b[3:11]) == string(stl.DiskFormatCode30_01)) {
+		return false
+	}
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		return bytes.HasPrefix(line, []byte("$")) || bytes.HasPrefix(line, []byte("//")) || subtitleLine.Match(line)
+	}
+	return false
+}
+
+func (opts Options) palette() []stl.TeletextColor {
+	if opts.Palette != nil {
+		return opts.Palette
+	}
+	return DefaultPalette
+}
+
+// verticalPosition returns the Vertical Position (VP) of a subtitle of n
+// single height rows with the vertical alignment.
+func verticalPosition(align string, n int) int {
+	var vp int
+	switch align {
+	case AlignTop:
+		vp = 1
+	case AlignCenter:
+		vp = (23-n)/2 + 1
+	default:
+		vp = 23 - n
+	}
+	if vp < 1 {
+		vp = 1
+	}
+	return vp
+}
+
+// verticalAlign returns the vertical alignment of a subtitle displayed from
+// row top to row bottom.
+func verticalAlign(top, bottom int) string {
+	switch mid := (top + bottom) / 2; {
+	case mid < 8:
+		return AlignTop
+	case mid < 16:
+		return AlignCenter
+	}
+	return AlignBottom
+}
+
+var (
+	errColorChanges = errors.New("color changes within the subtitle dropped")
+	errBold         = errors.New("bold text not supported, coded as regular text")
+)
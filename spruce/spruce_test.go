@@ -0,0 +1,149 @@
+package spruce
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/si0ls/subs/doc"
+	"github.com/si0ls/subs/internal/stltest"
+	"github.com/si0ls/subs/stl"
+)
+
+func testFile(t *testing.T) *stl.File {
+	t.Helper()
+
+	// italics on the second row and a colored subtitle at the top, which
+	// Spruce writes as ^I and a vertical position
+	return stltest.File{Subtitles: []stltest.Subtitle{
+		{TCI: stl.Timecode{Seconds: 1}, TCO: stl.Timecode{Seconds: 3, Frames: 12}, VP: 21, JC: stl.JustificationCodeCenteredText,
+			Text: "\x0b\x0bHello\u008a\x0b\x0b\u0080World"},
+		{TCI: stl.Timecode{Seconds: 4}, TCO: stl.Timecode{Seconds: 6}, VP: 1, JC: stl.JustificationCodeLeftJustifiedText,
+			Text: "\x03\x0b\x0bTop"},
+	}}.New(t)
+}
+
+func TestEncode(t *testing.T) {
+	var buf bytes.Buffer
+	warns, err := Encode(&buf, testFile(t), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) > 0 {
+		t.Errorf("unexpected warnings: %v", warns)
+	}
+
+	want := strings.Join([]string{
+		"//Subtitles",
+		"00:00:01:00 , 00:00:03:12 , Hello|^IWorld^I",
+		"$HorzAlign      = Left",
+		"$VertAlign      = Top",
+		"$ColorIndex1    = 5",
+		"00:00:04:00 , 00:00:06:00 , Top",
+		"",
+	}, "\r\n")
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("got:\n%s\nwant suffix:\n%s", buf.String(), want)
+	}
+	if !Detect(buf.Bytes()) {
+		t.Error("Spruce STL file not detected")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	f := testFile(t)
+	var buf bytes.Buffer
+	if _, err := Encode(&buf, f, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	got, warns, err := Decode(&buf, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) > 0 {
+		t.Errorf("unexpected warnings: %v", warns)
+	}
+	if len(got.TTI) != len(f.TTI) {
+		t.Fatalf("got %d subtitles, want %d", len(got.TTI), len(f.TTI))
+	}
+	for i, want := range f.TTI {
+		tti := got.TTI[i]
+		if tti.TCI != want.TCI || tti.TCO != want.TCO {
+			t.Errorf("subtitle %d: times %s-%s, want %s-%s", i, tti.TCI, tti.TCO, want.TCI, want.TCO)
+		}
+		if tti.VP != want.VP || tti.JC != want.JC {
+			t.Errorf("subtitle %d: VP %d JC %s, want VP %d JC %s", i, tti.VP, tti.JC, want.VP, want.JC)
+		}
+		if tti.TF != want.TF {
+			t.Errorf("subtitle %d: TF %q, want %q", i, tti.TF, want.TF)
+		}
+	}
+}
+
+func TestDecodeCharacterCodeTable(t *testing.T) {
+	data := "00:00:01:00 , 00:00:02:00 , Привет мир\n00:00:03:00 , 00:00:04:00 , Ёлки ♥\n"
+	f, warns, err := Decode(strings.NewReader(data), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.GSI.CCT != stl.CharacterCodeTableLatinCyrillic || f.GSI.LC != stl.LanguageCodeRussian {
+		t.Errorf("CCT %s LC %s, want Latin/Cyrillic and Russian", f.GSI.CCT, f.GSI.LC)
+	}
+	if len(f.TTI) != 2 {
+		t.Fatalf("got %d subtitles, want 2", len(f.TTI))
+	}
+	for i, want := range []string{"\x0b\x0bПривет мир", "\x0b\x0bЁлки ?"} {
+		if text, err := f.TTI[i].Text(f.GSI.CCT); err != nil || text != want {
+			t.Errorf("subtitle %d: text %q (%v), want %q", i, text, err, want)
+		}
+	}
+	if len(warns) != 1 || !errors.Is(warns[0], stl.ErrUnmappableRune) || !strings.HasPrefix(warns[0].Error(), "line 2: ") {
+		t.Errorf("warnings %v, want an unmappable rune on line 2", warns)
+	}
+}
+
+func TestConvertCharacterCodeTable(t *testing.T) {
+	data := "00:00:01:00 , 00:00:02:00 , Привет мир\n"
+	var buf bytes.Buffer
+	if _, err := doc.Convert(&buf, "spruce", strings.NewReader(data), "spruce"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "00:00:01:00 , 00:00:02:00 , Привет мир") {
+		t.Errorf("expected the Cyrillic text to be kept in\n%s", buf.String())
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, tc := range []struct {
+		data string
+		want bool
+	}{
+		{"850STL25.01" + strings.Repeat(" ", 32), false},
+		{"\xef\xbb\xbf//Font select and font size\r\n$FontName = Arial\r\n", true},
+		{"\r\n00:00:01:00 , 00:00:02:00 , Text\r\n", true},
+		{"1\n00:00:01,000 --> 00:00:02,000\nText\n", false},
+	} {
+		if got := Detect([]byte(tc.data)); got != tc.want {
+			t.Errorf("Detect(%q) = %t, want %t", tc.data, got, tc.want)
+		}
+	}
+}
+
+func TestEncodeTextOrder(t *testing.T) {
+	f := testFile(t)
+	f.GSI.CCT = stl.CharacterCodeTableLatinArabic
+	f.TextOrder = stl.TextOrderVisual
+	f.TTI = f.TTI[:1]
+	if err := f.TTI[0].SetTextOrdered("\x0b\x0bمرحبا بكم", f.GSI.CCT, f.TextOrder); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := Encode(&buf, f, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "00:00:01:00 , 00:00:03:12 , مرحبا بكم\r\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("missing %q in:\n%s", want, buf.String())
+	}
+}
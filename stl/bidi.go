@@ -0,0 +1,780 @@
+package stl
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/bidi"
+	"golang.org/x/text/unicode/norm"
+)
+
+// TextOrder is the order in which the characters of right-to-left (Arabic,
+// Hebrew) text are stored in the Text Fields (TF), which depends on the
+// authoring tool. Left-to-right text is stored the same in every order.
+type TextOrder int
+
+const (
+	TextOrderLogical      TextOrder = iota // Logical order, in which the text is read (default)
+	TextOrderVisual                        // Visual order, from left to right as displayed
+	TextOrderVisualShaped                  // Visual order, Arabic letters in their presentation forms
+)
+
+// String returns the name of the order.
+func (o TextOrder) String() string {
+	switch o {
+	case TextOrderLogical:
+		return "logical"
+	case TextOrderVisual:
+		return "visual"
+	case TextOrderVisualShaped:
+		return "visual shaped"
+	}
+	return "<invalid>"
+}
+
+// ConvertTextOrder converts UTF-8 text from an order to another. Each row
+// of the text, split on line breaks (U+000A, U+008A), is reordered with
+// the Unicode Bidirectional Algorithm, its direction given by its first
+// strong character in logical order and by its last one in visual order.
+// Visual order does not record the direction of the rows, which is then
+// guessed: a left-to-right row ending with right-to-left text, such as
+// "hello שלום עולם!", is read as a right-to-left row and does not convert
+// back to its logical order.
+// Brackets of right-to-left runs are mirrored. Arabic letters are shaped
+// to their presentation forms, or unshaped, when converting to or from
+// TextOrderVisualShaped. Control codes (U+0000..U+001F, U+0080..U+009F),
+// and the bytes 0x80..0x9F kept as is by the decoders of the character
+// code tables, keep their position in the row.
+func ConvertTextOrder(s string, from, to TextOrder) string {
+	if from == to || !hasRTL([]rune(s)) {
+		return s
+	}
+
+	var dst []byte
+	var row []rune
+	var raw []bool // control codes of row kept as single bytes
+	appendRune := func(r rune, raw bool) {
+		if raw {
+			dst = append(dst, byte(r))
+		} else {
+			dst = utf8.AppendRune(dst, r)
+		}
+	}
+	flush := func() {
+		var chars []bidiChar
+		var slots []int
+		for i, r := range row {
+			if !isControl(r) {
+				chars = append(chars, bidiChar{r: r, col: len(slots)})
+				slots = append(slots, i)
+			}
+		}
+		if len(slots) == 0 {
+			for i, r := range row {
+				appendRune(r, raw[i])
+			}
+			row, raw = row[:0], raw[:0]
+			return
+		}
+		chars = convertOrder(chars, from, to)
+		// Characters take the slots of the row in order, those in excess
+		// of the slots (unshaped ligatures) follow the last slot and the
+		// slots in excess (shaped ligatures) are removed.
+		j := 0
+		for i, r := range row {
+			if isControl(r) {
+				appendRune(r, raw[i])
+				continue
+			}
+			if j < len(chars) {
+				appendRune(chars[j].r, false)
+				j++
+			}
+			if i == slots[len(slots)-1] {
+				for ; j < len(chars); j++ {
+					appendRune(chars[j].r, false)
+				}
+			}
+		}
+		row, raw = row[:0], raw[:0]
+	}
+	for i := 0; i < len(s); {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		isRaw := r == utf8.RuneError && n == 1 && s[i] >= 0x80 && s[i] <= 0x9F
+		if isRaw {
+			r = rune(s[i])
+		}
+		i += n
+		if r == '\n' || r == rune(ControlCodeLineBreak) {
+			flush()
+			appendRune(r, isRaw)
+			continue
+		}
+		row = append(row, r)
+		raw = append(raw, isRaw)
+	}
+	flush()
+	return string(dst)
+}
+
+// Reorder converts the text of the row from an order to another, as
+// ConvertTextOrder. Characters keep their style and columns are given to
+// the characters in the order they are displayed.
+func (row TextRow) Reorder(from, to TextOrder) TextRow {
+	var chars []bidiChar
+	for _, run := range row {
+		for i, r := range []rune(run.Text) {
+			chars = append(chars, bidiChar{r: r, style: run.Style, col: run.Column + i})
+		}
+	}
+	if from == to || !hasRTL(runesOf(chars)) {
+		return row
+	}
+	chars = convertOrder(chars, from, to)
+
+	var dst TextRow
+	prev := -2
+	for _, c := range chars {
+		if n := len(dst); n > 0 && dst[n-1].Style == c.style && c.col <= prev+1 {
+			dst[n-1].Text += string(c.r)
+		} else {
+			dst = append(dst, TextRun{Text: string(c.r), Style: c.style, Column: c.col})
+		}
+		prev = c.col
+	}
+	return dst
+}
+
+// DetectTextOrder returns the order of the UTF-8 decoded texts, from the
+// position in their words of the Hebrew final letters and of the Arabic
+// letters which only start or end words. Texts in visual order with
+// Arabic presentation forms are TextOrderVisualShaped. TextOrderLogical
+// is returned for texts without such letters.
+func DetectTextOrder(texts ...string) TextOrder {
+	var logical, visual int
+	var shaped bool
+	for _, text := range texts {
+		runes := []rune(text)
+		for i := 0; i < len(runes); {
+			if !isRTLLetter(runes[i]) {
+				i++
+				continue
+			}
+			j := i
+			for j < len(runes) && isRTLLetter(runes[j]) {
+				shaped = shaped || isPresentationForm(runes[j])
+				j++
+			}
+			l, v := wordOrder(runesOf(unshapeArabic(charsOf(runes[i:j]))))
+			logical += l
+			visual += v
+			i = j
+		}
+	}
+	switch {
+	case visual <= logical:
+		return TextOrderLogical
+	case shaped:
+		return TextOrderVisualShaped
+	}
+	return TextOrderVisual
+}
+
+// DetectTextOrder returns the order of the text of the subtitles of f, as
+// DetectTextOrder. Subtitles which can not be decoded are ignored.
+func (f *File) DetectTextOrder() TextOrder {
+	if f.GSI == nil {
+		return TextOrderLogical
+	}
+	var texts []string
+	for _, tti := range f.Subtitles() {
+		rows, err := tti.Rows(f.GSI.CCT)
+		if err != nil {
+			continue
+		}
+		for _, row := range rows {
+			texts = append(texts, row.String())
+		}
+	}
+	return DetectTextOrder(texts...)
+}
+
+// TextOrdered returns the UTF-8 decoded Text Field (TF), stored in the
+// order, in logical order.
+func (tti *TTIBlock) TextOrdered(cct CharacterCodeTable, order TextOrder) (string, error) {
+	text, err := tti.Text(cct)
+	if err != nil {
+		return "", err
+	}
+	return ConvertTextOrder(text, order, TextOrderLogical), nil
+}
+
+// SetTextOrdered sets the Text Field (TF) from the UTF-8 encoded text in
+// logical order, stored in the order.
+func (tti *TTIBlock) SetTextOrdered(text string, cct CharacterCodeTable, order TextOrder) error {
+	return tti.SetText(ConvertTextOrder(text, TextOrderLogical, order), cct)
+}
+
+// RowsOrdered decodes the Text Field (TF), stored in the order, into rows
+// of styled runs of UTF-8 text in logical order, as Rows.
+func (tti *TTIBlock) RowsOrdered(cct CharacterCodeTable, order TextOrder) ([]TextRow, error) {
+	rows, err := tti.Rows(cct)
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		rows[i] = row.Reorder(order, TextOrderLogical)
+	}
+	return rows, nil
+}
+
+// bidiChar is a character of a row being reordered.
+type bidiChar struct {
+	r     rune
+	style TextStyle
+	col   int
+}
+
+// convertOrder converts the characters of a row from an order to another.
+func convertOrder(chars []bidiChar, from, to TextOrder) []bidiChar {
+	if from == to {
+		return chars
+	}
+	if from != TextOrderLogical {
+		chars = reorderChars(chars, true)
+		if from == TextOrderVisualShaped {
+			chars = unshapeArabic(chars)
+		}
+	}
+	if to != TextOrderLogical {
+		if to == TextOrderVisualShaped {
+			chars = shapeArabic(chars)
+		}
+		chars = reorderChars(chars, false)
+	}
+	return chars
+}
+
+// reorderChars reorders the characters of a row with the Unicode
+// Bidirectional Algorithm, from logical to visual order or, approximately,
+// from visual to logical order. Columns stay in place.
+func reorderChars(chars []bidiChar, visual bool) []bidiChar {
+	runes := runesOf(chars)
+	levels := bidiLevels(runes, isRTLParagraph(runes, visual))
+	dst := make([]bidiChar, len(chars))
+	for i, j := range bidiReorder(levels) {
+		dst[i] = chars[j]
+		dst[i].col = chars[i].col
+		if levels[j]%2 == 1 {
+			if m, ok := mirrors[dst[i].r]; ok {
+				dst[i].r = m
+			}
+		}
+	}
+	return dst
+}
+
+func runesOf(chars []bidiChar) []rune {
+	runes := make([]rune, len(chars))
+	for i, c := range chars {
+		runes[i] = c.r
+	}
+	return runes
+}
+
+func charsOf(runes []rune) []bidiChar {
+	chars := make([]bidiChar, len(runes))
+	for i, r := range runes {
+		chars[i] = bidiChar{r: r, col: i}
+	}
+	return chars
+}
+
+// isControl returns true for the STL control codes, which are not
+// reordered.
+func isControl(r rune) bool {
+	return r < 0x20 || r >= 0x80 && r <= 0x9F
+}
+
+// hasRTL returns true if runes hold right-to-left characters.
+func hasRTL(runes []rune) bool {
+	for _, r := range runes {
+		switch bidiClass(r) {
+		case bidi.R, bidi.AL, bidi.AN:
+			return true
+		}
+	}
+	return false
+}
+
+// isRTLParagraph returns true if the first strong character of runes, the
+// last one in visual order, is right-to-left (rules P2 and P3).
+func isRTLParagraph(runes []rune, visual bool) bool {
+	for i := range runes {
+		if visual {
+			i = len(runes) - 1 - i
+		}
+		switch bidiClass(runes[i]) {
+		case bidi.L:
+			return false
+		case bidi.R, bidi.AL:
+			return true
+		}
+	}
+	return false
+}
+
+// bidiClass returns the bidirectional class of r, explicit formatting
+// characters and boundary neutrals are other neutrals.
+func bidiClass(r rune) bidi.Class {
+	p, _ := bidi.LookupRune(r)
+	switch c := p.Class(); c {
+	case bidi.L, bidi.R, bidi.AL, bidi.EN, bidi.ES, bidi.ET, bidi.AN, bidi.CS, bidi.NSM, bidi.B, bidi.S, bidi.WS:
+		return c
+	}
+	return bidi.ON
+}
+
+// bidiLevels returns the embedding levels of runes in a paragraph of the
+// direction, resolved with the weak (W1..W7), neutral (N1, N2) and
+// implicit (I1, I2) rules of the Unicode Bidirectional Algorithm, and the
+// L1 rule. Explicit embeddings are not supported.
+func bidiLevels(runes []rune, rtl bool) []int {
+	n := len(runes)
+	level, sos := 0, bidi.L
+	if rtl {
+		level, sos = 1, bidi.R
+	}
+	orig := make([]bidi.Class, n)
+	for i, r := range runes {
+		orig[i] = bidiClass(r)
+	}
+	cls := append([]bidi.Class(nil), orig...)
+
+	// W1: non-spacing marks take the class of the previous character
+	prev := sos
+	for i, c := range cls {
+		if c == bidi.NSM {
+			cls[i] = prev
+		}
+		prev = cls[i]
+	}
+	// W2, W3: European numbers after Arabic letters are Arabic numbers,
+	// Arabic letters are right-to-left
+	strong := sos
+	for i, c := range cls {
+		switch c {
+		case bidi.L, bidi.R, bidi.AL:
+			strong = c
+		case bidi.EN:
+			if strong == bidi.AL {
+				cls[i] = bidi.AN
+			}
+		}
+	}
+	for i, c := range cls {
+		if c == bidi.AL {
+			cls[i] = bidi.R
+		}
+	}
+	// W4: single separators between numbers of the same class
+	for i := 1; i < n-1; i++ {
+		before, after := cls[i-1], cls[i+1]
+		switch {
+		case cls[i] == bidi.ES && before == bidi.EN && after == bidi.EN:
+			cls[i] = bidi.EN
+		case cls[i] == bidi.CS && (before == bidi.EN || before == bidi.AN) && after == before:
+			cls[i] = before
+		}
+	}
+	// W5: terminators adjacent to European numbers
+	for i := 0; i < n; {
+		if cls[i] != bidi.ET {
+			i++
+			continue
+		}
+		j := i
+		for j < n && cls[j] == bidi.ET {
+			j++
+		}
+		if i > 0 && cls[i-1] == bidi.EN || j < n && cls[j] == bidi.EN {
+			for k := i; k < j; k++ {
+				cls[k] = bidi.EN
+			}
+		}
+		i = j
+	}
+	// W6, W7: remaining separators and terminators are neutrals, European
+	// numbers in left-to-right text are left-to-right
+	strong = sos
+	for i, c := range cls {
+		switch c {
+		case bidi.ES, bidi.ET, bidi.CS:
+			cls[i] = bidi.ON
+		case bidi.L, bidi.R:
+			strong = c
+		case bidi.EN:
+			if strong == bidi.L {
+				cls[i] = bidi.L
+			}
+		}
+	}
+	direction := func(c bidi.Class) bidi.Class {
+		if c == bidi.L {
+			return bidi.L
+		}
+		return bidi.R
+	}
+	// N0: paired brackets take the direction of the paragraph if they
+	// enclose a character of it, the opposite direction if they only
+	// enclose characters of it and follow one
+	var stack []int
+	for i, r := range runes {
+		if cls[i] != bidi.ON {
+			continue
+		}
+		if _, ok := openingBrackets[r]; ok {
+			stack = append(stack, i)
+			continue
+		}
+		for k := len(stack) - 1; k >= 0; k-- {
+			o := stack[k]
+			if openingBrackets[runes[o]] != r {
+				continue
+			}
+			stack = stack[:k]
+			var inside, opposite bool
+			for _, c := range cls[o+1 : i] {
+				if c == bidi.L || c == bidi.R || c == bidi.EN || c == bidi.AN {
+					if direction(c) == sos {
+						inside = true
+					} else {
+						opposite = true
+					}
+				}
+			}
+			dir := bidi.ON
+			switch {
+			case inside:
+				dir = sos
+			case opposite:
+				dir = sos
+				for p := o - 1; p >= 0; p-- {
+					if c := cls[p]; c == bidi.L || c == bidi.R || c == bidi.EN || c == bidi.AN {
+						dir = direction(c)
+						break
+					}
+				}
+			}
+			if dir != bidi.ON {
+				cls[o], cls[i] = dir, dir
+			}
+			break
+		}
+	}
+	// N1, N2: neutrals between characters of the same direction take it,
+	// the direction of the paragraph otherwise
+	for i := 0; i < n; {
+		if !isNeutral(cls[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < n && isNeutral(cls[j]) {
+			j++
+		}
+		before, after := sos, sos
+		if i > 0 {
+			before = direction(cls[i-1])
+		}
+		if j < n {
+			after = direction(cls[j])
+		}
+		dir := sos
+		if before == after {
+			dir = before
+		}
+		for k := i; k < j; k++ {
+			cls[k] = dir
+		}
+		i = j
+	}
+	// I1, I2
+	levels := make([]int, n)
+	for i, c := range cls {
+		switch {
+		case level == 0 && c == bidi.R:
+			levels[i] = 1
+		case level == 0 && (c == bidi.EN || c == bidi.AN):
+			levels[i] = 2
+		case level == 1 && (c == bidi.L || c == bidi.EN || c == bidi.AN):
+			levels[i] = 2
+		default:
+			levels[i] = level
+		}
+	}
+	// L1: segment separators and trailing whitespaces are at the paragraph
+	// level
+	trailing := true
+	for i := n - 1; i >= 0; i-- {
+		switch orig[i] {
+		case bidi.S, bidi.B:
+			levels[i] = level
+			trailing = true
+		case bidi.WS:
+			if trailing {
+				levels[i] = level
+			}
+		default:
+			trailing = false
+		}
+	}
+	return levels
+}
+
+func isNeutral(c bidi.Class) bool {
+	return c == bidi.ON || c == bidi.WS || c == bidi.S || c == bidi.B
+}
+
+// bidiReorder returns the indexes of the characters of levels in visual
+// order, reversing the runs of each level from the highest to the lowest
+// odd level (rule L2).
+func bidiReorder(levels []int) []int {
+	n := len(levels)
+	order := make([]int, n)
+	lv := append([]int(nil), levels...)
+	highest, lowestOdd := 0, -1
+	for i, l := range levels {
+		order[i] = i
+		if l > highest {
+			highest = l
+		}
+		if l%2 == 1 && (lowestOdd < 0 || l < lowestOdd) {
+			lowestOdd = l
+		}
+	}
+	if lowestOdd < 0 {
+		return order
+	}
+	for level := highest; level >= lowestOdd; level-- {
+		for i := 0; i < n; {
+			if lv[i] < level {
+				i++
+				continue
+			}
+			j := i
+			for j < n && lv[j] >= level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				order[a], order[b] = order[b], order[a]
+				lv[a], lv[b] = lv[b], lv[a]
+			}
+			i = j
+		}
+	}
+	return order
+}
+
+// mirrors are the mirrored glyphs of the brackets displayed in
+// right-to-left runs (rule L4).
+var mirrors = map[rune]rune{
+	'(': ')', ')': '(',
+	'[': ']', ']': '[',
+	'{': '}', '}': '{',
+	'<': '>', '>': '<',
+	'«': '»', '»': '«',
+	'‹': '›', '›': '‹',
+}
+
+// openingBrackets are the closing brackets of the opening brackets paired
+// by rule N0.
+var openingBrackets = map[rune]rune{'(': ')', '[': ']', '{': '}'}
+
+const (
+	arabicLam     = 'ل'
+	arabicTatweel = 'ـ'
+)
+
+// arabicForm are the presentation forms of an Arabic letter, letters
+// without initial and medial forms do not join the next letter.
+type arabicForm struct {
+	isolated, final, initial, medial rune
+}
+
+var (
+	// arabicForms are the presentation forms of the Arabic letters.
+	arabicForms = map[rune]arabicForm{}
+	// lamAlefForms are the isolated and final forms of the lam-alef
+	// ligatures, by alef letter.
+	lamAlefForms = map[rune]arabicForm{}
+)
+
+// init builds the presentation forms tables from the Arabic Presentation
+// Forms-B block, in which the forms of each letter follow each other in the
+// isolated, final, initial, medial order.
+func init() {
+	var forms []rune
+	var letter rune
+	add := func() {
+		if len(forms) == 0 {
+			return
+		}
+		f := arabicForm{isolated: forms[0]}
+		if len(forms) >= 2 {
+			f.final = forms[1]
+		}
+		if len(forms) == 4 {
+			f.initial, f.medial = forms[2], forms[3]
+		}
+		arabicForms[letter] = f
+		forms = nil
+	}
+	for r := rune(0xFE80); r <= 0xFEF4; r++ {
+		base := []rune(norm.NFKC.String(string(r)))
+		if len(base) != 1 {
+			continue
+		}
+		if base[0] != letter {
+			add()
+			letter = base[0]
+		}
+		forms = append(forms, r)
+	}
+	add()
+
+	for r := rune(0xFEF5); r <= 0xFEFC; r += 2 {
+		base := []rune(norm.NFKC.String(string(r)))
+		lamAlefForms[base[1]] = arabicForm{isolated: r, final: r + 1}
+	}
+}
+
+// joinsNext returns true if r joins the following letter.
+func joinsNext(r rune) bool {
+	return r == arabicTatweel || arabicForms[r].initial != 0
+}
+
+// joinsPrevious returns true if r joins the preceding letter.
+func joinsPrevious(r rune) bool {
+	return r == arabicTatweel || arabicForms[r].final != 0
+}
+
+// shapeArabic replaces the Arabic letters of characters in logical order
+// by their presentation forms, lam followed by alef by their ligatures.
+func shapeArabic(chars []bidiChar) []bidiChar {
+	// neighbour returns the letter before or after i, skipping marks
+	neighbour := func(i, step int) rune {
+		for i += step; i >= 0 && i < len(chars); i += step {
+			if !unicode.Is(unicode.Mn, chars[i].r) {
+				return chars[i].r
+			}
+		}
+		return 0
+	}
+
+	var dst []bidiChar
+	for i := 0; i < len(chars); i++ {
+		c := chars[i]
+		f, ok := arabicForms[c.r]
+		if !ok {
+			dst = append(dst, c)
+			continue
+		}
+		joined := joinsNext(neighbour(i, -1))
+		if c.r == arabicLam && i+1 < len(chars) {
+			if lig, ok := lamAlefForms[chars[i+1].r]; ok {
+				c.r = lig.isolated
+				if joined {
+					c.r = lig.final
+				}
+				dst = append(dst, c)
+				i++
+				continue
+			}
+		}
+		joining := f.initial != 0 && joinsPrevious(neighbour(i, 1))
+		switch {
+		case f.final == 0:
+			c.r = f.isolated
+		case joined && joining:
+			c.r = f.medial
+		case joined:
+			c.r = f.final
+		case joining:
+			c.r = f.initial
+		default:
+			c.r = f.isolated
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+// unshapeArabic replaces the Arabic presentation forms of characters by
+// their letters, the lam-alef ligatures by lam followed by alef.
+func unshapeArabic(chars []bidiChar) []bidiChar {
+	var dst []bidiChar
+	for _, c := range chars {
+		if !isPresentationForm(c.r) {
+			dst = append(dst, c)
+			continue
+		}
+		for _, r := range norm.NFKC.String(string(c.r)) {
+			c.r = r
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+// isPresentationForm returns true for the Arabic presentation forms.
+func isPresentationForm(r rune) bool {
+	return r >= 0xFB50 && r <= 0xFDFF || r >= 0xFE70 && r <= 0xFEFF
+}
+
+// isRTLLetter returns true for the Hebrew and Arabic letters.
+func isRTLLetter(r rune) bool {
+	return unicode.IsLetter(r) && (unicode.Is(unicode.Hebrew, r) || unicode.Is(unicode.Arabic, r))
+}
+
+const (
+	arabicAlef        = 'ا'
+	arabicTehMarbuta  = 'ة'
+	arabicAlefMaksura = 'ى'
+)
+
+// hebrewFinalLetters are the Hebrew letters which only end words.
+var hebrewFinalLetters = map[rune]bool{
+	'ך': true, 'ם': true, 'ן': true, 'ף': true, 'ץ': true,
+}
+
+// wordOrder returns 1 as logical or visual score for a word of the
+// letters found where they are written in logical or visual order: Hebrew
+// final letters, Arabic teh marbuta and alef maksura ending words and the
+// Arabic article (alef lam) starting words.
+func wordOrder(word []rune) (logical, visual int) {
+	if len(word) < 2 {
+		return 0, 0
+	}
+	first, last := word[0], word[len(word)-1]
+	switch {
+	case hebrewFinalLetters[last] && !hebrewFinalLetters[first]:
+		return 1, 0
+	case hebrewFinalLetters[first] && !hebrewFinalLetters[last]:
+		return 0, 1
+	case last == arabicTehMarbuta || last == arabicAlefMaksura:
+		return 1, 0
+	case first == arabicTehMarbuta || first == arabicAlefMaksura:
+		return 0, 1
+	}
+	if len(word) < 3 {
+		return 0, 0
+	}
+	switch {
+	case word[0] == arabicAlef && word[1] == arabicLam:
+		return 1, 0
+	case word[len(word)-1] == arabicAlef && word[len(word)-2] == arabicLam:
+		return 0, 1
+	}
+	return 0, 0
+}
@@ -0,0 +1,95 @@
+package stl
+
+import (
+	"reflect"
+	"testing"
+)
+
+type convertTextOrderTest struct {
+	logical string
+	to      TextOrder
+	visual  string
+}
+
+var convertTextOrderTests = []convertTextOrderTest{
+	{"Hello world", TextOrderVisual, "Hello world"},
+	{"שלום עולם", TextOrderVisual, "םלוע םולש"},
+	{"שלום (abc) 12 עולם!", TextOrderVisual, "!םלוע 12 (abc) םולש"},
+	{"מחיר: 25.50 ₪", TextOrderVisual, "₪ 25.50 :ריחמ"},
+	{"\x0d\x0b\x0bשלום\u008a\u0080עולם 2024.", TextOrderVisual, "\x0d\x0b\x0bםולש\u008a\u0080.2024 םלוע"},
+	{"שלום\x8a\x80עולם", TextOrderVisual, "םולש\x8a\x80םלוע"},
+	{"مرحبا", TextOrderVisual, "ابحرم"},
+	{"مرحبا", TextOrderVisualShaped, "ﺎﺒﺣﺮﻣ"},
+	{"السلام", TextOrderVisualShaped, "ﻡﻼﺴﻟﺍ"},
+}
+
+func TestConvertTextOrder(t *testing.T) {
+	for _, test := range convertTextOrderTests {
+		if got := ConvertTextOrder(test.logical, TextOrderLogical, test.to); got != test.visual {
+			t.Errorf("ConvertTextOrder(%q, logical, %s) = %+q, want %+q", test.logical, test.to, got, test.visual)
+		}
+		if got := ConvertTextOrder(test.visual, test.to, TextOrderLogical); got != test.logical {
+			t.Errorf("ConvertTextOrder(%q, %s, logical) = %+q, want %+q", test.visual, test.to, got, test.logical)
+		}
+	}
+}
+
+func TestDetectTextOrder(t *testing.T) {
+	tests := []struct {
+		texts    []string
+		expected TextOrder
+	}{
+		{[]string{"Hello world"}, TextOrderLogical},
+		{[]string{"שלום עולם", "מה שלומך?"}, TextOrderLogical},
+		{[]string{"םלוע םולש", "?ךמולש המ"}, TextOrderVisual},
+		{[]string{"ذهبت إلى المدرسة"}, TextOrderLogical},
+		{[]string{"ةسردملا ىلإ تبهذ"}, TextOrderVisual},
+		{[]string{ConvertTextOrder("ذهبت إلى المدرسة", TextOrderLogical, TextOrderVisualShaped)}, TextOrderVisualShaped},
+	}
+	for _, test := range tests {
+		if got := DetectTextOrder(test.texts...); got != test.expected {
+			t.Errorf("DetectTextOrder(%q) = %s, want %s", test.texts, got, test.expected)
+		}
+	}
+}
+
+func TestTextRowReorder(t *testing.T) {
+	italic := DefaultTextStyle
+	italic.Italic = true
+	row := TextRow{
+		{Text: "שלום ", Style: DefaultTextStyle, Column: 2},
+		{Text: "עולם", Style: italic, Column: 7},
+	}
+	expected := TextRow{
+		{Text: "םלוע", Style: italic, Column: 2},
+		{Text: " םולש", Style: DefaultTextStyle, Column: 6},
+	}
+	visual := row.Reorder(TextOrderLogical, TextOrderVisual)
+	if !reflect.DeepEqual(visual, expected) {
+		t.Errorf("expected %+v but got %+v", expected, visual)
+	}
+	if logical := visual.Reorder(TextOrderVisual, TextOrderLogical); !reflect.DeepEqual(logical, row) {
+		t.Errorf("expected %+v but got %+v", row, logical)
+	}
+}
+
+func TestSetTextOrdered(t *testing.T) {
+	tti := NewTTIBlock()
+	if err := tti.SetTextOrdered("שלום", CharacterCodeTableLatinHebrew, TextOrderVisual); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\xed\xe5\xec\xf9"; tti.TF != want {
+		t.Errorf("expected TF %q but got %q", want, tti.TF)
+	}
+	if text, err := tti.TextOrdered(CharacterCodeTableLatinHebrew, TextOrderVisual); err != nil || text != "שלום" {
+		t.Errorf("expected text %q but got %q, %v", "שלום", text, err)
+	}
+
+	// decoders keep the control codes as single bytes
+	if err := tti.SetTextOrdered("שלום\u008aעולם", CharacterCodeTableLatinHebrew, TextOrderVisual); err != nil {
+		t.Fatal(err)
+	}
+	if text, err := tti.TextOrdered(CharacterCodeTableLatinHebrew, TextOrderVisual); err != nil || text != "שלום\x8aעולם" {
+		t.Errorf("expected text %q but got %q, %v", "שלום\x8aעולם", text, err)
+	}
+}
@@ -0,0 +1,160 @@
+package stl
+
+import (
+	"unicode"
+)
+
+// CharacterCodeTableAnalysis is the result of the analysis of UTF-8 texts
+// to encode in a STL file.
+type CharacterCodeTableAnalysis struct {
+	CCT        CharacterCodeTable // Character Code Table representing the most characters of the texts
+	LC         LanguageCode       // Language Code guessed from the characters, LanguageCodeUnknown if none
+	Unmappable []rune             // Characters the table can not represent, in order of appearance
+}
+
+// analyzedCharacterCodeTables are the tables tried by
+// AnalyzeCharacterCodeTable, in order of preference.
+var analyzedCharacterCodeTables = []CharacterCodeTable{
+	CharacterCodeTableLatin,
+	CharacterCodeTableLatinCyrillic,
+	CharacterCodeTableLatinArabic,
+	CharacterCodeTableLatinGreek,
+	CharacterCodeTableLatinHebrew,
+}
+
+// AnalyzeCharacterCodeTable returns the Character Code Table (CCT) which
+// represents every character of the UTF-8 texts, or the fewest unmappable
+// characters if none does, preferring Latin on ties. The characters the
+// table can not represent are returned, with a Language Code (LC) guessed
+// from the script and the letters of the texts.
+func AnalyzeCharacterCodeTable(texts ...string) CharacterCodeTableAnalysis {
+	a := CharacterCodeTableAnalysis{CCT: CharacterCodeTableLatin, LC: guessLanguageCode(texts)}
+	best := -1
+	for _, cct := range analyzedCharacterCodeTables {
+		enc, ok := CharacterCodeTableEncoders[cct].(FallbackEncoder)
+		if !ok {
+			continue
+		}
+		var count int
+		var unmappable []rune
+		seen := map[rune]bool{}
+		for _, text := range texts {
+			_, unmappables, _ := enc.EncodeFallback([]byte(text), FallbackReplace)
+			count += len(unmappables)
+			for _, u := range unmappables {
+				if !seen[u.Rune] {
+					seen[u.Rune] = true
+					unmappable = append(unmappable, u.Rune)
+				}
+			}
+		}
+		if best < 0 || count < best {
+			best = count
+			a.CCT, a.Unmappable = cct, unmappable
+		}
+		if count == 0 {
+			break
+		}
+	}
+	return a
+}
+
+// SetCharacterCodeTable sets the Character Code Table (CCT) from the
+// analysis of the UTF-8 texts, and the Language Code (LC) if one is
+// guessed. The analysis is returned to report the unmappable characters.
+func (gsi *GSIBlock) SetCharacterCodeTable(texts ...string) CharacterCodeTableAnalysis {
+	a := AnalyzeCharacterCodeTable(texts...)
+	gsi.CCT = a.CCT
+	if a.LC != LanguageCodeUnknown {
+		gsi.LC = a.LC
+	}
+	return a
+}
+
+// languageLetters are letters specific to languages, used to guess the
+// language of texts. Languages sharing letters are in order of preference.
+var languageLetters = []struct {
+	lc      LanguageCode
+	letters string
+}{
+	{LanguageCodeHungarian, "őű"},
+	{LanguageCodePolish, "łąęśźżń"},
+	{LanguageCodeCzech, "řůěť"},
+	{LanguageCodeRomanian, "șțşţă"},
+	{LanguageCodeTurkish, "ğış"},
+	{LanguageCodeIcelandic, "þð"},
+	{LanguageCodePortugese, "ãõ"},
+	{LanguageCodeSpanish, "ñ¿¡"},
+	{LanguageCodeGerman, "äöüß"},
+	{LanguageCodeSwedish, "åäö"},
+	{LanguageCodeDanish, "æøå"},
+	{LanguageCodeFrench, "èêàçœùâîôûëï"},
+	{LanguageCodeItalian, "ìò"},
+	{LanguageCodeUkrainian, "іїєґ"},
+	{LanguageCodeSerbian, "ђјљњћџ"},
+	{LanguageCodeRussian, "ыэё"},
+	{LanguageCodeBulgarian, "ъ"},
+}
+
+// languageScripts are the languages of scripts, used when no specific
+// letter is found.
+var languageScripts = []struct {
+	lc     LanguageCode
+	script *unicode.RangeTable
+}{
+	{LanguageCodeRussian, unicode.Cyrillic},
+	{LanguageCodeArabic, unicode.Arabic},
+	{LanguageCodeGreek, unicode.Greek},
+	{LanguageCodeHebrew, unicode.Hebrew},
+}
+
+// guessLanguageCode guesses the language of texts from their letters.
+// LanguageCodeUnknown is returned for texts without specific letters.
+func guessLanguageCode(texts []string) LanguageCode {
+	letterScores := make([]int, len(languageLetters))
+	scriptScores := make([]int, len(languageScripts))
+	for _, text := range texts {
+		for _, r := range text {
+			r = unicode.ToLower(r)
+			for i, l := range languageLetters {
+				for _, x := range l.letters {
+					if r == x {
+						letterScores[i]++
+					}
+				}
+			}
+			for i, s := range languageScripts {
+				if unicode.Is(s.script, r) {
+					scriptScores[i]++
+				}
+			}
+		}
+	}
+
+	// The script, if any, restricts the languages to guess
+	script, scriptScore := LanguageCodeUnknown, 0
+	for i, s := range languageScripts {
+		if scriptScores[i] > scriptScore {
+			script, scriptScore = s.lc, scriptScores[i]
+		}
+	}
+	if script != LanguageCodeUnknown && script != LanguageCodeRussian {
+		return script
+	}
+
+	lc, score := script, 0
+	for i, l := range languageLetters {
+		if letterScores[i] > score && isCyrillic(l.letters) == (script == LanguageCodeRussian) {
+			lc, score = l.lc, letterScores[i]
+		}
+	}
+	return lc
+}
+
+// isCyrillic returns true if the letters are Cyrillic.
+func isCyrillic(letters string) bool {
+	for _, r := range letters {
+		return unicode.Is(unicode.Cyrillic, r)
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package stl
+
+import (
+	"reflect"
+	"testing"
+)
+
+type analyzeCharacterCodeTableTest struct {
+	texts      []string
+	cct        CharacterCodeTable
+	lc         LanguageCode
+	unmappable []rune
+}
+
+var analyzeCharacterCodeTableTests = []analyzeCharacterCodeTableTest{
+	{[]string{"Hello", "world"}, CharacterCodeTableLatin, LanguageCodeUnknown, nil},
+	{[]string{"Où est le café ?", "Là-bas, à côté."}, CharacterCodeTableLatin, LanguageCodeFrench, nil},
+	{[]string{"Árvíztűrő tükörfúrógép"}, CharacterCodeTableLatin, LanguageCodeHungarian, nil},
+	{[]string{"Straße — Übung"}, CharacterCodeTableLatin, LanguageCodeGerman, []rune{'—'}},
+	{[]string{"Добрый вечер", "Это я."}, CharacterCodeTableLatinCyrillic, LanguageCodeRussian, nil},
+	{[]string{"Добрий вечір, їжак"}, CharacterCodeTableLatinCyrillic, LanguageCodeUkrainian, nil},
+	{[]string{"Καλησπέρα"}, CharacterCodeTableLatinGreek, LanguageCodeGreek, nil},
+	{[]string{"مرحبا", "بك"}, CharacterCodeTableLatinArabic, LanguageCodeArabic, nil},
+	{[]string{"שלום עולם"}, CharacterCodeTableLatinHebrew, LanguageCodeHebrew, nil},
+	{[]string{"日本", "ok"}, CharacterCodeTableLatin, LanguageCodeUnknown, []rune{'日', '本'}},
+}
+
+func TestAnalyzeCharacterCodeTable(t *testing.T) {
+	for _, test := range analyzeCharacterCodeTableTests {
+		a := AnalyzeCharacterCodeTable(test.texts...)
+		if a.CCT != test.cct {
+			t.Errorf("AnalyzeCharacterCodeTable(%q) CCT = %s, want %s", test.texts, a.CCT, test.cct)
+		}
+		if a.LC != test.lc {
+			t.Errorf("AnalyzeCharacterCodeTable(%q) LC = %s, want %s", test.texts, a.LC, test.lc)
+		}
+		if !reflect.DeepEqual(a.Unmappable, test.unmappable) {
+			t.Errorf("AnalyzeCharacterCodeTable(%q) unmappable = %q, want %q", test.texts, a.Unmappable, test.unmappable)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package stl
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 func PrintGSI(gsi *GSIBlock) {
 	fmt.Printf("--- GSI ---\n")
@@ -33,7 +36,17 @@ func PrintGSI(gsi *GSIBlock) {
 	fmt.Println("PUB (Publisher):", gsi.PUB)
 	fmt.Println("EN (Editor's Name):", gsi.EN)
 	fmt.Println("ECD (Editor's Contact Details):", gsi.ECD)
-	fmt.Println("UDA (User-Defined Area):", gsi.UDA)
+	if p, data, err := gsi.DecodeUDA(); err == nil {
+		fmt.Printf("UDA (User-Defined Area): %s profile\n", p.Name())
+		for _, f := range data.Fields() {
+			fmt.Printf("  %s: %s\n", f.Name, f.Value)
+		}
+	} else {
+		fmt.Printf("UDA (User-Defined Area): %q\n", gsi.UDA)
+		if !errors.Is(err, ErrNoUDAProfile) {
+			fmt.Println(" ", err)
+		}
+	}
 	fmt.Println("Framerate (additional):", gsi.Framerate())
 }
 
@@ -0,0 +1,347 @@
+package stl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DiffKind is a kind of change of a subtitle between two files.
+type DiffKind string
+
+const (
+	DiffAdded    DiffKind = "added"    // Subtitle only in the new file
+	DiffRemoved  DiffKind = "removed"  // Subtitle only in the old file
+	DiffRetimed  DiffKind = "retimed"  // Time Code In (TCI) or Out (TCO) changed
+	DiffRetexted DiffKind = "retexted" // Text changed
+	DiffRestyled DiffKind = "restyled" // Vertical position, justification, comment flag or text style changed
+)
+
+// FileDiff is the semantic difference between two files.
+type FileDiff struct {
+	GSI       []GSIChange      `json:"gsi"`       // Changed GSI fields, in order of the block
+	Subtitles []SubtitleChange `json:"subtitles"` // Changed subtitles, in order of time
+}
+
+// GSIChange is a GSI field changed between two files.
+type GSIChange struct {
+	Field GSIField `json:"field"`
+	Old   string   `json:"old"`
+	New   string   `json:"new"`
+}
+
+// SubtitleChange is a subtitle added, removed or changed between two files.
+type SubtitleChange struct {
+	Kinds []DiffKind    `json:"kinds"`
+	Old   *DiffSubtitle `json:"old,omitempty"` // Subtitle of the old file, nil if added
+	New   *DiffSubtitle `json:"new,omitempty"` // Subtitle of the new file, nil if removed
+}
+
+// DiffSubtitle is a subtitle of a compared file.
+type DiffSubtitle struct {
+	Index int               `json:"index"` // Index of the subtitle, as returned by File.Subtitles
+	SGN   int               `json:"sgn"`
+	SN    int               `json:"sn"`
+	TCI   Timecode          `json:"tci"`
+	TCO   Timecode          `json:"tco"`
+	VP    int               `json:"vp"`
+	JC    JustificationCode `json:"jc"`
+	CF    CommentFlag       `json:"cf"`
+	Text  string            `json:"text"` // Text in logical order without styling, rows separated by line feeds
+
+	tci, tco time.Duration // timing, comparable across framerates
+	style    string        // styled runs of the text
+	styles   string        // styles of the runs of the text, repeated styles merged
+}
+
+// restyled reports whether the style of the text changed from s to n. The
+// styled runs are compared if the text is the same, the sequence of their
+// styles otherwise.
+func (s *DiffSubtitle) restyled(n *DiffSubtitle) bool {
+	if s.Text == n.Text {
+		return s.style != n.style
+	}
+	return s.styles != n.styles
+}
+
+// Diff compares the files oldFile and newFile semantically: subtitles are matched
+// by timing and text rather than by block, so renumbered, inserted or
+// removed subtitles only report the subtitles actually added, removed or
+// changed. Subtitles of identical text are matched first, the closest in
+// time, then those close in time and of similar text.
+// Text is compared decoded, with the Character Code Table (CCT) and text
+// order of each file, and timing in time, with the framerate of each file.
+// User data blocks are not compared.
+func Diff(oldFile, newFile *File) (*FileDiff, error) {
+	if oldFile.GSI == nil || newFile.GSI == nil {
+		return nil, ErrNilGSI
+	}
+	d := &FileDiff{GSI: diffGSI(oldFile.GSI, newFile.GSI), Subtitles: []SubtitleChange{}}
+
+	olds, err := diffSubtitles(oldFile)
+	if err != nil {
+		return nil, fmt.Errorf("old file: %w", err)
+	}
+	news, err := diffSubtitles(newFile)
+	if err != nil {
+		return nil, fmt.Errorf("new file: %w", err)
+	}
+
+	type pair struct {
+		o, n  int
+		score float64
+	}
+	matchedOld := make([]int, len(olds))
+	matchedNew := make([]bool, len(news))
+	for i := range matchedOld {
+		matchedOld[i] = -1
+	}
+	match := func(pairs []pair) {
+		sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].score > pairs[j].score })
+		for _, p := range pairs {
+			if matchedOld[p.o] < 0 && !matchedNew[p.n] {
+				matchedOld[p.o] = p.n
+				matchedNew[p.n] = true
+			}
+		}
+	}
+
+	// identical text, closest in time first
+	byText := map[string][]int{}
+	for i, n := range news {
+		byText[n.Text] = append(byText[n.Text], i)
+	}
+	var pairs []pair
+	for i, o := range olds {
+		for _, j := range byText[o.Text] {
+			pairs = append(pairs, pair{i, j, -absDuration(news[j].tci - o.tci).Seconds()})
+		}
+	}
+	match(pairs)
+
+	// close in time, most similar text first
+	pairs = nil
+	for i, o := range olds {
+		if matchedOld[i] >= 0 {
+			continue
+		}
+		for j, n := range news {
+			if matchedNew[j] || n.tci >= o.tco+diffMaxShift || o.tci >= n.tco+diffMaxShift {
+				continue
+			}
+			sameTiming := n.tci == o.tci && n.tco == o.tco
+			similarity := textSimilarity(o.Text, n.Text)
+			if similarity < diffMinSimilarity && !sameTiming {
+				continue
+			}
+			if sameTiming {
+				similarity++
+			}
+			pairs = append(pairs, pair{i, j, similarity})
+		}
+	}
+	match(pairs)
+
+	for i, j := range matchedOld {
+		o := &olds[i]
+		if j < 0 {
+			d.Subtitles = append(d.Subtitles, SubtitleChange{Kinds: []DiffKind{DiffRemoved}, Old: o})
+			continue
+		}
+		n := &news[j]
+		var kinds []DiffKind
+		if o.tci != n.tci || o.tco != n.tco {
+			kinds = append(kinds, DiffRetimed)
+		}
+		if o.Text != n.Text {
+			kinds = append(kinds, DiffRetexted)
+		}
+		if o.VP != n.VP || o.JC != n.JC || o.CF != n.CF || o.restyled(n) {
+			kinds = append(kinds, DiffRestyled)
+		}
+		if len(kinds) > 0 {
+			d.Subtitles = append(d.Subtitles, SubtitleChange{Kinds: kinds, Old: o, New: n})
+		}
+	}
+	for j := range news {
+		if !matchedNew[j] {
+			d.Subtitles = append(d.Subtitles, SubtitleChange{Kinds: []DiffKind{DiffAdded}, New: &news[j]})
+		}
+	}
+
+	sort.SliceStable(d.Subtitles, func(i, j int) bool {
+		return d.Subtitles[i].at() < d.Subtitles[j].at()
+	})
+	return d, nil
+}
+
+const (
+	diffMaxShift      = 2 * time.Second // Maximum shift of the timing of matched subtitles of different text
+	diffMinSimilarity = 0.5             // Minimum similarity of the text of matched subtitles of different timing
+)
+
+// at returns the time of the change, the time of the new subtitle if any.
+func (c SubtitleChange) at() time.Duration {
+	if c.New != nil {
+		return c.New.tci
+	}
+	return c.Old.tci
+}
+
+// diffSubtitles returns the subtitles of the file to compare.
+func diffSubtitles(f *File) ([]DiffSubtitle, error) {
+	framerate := f.GSI.Framerate()
+	if framerate == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDFC, f.GSI.DFC)
+	}
+	var subs []DiffSubtitle
+	for i, sub := range f.Subtitles() {
+		rows, err := sub.RowsOrdered(f.GSI.CCT, f.TextOrder)
+		if err != nil {
+			return nil, fmt.Errorf("subtitle %d: %w", i, err)
+		}
+		var text []string
+		var style, styles strings.Builder
+		var last string
+		for _, row := range rows {
+			if s := strings.TrimSpace(row.String()); s != "" {
+				text = append(text, s)
+			}
+			for _, run := range row {
+				if s := strings.TrimSpace(run.Text); s != "" {
+					fmt.Fprintf(&style, "%v%q", run.Style, s)
+					if runStyle := fmt.Sprint(run.Style); runStyle != last {
+						styles.WriteString(runStyle)
+						last = runStyle
+					}
+				}
+			}
+		}
+		subs = append(subs, DiffSubtitle{
+			Index:  i,
+			SGN:    sub.SGN,
+			SN:     sub.SN,
+			TCI:    sub.TCI,
+			TCO:    sub.TCO,
+			VP:     sub.VP,
+			JC:     sub.JC,
+			CF:     sub.CF,
+			Text:   strings.Join(text, "\n"),
+			tci:    sub.TCI.ToDuration(framerate),
+			tco:    sub.TCO.ToDuration(framerate),
+			style:  style.String(),
+			styles: styles.String(),
+		})
+	}
+	return subs, nil
+}
+
+// diffGSI returns the changed fields of two GSI blocks.
+func diffGSI(oldGSI, newGSI *GSIBlock) []GSIChange {
+	changes := []GSIChange{}
+	olds, news := gsiFieldValues(oldGSI), gsiFieldValues(newGSI)
+	for i, o := range olds {
+		if n := news[i]; o.value != n.value {
+			changes = append(changes, GSIChange{Field: o.field, Old: o.value, New: n.value})
+		}
+	}
+	return changes
+}
+
+// gsiFieldValues returns the printable values of the fields of the GSI
+// block, in order of the block.
+func gsiFieldValues(gsi *GSIBlock) []gsiFieldValue {
+	date := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("2006-01-02")
+	}
+	return []gsiFieldValue{
+		{GSIFieldCPN, gsi.CPN.String()},
+		{GSIFieldDFC, gsi.DFC.String()},
+		{GSIFieldDSC, gsi.DSC.String()},
+		{GSIFieldCCT, gsi.CCT.String()},
+		{GSIFieldLC, gsi.LC.String()},
+		{GSIFieldOPT, gsi.OPT},
+		{GSIFieldOET, gsi.OET},
+		{GSIFieldTPT, gsi.TPT},
+		{GSIFieldTET, gsi.TET},
+		{GSIFieldTN, gsi.TN},
+		{GSIFieldTCD, gsi.TCD},
+		{GSIFieldSLR, gsi.SLR},
+		{GSIFieldCD, date(gsi.CD)},
+		{GSIFieldRD, date(gsi.RD)},
+		{GSIFieldRN, fmt.Sprint(gsi.RN)},
+		{GSIFieldTNB, fmt.Sprint(gsi.TNB)},
+		{GSIFieldTNS, fmt.Sprint(gsi.TNS)},
+		{GSIFieldTNG, fmt.Sprint(gsi.TNG)},
+		{GSIFieldMNC, fmt.Sprint(gsi.MNC)},
+		{GSIFieldMNR, fmt.Sprint(gsi.MNR)},
+		{GSIFieldTCS, gsi.TCS.String()},
+		{GSIFieldTCP, gsi.TCP.String()},
+		{GSIFieldTCF, gsi.TCF.String()},
+		{GSIFieldTND, fmt.Sprint(gsi.TND)},
+		{GSIFieldDSN, fmt.Sprint(gsi.DSN)},
+		{GSIFieldCO, gsi.CO},
+		{GSIFieldPUB, gsi.PUB},
+		{GSIFieldEN, gsi.EN},
+		{GSIFieldECD, gsi.ECD},
+		{GSIFieldUDA, fmt.Sprintf("%q", gsi.UDA)},
+	}
+}
+
+// gsiFieldValue is the printable value of a field of a GSI block.
+type gsiFieldValue struct {
+	field GSIField
+	value string
+}
+
+// textSimilarity returns the similarity of two texts, from 0 for different
+// texts to 1 for identical texts, from their Levenshtein distance in runes.
+func textSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+// minInt returns the smallest of the integers.
+func minInt(x int, ys ...int) int {
+	for _, y := range ys {
+		if y < x {
+			x = y
+		}
+	}
+	return x
+}
+
+// absDuration returns the absolute value of d.
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
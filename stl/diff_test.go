@@ -0,0 +1,134 @@
+package stl
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestDiffFile(t *testing.T, texts ...string) *File {
+	t.Helper()
+	f := newTestDiskFile(t, len(texts), -1)
+	for i, text := range texts {
+		f.TTI[i].TF = text
+	}
+	return f
+}
+
+func TestDiff(t *testing.T) {
+	oldFile := newTestDiffFile(t, "One", "Two", "Three", "Four", "Five", "Six")
+	newFile := newTestDiffFile(t, "One", "Three", "Four!", "Five", "Six", "Seven")
+	newFile.GSI.OPT = "Programme"
+	for i, tti := range newFile.TTI[1:5] { // Two removed, Seven added
+		tti.TCI, tti.TCO = oldFile.TTI[i+2].TCI, oldFile.TTI[i+2].TCO
+	}
+	newFile.TTI[1].TCO.Frames += 2                // Three retimed
+	newFile.TTI[3].VP = 2                         // Five restyled
+	newFile.TTI[4].TF = "\x01Six"                 // Six restyled
+	newFile.TTI[0].SN, newFile.TTI[1].SN = 10, 11 // renumbering is not a change
+
+	d, err := Diff(oldFile, newFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.GSI) != 1 || d.GSI[0] != (GSIChange{GSIFieldOPT, "", "Programme"}) {
+		t.Errorf("expected the OPT change but got %v", d.GSI)
+	}
+
+	expected := []struct {
+		kinds    []DiffKind
+		old, new string
+	}{
+		{[]DiffKind{DiffRemoved}, "Two", ""},
+		{[]DiffKind{DiffRetimed}, "Three", "Three"},
+		{[]DiffKind{DiffRetexted}, "Four", "Four!"},
+		{[]DiffKind{DiffRestyled}, "Five", "Five"},
+		{[]DiffKind{DiffRestyled}, "Six", "Six"},
+		{[]DiffKind{DiffAdded}, "", "Seven"},
+	}
+	if len(d.Subtitles) != len(expected) {
+		t.Fatalf("expected %d changes but got %d: %+v", len(expected), len(d.Subtitles), d.Subtitles)
+	}
+	for i, e := range expected {
+		c := d.Subtitles[i]
+		if !reflect.DeepEqual(c.Kinds, e.kinds) {
+			t.Errorf("change %d: expected %v but got %v", i, e.kinds, c.Kinds)
+		}
+		if (c.Old == nil) != (e.old == "") || (c.Old != nil && c.Old.Text != e.old) {
+			t.Errorf("change %d: expected old subtitle %q but got %+v", i, e.old, c.Old)
+		}
+		if (c.New == nil) != (e.new == "") || (c.New != nil && c.New.Text != e.new) {
+			t.Errorf("change %d: expected new subtitle %q but got %+v", i, e.new, c.New)
+		}
+	}
+
+	b, err := json.Marshal(d.Subtitles[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`"kinds":["retimed"]`, `"tci":"00:00:03:00"`, `"tco":"00:00:03:22"`, `"text":"Three"`} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("expected %s in %s", s, b)
+		}
+	}
+}
+
+func TestDiffIdentical(t *testing.T) {
+	f := newTestDiffFile(t, "One", "Two")
+	d, err := Diff(f, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.GSI) != 0 || len(d.Subtitles) != 0 {
+		t.Errorf("expected no differences but got %+v", d)
+	}
+}
+
+func TestDiffRestyledRetexted(t *testing.T) {
+	oldFile := newTestDiffFile(t, "Four", "\x80Five")
+	newFile := newTestDiffFile(t, "\x01Four!", "\x80Five!")
+	d, err := Diff(oldFile, newFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]DiffKind{
+		{DiffRetexted, DiffRestyled},
+		{DiffRetexted},
+	}
+	if len(d.Subtitles) != len(expected) {
+		t.Fatalf("expected %d changes but got %d: %+v", len(expected), len(d.Subtitles), d.Subtitles)
+	}
+	for i, e := range expected {
+		if c := d.Subtitles[i]; !reflect.DeepEqual(c.Kinds, e) {
+			t.Errorf("change %d: expected %v but got %v", i, e, c.Kinds)
+		}
+	}
+}
+
+func TestDiffNilGSI(t *testing.T) {
+	f := newTestDiffFile(t, "One")
+	if _, err := Diff(f, NewFile()); !errors.Is(err, ErrNilGSI) {
+		t.Errorf("expected %q error but got %v", ErrNilGSI, err)
+	}
+}
+
+func TestTextSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected float64
+	}{
+		{"", "", 1},
+		{"abcd", "abcd", 1},
+		{"abcd", "abce", 0.75},
+		{"abcd", "", 0},
+		{"été", "ete", 1.0 / 3},
+	}
+	for _, test := range tests {
+		if s := textSimilarity(test.a, test.b); math.Abs(s-test.expected) > 1e-9 {
+			t.Errorf("%q, %q: expected %v but got %v", test.a, test.b, test.expected, s)
+		}
+	}
+}
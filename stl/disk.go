@@ -0,0 +1,180 @@
+package stl
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// MaxDisks is the maximum number of disks of a set, the Total Number of
+// Disks (TND) being a single digit.
+const MaxDisks = 9
+
+var (
+	ErrNoDisks              = errors.New("no disks")
+	ErrDiskTNDMismatch      = errors.New("TND mismatch")
+	ErrDiskDSNNotContiguous = errors.New("DSN not contiguous")
+	ErrDiskSLRMismatch      = errors.New("SLR mismatch")
+	ErrDiskFormatMismatch   = errors.New("disk format mismatch")
+	ErrDiskTimingOverlap    = errors.New("disk timing overlap")
+	ErrInvalidDiskSize      = errors.New("invalid disk size")
+	ErrSubtitleExceedsDisk  = errors.New("subtitle exceeds disk size")
+	ErrTooManyDisks         = errors.New("too many disks")
+)
+
+// JoinDisks joins the files of a multi-disk set into a single file.
+// The files may be given in any order, they are sorted by Disk Sequence
+// Number (DSN). The set must be complete: all files share the Total Number
+// of Disks (TND), the Subtitle List Reference code (SLR), the Disk Format
+// Code (DFC), the Display Standard Code (DSC) and the Character Code Table
+// (CCT), and the subtitles of a disk do not start before the end of those of
+// the previous disk.
+// The GSI block of the first disk is used for the joined file, with a single
+// disk, the maxima of MNC and MNR, and updated counters. Subtitle numbers are
+// kept as they continue from disk to disk.
+// The given files are left unchanged.
+func JoinDisks(files ...*File) (*File, error) {
+	if len(files) == 0 {
+		return nil, ErrNoDisks
+	}
+
+	disks := append([]*File(nil), files...)
+	for i, d := range disks {
+		if d.GSI == nil {
+			return nil, fmt.Errorf("disk %d: %w", i, ErrNilGSI)
+		}
+	}
+	sort.SliceStable(disks, func(i, j int) bool {
+		return disks[i].GSI.DSN < disks[j].GSI.DSN
+	})
+
+	first := disks[0].GSI
+	for i, d := range disks {
+		gsi := d.GSI
+		if gsi.TND != len(disks) {
+			return nil, fmt.Errorf("%w: disk %d has TND %d, %d disks given", ErrDiskTNDMismatch, gsi.DSN, gsi.TND, len(disks))
+		}
+		if gsi.DSN != i+1 {
+			return nil, fmt.Errorf("%w: disk %d expected, got disk %d", ErrDiskDSNNotContiguous, i+1, gsi.DSN)
+		}
+		if gsi.SLR != first.SLR {
+			return nil, fmt.Errorf("%w: disk %d has SLR %q, disk 1 has %q", ErrDiskSLRMismatch, gsi.DSN, gsi.SLR, first.SLR)
+		}
+		if gsi.DFC != first.DFC || gsi.DSC != first.DSC || gsi.CCT != first.CCT {
+			return nil, fmt.Errorf("%w: disk %d has DFC %s, DSC %s and CCT %s, disk 1 has %s, %s and %s", ErrDiskFormatMismatch,
+				gsi.DSN, gsi.DFC, gsi.DSC, gsi.CCT, first.DFC, first.DSC, first.CCT)
+		}
+	}
+
+	framerate := first.Framerate()
+	var last *TTIBlock
+	for _, d := range disks {
+		if len(d.TTI) == 0 {
+			continue
+		}
+		if last != nil && d.TTI[0].TCI.ToFrames(framerate) < last.TCO.ToFrames(framerate) {
+			return nil, fmt.Errorf("%w: disk %d starts at %s, before the end of the previous disk at %s", ErrDiskTimingOverlap,
+				d.GSI.DSN, d.TTI[0].TCI, last.TCO)
+		}
+		last = d.TTI[len(d.TTI)-1]
+	}
+
+	joined := &File{GSI: first.clone(), TextOrder: disks[0].TextOrder}
+	for _, d := range disks {
+		if d.GSI.MNC > joined.GSI.MNC {
+			joined.GSI.MNC = d.GSI.MNC
+		}
+		if d.GSI.MNR > joined.GSI.MNR {
+			joined.GSI.MNR = d.GSI.MNR
+		}
+		joined.TTI = append(joined.TTI, cloneTTIBlocks(d.TTI)...)
+	}
+	joined.GSI.TND = 1
+	joined.GSI.DSN = 1
+	joined.UpdateCounters()
+	return joined, nil
+}
+
+// SplitDisks splits the file into a multi-disk set of files holding at most
+// maxBlocks TTI blocks each, numbered with the Total Number of Disks (TND)
+// and the Disk Sequence Number (DSN), with counters updated for each disk.
+// The extension blocks of a subtitle and the subtitles of a cumulative set
+// are kept on the same disk. Subtitle numbers are kept, they continue from
+// disk to disk.
+// The file is left unchanged.
+func (f *File) SplitDisks(maxBlocks int) ([]*File, error) {
+	if maxBlocks < 1 {
+		return nil, fmt.Errorf("%w: %d blocks", ErrInvalidDiskSize, maxBlocks)
+	}
+
+	var bounds []int // index of the first TTI block of each disk
+	start := 0
+	for start < len(f.TTI) {
+		end := start
+		for i := start + 1; i <= len(f.TTI) && i-start <= maxBlocks; i++ {
+			if i == len(f.TTI) || isDiskBoundary(f.TTI[i-1], f.TTI[i]) {
+				end = i
+			}
+		}
+		if end == start {
+			return nil, fmt.Errorf("%w: TTI block %d, at most %d blocks per disk", ErrSubtitleExceedsDisk, start, maxBlocks)
+		}
+		bounds = append(bounds, start)
+		start = end
+	}
+	if len(bounds) == 0 {
+		bounds = []int{0}
+	}
+	if len(bounds) > MaxDisks {
+		return nil, fmt.Errorf("%w: %d disks, at most %d", ErrTooManyDisks, len(bounds), MaxDisks)
+	}
+
+	gsi := f.GSI
+	if gsi == nil {
+		gsi = NewGSIBlock()
+	}
+	disks := make([]*File, len(bounds))
+	for i, start := range bounds {
+		end := len(f.TTI)
+		if i+1 < len(bounds) {
+			end = bounds[i+1]
+		}
+		disk := &File{
+			GSI:       gsi.clone(),
+			TTI:       cloneTTIBlocks(f.TTI[start:end]),
+			TextOrder: f.TextOrder,
+		}
+		disk.GSI.TND = len(bounds)
+		disk.GSI.DSN = i + 1
+		disk.UpdateCounters()
+		disks[i] = disk
+	}
+	return disks, nil
+}
+
+// isDiskBoundary reports whether a disk can end with the TTI block prev and
+// the next one start with next: next starts a new subtitle which does not
+// continue a cumulative set.
+func isDiskBoundary(prev, next *TTIBlock) bool {
+	if prev.SGN == next.SGN && prev.SN == next.SN && prev.EBN != EBNLastBlock && prev.EBN != EBNUserDataBlock {
+		return false
+	}
+	return !continuesCumulativeSet(prev, next)
+}
+
+// clone returns a copy of the GSI block.
+func (gsi *GSIBlock) clone() *GSIBlock {
+	c := *gsi
+	c.UDA = append([]byte(nil), gsi.UDA...)
+	return &c
+}
+
+// cloneTTIBlocks returns copies of the TTI blocks.
+func cloneTTIBlocks(blocks []*TTIBlock) []*TTIBlock {
+	clones := make([]*TTIBlock, len(blocks))
+	for i, tti := range blocks {
+		c := *tti
+		clones[i] = &c
+	}
+	return clones
+}
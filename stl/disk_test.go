@@ -0,0 +1,155 @@
+package stl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// newTestDiskFile returns a file of n subtitles of a single group, one
+// second each, the subtitle long being split into 3 extension blocks.
+func newTestDiskFile(t *testing.T, n int, long int) *File {
+	t.Helper()
+	f := NewFile()
+	f.GSI = NewGSIBlock()
+	f.GSI.SetDefaults(25, DisplayStandardCodeLevel1Teletext)
+	f.GSI.SLR = "PROG-1"
+	for sn := 0; sn < n; sn++ {
+		tti := NewTTIBlock()
+		tti.SGN = 0
+		tti.SN = sn
+		tti.EBN = EBNLastBlock
+		tti.CS = CumulativeStatusNone
+		tti.TCI = TimecodeFromFrames(25*(sn+1), 25)
+		tti.TCO = TimecodeFromFrames(25*(sn+2)-5, 25)
+		tti.VP = 20
+		tti.JC = JustificationCodeCenteredText
+		tti.CF = CommentFlagSubtitleData
+		tti.TF = "Text"
+		if sn == long {
+			tti.TF = strings.Repeat("a", 3*TFSize-10)
+		}
+		f.TTI = append(f.TTI, tti.ExtensionBlocks()...)
+	}
+	f.UpdateCounters()
+	return f
+}
+
+func TestSplitDisks(t *testing.T) {
+	tests := []struct {
+		n, long   int
+		maxBlocks int
+		expected  []int // blocks per disk
+		err       error
+	}{
+		{4, -1, 10, []int{4}, nil},
+		{4, -1, 2, []int{2, 2}, nil},
+		{5, -1, 2, []int{2, 2, 1}, nil},
+		{4, 1, 3, []int{1, 3, 2}, nil},
+		{4, 1, 4, []int{4, 2}, nil},
+		{4, 1, 2, nil, ErrSubtitleExceedsDisk},
+		{10, -1, 1, nil, ErrTooManyDisks},
+		{4, -1, 0, nil, ErrInvalidDiskSize},
+	}
+	for _, test := range tests {
+		f := newTestDiskFile(t, test.n, test.long)
+		disks, err := f.SplitDisks(test.maxBlocks)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%d blocks: expected error %v but got %v", test.maxBlocks, test.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if len(disks) != len(test.expected) {
+			t.Fatalf("%d blocks: expected %d disks but got %d", test.maxBlocks, len(test.expected), len(disks))
+		}
+		for i, d := range disks {
+			if len(d.TTI) != test.expected[i] {
+				t.Errorf("%d blocks: expected %d blocks on disk %d but got %d", test.maxBlocks, test.expected[i], i+1, len(d.TTI))
+			}
+			if d.GSI.TND != len(disks) || d.GSI.DSN != i+1 {
+				t.Errorf("%d blocks: expected disk %d/%d but got %d/%d", test.maxBlocks, i+1, len(disks), d.GSI.DSN, d.GSI.TND)
+			}
+			warns, err := d.Validate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w := diskWarning(warns); w != nil {
+				t.Errorf("%d blocks: unexpected warning on disk %d: %v", test.maxBlocks, i+1, w)
+			}
+		}
+
+		joined, err := JoinDisks(disks...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(joined.TTI) != len(f.TTI) || *joined.TTI[len(f.TTI)-1] != *f.TTI[len(f.TTI)-1] {
+			t.Errorf("%d blocks: expected the joined disks to match the file", test.maxBlocks)
+		}
+		if joined.GSI.TND != 1 || joined.GSI.DSN != 1 || joined.GSI.TNB != f.GSI.TNB || joined.GSI.TNS != f.GSI.TNS {
+			t.Errorf("%d blocks: expected the GSI counters of the file but got TND %d, DSN %d, TNB %d, TNS %d",
+				test.maxBlocks, joined.GSI.TND, joined.GSI.DSN, joined.GSI.TNB, joined.GSI.TNS)
+		}
+	}
+}
+
+func TestJoinDisks(t *testing.T) {
+	split := func(t *testing.T) []*File {
+		disks, err := newTestDiskFile(t, 6, -1).SplitDisks(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return disks
+	}
+
+	tests := []struct {
+		name   string
+		change func(disks []*File) []*File
+		err    error
+	}{
+		{"unordered", func(d []*File) []*File { return []*File{d[2], d[0], d[1]} }, nil},
+		{"empty", func(d []*File) []*File { return nil }, ErrNoDisks},
+		{"missing disk", func(d []*File) []*File { return d[:2] }, ErrDiskTNDMismatch},
+		{"duplicate disk", func(d []*File) []*File { d[2].GSI.DSN = 2; return d }, ErrDiskDSNNotContiguous},
+		{"other programme", func(d []*File) []*File { d[1].GSI.SLR = "PROG-2"; return d }, ErrDiskSLRMismatch},
+		{"other framerate", func(d []*File) []*File { d[1].GSI.DFC = DiskFormatCode30_01; return d }, ErrDiskFormatMismatch},
+		{"overlap", func(d []*File) []*File { d[2].TTI[0].TCI = d[1].TTI[0].TCO; return d }, ErrDiskTimingOverlap},
+		{"nil GSI", func(d []*File) []*File { d[1].GSI = nil; return d }, ErrNilGSI},
+	}
+	for _, test := range tests {
+		joined, err := JoinDisks(test.change(split(t))...)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected error %v but got %v", test.name, test.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		warns, err := joined.Validate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w := diskWarning(warns); w != nil {
+			t.Errorf("%s: unexpected warning: %v", test.name, w)
+		}
+	}
+}
+
+// diskWarning returns the first warning about the numbering of the disks,
+// the subtitles or the counters.
+func diskWarning(warns []error) error {
+	for _, w := range warns {
+		for _, target := range []error{
+			ErrUnsupportedTND, ErrUnsupportedDSN,
+			ErrTTIBlocksCountMismatch, ErrTCFFirstTCIMismatch,
+			ErrSubtitleCountMismatch, ErrGroupCountMismatch,
+			ErrSNNotConsecutive, ErrSGNNotConsecutive, ErrNoFirstSubtitleInNewGroup,
+		} {
+			if errors.Is(w, target) {
+				return w
+			}
+		}
+	}
+	return nil
+}
@@ -6,19 +6,20 @@ import (
 )
 
 var (
-	ErrInvalidGSIIntValue      = errors.New("invalid GSI int value")
-	ErrEmptyGSIIntValue        = errors.New("empty GSI int value")
-	ErrInvalidGSIByteValue     = errors.New("invalid GSI byte value")
-	ErrEmptyGSIByteValue       = errors.New("empty GSI byte value")
-	ErrInvalidGSIHexValue      = errors.New("invalid GSI hex value")
-	ErrEmptyGSIHexValue        = errors.New("empty GSI hex value")
-	ErrInvalidGSIStringValue   = errors.New("invalid GSI string value")
-	ErrEmptyGSIStringValue     = errors.New("empty GSI string value")
-	ErrUnsupportedGSICodePage  = errors.New("unsupported code page")
-	ErrInvalidGSIDateValue     = errors.New("invalid GSI date value")
-	ErrEmptyGSIDateValue       = errors.New("empty GSI date value")
-	ErrInvalidGSITimecodeValue = errors.New("invalid GSI timecode value")
-	ErrEmptyGSITimecodeValue   = errors.New("empty GSI timecode value")
+	ErrInvalidGSIIntValue               = errors.New("invalid GSI int value")
+	ErrEmptyGSIIntValue                 = errors.New("empty GSI int value")
+	ErrInvalidGSIByteValue              = errors.New("invalid GSI byte value")
+	ErrEmptyGSIByteValue                = errors.New("empty GSI byte value")
+	ErrInvalidGSIHexValue               = errors.New("invalid GSI hex value")
+	ErrEmptyGSIHexValue                 = errors.New("empty GSI hex value")
+	ErrInvalidGSIStringValue            = errors.New("invalid GSI string value")
+	ErrEmptyGSIStringValue              = errors.New("empty GSI string value")
+	ErrUnsupportedGSICodePage           = errors.New("unsupported code page")
+	ErrUnsupportedGSICharacterCodeTable = errors.New("unsupported character code table")
+	ErrInvalidGSIDateValue              = errors.New("invalid GSI date value")
+	ErrEmptyGSIDateValue                = errors.New("empty GSI date value")
+	ErrInvalidGSITimecodeValue          = errors.New("invalid GSI timecode value")
+	ErrEmptyGSITimecodeValue            = errors.New("empty GSI timecode value")
 )
 
 // EncodingError is an error that occurred during encoding or decoding.
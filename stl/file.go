@@ -1,16 +1,24 @@
 package stl
 
 import (
+	"errors"
 	"fmt"
 	"io"
 )
 
+// ErrNilGSI is returned by the functions combining or comparing files when
+// a file has no GSI block.
+var ErrNilGSI = errors.New("nil GSI block")
+
 // File is the representation of a STL file.
 // The file comprises one General Subtitle Information (GSI) block and a
 // number of Text and Timing Information (TTI) blocks.
+// The order of right-to-left text is not stored in the file, it is
+// detected by Decode and used by the exporters reading the text.
 type File struct {
-	GSI *GSIBlock
-	TTI []*TTIBlock
+	GSI       *GSIBlock
+	TTI       []*TTIBlock
+	TextOrder TextOrder // Order of the right-to-left text of the Text Fields (TF)
 }
 
 // NewFile returns a new stl.File.
@@ -19,6 +27,7 @@ func NewFile() *File {
 }
 
 // Decode reads and decodes the STL file from r.
+// The order of right-to-left text is detected with DetectTextOrder.
 func (f *File) Decode(r io.Reader) (warns []error, err error) {
 	f.GSI = NewGSIBlock()
 
@@ -44,6 +53,7 @@ func (f *File) Decode(r io.Reader) (warns []error, err error) {
 		f.TTI = append(f.TTI, tti)
 		i++
 	}
+	f.TextOrder = f.DetectTextOrder()
 
 	return
 }
@@ -58,8 +68,36 @@ func (f *File) Encode(w io.Writer) error {
 			if ttiErr, ok := err.(*TTIError); ok {
 				ttiErr.setBlockNumber(i)
 			}
-			panic(fmt.Errorf("unexpected error type: %T", err))
+			return err
 		}
 	}
 	return nil
 }
+
+// UpdateCounters updates the GSI block fields derived from the TTI blocks:
+// Total Number of TTI blocks (TNB), Total Number of Subtitles (TNS), Total
+// Number of Subtitle Groups (TNG) and Time Code First In-Cue (TCF).
+func (f *File) UpdateCounters() {
+	if f.GSI == nil {
+		f.GSI = NewGSIBlock()
+	}
+
+	f.GSI.TNB = len(f.TTI)
+	f.GSI.TNS = 0
+	f.GSI.TNG = 0
+
+	lastSGN, lastSN := -1, -1
+	for _, tti := range f.TTI {
+		if tti.SGN != lastSGN {
+			f.GSI.TNG++
+		}
+		if tti.SGN != lastSGN || tti.SN != lastSN {
+			f.GSI.TNS++
+		}
+		lastSGN, lastSN = tti.SGN, tti.SN
+	}
+
+	if len(f.TTI) > 0 {
+		f.GSI.TCF = f.TTI[0].TCI
+	}
+}
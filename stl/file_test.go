@@ -0,0 +1,31 @@
+package stl
+
+import (
+	"errors"
+	"testing"
+)
+
+var errTestWrite = errors.New("write failed")
+
+// limitedWriter accepts n bytes and fails afterwards.
+type limitedWriter struct {
+	n int
+}
+
+func (w *limitedWriter) Write(b []byte) (int, error) {
+	if len(b) > w.n {
+		return 0, errTestWrite
+	}
+	w.n -= len(b)
+	return len(b), nil
+}
+
+func TestEncodeFileWriteError(t *testing.T) {
+	f := newTestDiskFile(t, 3, -1)
+
+	// the GSI block and the first TTI block are written, the second fails
+	err := f.Encode(&limitedWriter{n: GSIBlockSize + TTIBlockSize})
+	if !errors.Is(err, errTestWrite) {
+		t.Errorf("expected error %v but got %v", errTestWrite, err)
+	}
+}
@@ -42,13 +42,18 @@ func (f *File) Validate() ([]error, error) {
 	}
 
 	var subtitles int
-	var groups int
+	var groups int = 1
 
 	var lastSN int = -1
 	var lastSGN int = f.TTI[0].SGN
 	var lastEBN int = 0xFF
 	var lastCS CumulativeStatus = CumulativeStatusNone
 
+	// disks following the first one of a set continue the subtitle numbering
+	if f.GSI.DSN > 1 {
+		lastSN = f.TTI[0].SN - 1
+	}
+
 	for i, tti := range f.TTI {
 		// non nil TTI block
 		if tti == nil {
@@ -0,0 +1,44 @@
+package stl
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateGroupCount(t *testing.T) {
+	tests := []struct {
+		groups []int // SGN of each subtitle
+		tng    int
+		warn   bool
+	}{
+		{[]int{0, 0, 0}, 1, false},
+		{[]int{0, 0, 1, 1}, 2, false},
+		{[]int{0, 1, 2}, 3, false},
+		{[]int{0, 0, 0}, 0, true},
+		{[]int{0, 0, 1}, 1, true},
+	}
+	for _, test := range tests {
+		f := newTestDiskFile(t, len(test.groups), -1)
+		for i, sgn := range test.groups {
+			if i > 0 && sgn != test.groups[i-1] {
+				f.TTI[i].SN = 0
+			} else if i > 0 {
+				f.TTI[i].SN = f.TTI[i-1].SN + 1
+			}
+			f.TTI[i].SGN = sgn
+		}
+		f.GSI.TNG = test.tng
+
+		warns, err := f.Validate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var warned bool
+		for _, w := range warns {
+			warned = warned || errors.Is(w, ErrGroupCountMismatch)
+		}
+		if warned != test.warn {
+			t.Errorf("%v with TNG %d: expected group count mismatch %t but got %t", test.groups, test.tng, test.warn, warned)
+		}
+	}
+}
@@ -104,3 +104,31 @@ func (gsi *GSIBlock) Reset() {
 	gsi.ECD = ""
 	gsi.UDA = []byte{}
 }
+
+// SetDefaults sets the GSI block fields to default values suitable for a STL
+// file created from scratch at the given framerate (25 or 30 fps) and
+// display standard.
+// Descriptive fields (titles, names, dates...) are left empty and counters
+// are left to File.UpdateCounters.
+func (gsi *GSIBlock) SetDefaults(framerate uint, dsc DisplayStandardCode) {
+	gsi.Reset()
+	gsi.CPN = CodePageNumberMultiLingual
+	gsi.DFC = DiskFormatCode25_01
+	if framerate == 30 {
+		gsi.DFC = DiskFormatCode30_01
+	}
+	gsi.DSC = dsc
+	gsi.CCT = CharacterCodeTableLatin
+	gsi.LC = LanguageCodeUnknown
+	gsi.RN = 0
+	gsi.TNB = 0
+	gsi.TNS = 0
+	gsi.TNG = 0
+	gsi.MNC = 40
+	gsi.MNR = 23
+	gsi.TCS = TimeCodeStatusIntendedForUse
+	gsi.TCP = Timecode{}
+	gsi.TCF = Timecode{}
+	gsi.TND = 1
+	gsi.DSN = 1
+}
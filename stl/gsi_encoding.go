@@ -24,51 +24,90 @@ func (gsi *GSIBlock) Decode(r io.Reader) ([]error, error) {
 
 	var warns []error
 
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[0:3], (*int)(&gsi.CPN)), GSIFieldCPN))                 // CPN - bytes 0..2 (3 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[3:11], (*string)(&gsi.DFC), gsi.CPN), GSIFieldDFC)) // DFC - bytes 3..10 (8 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIByte(b[11:12], (*byte)(&gsi.DSC)), GSIFieldDSC))             // DSC - byte 11 (1 byte)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIByte(b[12:14], (*byte)(&gsi.CCT)), GSIFieldCCT))             // CCT - bytes 12..13 (2 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIHex(b[14:16], (*byte)(&gsi.LC)), GSIFieldLC))                // LC - bytes 14..15 (2 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[16:48], &gsi.OPT, gsi.CPN), GSIFieldOPT))           // OPT - bytes 16..47 (32 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[48:80], &gsi.OET, gsi.CPN), GSIFieldOET))           // OET - bytes 48..79 (32 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[80:112], &gsi.TPT, gsi.CPN), GSIFieldTPT))          // TPT - bytes 80..111 (32 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[112:144], &gsi.TET, gsi.CPN), GSIFieldTET))         // TET - bytes 112..143 (32 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[144:176], &gsi.TN, gsi.CPN), GSIFieldTN))           // TN - bytes 144..175 (32 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[176:208], &gsi.TCD, gsi.CPN), GSIFieldTCD))         // TCD - bytes 176..207 (32 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[208:224], &gsi.SLR, gsi.CPN), GSIFieldSLR))         // SLR - bytes 208..223 (16 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIDate(b[224:230], &gsi.CD), GSIFieldCD))                      // CD - bytes 224..229 (6 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIDate(b[230:236], &gsi.RD), GSIFieldRD))                      // RD - bytes 230..235 (6 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[236:238], &gsi.RN), GSIFieldRN))                       // RN - bytes 236..237 (2 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[238:243], &gsi.TNB), GSIFieldTNB))                     // TNB - bytes 238..242 (5 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[243:248], &gsi.TNS), GSIFieldTNS))                     // TNS - bytes 243..247 (5 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[248:251], &gsi.TNG), GSIFieldTNG))                     // TNG - bytes 248..250 (3 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[251:253], &gsi.MNC), GSIFieldMNC))                     // MNC - bytes 251..252 (2 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[253:255], &gsi.MNR), GSIFieldMNR))                     // MNR - bytes 253..254 (2 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIByte(b[255:256], (*byte)(&gsi.TCS)), GSIFieldTCS))           // TCS - bytes 255 (1 byte)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSITimecode(b[256:264], &gsi.TCP), GSIFieldTCP))                // TCP - bytes 256..263 (8 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSITimecode(b[264:272], &gsi.TCF), GSIFieldTCF))                // TCF - bytes 264..271 (8 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[272:273], &gsi.TND), GSIFieldTND))                     // TND - byte 272 (1 byte)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[273:274], &gsi.DSN), GSIFieldDSN))                     // DSN - byte 273 (1 byte)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[274:277], &gsi.CO, gsi.CPN), GSIFieldCO))           // CO - bytes 274..276 (3 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[277:309], &gsi.PUB, gsi.CPN), GSIFieldPUB))         // PUB - bytes 277..308 (32 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[309:341], &gsi.EN, gsi.CPN), GSIFieldEN))           // EN - bytes 309..340 (32 bytes)
-	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[341:373], &gsi.ECD, gsi.CPN), GSIFieldECD))         // ECD - bytes 341..372 (32 bytes)
-	copy(gsi.UDA, b[448:1024])                                                                                   // UDA - bytes 448..1023 (576 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[0:3], (*int)(&gsi.CPN)), GSIFieldCPN)) // CPN - bytes 0..2 (3 bytes)
+
+	// Strings of unsupported code pages are decoded with the Multilingual code page
+	cpn := gsi.CPN
+	if _, ok := CodePageNumberDecoders[cpn]; !ok {
+		if cpn != CodePageNumberInvalid {
+			warns = append(warns, gsiErr(decodeErr(fmt.Errorf("%w %d, strings decoded with code page %d", ErrUnsupportedGSICodePage, cpn, CodePageNumberMultiLingual), b[0:3]), GSIFieldCPN))
+		}
+		cpn = CodePageNumberMultiLingual
+	}
+
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[3:11], (*string)(&gsi.DFC), cpn), GSIFieldDFC)) // DFC - bytes 3..10 (8 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIByte(b[11:12], (*byte)(&gsi.DSC)), GSIFieldDSC))         // DSC - byte 11 (1 byte)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIByte(b[12:14], (*byte)(&gsi.CCT)), GSIFieldCCT))         // CCT - bytes 12..13 (2 bytes)
+	if _, ok := CharacterCodeTableDecoders[gsi.CCT]; !ok && gsi.CCT != CharacterCodeTableInvalid {
+		warns = append(warns, gsiErr(decodeErr(fmt.Errorf("%w %d", ErrUnsupportedGSICharacterCodeTable, gsi.CCT), b[12:14]), GSIFieldCCT))
+	}
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIHex(b[14:16], (*byte)(&gsi.LC)), GSIFieldLC))      // LC - bytes 14..15 (2 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[16:48], &gsi.OPT, cpn), GSIFieldOPT))     // OPT - bytes 16..47 (32 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[48:80], &gsi.OET, cpn), GSIFieldOET))     // OET - bytes 48..79 (32 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[80:112], &gsi.TPT, cpn), GSIFieldTPT))    // TPT - bytes 80..111 (32 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[112:144], &gsi.TET, cpn), GSIFieldTET))   // TET - bytes 112..143 (32 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[144:176], &gsi.TN, cpn), GSIFieldTN))     // TN - bytes 144..175 (32 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[176:208], &gsi.TCD, cpn), GSIFieldTCD))   // TCD - bytes 176..207 (32 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[208:224], &gsi.SLR, cpn), GSIFieldSLR))   // SLR - bytes 208..223 (16 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIDate(b[224:230], &gsi.CD), GSIFieldCD))            // CD - bytes 224..229 (6 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIDate(b[230:236], &gsi.RD), GSIFieldRD))            // RD - bytes 230..235 (6 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[236:238], &gsi.RN), GSIFieldRN))             // RN - bytes 236..237 (2 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[238:243], &gsi.TNB), GSIFieldTNB))           // TNB - bytes 238..242 (5 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[243:248], &gsi.TNS), GSIFieldTNS))           // TNS - bytes 243..247 (5 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[248:251], &gsi.TNG), GSIFieldTNG))           // TNG - bytes 248..250 (3 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[251:253], &gsi.MNC), GSIFieldMNC))           // MNC - bytes 251..252 (2 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[253:255], &gsi.MNR), GSIFieldMNR))           // MNR - bytes 253..254 (2 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIByte(b[255:256], (*byte)(&gsi.TCS)), GSIFieldTCS)) // TCS - bytes 255 (1 byte)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSITimecode(b[256:264], &gsi.TCP), GSIFieldTCP))      // TCP - bytes 256..263 (8 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSITimecode(b[264:272], &gsi.TCF), GSIFieldTCF))      // TCF - bytes 264..271 (8 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[272:273], &gsi.TND), GSIFieldTND))           // TND - byte 272 (1 byte)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIInt(b[273:274], &gsi.DSN), GSIFieldDSN))           // DSN - byte 273 (1 byte)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[274:277], &gsi.CO, cpn), GSIFieldCO))     // CO - bytes 274..276 (3 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[277:309], &gsi.PUB, cpn), GSIFieldPUB))   // PUB - bytes 277..308 (32 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[309:341], &gsi.EN, cpn), GSIFieldEN))     // EN - bytes 309..340 (32 bytes)
+	warns = appendNonNilErrs(warns, gsiErr(decodeGSIString(b[341:373], &gsi.ECD, cpn), GSIFieldECD))   // ECD - bytes 341..372 (32 bytes)
+	gsi.UDA = bytes.TrimRight(append([]byte(nil), b[448:1024]...), " ")                                // UDA - bytes 448..1023 (576 bytes), padding spaces trimmed
 
 	return warns, nil
 }
 
 // Encode encodes and writes GSI block to writer.
-// An error is returned if a fatal error occurs that prevents further encoding.
+// An error is returned if a fatal error occurs that prevents further encoding,
+// string fields with runes which can not be represented in the code page
+// included.
 func (gsi *GSIBlock) Encode(w io.Writer) error {
-	b := make([]byte, GSIBlockSize)
+	_, err := gsi.EncodeFallback(w, FallbackError)
+	return err
+}
+
+// EncodeFallback encodes and writes GSI block to writer, applying the
+// fallback to the runes of string fields which can not be represented in the
+// code page.
+// It returns a slice of warnings and an error if any.
+// A warning carrying an UnmappableError is returned for each string field
+// with such runes.
+// An error is returned if a fatal error occurs that prevents further encoding.
+func (gsi *GSIBlock) EncodeFallback(w io.Writer, fallback Fallback) ([]error, error) {
+	b := bytes.Repeat([]byte(" "), GSIBlockSize)
+
+	var warns []error
+	encodeString := func(b []byte, v string, field GSIField) error {
+		unmappables, err := encodeGSIString(b, v, gsi.CPN, fallback)
+		if err != nil {
+			return gsiErr(err, field)
+		}
+		if len(unmappables) > 0 {
+			warns = append(warns, gsiErr(encodeErr(&UnmappableError{Runes: unmappables}, []byte(v)), field))
+		}
+		return nil
+	}
 
 	// CPN - bytes 0..2 (3 bytes)
-	encodeGSIInt(b[0:2], (int)(gsi.CPN))
+	encodeGSIInt(b[0:3], (int)(gsi.CPN))
 
 	// DFC - bytes 3..10 (8 bytes)
-	if err := encodeGSIString(b[3:11], (string)(gsi.DFC), gsi.CPN); err != nil {
-		return gsiErr(err, GSIFieldDFC)
+	if err := encodeString(b[3:11], (string)(gsi.DFC), GSIFieldDFC); err != nil {
+		return warns, err
 	}
 
 	// DSC - byte 11 (1 byte)
@@ -81,45 +120,45 @@ func (gsi *GSIBlock) Encode(w io.Writer) error {
 	encodeGSIHex(b[14:16], (byte)(gsi.LC))
 
 	// OPT - bytes 16..47 (32 bytes)
-	if err := encodeGSIString(b[16:48], gsi.OPT, gsi.CPN); err != nil {
-		return gsiErr(err, GSIFieldOPT)
+	if err := encodeString(b[16:48], gsi.OPT, GSIFieldOPT); err != nil {
+		return warns, err
 	}
 
 	// OET - bytes 48..79 (32 bytes)
-	if err := encodeGSIString(b[48:80], gsi.OET, gsi.CPN); err != nil {
-		return gsiErr(err, GSIFieldOET)
+	if err := encodeString(b[48:80], gsi.OET, GSIFieldOET); err != nil {
+		return warns, err
 	}
 
 	// TPT - bytes 80..111 (32 bytes)
-	if err := encodeGSIString(b[80:112], gsi.TPT, gsi.CPN); err != nil {
-		return gsiErr(err, GSIFieldTPT)
+	if err := encodeString(b[80:112], gsi.TPT, GSIFieldTPT); err != nil {
+		return warns, err
 	}
 
 	// TET - bytes 112..143 (32 bytes)
-	if err := encodeGSIString(b[112:144], gsi.TET, gsi.CPN); err != nil {
-		return gsiErr(err, GSIFieldTET)
+	if err := encodeString(b[112:144], gsi.TET, GSIFieldTET); err != nil {
+		return warns, err
 	}
 
 	// TN - bytes 144..175 (32 bytes)
-	if err := encodeGSIString(b[144:176], gsi.TN, gsi.CPN); err != nil {
-		return gsiErr(err, GSIFieldTN)
+	if err := encodeString(b[144:176], gsi.TN, GSIFieldTN); err != nil {
+		return warns, err
 	}
 
 	// TCD - bytes 176..207 (32 bytes)
-	if err := encodeGSIString(b[176:208], gsi.TCD, gsi.CPN); err != nil {
-		return gsiErr(err, GSIFieldTCD)
+	if err := encodeString(b[176:208], gsi.TCD, GSIFieldTCD); err != nil {
+		return warns, err
 	}
 
 	// SLR - bytes 208..223 (16 bytes)
-	if err := encodeGSIString(b[208:224], gsi.SLR, gsi.CPN); err != nil {
-		return gsiErr(err, GSIFieldSLR)
+	if err := encodeString(b[208:224], gsi.SLR, GSIFieldSLR); err != nil {
+		return warns, err
 	}
 
 	// CD - bytes 224..229 (6 bytes)
 	encodeGSIDate(b[224:230], gsi.CD)
 
 	// RD - bytes 230..235 (6 bytes)
-	encodeGSIDate(b[130:236], gsi.RD)
+	encodeGSIDate(b[230:236], gsi.RD)
 
 	// RN - bytes 236..237 (2 bytes)
 	encodeGSIInt(b[236:238], gsi.RN)
@@ -155,30 +194,30 @@ func (gsi *GSIBlock) Encode(w io.Writer) error {
 	encodeGSIInt(b[273:274], gsi.DSN)
 
 	// CO - bytes 274..276 (3 bytes)
-	if err := encodeGSIString(b[274:277], gsi.CO, gsi.CPN); err != nil {
-		return gsiErr(err, GSIFieldCO)
+	if err := encodeString(b[274:277], gsi.CO, GSIFieldCO); err != nil {
+		return warns, err
 	}
 
 	// PUB - bytes 277..308 (32 bytes)
-	if err := encodeGSIString(b[277:309], gsi.PUB, gsi.CPN); err != nil {
-		return gsiErr(err, GSIFieldPUB)
+	if err := encodeString(b[277:309], gsi.PUB, GSIFieldPUB); err != nil {
+		return warns, err
 	}
 
 	// EN - bytes 309..340 (32 bytes)
-	if err := encodeGSIString(b[309:341], gsi.EN, gsi.CPN); err != nil {
-		return gsiErr(err, GSIFieldEN)
+	if err := encodeString(b[309:341], gsi.EN, GSIFieldEN); err != nil {
+		return warns, err
 	}
 
 	// ECD - bytes 341..372 (32 bytes)
-	if err := encodeGSIString(b[341:373], gsi.ECD, gsi.CPN); err != nil {
-		return gsiErr(err, GSIFieldECD)
+	if err := encodeString(b[341:373], gsi.ECD, GSIFieldECD); err != nil {
+		return warns, err
 	}
 
 	// UDA - bytes 448..1023 (576 bytes)
 	copy(b[448:1024], gsi.UDA)
 
 	_, err := w.Write(b)
-	return err
+	return warns, err
 }
 
 func decodeGSIInt(b []byte, v *int) error {
@@ -270,17 +309,27 @@ func decodeGSIString(b []byte, v *string, cpn CodePageNumber) error {
 	return nil
 }
 
-func encodeGSIString(b []byte, v string, cpn CodePageNumber) error {
-	if enc, ok := CodePageNumberEncoders[cpn]; ok {
-		e, err := enc.Encode([]byte(v))
-		if err != nil {
-			return encodeErr(ErrInvalidGSIStringValue, []byte(v))
-		}
-		copy(b, cutPad(e, len(b), ' '))
+func encodeGSIString(b []byte, v string, cpn CodePageNumber, fallback Fallback) ([]Unmappable, error) {
+	enc, ok := CodePageNumberEncoders[cpn]
+	if !ok {
+		return nil, encodeErr(ErrUnsupportedGSICodePage, []byte(v))
+	}
+	var e []byte
+	var unmappables []Unmappable
+	var err error
+	if fEnc, ok := enc.(FallbackEncoder); ok {
+		e, unmappables, err = fEnc.EncodeFallback([]byte(v), fallback)
 	} else {
-		return encodeErr(ErrUnsupportedGSICodePage, []byte(v))
+		e, err = enc.Encode([]byte(v))
 	}
-	return nil
+	if err != nil {
+		if uErr, ok := err.(*UnmappableError); ok {
+			return unmappables, encodeErr(fmt.Errorf("%w: %s", ErrInvalidGSIStringValue, uErr), []byte(v))
+		}
+		return unmappables, encodeErr(ErrInvalidGSIStringValue, []byte(v))
+	}
+	copy(b, cutPad(e, len(b), ' '))
+	return unmappables, nil
 }
 
 func decodeGSIDate(b []byte, v *time.Time) error {
@@ -312,7 +361,7 @@ func encodeGSIDate(b []byte, v time.Time) {
 		panic(fmt.Errorf("invalid GSI date length %d", len(b)))
 	}
 
-	encodeGSIInt(b[0:2], v.Year()-2000)
+	encodeGSIInt(b[0:2], v.Year()%100)
 	encodeGSIInt(b[2:4], int(v.Month()))
 	encodeGSIInt(b[4:6], v.Day())
 }
@@ -243,7 +243,7 @@ var encodeGSIStringTests = []encodeGSIStringTest{
 func TestEncodeGSIString(t *testing.T) {
 	for _, test := range encodeGSIStringTests {
 		var v []byte = make([]byte, len(test.input))
-		err := encodeGSIString(v, test.input, test.cpn)
+		_, err := encodeGSIString(v, test.input, test.cpn, FallbackError)
 		testError(t, err, test.err)
 		if !bytes.Equal(v, test.output) {
 			t.Errorf("expected %x but got %x", test.output, v)
@@ -290,6 +290,7 @@ type encodeGSIDateTest struct {
 
 var encodeGSIDateTests = []encodeGSIDateTest{
 	{time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), []byte("170101"), false},
+	{time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC), []byte("991231"), false},
 	{time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), []byte("1701"), true},
 	{time.Time{}, []byte(""), true},
 }
@@ -365,3 +366,60 @@ func TestEncodeGSITimecode(t *testing.T) {
 		}()
 	}
 }
+
+func encodeTestGSI(t *testing.T, gsi *GSIBlock) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gsi.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != GSIBlockSize {
+		t.Fatalf("expected %d bytes but got %d", GSIBlockSize, buf.Len())
+	}
+	return buf.Bytes()
+}
+
+func TestEncodeGSIBlockCPN(t *testing.T) {
+	gsi := NewGSIBlock()
+	gsi.SetDefaults(25, DisplayStandardCodeLevel1Teletext)
+	gsi.CPN = CodePageNumberMultiLingual
+
+	b := encodeTestGSI(t, gsi)
+	if string(b[0:3]) != "850" {
+		t.Errorf("expected CPN 850 but got %q", b[0:3])
+	}
+	decoded := NewGSIBlock()
+	if _, err := decoded.Decode(bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.CPN != gsi.CPN {
+		t.Errorf("expected CPN %s but got %s", gsi.CPN, decoded.CPN)
+	}
+}
+
+func TestEncodeGSIBlockDates(t *testing.T) {
+	gsi := NewGSIBlock()
+	gsi.SetDefaults(25, DisplayStandardCodeLevel1Teletext)
+	gsi.CD = time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	gsi.RD = time.Date(2018, 4, 2, 0, 0, 0, 0, time.UTC)
+	gsi.RN = 3
+
+	b := encodeTestGSI(t, gsi)
+	if string(b[224:238]) != "17030118040203" {
+		t.Errorf("expected CD, RD and RN 17030118040203 but got %q", b[224:238])
+	}
+}
+
+func TestEncodeGSIBlockSpaces(t *testing.T) {
+	gsi := NewGSIBlock()
+	gsi.SetDefaults(25, DisplayStandardCodeLevel1Teletext)
+	gsi.UDA = []byte("UDA")
+
+	b := encodeTestGSI(t, gsi)
+	// spare bytes and unused User-Defined Area
+	for _, r := range [][2]int{{373, 448}, {451, 1024}} {
+		if s := b[r[0]:r[1]]; !bytes.Equal(s, bytes.Repeat([]byte(" "), len(s))) {
+			t.Errorf("expected spaces in bytes %d..%d but got %q", r[0], r[1]-1, s)
+		}
+	}
+}
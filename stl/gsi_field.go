@@ -6,7 +6,7 @@ type GSIField Field
 
 const (
 	GSIFieldCPN GSIField = "CPN" // Code Page Number
-	GSIFieldDFC GSIField = "DSC" // Disk Format Code
+	GSIFieldDFC GSIField = "DFC" // Disk Format Code
 	GSIFieldDSC GSIField = "DSC" // Display Standard Code
 	GSIFieldCCT GSIField = "CCT" // Character Code Table number
 	GSIFieldLC  GSIField = "LC"  // Language Code
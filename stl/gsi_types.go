@@ -11,6 +11,12 @@ const (
 	CodePageNumberPortugal       CodePageNumber = 860
 	CodePageNumberCanadianFrench CodePageNumber = 863
 	CodePageNumberNordic         CodePageNumber = 865
+
+	// Non-standard code pages found in files of some authoring tools.
+	// Windows code page 1252 is written with its last three digits, the
+	// field being three bytes long.
+	CodePageNumberCyrillic      CodePageNumber = 866
+	CodePageNumberWindowsLatin1 CodePageNumber = 252
 )
 
 var cpnStringMap = map[CodePageNumber]string{
@@ -20,6 +26,8 @@ var cpnStringMap = map[CodePageNumber]string{
 	CodePageNumberPortugal:       "Portugal",
 	CodePageNumberCanadianFrench: "Canadian/French",
 	CodePageNumberNordic:         "Nordic",
+	CodePageNumberCyrillic:       "Cyrillic (non-standard)",
+	CodePageNumberWindowsLatin1:  "Windows Latin 1 (non-standard)",
 }
 
 // String returns the string representation of CodePageNumber.
@@ -87,6 +95,10 @@ const (
 	CharacterCodeTableLatinArabic   CharacterCodeTable = 0x02
 	CharacterCodeTableLatinGreek    CharacterCodeTable = 0x03
 	CharacterCodeTableLatinHebrew   CharacterCodeTable = 0x04
+
+	// Non-standard table of files whose Text Fields are UTF-8 encoded,
+	// the last value of the field as none is defined by the EBU.
+	CharacterCodeTableUTF8 CharacterCodeTable = 99
 )
 
 var cctStringMap = map[CharacterCodeTable]string{
@@ -96,6 +108,7 @@ var cctStringMap = map[CharacterCodeTable]string{
 	CharacterCodeTableLatinArabic:   "Latin/Arabic",
 	CharacterCodeTableLatinGreek:    "Latin/Greek",
 	CharacterCodeTableLatinHebrew:   "Latin/Hebrew",
+	CharacterCodeTableUTF8:          "UTF-8 (non-standard)",
 }
 
 // String returns the string representation of CharacterCodeTable.
@@ -14,8 +14,12 @@ import (
 func (gsi *GSIBlock) Validate() ([]error, error) {
 	var warns []error
 
-	// CPN - in list -> fatal
-	warns = appendNonNilErrs(warns, gsiErr(validateList(gsi.CPN, cpnValidValues, ErrUnsupportedCPN, true), GSIFieldCPN))
+	// CPN - in list -> fatal, unless a registered non-standard code page
+	if _, ok := CodePageNumberDecoders[gsi.CPN]; ok {
+		warns = appendNonNilErrs(warns, gsiErr(validateList(gsi.CPN, cpnValidValues, ErrNonStandardCPN, false), GSIFieldCPN))
+	} else {
+		warns = appendNonNilErrs(warns, gsiErr(validateList(gsi.CPN, cpnValidValues, ErrUnsupportedCPN, true), GSIFieldCPN))
+	}
 
 	// DFC - in list -> fatal
 	warns = appendNonNilErrs(warns, gsiErr(validateList(gsi.DFC, dfcValidValues, ErrUnsupportedDFC, true), GSIFieldDFC))
@@ -28,8 +32,12 @@ func (gsi *GSIBlock) Validate() ([]error, error) {
 	// DSC - in list
 	warns = appendNonNilErrs(warns, gsiErr(validateList(gsi.DSC, dscValidValues, ErrUnsupportedDSC, false), GSIFieldDSC))
 
-	// CCT - in list -> fatal
-	warns = appendNonNilErrs(warns, gsiErr(validateList(gsi.CCT, cctValidValues, ErrUnsupportedCCT, true), GSIFieldCCT))
+	// CCT - in list -> fatal, unless a registered non-standard table
+	if _, ok := CharacterCodeTableDecoders[gsi.CCT]; ok {
+		warns = appendNonNilErrs(warns, gsiErr(validateList(gsi.CCT, cctValidValues, ErrNonStandardCCT, false), GSIFieldCCT))
+	} else {
+		warns = appendNonNilErrs(warns, gsiErr(validateList(gsi.CCT, cctValidValues, ErrUnsupportedCCT, true), GSIFieldCCT))
+	}
 
 	// LC - in list
 	// Trick: do not validate list to avoid enormous error message
@@ -120,15 +128,25 @@ func (gsi *GSIBlock) Validate() ([]error, error) {
 	// ECD - not empty
 	warns = appendNonNilErrs(warns, gsiErr(validateNonEmptyString(gsi.ECD, ErrEmptyECD, false), GSIFieldECD))
 
+	// UDA - length, data of the matching profile
+	if len(gsi.UDA) > UDASize {
+		warns = appendNonNilErrs(warns, gsiErr(validateErr(fmt.Errorf("%w: must be at most %d bytes, truncated when encoded", ErrUDATooLong, UDASize), len(gsi.UDA), false), GSIFieldUDA))
+	}
+	if _, _, err := gsi.DecodeUDA(); err != nil && !errors.Is(err, ErrNoUDAProfile) {
+		warns = appendNonNilErrs(warns, gsiErr(validateErr(err, string(gsi.UDA), false), GSIFieldUDA))
+	}
+
 	return warns, nil
 }
 
 var (
 	ErrUnsupportedCPN               = errors.New("unsupported CPN")
+	ErrNonStandardCPN               = errors.New("non-standard CPN")
 	ErrUnsupportedDFC               = errors.New("unsupported DFC")
 	ErrUnsupportedFramerate         = errors.New("unsupported framerate")
 	ErrUnsupportedDSC               = errors.New("unsupported DSC")
 	ErrUnsupportedCCT               = errors.New("unsupported CCT")
+	ErrNonStandardCCT               = errors.New("non-standard CCT")
 	ErrUnsupportedLC                = errors.New("unsupported LC")
 	ErrEmptyOPT                     = errors.New("empty OPT")
 	ErrEmptyOET                     = errors.New("empty OET")
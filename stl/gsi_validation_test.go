@@ -0,0 +1,31 @@
+package stl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateDFCField(t *testing.T) {
+	gsi := NewGSIBlock()
+	gsi.SetDefaults(25, DisplayStandardCodeLevel1Teletext)
+	gsi.DFC = "STL24.01"
+
+	warns, _ := gsi.Validate()
+	var gsiErr *GSIError
+	for _, w := range warns {
+		var e *GSIError
+		if errors.Is(w, ErrUnsupportedDFC) && errors.As(w, &e) {
+			gsiErr = e
+		}
+	}
+	if gsiErr == nil {
+		t.Fatalf("expected an unsupported DFC warning in %v", warns)
+	}
+	if gsiErr.Field() != GSIFieldDFC || gsiErr.Field() == GSIFieldDSC {
+		t.Errorf("expected field DFC but got %s", gsiErr.Field())
+	}
+	if !strings.HasPrefix(gsiErr.Error(), "GSI DFC: ") {
+		t.Errorf("expected the DFC to be named in %q", gsiErr.Error())
+	}
+}
@@ -69,9 +69,9 @@ var ISO6937 = iso6937{}
 
 type iso6937 struct{}
 
-// iso6937 implements TextDecoder and TextEncoder.
+// iso6937 implements TextDecoder and FallbackEncoder.
 var _ TextDecoder = (*iso6937)(nil)
-var _ TextEncoder = (*iso6937)(nil)
+var _ FallbackEncoder = (*iso6937)(nil)
 
 // Decode decodes ISO 6937 encoded bytes into an UTF-8 encoded string.
 func (e *iso6937) Decode(src []byte) ([]byte, error) {
@@ -111,20 +111,19 @@ func (e *iso6937) Decode(src []byte) ([]byte, error) {
 }
 
 // Encode encodes a UTF-8 encoded string into ISO 6937 encoded bytes.
+// Runes which can not be represented are replaced by '?'.
 func (e *iso6937) Encode(src []byte) ([]byte, error) {
-	// Encode
-	var dst []byte
-	for _, c := range norm.NFD.String(string(src)) {
-		var result byte
-		if (c >= 0x00 && c <= 0x1F) || (c >= 0x80 && c <= 0x9F) {
-			result = byte(c)
-		} else if b, ok := encode[c]; ok {
-			result = b
-		} else {
-			result = 0x3F
-		}
+	dst, _, err := e.EncodeFallback(src, FallbackReplace)
+	return dst, err
+}
 
-		dst = append(dst, result)
+// EncodeFallback encodes a UTF-8 encoded string into ISO 6937 encoded bytes,
+// applying the fallback to the runes which can not be represented.
+func (e *iso6937) EncodeFallback(src []byte, fallback Fallback) ([]byte, []Unmappable, error) {
+	// Encode
+	dst, unmappables, err := encodeFallback(src, fallback, encodeISO6937Rune)
+	if err != nil {
+		return nil, unmappables, err
 	}
 
 	// Swap diacritics
@@ -133,10 +132,30 @@ func (e *iso6937) Encode(src []byte) ([]byte, error) {
 			if i != 0 {
 				dst[i-1], dst[i] = dst[i], dst[i-1]
 			} else {
-				return nil, transform.ErrShortSrc
+				return nil, unmappables, transform.ErrShortSrc
 			}
 		}
 	}
 
-	return dst, nil
+	return dst, unmappables, nil
+}
+
+// encodeISO6937Rune encodes a rune, decomposed into its base character and
+// diacritics, the diacritics following the base character.
+func encodeISO6937Rune(r rune) ([]byte, bool) {
+	if (r >= 0x00 && r <= 0x1F) || (r >= 0x80 && r <= 0x9F) {
+		return []byte{byte(r)}, true
+	}
+	if b, ok := encode[r]; ok {
+		return []byte{b}, true
+	}
+	var dst []byte
+	for _, c := range norm.NFD.String(string(r)) {
+		b, ok := encode[c]
+		if !ok || c == r {
+			return nil, false
+		}
+		dst = append(dst, b)
+	}
+	return dst, len(dst) > 0
 }
@@ -0,0 +1,451 @@
+package stl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var ErrUnknownEnumName = errors.New("unknown name")
+
+// enum is the type of the enumerated values of the blocks.
+type enum interface {
+	~byte | ~int
+}
+
+// marshalEnum returns the JSON encoding of an enumerated value: its name if
+// it has one, its number otherwise.
+func marshalEnum[T enum](v T, names map[T]string) ([]byte, error) {
+	if name, ok := names[v]; ok {
+		return json.Marshal(name)
+	}
+	return json.Marshal(int(v))
+}
+
+// unmarshalEnum decodes an enumerated value encoded by marshalEnum.
+func unmarshalEnum[T enum](b []byte, names map[T]string) (T, error) {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		for v, n := range names {
+			if n == name {
+				return v, nil
+			}
+		}
+		return 0, fmt.Errorf("%w: %q", ErrUnknownEnumName, name)
+	}
+	var number int
+	if err := json.Unmarshal(b, &number); err != nil {
+		return 0, err
+	}
+	if v := T(number); int(v) == number {
+		return v, nil
+	}
+	return 0, fmt.Errorf("%w: %d", ErrUnknownEnumName, number)
+}
+
+// MarshalJSON returns the name of the code page, or its number if unknown.
+func (cpn CodePageNumber) MarshalJSON() ([]byte, error) {
+	return marshalEnum(cpn, cpnStringMap)
+}
+
+// UnmarshalJSON decodes a code page encoded by MarshalJSON.
+func (cpn *CodePageNumber) UnmarshalJSON(b []byte) (err error) {
+	*cpn, err = unmarshalEnum(b, cpnStringMap)
+	return
+}
+
+// MarshalJSON returns the name of the display standard, or its number if
+// unknown.
+func (dsc DisplayStandardCode) MarshalJSON() ([]byte, error) {
+	return marshalEnum(dsc, dscStringMap)
+}
+
+// UnmarshalJSON decodes a display standard encoded by MarshalJSON.
+func (dsc *DisplayStandardCode) UnmarshalJSON(b []byte) (err error) {
+	*dsc, err = unmarshalEnum(b, dscStringMap)
+	return
+}
+
+// MarshalJSON returns the name of the character code table, or its number
+// if unknown.
+func (cct CharacterCodeTable) MarshalJSON() ([]byte, error) {
+	return marshalEnum(cct, cctStringMap)
+}
+
+// UnmarshalJSON decodes a character code table encoded by MarshalJSON.
+func (cct *CharacterCodeTable) UnmarshalJSON(b []byte) (err error) {
+	*cct, err = unmarshalEnum(b, cctStringMap)
+	return
+}
+
+// MarshalJSON returns the name of the language, or its number if unknown.
+func (lc LanguageCode) MarshalJSON() ([]byte, error) {
+	return marshalEnum(lc, lcStringMap)
+}
+
+// UnmarshalJSON decodes a language encoded by MarshalJSON.
+func (lc *LanguageCode) UnmarshalJSON(b []byte) (err error) {
+	*lc, err = unmarshalEnum(b, lcStringMap)
+	return
+}
+
+// MarshalJSON returns the name of the time code status, or its number if
+// unknown.
+func (tcs TimeCodeStatus) MarshalJSON() ([]byte, error) {
+	return marshalEnum(tcs, tcsStringMap)
+}
+
+// UnmarshalJSON decodes a time code status encoded by MarshalJSON.
+func (tcs *TimeCodeStatus) UnmarshalJSON(b []byte) (err error) {
+	*tcs, err = unmarshalEnum(b, tcsStringMap)
+	return
+}
+
+// MarshalJSON returns the name of the cumulative status, or its number if
+// unknown.
+func (cs CumulativeStatus) MarshalJSON() ([]byte, error) {
+	return marshalEnum(cs, csStringMap)
+}
+
+// UnmarshalJSON decodes a cumulative status encoded by MarshalJSON.
+func (cs *CumulativeStatus) UnmarshalJSON(b []byte) (err error) {
+	*cs, err = unmarshalEnum(b, csStringMap)
+	return
+}
+
+// MarshalJSON returns the name of the justification, or its number if
+// unknown.
+func (jc JustificationCode) MarshalJSON() ([]byte, error) {
+	return marshalEnum(jc, jcStringMap)
+}
+
+// UnmarshalJSON decodes a justification encoded by MarshalJSON.
+func (jc *JustificationCode) UnmarshalJSON(b []byte) (err error) {
+	*jc, err = unmarshalEnum(b, jcStringMap)
+	return
+}
+
+// MarshalJSON returns the name of the comment flag, or its number if
+// unknown.
+func (cf CommentFlag) MarshalJSON() ([]byte, error) {
+	return marshalEnum(cf, cfStringMap)
+}
+
+// UnmarshalJSON decodes a comment flag encoded by MarshalJSON.
+func (cf *CommentFlag) UnmarshalJSON(b []byte) (err error) {
+	*cf, err = unmarshalEnum(b, cfStringMap)
+	return
+}
+
+var (
+	ccStringMap         = map[ControlCode]string{}
+	teletextCCStringMap = map[TeletextControlCode]string{}
+	textOrderStringMap  = map[TextOrder]string{}
+)
+
+func init() {
+	for c := ControlCode(0x80); c <= 0x9F; c++ {
+		if s := c.String(); s != "Unknown" {
+			ccStringMap[c] = s
+		}
+	}
+	for c := TeletextControlCode(0x00); c <= 0x1F; c++ {
+		if s := c.String(); s != "Unknown" {
+			teletextCCStringMap[c] = s
+		}
+	}
+	for _, o := range []TextOrder{TextOrderLogical, TextOrderVisual, TextOrderVisualShaped} {
+		textOrderStringMap[o] = o.String()
+	}
+}
+
+// MarshalJSON returns the name of the control code, or its number if
+// unknown.
+func (cc ControlCode) MarshalJSON() ([]byte, error) {
+	return marshalEnum(cc, ccStringMap)
+}
+
+// UnmarshalJSON decodes a control code encoded by MarshalJSON.
+func (cc *ControlCode) UnmarshalJSON(b []byte) (err error) {
+	*cc, err = unmarshalEnum(b, ccStringMap)
+	return
+}
+
+// MarshalJSON returns the name of the teletext control code, or its number
+// if unknown.
+func (c TeletextControlCode) MarshalJSON() ([]byte, error) {
+	return marshalEnum(c, teletextCCStringMap)
+}
+
+// UnmarshalJSON decodes a teletext control code encoded by MarshalJSON.
+func (c *TeletextControlCode) UnmarshalJSON(b []byte) (err error) {
+	*c, err = unmarshalEnum(b, teletextCCStringMap)
+	return
+}
+
+// MarshalJSON returns the name of the text order.
+func (o TextOrder) MarshalJSON() ([]byte, error) {
+	return marshalEnum(o, textOrderStringMap)
+}
+
+// UnmarshalJSON decodes a text order encoded by MarshalJSON.
+func (o *TextOrder) UnmarshalJSON(b []byte) (err error) {
+	*o, err = unmarshalEnum(b, textOrderStringMap)
+	return
+}
+
+// gsiJSON is the JSON representation of a GSI block.
+type gsiJSON struct {
+	CPN CodePageNumber      `json:"cpn"`
+	DFC DiskFormatCode      `json:"dfc"`
+	DSC DisplayStandardCode `json:"dsc"`
+	CCT CharacterCodeTable  `json:"cct"`
+	LC  LanguageCode        `json:"lc"`
+	OPT string              `json:"opt"`
+	OET string              `json:"oet"`
+	TPT string              `json:"tpt"`
+	TET string              `json:"tet"`
+	TN  string              `json:"tn"`
+	TCD string              `json:"tcd"`
+	SLR string              `json:"slr"`
+	CD  string              `json:"cd"`
+	RD  string              `json:"rd"`
+	RN  int                 `json:"rn"`
+	TNB int                 `json:"tnb"`
+	TNS int                 `json:"tns"`
+	TNG int                 `json:"tng"`
+	MNC int                 `json:"mnc"`
+	MNR int                 `json:"mnr"`
+	TCS TimeCodeStatus      `json:"tcs"`
+	TCP Timecode            `json:"tcp"`
+	TCF Timecode            `json:"tcf"`
+	TND int                 `json:"tnd"`
+	DSN int                 `json:"dsn"`
+	CO  string              `json:"co"`
+	PUB string              `json:"pub"`
+	EN  string              `json:"en"`
+	ECD string              `json:"ecd"`
+	UDA []byte              `json:"uda"`
+}
+
+// jsonDateLayout is the layout of the dates of the JSON representation.
+const jsonDateLayout = "2006-01-02"
+
+// MarshalJSON returns the JSON representation of the GSI block.
+func (gsi *GSIBlock) MarshalJSON() ([]byte, error) {
+	date := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(jsonDateLayout)
+	}
+	return json.Marshal(gsiJSON{
+		CPN: gsi.CPN, DFC: gsi.DFC, DSC: gsi.DSC, CCT: gsi.CCT, LC: gsi.LC,
+		OPT: gsi.OPT, OET: gsi.OET, TPT: gsi.TPT, TET: gsi.TET, TN: gsi.TN, TCD: gsi.TCD, SLR: gsi.SLR,
+		CD: date(gsi.CD), RD: date(gsi.RD), RN: gsi.RN,
+		TNB: gsi.TNB, TNS: gsi.TNS, TNG: gsi.TNG, MNC: gsi.MNC, MNR: gsi.MNR,
+		TCS: gsi.TCS, TCP: gsi.TCP, TCF: gsi.TCF, TND: gsi.TND, DSN: gsi.DSN,
+		CO: gsi.CO, PUB: gsi.PUB, EN: gsi.EN, ECD: gsi.ECD, UDA: gsi.UDA,
+	})
+}
+
+// UnmarshalJSON decodes the JSON representation of a GSI block.
+func (gsi *GSIBlock) UnmarshalJSON(b []byte) error {
+	var j gsiJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	date := func(s string, field GSIField) (time.Time, error) {
+		if s == "" {
+			return time.Time{}, nil
+		}
+		t, err := time.Parse(jsonDateLayout, s)
+		if err != nil {
+			return t, fmt.Errorf("GSI %s: %w", field, err)
+		}
+		return t, nil
+	}
+	cd, err := date(j.CD, GSIFieldCD)
+	if err != nil {
+		return err
+	}
+	rd, err := date(j.RD, GSIFieldRD)
+	if err != nil {
+		return err
+	}
+	uda := j.UDA
+	if uda == nil {
+		uda = []byte{}
+	}
+	*gsi = GSIBlock{
+		CPN: j.CPN, DFC: j.DFC, DSC: j.DSC, CCT: j.CCT, LC: j.LC,
+		OPT: j.OPT, OET: j.OET, TPT: j.TPT, TET: j.TET, TN: j.TN, TCD: j.TCD, SLR: j.SLR,
+		CD: cd, RD: rd, RN: j.RN,
+		TNB: j.TNB, TNS: j.TNS, TNG: j.TNG, MNC: j.MNC, MNR: j.MNR,
+		TCS: j.TCS, TCP: j.TCP, TCF: j.TCF, TND: j.TND, DSN: j.DSN,
+		CO: j.CO, PUB: j.PUB, EN: j.EN, ECD: j.ECD, UDA: uda,
+	}
+	return nil
+}
+
+// ttiJSON is the JSON representation of a TTI block.
+type ttiJSON struct {
+	SGN int               `json:"sgn"`
+	SN  int               `json:"sn"`
+	EBN int               `json:"ebn"`
+	CS  CumulativeStatus  `json:"cs"`
+	TCI Timecode          `json:"tci"`
+	TCO Timecode          `json:"tco"`
+	VP  int               `json:"vp"`
+	JC  JustificationCode `json:"jc"`
+	CF  CommentFlag       `json:"cf"`
+	TF  []tfSegment       `json:"tf"`
+	Raw []byte            `json:"raw,omitempty"` // Text Field (TF) which can not be decoded losslessly
+}
+
+// tfSegment is a segment of a Text Field (TF): UTF-8 decoded text or a
+// control code.
+type tfSegment struct {
+	Text     string               `json:"text,omitempty"`
+	Control  *ControlCode         `json:"control,omitempty"`
+	Teletext *TeletextControlCode `json:"teletext,omitempty"`
+}
+
+// fileJSON is the JSON representation of a file.
+type fileJSON struct {
+	GSI       *GSIBlock `json:"gsi"`
+	TextOrder TextOrder `json:"textOrder"`
+	TTI       []ttiJSON `json:"tti"`
+}
+
+// MarshalJSON returns the JSON representation of the file, an object of its
+// GSI block, its text order and its TTI blocks:
+//
+//	{
+//	  "gsi": {"cpn": "Multilingual", "dfc": "STL25.01", "cct": "Latin", ..., "tcp": "10:00:00:00", ...},
+//	  "textOrder": "logical",
+//	  "tti": [
+//	    {"sgn": 0, "sn": 0, "ebn": 255, "cs": "None", "tci": "10:00:01:00", ..., "jc": "Centered text",
+//	     "tf": [{"teletext": "Alpha red"}, {"text": "Hello"}, {"control": "Line break"}, {"text": "world"}]}
+//	  ]
+//	}
+//
+// Enumerated values are given by the name of their String method, or by
+// their number if they have none. Timecodes are strings "HH:MM:SS:FF",
+// dates strings "YYYY-MM-DD" and the User-Defined Area (UDA) is base64
+// encoded. The Text Field (TF) is a list of segments of control codes and of
+// UTF-8 decoded text, NFC normalized and in the text order of the file; it is
+// given by its base64 encoded bytes as "raw" instead if it can not be decoded
+// and encoded back identically, e.g. for an unsupported Character Code Table
+// (CCT).
+// Files encoded after a JSON round trip are identical to the original ones.
+func (f *File) MarshalJSON() ([]byte, error) {
+	if f.GSI == nil {
+		panic(fmt.Errorf("GSI block is nil"))
+	}
+	j := fileJSON{GSI: f.GSI, TextOrder: f.TextOrder, TTI: make([]ttiJSON, len(f.TTI))}
+	for i, tti := range f.TTI {
+		j.TTI[i] = ttiJSON{
+			SGN: tti.SGN, SN: tti.SN, EBN: tti.EBN, CS: tti.CS,
+			TCI: tti.TCI, TCO: tti.TCO, VP: tti.VP, JC: tti.JC, CF: tti.CF,
+		}
+		if segments, ok := tfSegments(tti, f.GSI.CCT); ok {
+			j.TTI[i].TF = segments
+		} else {
+			j.TTI[i].Raw = []byte(tti.TF)
+		}
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes the JSON representation of a file, the Text Fields
+// (TF) being encoded with the Character Code Table (CCT) of its GSI block.
+func (f *File) UnmarshalJSON(b []byte) error {
+	var j fileJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	if j.GSI == nil {
+		return fmt.Errorf("no GSI block")
+	}
+	tti := make([]*TTIBlock, len(j.TTI))
+	for i, t := range j.TTI {
+		tti[i] = &TTIBlock{
+			SGN: t.SGN, SN: t.SN, EBN: t.EBN, CS: t.CS,
+			TCI: t.TCI, TCO: t.TCO, VP: t.VP, JC: t.JC, CF: t.CF,
+		}
+		if t.Raw != nil {
+			tti[i].TF = string(t.Raw)
+		} else if err := tti[i].SetText(tfText(t.TF), j.GSI.CCT); err != nil {
+			return fmt.Errorf("TTI block %d: %w", i, err)
+		}
+	}
+	f.GSI, f.TTI, f.TextOrder = j.GSI, tti, j.TextOrder
+	return nil
+}
+
+// tfSegments returns the segments of the Text Field (TF) of the TTI block,
+// and whether they encode back to the Text Field.
+func tfSegments(tti *TTIBlock, cct CharacterCodeTable) ([]tfSegment, bool) {
+	text, err := tti.Text(cct)
+	if err != nil {
+		return nil, false
+	}
+	segments := []tfSegment{}
+	var s strings.Builder
+	flush := func() {
+		if s.Len() > 0 {
+			segments = append(segments, tfSegment{Text: norm.NFC.String(s.String())})
+			s.Reset()
+		}
+	}
+	for i := 0; i < len(text); {
+		r, n := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r <= 0x1F:
+			flush()
+			c := TeletextControlCode(r)
+			segments = append(segments, tfSegment{Teletext: &c})
+		case r == utf8.RuneError && n == 1 && text[i] >= 0x80 && text[i] <= 0x9F:
+			flush()
+			c := ControlCode(text[i])
+			segments = append(segments, tfSegment{Control: &c})
+		case r >= 0x80 && r <= 0x9F:
+			flush()
+			c := ControlCode(r)
+			segments = append(segments, tfSegment{Control: &c})
+		default:
+			s.WriteString(text[i : i+n])
+		}
+		i += n
+	}
+	flush()
+
+	check := TTIBlock{}
+	if err := check.SetText(tfText(segments), cct); err != nil || check.TF != tti.TF {
+		return nil, false
+	}
+	return segments, true
+}
+
+// tfText returns the UTF-8 text of the segments of a Text Field (TF), with
+// control codes as the runes U+0000..U+001F and U+0080..U+009F.
+func tfText(segments []tfSegment) string {
+	var s strings.Builder
+	for _, seg := range segments {
+		switch {
+		case seg.Control != nil:
+			s.WriteRune(rune(*seg.Control))
+		case seg.Teletext != nil:
+			s.WriteRune(rune(*seg.Teletext))
+		default:
+			s.WriteString(seg.Text)
+		}
+	}
+	return s.String()
+}
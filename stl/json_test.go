@@ -0,0 +1,145 @@
+package stl
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestJSONFile(t *testing.T) *File {
+	t.Helper()
+	f := newTestDiskFile(t, 3, 2)
+	f.GSI.CPN = CodePageNumberWindowsLatin1
+	f.GSI.OPT = "“Café”"
+	f.GSI.CD = time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	f.GSI.TCP = Timecode{Hours: 10}
+	f.GSI.UDA = []byte("AUD1\x00\xff")
+	if err := f.TTI[0].SetText("\x01Été\u008aà \u0080bientôt\u0081", f.GSI.CCT); err != nil {
+		t.Fatal(err)
+	}
+	f.TTI[1].JC = 0x07 // unknown
+	f.TTI[1].TF = "\xc1"
+	return f
+}
+
+func encodeTestFile(t *testing.T, f *File) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFileJSON(t *testing.T) {
+	f := newTestJSONFile(t)
+	expected := encodeTestFile(t, f)
+
+	decoded := NewFile()
+	if _, err := decoded.Decode(bytes.NewReader(expected)); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []*File{f, decoded} {
+		b, err := json.Marshal(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range []string{
+			`"cpn":"Windows Latin 1 (non-standard)"`, `"dfc":"STL25.01"`, `"cct":"Latin"`, `"lc":"Unknown/not applicable"`,
+			`"opt":"“Café”"`, `"cd":"2017-03-01"`, `"tcp":"10:00:00:00"`, `"uda":"QVVEMQD/"`,
+			`"textOrder":"logical"`, `"cs":"None"`, `"tci":"00:00:01:00"`, `"cf":"Subtitle data"`,
+			`"jc":"Centered text","cf":"Subtitle data","tf":[{"teletext":"Alpha red"},{"text":"Été"},{"control":"Line break"},` +
+				`{"text":"à "},{"control":"Italic on"},{"text":"bientôt"},{"control":"Italic off"}]`,
+			`"jc":7,"cf":"Subtitle data","tf":null,"raw":"wQ=="`,
+		} {
+			if !strings.Contains(string(b), s) {
+				t.Errorf("expected %s in %s", s, b)
+			}
+		}
+
+		var g File
+		if err := json.Unmarshal(b, &g); err != nil {
+			t.Fatal(err)
+		}
+		if b := encodeTestFile(t, &g); !bytes.Equal(b, expected) {
+			t.Errorf("expected the file to round trip\n%q\n%q", expected, b)
+		}
+	}
+}
+
+func TestFileJSONUnsupportedCCT(t *testing.T) {
+	f := newTestJSONFile(t)
+	f.GSI.CCT = 0x05
+	expected := encodeTestFile(t, f)
+
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"cct":5`) || strings.Contains(string(b), `"tf":[`) {
+		t.Errorf("expected raw Text Fields of an unknown CCT in %s", b)
+	}
+	var g File
+	if err := json.Unmarshal(b, &g); err != nil {
+		t.Fatal(err)
+	}
+	if b := encodeTestFile(t, &g); !bytes.Equal(b, expected) {
+		t.Errorf("expected the file to round trip\n%q\n%q", expected, b)
+	}
+}
+
+func TestUnmarshalJSONEnum(t *testing.T) {
+	tests := []struct {
+		json     string
+		expected JustificationCode
+		err      bool
+	}{
+		{`"Left-justified text"`, JustificationCodeLeftJustifiedText, false},
+		{`3`, JustificationCodeRightJustifiedText, false},
+		{`9`, 9, false},
+		{`"Justified"`, 0, true},
+		{`256`, 0, true},
+		{`true`, 0, true},
+	}
+	for _, test := range tests {
+		var jc JustificationCode
+		err := json.Unmarshal([]byte(test.json), &jc)
+		if (err != nil) != test.err {
+			t.Errorf("%s: unexpected error %v", test.json, err)
+		} else if err == nil && jc != test.expected {
+			t.Errorf("%s: expected %d but got %d", test.json, test.expected, jc)
+		}
+	}
+}
+
+func TestEnumNamesUnique(t *testing.T) {
+	unique := func(name string, names []string) {
+		seen := map[string]bool{}
+		for _, n := range names {
+			if seen[n] {
+				t.Errorf("%s: duplicate name %q", name, n)
+			}
+			seen[n] = true
+		}
+	}
+	unique("CPN", mapValues(cpnStringMap))
+	unique("DSC", mapValues(dscStringMap))
+	unique("CCT", mapValues(cctStringMap))
+	unique("LC", mapValues(lcStringMap))
+	unique("TCS", mapValues(tcsStringMap))
+	unique("CS", mapValues(csStringMap))
+	unique("JC", mapValues(jcStringMap))
+	unique("CF", mapValues(cfStringMap))
+	unique("control codes", mapValues(ccStringMap))
+	unique("teletext control codes", mapValues(teletextCCStringMap))
+}
+
+func mapValues[K comparable](m map[K]string) []string {
+	var values []string
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
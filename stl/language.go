@@ -0,0 +1,149 @@
+package stl
+
+import "strings"
+
+// languageCodeISO639 holds the ISO 639-1 (two letters) and ISO 639-2/B
+// (three letters) codes of a LanguageCode.
+// ISO 639-1 is empty when the language has no two letters code.
+type languageCodeISO639 struct {
+	part1 string
+	part2 string
+}
+
+var lcISO639Map = map[LanguageCode]languageCodeISO639{
+	LanguageCodeAlbanian:      {"sq", "alb"},
+	LanguageCodeBreton:        {"br", "bre"},
+	LanguageCodeCatalan:       {"ca", "cat"},
+	LanguageCodeCroatian:      {"hr", "hrv"},
+	LanguageCodeWelsh:         {"cy", "wel"},
+	LanguageCodeCzech:         {"cs", "cze"},
+	LanguageCodeDanish:        {"da", "dan"},
+	LanguageCodeGerman:        {"de", "ger"},
+	LanguageCodeEnglish:       {"en", "eng"},
+	LanguageCodeSpanish:       {"es", "spa"},
+	LanguageCodeEsperanto:     {"eo", "epo"},
+	LanguageCodeEstonian:      {"et", "est"},
+	LanguageCodeBasque:        {"eu", "baq"},
+	LanguageCodeFaroese:       {"fo", "fao"},
+	LanguageCodeFrench:        {"fr", "fre"},
+	LanguageCodeFrisian:       {"fy", "fry"},
+	LanguageCodeIrish:         {"ga", "gle"},
+	LanguageCodeGaelic:        {"gd", "gla"},
+	LanguageCodeGalician:      {"gl", "glg"},
+	LanguageCodeIcelandic:     {"is", "ice"},
+	LanguageCodeItalian:       {"it", "ita"},
+	LanguageCodeLappish:       {"se", "sme"},
+	LanguageCodeLatin:         {"la", "lat"},
+	LanguageCodeLatvian:       {"lv", "lav"},
+	LanguageCodeLuxembourgian: {"lb", "ltz"},
+	LanguageCodeLithuanian:    {"lt", "lit"},
+	LanguageCodeHungarian:     {"hu", "hun"},
+	LanguageCodeMaltese:       {"mt", "mlt"},
+	LanguageCodeDutch:         {"nl", "dut"},
+	LanguageCodeNorwegian:     {"no", "nor"},
+	LanguageCodeOccitan:       {"oc", "oci"},
+	LanguageCodePolish:        {"pl", "pol"},
+	LanguageCodePortugese:     {"pt", "por"},
+	LanguageCodeRomanian:      {"ro", "rum"},
+	LanguageCodeRomansh:       {"rm", "roh"},
+	LanguageCodeSerbian:       {"sr", "srp"},
+	LanguageCodeSlovak:        {"sk", "slo"},
+	LanguageCodeSlovenian:     {"sl", "slv"},
+	LanguageCodeFinnish:       {"fi", "fin"},
+	LanguageCodeSwedish:       {"sv", "swe"},
+	LanguageCodeTurkish:       {"tr", "tur"},
+	LanguageCodeFlemish:       {"nl", "dut"},
+	LanguageCodeWallon:        {"wa", "wln"},
+	LanguageCodeAmharic:       {"am", "amh"},
+	LanguageCodeArabic:        {"ar", "ara"},
+	LanguageCodeArmenian:      {"hy", "arm"},
+	LanguageCodeAssamese:      {"as", "asm"},
+	LanguageCodeAzerbaijani:   {"az", "aze"},
+	LanguageCodeBambora:       {"bm", "bam"},
+	LanguageCodeBielorussian:  {"be", "bel"},
+	LanguageCodeBengali:       {"bn", "ben"},
+	LanguageCodeBulgarian:     {"bg", "bul"},
+	LanguageCodeBurmese:       {"my", "bur"},
+	LanguageCodeChinese:       {"zh", "chi"},
+	LanguageCodeChurash:       {"cv", "chv"},
+	LanguageCodeDari:          {"", "prs"},
+	LanguageCodeFulani:        {"ff", "ful"},
+	LanguageCodeGeorgian:      {"ka", "geo"},
+	LanguageCodeGreek:         {"el", "gre"},
+	LanguageCodeGujurati:      {"gu", "guj"},
+	LanguageCodeGurani:        {"gn", "grn"},
+	LanguageCodeHausa:         {"ha", "hau"},
+	LanguageCodeHebrew:        {"he", "heb"},
+	LanguageCodeHindi:         {"hi", "hin"},
+	LanguageCodeIndonesian:    {"id", "ind"},
+	LanguageCodeJapanese:      {"ja", "jpn"},
+	LanguageCodeKannada:       {"kn", "kan"},
+	LanguageCodeKazakh:        {"kk", "kaz"},
+	LanguageCodeKhmer:         {"km", "khm"},
+	LanguageCodeKorean:        {"ko", "kor"},
+	LanguageCodeLaotian:       {"lo", "lao"},
+	LanguageCodeMacedonian:    {"mk", "mac"},
+	LanguageCodeMalagasay:     {"mg", "mlg"},
+	LanguageCodeMalaysian:     {"ms", "may"},
+	LanguageCodeMoldavian:     {"ro", "rum"},
+	LanguageCodeMarathi:       {"mr", "mar"},
+	LanguageCodeNdebele:       {"nd", "nde"},
+	LanguageCodeNepali:        {"ne", "nep"},
+	LanguageCodeOriya:         {"or", "ori"},
+	LanguageCodePapamiento:    {"", "pap"},
+	LanguageCodePersian:       {"fa", "per"},
+	LanguageCodePunjabi:       {"pa", "pan"},
+	LanguageCodePushtu:        {"ps", "pus"},
+	LanguageCodeQuechua:       {"qu", "que"},
+	LanguageCodeRussian:       {"ru", "rus"},
+	LanguageCodeRuthenian:     {"", "rue"},
+	LanguageCodeSerboCroat:    {"sh", "hbs"},
+	LanguageCodeShona:         {"sn", "sna"},
+	LanguageCodeSinhalese:     {"si", "sin"},
+	LanguageCodeSomali:        {"so", "som"},
+	LanguageCodeSrananTongo:   {"", "srn"},
+	LanguageCodeSwahili:       {"sw", "swa"},
+	LanguageCodeTadzhik:       {"tg", "tgk"},
+	LanguageCodeTamil:         {"ta", "tam"},
+	LanguageCodeTatar:         {"tt", "tat"},
+	LanguageCodeTelugu:        {"te", "tel"},
+	LanguageCodeThai:          {"th", "tha"},
+	LanguageCodeUkrainian:     {"uk", "ukr"},
+	LanguageCodeUrdu:          {"ur", "urd"},
+	LanguageCodeUzbek:         {"uz", "uzb"},
+	LanguageCodeVietnamese:    {"vi", "vie"},
+	LanguageCodeZulu:          {"zu", "zul"},
+}
+
+// ISO639_1 returns the ISO 639-1 two letters code of the language.
+// Returns an empty string if the language is unknown or has no two letters
+// code.
+func (lc LanguageCode) ISO639_1() string {
+	return lcISO639Map[lc].part1
+}
+
+// ISO639_2 returns the ISO 639-2/B three letters code of the language.
+// Returns "und" (undetermined) if the language is unknown.
+func (lc LanguageCode) ISO639_2() string {
+	if c, ok := lcISO639Map[lc]; ok {
+		return c.part2
+	}
+	return "und"
+}
+
+// LanguageCodeFromISO639 returns the LanguageCode of an ISO 639-1 (two
+// letters) or ISO 639-2/B (three letters) language code, case insensitive.
+// Returns LanguageCodeUnknown if the code is not known.
+func LanguageCodeFromISO639(code string) LanguageCode {
+	code = strings.ToLower(code)
+	for lc, c := range lcISO639Map {
+		// Flemish and Dutch share their codes
+		if lc == LanguageCodeFlemish {
+			continue
+		}
+		if code != "" && (c.part1 == code || c.part2 == code) {
+			return lc
+		}
+	}
+	return LanguageCodeUnknown
+}
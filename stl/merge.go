@@ -0,0 +1,153 @@
+package stl
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	ErrOverlappingSubtitles = errors.New("overlapping subtitles")
+	ErrNegativeTimecode     = errors.New("negative timecode")
+	ErrTooManyGroups        = errors.New("too many subtitle groups")
+)
+
+// MergeOptions are the options of Merge.
+type MergeOptions struct {
+	Offset   time.Duration // Offset added to the timecodes of the second file, e.g. the duration of the first part
+	Fallback Fallback      // Fallback for the text of the second file which can not be encoded in the CCT of the first one (default replace)
+}
+
+// Merge merges the subtitles of two files, e.g. the parts of a programme or
+// a main track and its forced narratives, into a new file.
+// The subtitles are combined by Time Code In (TCI), cumulative sets being
+// kept together, and renumbered: a new subtitle group starts whenever the
+// subtitles switch to another group of either file, subtitle numbers restart
+// with each group and extension blocks are recomputed. User data blocks are
+// not merged. As the Subtitle Group Number (SGN) is a single byte,
+// ErrTooManyGroups is returned if the merged file needs more than 256
+// groups, e.g. for files whose subtitles alternate more than 255 times.
+// The GSI block of a is used for the merged file, with the maxima of MNC and
+// MNR, a single disk and updated counters. The timecodes of b are converted
+// to the framerate of a, and its text to the Character Code Table (CCT) and
+// the text order of a.
+// Overlapping subtitles of a and b and the characters of b which can not be
+// encoded are returned as warnings.
+// The given files are left unchanged.
+func Merge(a, b *File, opts MergeOptions) (*File, []error, error) {
+	if a.GSI == nil || b.GSI == nil {
+		return nil, nil, ErrNilGSI
+	}
+	var warns []error
+
+	fa, fb := a.GSI.Framerate(), b.GSI.Framerate()
+	if fa == 0 || fb == 0 {
+		return nil, nil, fmt.Errorf("%w: %s and %s", ErrUnsupportedDFC, a.GSI.DFC, b.GSI.DFC)
+	}
+
+	type subtitle struct {
+		*TTIBlock
+		source int // 0 for a, 1 for b
+		index  int // index of the subtitle in its file
+	}
+	var units [][]subtitle // subtitles, cumulative sets kept together
+	add := func(sub *TTIBlock, source, index int) {
+		n := len(units)
+		if index == 0 || !continuesCumulativeSet(units[n-1][len(units[n-1])-1].TTIBlock, sub) {
+			units = append(units, nil)
+			n++
+		}
+		units[n-1] = append(units[n-1], subtitle{sub, source, index})
+	}
+
+	for i, sub := range a.Subtitles() {
+		add(sub, 0, i)
+	}
+	for i, sub := range b.Subtitles() {
+		if fa != fb || opts.Offset != 0 {
+			tci := sub.TCI.ToDuration(fb) + opts.Offset
+			tco := sub.TCO.ToDuration(fb) + opts.Offset
+			if tci < 0 || tco < 0 {
+				return nil, warns, fmt.Errorf("%w: subtitle %d of b at %s with offset %s", ErrNegativeTimecode, i, sub.TCI, opts.Offset)
+			}
+			sub.TCI = TimecodeFromDuration(tci, fa)
+			sub.TCO = TimecodeFromDuration(tco, fa)
+		}
+		if b.GSI.CCT != a.GSI.CCT || b.TextOrder != a.TextOrder {
+			text, err := sub.TextOrdered(b.GSI.CCT, b.TextOrder)
+			if err != nil {
+				return nil, warns, fmt.Errorf("subtitle %d of b: %w", i, err)
+			}
+			unmappables, err := sub.SetTextFallback(ConvertTextOrder(text, TextOrderLogical, a.TextOrder), a.GSI.CCT, opts.Fallback)
+			if err != nil {
+				return nil, warns, fmt.Errorf("subtitle %d of b: %w", i, err)
+			}
+			if len(unmappables) > 0 {
+				warns = append(warns, fmt.Errorf("subtitle %d of b: %w, fallback %s", i, &UnmappableError{Runes: unmappables}, opts.Fallback))
+			}
+		}
+		add(sub, 1, i)
+	}
+
+	sort.SliceStable(units, func(i, j int) bool {
+		return units[i][0].TCI.ToFrames(fa) < units[j][0].TCI.ToFrames(fa)
+	})
+
+	merged := &File{GSI: a.GSI.clone(), TextOrder: a.TextOrder}
+	if b.GSI.MNC > merged.GSI.MNC {
+		merged.GSI.MNC = b.GSI.MNC
+	}
+	if b.GSI.MNR > merged.GSI.MNR {
+		merged.GSI.MNR = b.GSI.MNR
+	}
+	merged.GSI.TND = 1
+	merged.GSI.DSN = 1
+
+	var ends [2]*subtitle // subtitle of each file ending last so far
+	sgn, sn := -1, 0
+	var last *subtitle
+	for _, unit := range units {
+		for i := range unit {
+			sub := &unit[i]
+			if other := ends[1-sub.source]; other != nil && other.TCO.ToFrames(fa) > sub.TCI.ToFrames(fa) {
+				x, y := other, sub
+				if x.source == 1 {
+					x, y = y, x
+				}
+				warns = append(warns, fmt.Errorf("%w: subtitle %d of a (%s - %s) and subtitle %d of b (%s - %s)", ErrOverlappingSubtitles,
+					x.index, x.TCI, x.TCO, y.index, y.TCI, y.TCO))
+			}
+			if end := ends[sub.source]; end == nil || sub.TCO.ToFrames(fa) > end.TCO.ToFrames(fa) {
+				ends[sub.source] = sub
+			}
+
+			if last == nil || last.source != sub.source || last.SGN != sub.SGN {
+				sgn++
+				sn = 0
+				if sgn > 0xFF {
+					return nil, warns, fmt.Errorf("%w: subtitle %d of %s at %s would start group %d, SGN must be at most 255",
+						ErrTooManyGroups, sub.index, [2]string{"a", "b"}[sub.source], sub.TCI, sgn)
+				}
+			} else {
+				sn++
+			}
+			last = sub
+
+			tti := *sub.TTIBlock
+			tti.SGN = sgn
+			tti.SN = sn
+			merged.TTI = append(merged.TTI, tti.ExtensionBlocks()...)
+		}
+	}
+	merged.UpdateCounters()
+
+	return merged, warns, nil
+}
+
+// continuesCumulativeSet reports whether the subtitle next continues the
+// cumulative set of the subtitle prev.
+func continuesCumulativeSet(prev, next *TTIBlock) bool {
+	return (prev.CS == CumulativeStatusFirst || prev.CS == CumulativeStatusIntermediate) &&
+		(next.CS == CumulativeStatusIntermediate || next.CS == CumulativeStatusLast)
+}
@@ -0,0 +1,132 @@
+package stl
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	a := newTestDiskFile(t, 3, -1)
+	b := newTestDiskFile(t, 2, 1)
+
+	merged, warns, err := Merge(a, b, MergeOptions{Offset: 4 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) != 0 {
+		t.Errorf("expected no warnings but got %v", warns)
+	}
+	expected := []struct{ sgn, sn, ebn, seconds int }{
+		{0, 0, EBNLastBlock, 1}, {0, 1, EBNLastBlock, 2}, {0, 2, EBNLastBlock, 3},
+		{1, 0, EBNLastBlock, 5}, {1, 1, 0, 6}, {1, 1, 1, 6}, {1, 1, EBNLastBlock, 6},
+	}
+	if len(merged.TTI) != len(expected) {
+		t.Fatalf("expected %d blocks but got %d", len(expected), len(merged.TTI))
+	}
+	for i, e := range expected {
+		tti := merged.TTI[i]
+		if tti.SGN != e.sgn || tti.SN != e.sn || tti.EBN != e.ebn || tti.TCI != (Timecode{Seconds: e.seconds}) {
+			t.Errorf("block %d: expected %d/%d/%d at %ds but got %d/%d/%d at %s", i, e.sgn, e.sn, e.ebn, e.seconds, tti.SGN, tti.SN, tti.EBN, tti.TCI)
+		}
+	}
+	if merged.GSI.TNB != 7 || merged.GSI.TNS != 5 || merged.GSI.TNG != 2 {
+		t.Errorf("expected TNB 7, TNS 5 and TNG 2 but got %d, %d and %d", merged.GSI.TNB, merged.GSI.TNS, merged.GSI.TNG)
+	}
+	warns, err = merged.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := diskWarning(warns); w != nil {
+		t.Errorf("unexpected warning: %v", w)
+	}
+	if len(a.TTI) != 3 || a.TTI[0].SGN != 0 || len(b.TTI) != 4 || b.TTI[0].TCI != (Timecode{Seconds: 1}) {
+		t.Error("expected the merged files to be left unchanged")
+	}
+}
+
+func TestMergeOverlapping(t *testing.T) {
+	a := newTestDiskFile(t, 3, -1)
+	b := newTestDiskFile(t, 1, -1)
+
+	merged, warns, err := Merge(a, b, MergeOptions{Offset: 1500 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) != 2 || !errors.Is(warns[0], ErrOverlappingSubtitles) || !errors.Is(warns[1], ErrOverlappingSubtitles) {
+		t.Fatalf("expected 2 %q warnings but got %v", ErrOverlappingSubtitles, warns)
+	}
+	for i, sgn := range []int{0, 0, 1, 2} {
+		if merged.TTI[i].SGN != sgn {
+			t.Errorf("block %d: expected SGN %d but got %d", i, sgn, merged.TTI[i].SGN)
+		}
+	}
+}
+
+func TestMergeTooManyGroups(t *testing.T) {
+	// subtitles of a and b alternate, each switch starting a group
+	a := newTestDiskFile(t, 129, -1)
+	b := newTestDiskFile(t, 129, -1)
+
+	_, _, err := Merge(a, b, MergeOptions{Offset: 500 * time.Millisecond})
+	if !errors.Is(err, ErrTooManyGroups) {
+		t.Fatalf("expected %q error but got %v", ErrTooManyGroups, err)
+	}
+
+	merged, _, err := Merge(a, b, MergeOptions{Offset: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.GSI.TNG != 2 {
+		t.Errorf("expected 2 groups but got %d", merged.GSI.TNG)
+	}
+}
+
+func TestMergeNilGSI(t *testing.T) {
+	a := newTestDiskFile(t, 1, -1)
+	if _, _, err := Merge(a, NewFile(), MergeOptions{}); !errors.Is(err, ErrNilGSI) {
+		t.Errorf("expected %q error but got %v", ErrNilGSI, err)
+	}
+}
+
+func TestMergeConversion(t *testing.T) {
+	a := newTestDiskFile(t, 1, -1)
+	b := newTestDiskFile(t, 1, -1)
+	b.GSI.DFC = DiskFormatCode30_01
+	b.GSI.CCT = CharacterCodeTableLatinCyrillic
+	b.GSI.MNC = 60
+	b.TTI[0].TCI = Timecode{Seconds: 3, Frames: 15}
+	if err := b.TTI[0].SetText("Да", b.GSI.CCT); err != nil {
+		t.Fatal(err)
+	}
+
+	merged, warns, err := Merge(a, b, MergeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) != 1 || !errors.Is(warns[0], ErrUnmappableRune) {
+		t.Errorf("expected one %q warning but got %v", ErrUnmappableRune, warns)
+	}
+	if merged.TTI[1].TCI != (Timecode{Seconds: 3, Frames: 12}) {
+		t.Errorf("expected TCI 00:00:03:12 but got %s", merged.TTI[1].TCI)
+	}
+	if merged.TTI[1].TF != "??" {
+		t.Errorf("expected TF %q but got %q", "??", merged.TTI[1].TF)
+	}
+	if merged.GSI.MNC != 60 {
+		t.Errorf("expected MNC 60 but got %d", merged.GSI.MNC)
+	}
+
+	a.GSI.CCT = CharacterCodeTableUTF8
+	merged, warns, err = Merge(a, b, MergeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) != 0 || merged.TTI[1].TF != "Да" {
+		t.Errorf("expected TF %q but got %q, %v", "Да", merged.TTI[1].TF, warns)
+	}
+
+	if _, _, err := Merge(a, b, MergeOptions{Offset: -time.Hour}); !errors.Is(err, ErrNegativeTimecode) {
+		t.Errorf("expected error %q but got %v", ErrNegativeTimecode, err)
+	}
+}
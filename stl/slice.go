@@ -0,0 +1,128 @@
+package stl
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidTimeRange = errors.New("invalid time range")
+
+// Slice returns a new file holding the subtitles of the file displayed
+// between from and to. The timing of the subtitles crossing the boundaries
+// is clipped to the range, and the cumulative sets cut by the range are
+// closed.
+// Subtitles are renumbered from group 0 and subtitle 0, extension blocks are
+// recomputed and user data blocks are not kept. The GSI block of the file is
+// used with the Start-of-Programme (TCP) set to from, a single disk and
+// updated counters. Timecodes are kept, Rebase rebases them to another TCP.
+// The file is left unchanged.
+func (f *File) Slice(from, to Timecode) (*File, error) {
+	framerate := f.GSI.Framerate()
+	if framerate == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDFC, f.GSI.DFC)
+	}
+	start, end := from.ToFrames(framerate), to.ToFrames(framerate)
+	if start >= end {
+		return nil, fmt.Errorf("%w: %s - %s", ErrInvalidTimeRange, from, to)
+	}
+
+	var subs []*TTIBlock
+	for _, sub := range f.Subtitles() {
+		if sub.TCO.ToFrames(framerate) <= start || sub.TCI.ToFrames(framerate) >= end {
+			continue
+		}
+		if sub.TCI.ToFrames(framerate) < start {
+			sub.TCI = from
+		}
+		if sub.TCO.ToFrames(framerate) > end {
+			sub.TCO = to
+		}
+		subs = append(subs, sub)
+	}
+	return f.segment(subs, from), nil
+}
+
+// SplitByGroup splits the file into new files holding the subtitles of
+// each subtitle group, in order of the groups.
+// Subtitles are renumbered from group 0 and subtitle 0, extension blocks are
+// recomputed and user data blocks are not kept. The GSI block of the file is
+// used with the Start-of-Programme (TCP) set to the Time Code In (TCI) of
+// the first subtitle of the group, a single disk and updated counters.
+// Timecodes are kept, Rebase rebases them to another TCP.
+// The file is left unchanged.
+func (f *File) SplitByGroup() []*File {
+	var files []*File
+	var subs []*TTIBlock
+	for _, sub := range f.Subtitles() {
+		if len(subs) > 0 && sub.SGN != subs[0].SGN {
+			files = append(files, f.segment(subs, subs[0].TCI))
+			subs = nil
+		}
+		subs = append(subs, sub)
+	}
+	if len(subs) > 0 {
+		files = append(files, f.segment(subs, subs[0].TCI))
+	}
+	return files
+}
+
+// Rebase shifts the timecodes of the TTI blocks by the difference between
+// tcp and the Start-of-Programme (TCP), which is set to tcp, and updates the
+// Time Code First In-Cue (TCF).
+func (f *File) Rebase(tcp Timecode) error {
+	framerate := f.GSI.Framerate()
+	if framerate == 0 {
+		return fmt.Errorf("%w: %s", ErrUnsupportedDFC, f.GSI.DFC)
+	}
+	offset := tcp.ToFrames(framerate) - f.GSI.TCP.ToFrames(framerate)
+	for i, tti := range f.TTI {
+		if tti.TCI.ToFrames(framerate)+offset < 0 || tti.TCO.ToFrames(framerate)+offset < 0 {
+			return fmt.Errorf("%w: TTI block %d at %s with TCP %s", ErrNegativeTimecode, i, tti.TCI, tcp)
+		}
+	}
+	for _, tti := range f.TTI {
+		tti.TCI = TimecodeFromFrames(tti.TCI.ToFrames(framerate)+offset, framerate)
+		tti.TCO = TimecodeFromFrames(tti.TCO.ToFrames(framerate)+offset, framerate)
+	}
+	f.GSI.TCP = tcp
+	f.UpdateCounters()
+	return nil
+}
+
+// segment returns a new file of the subtitles of the file, as returned by
+// Subtitles, starting at tcp. The subtitles are renumbered and the
+// cumulative sets closed.
+func (f *File) segment(subs []*TTIBlock, tcp Timecode) *File {
+	s := &File{GSI: f.GSI.clone(), TextOrder: f.TextOrder}
+	s.GSI.TCP = tcp
+	s.GSI.TND = 1
+	s.GSI.DSN = 1
+
+	sgn, sn := -1, 0
+	for i, sub := range subs {
+		if i == 0 || sub.SGN != subs[i-1].SGN {
+			sgn++
+			sn = 0
+		} else {
+			sn++
+		}
+
+		tti := *sub
+		tti.SGN = sgn
+		tti.SN = sn
+		// close the cumulative sets whose first or last subtitle is cut
+		continues := i > 0 && continuesCumulativeSet(subs[i-1], sub)
+		continued := i+1 < len(subs) && continuesCumulativeSet(sub, subs[i+1])
+		switch {
+		case !continues && continued:
+			tti.CS = CumulativeStatusFirst
+		case continues && !continued:
+			tti.CS = CumulativeStatusLast
+		case !continues && !continued:
+			tti.CS = CumulativeStatusNone
+		}
+		s.TTI = append(s.TTI, tti.ExtensionBlocks()...)
+	}
+	s.UpdateCounters()
+	return s
+}
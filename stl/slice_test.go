@@ -0,0 +1,118 @@
+package stl
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSlice(t *testing.T) {
+	f := newTestDiskFile(t, 5, 2)
+	f.GSI.TCP = Timecode{Seconds: 1}
+	f.TTI[0].CS = CumulativeStatusFirst
+	f.TTI[1].CS = CumulativeStatusLast
+
+	type block struct {
+		sn, ebn  int
+		cs       CumulativeStatus
+		tci, tco Timecode
+	}
+	tests := []struct {
+		from, to Timecode
+		expected []block
+		err      error
+	}{
+		{Timecode{Seconds: 2}, Timecode{Seconds: 3}, []block{
+			{0, EBNLastBlock, CumulativeStatusNone, Timecode{Seconds: 2}, Timecode{Seconds: 2, Frames: 20}},
+		}, nil},
+		{Timecode{Seconds: 2, Frames: 10}, Timecode{Seconds: 3, Frames: 5}, []block{
+			{0, EBNLastBlock, CumulativeStatusNone, Timecode{Seconds: 2, Frames: 10}, Timecode{Seconds: 2, Frames: 20}},
+			{1, 0, CumulativeStatusNone, Timecode{Seconds: 3}, Timecode{Seconds: 3, Frames: 5}},
+			{1, 1, CumulativeStatusNone, Timecode{Seconds: 3}, Timecode{Seconds: 3, Frames: 5}},
+			{1, EBNLastBlock, CumulativeStatusNone, Timecode{Seconds: 3}, Timecode{Seconds: 3, Frames: 5}},
+		}, nil},
+		{Timecode{Seconds: 0}, Timecode{Seconds: 2, Frames: 1}, []block{
+			{0, EBNLastBlock, CumulativeStatusFirst, Timecode{Seconds: 1}, Timecode{Seconds: 1, Frames: 20}},
+			{1, EBNLastBlock, CumulativeStatusLast, Timecode{Seconds: 2}, Timecode{Seconds: 2, Frames: 1}},
+		}, nil},
+		{Timecode{Seconds: 8}, Timecode{Seconds: 9}, nil, nil},
+		{Timecode{Seconds: 3}, Timecode{Seconds: 3}, nil, ErrInvalidTimeRange},
+	}
+	for _, test := range tests {
+		s, err := f.Slice(test.from, test.to)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s - %s: expected error %v but got %v", test.from, test.to, test.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if len(s.TTI) != len(test.expected) {
+			t.Errorf("%s - %s: expected %d blocks but got %d", test.from, test.to, len(test.expected), len(s.TTI))
+			continue
+		}
+		for i, e := range test.expected {
+			tti := s.TTI[i]
+			if tti.SGN != 0 || tti.SN != e.sn || tti.EBN != e.ebn || tti.CS != e.cs || tti.TCI != e.tci || tti.TCO != e.tco {
+				t.Errorf("%s - %s: block %d: expected %d/%d %s %s - %s but got %d/%d %s %s - %s", test.from, test.to, i,
+					e.sn, e.ebn, e.cs, e.tci, e.tco, tti.SN, tti.EBN, tti.CS, tti.TCI, tti.TCO)
+			}
+		}
+		if s.GSI.TCP != test.from || s.GSI.TNB != len(test.expected) {
+			t.Errorf("%s - %s: expected TCP %s and TNB %d but got %s and %d", test.from, test.to, test.from, len(test.expected), s.GSI.TCP, s.GSI.TNB)
+		}
+		if len(test.expected) > 0 && s.GSI.TCF != test.expected[0].tci {
+			t.Errorf("%s - %s: expected TCF %s but got %s", test.from, test.to, test.expected[0].tci, s.GSI.TCF)
+		}
+	}
+	if f.TTI[0].CS != CumulativeStatusFirst || f.GSI.TCP != (Timecode{Seconds: 1}) {
+		t.Error("expected the sliced file to be left unchanged")
+	}
+}
+
+func TestSplitByGroup(t *testing.T) {
+	f := newTestDiskFile(t, 4, -1)
+	f.TTI[2].SGN, f.TTI[2].SN = 1, 0
+	f.TTI[3].SGN, f.TTI[3].SN = 1, 1
+
+	files := f.SplitByGroup()
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files but got %d", len(files))
+	}
+	for i, s := range files {
+		if len(s.TTI) != 2 || s.TTI[0].SGN != 0 || s.TTI[1].SGN != 0 || s.TTI[1].SN != 1 {
+			t.Errorf("file %d: expected 2 subtitles of group 0", i)
+		}
+		if s.GSI.TCP != f.TTI[2*i].TCI || s.GSI.TCF != f.TTI[2*i].TCI || s.GSI.TNG != 1 || s.GSI.TNS != 2 {
+			t.Errorf("file %d: expected TCP and TCF %s, TNG 1 and TNS 2 but got %s, %s, %d and %d", i, f.TTI[2*i].TCI,
+				s.GSI.TCP, s.GSI.TCF, s.GSI.TNG, s.GSI.TNS)
+		}
+		warns, err := s.Validate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w := diskWarning(warns); w != nil {
+			t.Errorf("file %d: unexpected warning: %v", i, w)
+		}
+	}
+
+	second := files[1]
+	if err := second.Rebase(Timecode{Hours: 10}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Timecode{{10, 0, 0, 0}, {10, 0, 1, 0}}
+	for i, tci := range expected {
+		if second.TTI[i].TCI != tci {
+			t.Errorf("expected rebased TCI %s but got %s", tci, second.TTI[i].TCI)
+		}
+	}
+	if second.GSI.TCP != expected[0] || second.GSI.TCF != expected[0] {
+		t.Errorf("expected TCP and TCF %s but got %s and %s", expected[0], second.GSI.TCP, second.GSI.TCF)
+	}
+	if err := second.Rebase(Timecode{}); err != nil {
+		t.Fatal(err)
+	}
+	second.GSI.TCP = Timecode{Seconds: 5}
+	if err := second.Rebase(Timecode{}); !errors.Is(err, ErrNegativeTimecode) {
+		t.Errorf("expected error %q but got %v", ErrNegativeTimecode, err)
+	}
+}
@@ -0,0 +1,86 @@
+package stl
+
+import "unicode/utf8"
+
+// EBN values with a special meaning.
+const (
+	EBNLastBlock     = 0xFF // Last (or only) TTI block of a subtitle
+	EBNUserDataBlock = 0xFE // TTI block containing User Data
+)
+
+// Subtitles returns the subtitles of the file, one TTI block per subtitle.
+// Extension blocks of a subtitle are merged into a single block holding the
+// whole Text Field (TF), the timing and positioning of the first block.
+// User data blocks (EBN 0xFE) are skipped.
+// The TTI blocks of the file are left unchanged.
+func (f *File) Subtitles() []*TTIBlock {
+	var subs []*TTIBlock
+	var cur *TTIBlock
+	for _, tti := range f.TTI {
+		if tti == nil || tti.EBN == EBNUserDataBlock {
+			continue
+		}
+		if cur != nil && cur.EBN != EBNLastBlock && tti.SGN == cur.SGN && tti.SN == cur.SN {
+			cur.TF += tti.TF
+			cur.EBN = tti.EBN
+			continue
+		}
+		c := *tti
+		cur = &c
+		subs = append(subs, cur)
+	}
+	return subs
+}
+
+// TFSize is the size in bytes of the Text Field (TF) of a TTI block.
+const TFSize = 112
+
+// ExtensionBlocks splits a subtitle whose Text Field (TF) does not fit in a
+// single TTI block into extension blocks, numbered from 0 with the last
+// one numbered 0xFF. Other fields are copied to each block. Characters are
+// kept whole: ISO 6937 diacritical marks stay with their letter and UTF-8
+// encoded characters are not split.
+// A subtitle that fits in a single block is returned as is, with EBN 0xFF.
+// It is the reverse operation of File.Subtitles.
+func (tti *TTIBlock) ExtensionBlocks() []*TTIBlock {
+	var blocks []*TTIBlock
+	tf := []byte(tti.TF)
+	for ebn := 0; ; ebn++ {
+		n := len(tf)
+		if n > TFSize {
+			n = splitTF(tf)
+		}
+		block := *tti
+		block.TF = string(tf[:n])
+		block.EBN = ebn
+		tf = tf[n:]
+		if len(tf) == 0 {
+			block.EBN = EBNLastBlock
+		}
+		blocks = append(blocks, &block)
+		if len(tf) == 0 {
+			return blocks
+		}
+	}
+}
+
+// splitTF returns the length of the first extension block of tf, longer
+// than TFSize: TFSize, shortened not to split a character.
+func splitTF(tf []byte) int {
+	n := TFSize
+	// do not split an ISO 6937 diacritical mark from its letter
+	if tf[n-1] >= 0xC1 && tf[n-1] <= 0xCF {
+		return n - 1
+	}
+	// do not split a UTF-8 encoded character
+	start := n
+	for start > n-utf8.UTFMax && !utf8.RuneStart(tf[start]) {
+		start--
+	}
+	if start < n {
+		if r, size := utf8.DecodeRune(tf[start:]); r != utf8.RuneError && start+size > n {
+			return start
+		}
+	}
+	return n
+}
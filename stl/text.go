@@ -0,0 +1,171 @@
+package stl
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// TextStyle is the presentation state in effect for a run of text of a
+// Text Field (TF).
+type TextStyle struct {
+	Italic       bool          // Italic (open subtitling)
+	Underline    bool          // Underline (open subtitling)
+	Boxing       bool          // Boxing (open subtitling) or start box (teletext)
+	DoubleHeight bool          // Double height (teletext)
+	Flash        bool          // Flash (teletext)
+	Foreground   TeletextColor // Foreground color (teletext)
+	Background   TeletextColor // Background color (teletext)
+}
+
+// DefaultTextStyle is the style in effect at the start of a Text Field (TF).
+var DefaultTextStyle = TextStyle{
+	Foreground: TeletextColorWhite,
+	Background: TeletextColorBlack,
+}
+
+// TextRun is a run of UTF-8 decoded text sharing the same style.
+type TextRun struct {
+	Text   string    // UTF-8 decoded text
+	Style  TextStyle // Style of the text
+	Column int       // Character cell at which the run starts in the row
+}
+
+// TextRow is a row of text of a Text Field (TF), rows are separated by
+// line break control codes (0x8A).
+type TextRow []TextRun
+
+// String returns the text of the row without styling.
+func (row TextRow) String() string {
+	var s string
+	for _, run := range row {
+		s += run.Text
+	}
+	return s
+}
+
+// Rows decodes the Text Field (TF) into rows of styled runs of UTF-8 text.
+// The Text Field is decoded with the character code table before control
+// codes are told apart from characters, the bytes of multi-byte characters
+// (UTF-8 tables) not being taken for control codes.
+// Teletext spacing attributes (0x00..0x1F) reset at the start of each row
+// while open subtitling attributes (italic, underline, boxing) carry on to
+// the next row until switched off, as they do on screen.
+// Empty rows are kept, as they are meaningful for vertical positioning
+// (e.g. between two double height rows).
+func (tti *TTIBlock) Rows(cct CharacterCodeTable) ([]TextRow, error) {
+	dec, ok := CharacterCodeTableDecoders[cct]
+	if !ok {
+		return nil, fmt.Errorf("unsupported character code table %d", cct)
+	}
+	b, err := dec.Decode([]byte(tti.TF))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []TextRow
+	var row TextRow
+	var buf []byte
+	style := DefaultTextStyle
+	runStyle := style
+	column, runColumn := 0, 0
+	boxing, teletextBoxing := false, false
+
+	flush := func() {
+		if len(buf) == 0 {
+			return
+		}
+		text := norm.NFC.String(string(buf))
+		if n := len(row); n > 0 && row[n-1].Style == runStyle && row[n-1].Column+len([]rune(row[n-1].Text)) == runColumn {
+			row[n-1].Text += text
+		} else {
+			row = append(row, TextRun{Text: text, Style: runStyle, Column: runColumn})
+		}
+		column = runColumn + len([]rune(text))
+		buf = buf[:0]
+	}
+
+	// decoders keep the control codes as single bytes, which are not
+	// valid UTF-8
+	for i := 0; i < len(b); {
+		c := b[i]
+		r, n := utf8.DecodeRune(b[i:])
+		switch {
+		case c <= 0x1F: // teletext spacing attribute, occupies one character cell
+			flush()
+			switch TeletextControlCode(c) {
+			case TeletextControlCodeStartBox:
+				teletextBoxing = true
+			case TeletextControlCodeEndBox:
+				teletextBoxing = false
+			default:
+				applyTeletextControlCode(&style, TeletextControlCode(c))
+			}
+			style.Boxing = boxing || teletextBoxing
+			column++
+		case c >= 0x80 && c <= 0x9F && r == utf8.RuneError && n == 1:
+			flush()
+			switch ControlCode(c) {
+			case ControlCodeItalicOn:
+				style.Italic = true
+			case ControlCodeItalicOff:
+				style.Italic = false
+			case ControlCodeUnderlineOn:
+				style.Underline = true
+			case ControlCodeUnderlineOff:
+				style.Underline = false
+			case ControlCodeBoxingOn:
+				boxing = true
+			case ControlCodeBoxingOff:
+				boxing = false
+			case ControlCodeLineBreak:
+				rows = append(rows, row)
+				row = nil
+				column = 0
+				// teletext attributes are reset at the start of each row
+				style.Foreground = DefaultTextStyle.Foreground
+				style.Background = DefaultTextStyle.Background
+				style.DoubleHeight = false
+				style.Flash = false
+				teletextBoxing = false
+			}
+			style.Boxing = boxing || teletextBoxing
+		default:
+			if len(buf) == 0 {
+				runStyle = style
+				runColumn = column
+			}
+			buf = append(buf, b[i:i+n]...)
+		}
+		i += n
+	}
+	flush()
+	rows = append(rows, row)
+
+	return rows, nil
+}
+
+func applyTeletextControlCode(style *TextStyle, c TeletextControlCode) {
+	switch {
+	case c <= TeletextControlCodeAlphaWhite:
+		style.Foreground = TeletextColor(c)
+	case c >= TeletextControlCodeMosaicBlack && c <= TeletextControlCodeMosaicWhite:
+		style.Foreground = TeletextColor(c - TeletextControlCodeMosaicBlack)
+	}
+
+	switch c {
+	case TeletextControlCodeFlash:
+		style.Flash = true
+	case TeletextControlCodeSteady:
+		style.Flash = false
+	case TeletextControlCodeNormalHeight:
+		style.DoubleHeight = false
+	case TeletextControlCodeDoubleHeight, TeletextControlCodeDoubleSize:
+		style.DoubleHeight = true
+	case TeletextControlCodeBlackBackground:
+		style.Background = TeletextColorBlack
+	case TeletextControlCodeNewBackground:
+		style.Background = style.Foreground
+	}
+}
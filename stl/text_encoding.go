@@ -1,39 +1,85 @@
 package stl
 
 import (
+	"unicode/utf8"
+
 	"golang.org/x/text/encoding/charmap"
 )
 
 var CodePageNumberEncoders = map[CodePageNumber]TextEncoder{
-	CodePageNumberUnitedStates:   &Charmap{charmap.CodePage437},
-	CodePageNumberMultiLingual:   &Charmap{charmap.CodePage850},
-	CodePageNumberPortugal:       &Charmap{charmap.CodePage860},
-	CodePageNumberCanadianFrench: &Charmap{charmap.CodePage863},
-	CodePageNumberNordic:         &Charmap{charmap.CodePage865},
+	CodePageNumberUnitedStates:   &Charmap{codePage: charmap.CodePage437},
+	CodePageNumberMultiLingual:   &Charmap{codePage: charmap.CodePage850},
+	CodePageNumberPortugal:       &Charmap{codePage: charmap.CodePage860},
+	CodePageNumberCanadianFrench: &Charmap{codePage: charmap.CodePage863},
+	CodePageNumberNordic:         &Charmap{codePage: charmap.CodePage865},
+	CodePageNumberCyrillic:       &Charmap{codePage: charmap.CodePage866},
+	CodePageNumberWindowsLatin1:  &Charmap{codePage: charmap.Windows1252},
 }
 
 var CodePageNumberDecoders = map[CodePageNumber]TextDecoder{
-	CodePageNumberUnitedStates:   &Charmap{charmap.CodePage437},
-	CodePageNumberMultiLingual:   &Charmap{charmap.CodePage850},
-	CodePageNumberPortugal:       &Charmap{charmap.CodePage860},
-	CodePageNumberCanadianFrench: &Charmap{charmap.CodePage863},
-	CodePageNumberNordic:         &Charmap{charmap.CodePage865},
+	CodePageNumberUnitedStates:   &Charmap{codePage: charmap.CodePage437},
+	CodePageNumberMultiLingual:   &Charmap{codePage: charmap.CodePage850},
+	CodePageNumberPortugal:       &Charmap{codePage: charmap.CodePage860},
+	CodePageNumberCanadianFrench: &Charmap{codePage: charmap.CodePage863},
+	CodePageNumberNordic:         &Charmap{codePage: charmap.CodePage865},
+	CodePageNumberCyrillic:       &Charmap{codePage: charmap.CodePage866},
+	CodePageNumberWindowsLatin1:  &Charmap{codePage: charmap.Windows1252},
 }
 
 var CharacterCodeTableEncoders = map[CharacterCodeTable]TextEncoder{
 	CharacterCodeTableLatin:         &ISO6937,
-	CharacterCodeTableLatinCyrillic: &Charmap{charmap.ISO8859_5},
-	CharacterCodeTableLatinArabic:   &Charmap{charmap.ISO8859_6},
-	CharacterCodeTableLatinGreek:    &Charmap{charmap.ISO8859_7},
-	CharacterCodeTableLatinHebrew:   &Charmap{charmap.ISO8859_8},
+	CharacterCodeTableLatinCyrillic: &Charmap{codePage: charmap.ISO8859_5, controls: true},
+	CharacterCodeTableLatinArabic:   &Charmap{codePage: charmap.ISO8859_6, controls: true},
+	CharacterCodeTableLatinGreek:    &Charmap{codePage: charmap.ISO8859_7, controls: true},
+	CharacterCodeTableLatinHebrew:   &Charmap{codePage: charmap.ISO8859_8, controls: true},
+	CharacterCodeTableUTF8:          &UTF8,
 }
 
 var CharacterCodeTableDecoders = map[CharacterCodeTable]TextDecoder{
 	CharacterCodeTableLatin:         &ISO6937,
-	CharacterCodeTableLatinCyrillic: &Charmap{charmap.ISO8859_5},
-	CharacterCodeTableLatinArabic:   &Charmap{charmap.ISO8859_6},
-	CharacterCodeTableLatinGreek:    &Charmap{charmap.ISO8859_7},
-	CharacterCodeTableLatinHebrew:   &Charmap{charmap.ISO8859_8},
+	CharacterCodeTableLatinCyrillic: &Charmap{codePage: charmap.ISO8859_5, controls: true},
+	CharacterCodeTableLatinArabic:   &Charmap{codePage: charmap.ISO8859_6, controls: true},
+	CharacterCodeTableLatinGreek:    &Charmap{codePage: charmap.ISO8859_7, controls: true},
+	CharacterCodeTableLatinHebrew:   &Charmap{codePage: charmap.ISO8859_8, controls: true},
+	CharacterCodeTableUTF8:          &UTF8,
+}
+
+// RegisterCodePage makes the encoder and decoder of a code page available
+// for the strings of the GSI block, replacing those already registered for
+// the number. A nil encoder or decoder is not registered.
+// Code pages are meant to be registered in init functions, as the
+// CodePageNumberEncoders and CodePageNumberDecoders maps are not safe for
+// concurrent use.
+func RegisterCodePage(cpn CodePageNumber, name string, enc TextEncoder, dec TextDecoder) {
+	if enc != nil {
+		CodePageNumberEncoders[cpn] = enc
+	}
+	if dec != nil {
+		CodePageNumberDecoders[cpn] = dec
+	}
+	if name != "" {
+		cpnStringMap[cpn] = name
+	}
+}
+
+// RegisterCharacterCodeTable makes the encoder and decoder of a character
+// code table available for the Text Fields of the TTI blocks, replacing
+// those already registered for the table. A nil encoder or decoder is not
+// registered. Encoders and decoders must keep the TTI control codes
+// (U+0080..U+009F) as single bytes.
+// Tables are meant to be registered in init functions, as the
+// CharacterCodeTableEncoders and CharacterCodeTableDecoders maps are not safe
+// for concurrent use.
+func RegisterCharacterCodeTable(cct CharacterCodeTable, name string, enc TextEncoder, dec TextDecoder) {
+	if enc != nil {
+		CharacterCodeTableEncoders[cct] = enc
+	}
+	if dec != nil {
+		CharacterCodeTableDecoders[cct] = dec
+	}
+	if name != "" {
+		cctStringMap[cct] = name
+	}
 }
 
 // TextDecoder is a decoder for text.
@@ -52,18 +98,116 @@ type TextEncoder interface {
 // It implements the TextDecoder and TextEncoder interfaces.
 type Charmap struct {
 	codePage *charmap.Charmap
+	controls bool // Keep the TTI control codes (0x80..0x9F) as is
 }
 
-// Charmap implements TextDecoder and TextEncoder interfaces.
+// NewCharmap returns a Charmap of the code page, to register additional
+// code pages or character code tables.
+// Character code tables must keep the TTI control codes (0x80..0x9F).
+func NewCharmap(codePage *charmap.Charmap, controls bool) *Charmap {
+	return &Charmap{codePage: codePage, controls: controls}
+}
+
+// Charmap implements TextDecoder and FallbackEncoder interfaces.
 var _ TextDecoder = (*Charmap)(nil)
-var _ TextEncoder = (*Charmap)(nil)
+var _ FallbackEncoder = (*Charmap)(nil)
 
 // Encode encodes b using the charmap.
+// Character code tables keep the runes U+0080..U+009F as TTI control codes.
+// An UnmappableError is returned if runes can not be represented.
 func (c *Charmap) Encode(b []byte) ([]byte, error) {
-	return c.codePage.NewEncoder().Bytes(b)
+	dst, _, err := c.EncodeFallback(b, FallbackError)
+	return dst, err
+}
+
+// EncodeFallback encodes b using the charmap, applying the fallback to the
+// runes which can not be represented.
+// Character code tables keep the runes U+0080..U+009F as TTI control codes.
+func (c *Charmap) EncodeFallback(b []byte, fallback Fallback) ([]byte, []Unmappable, error) {
+	return encodeFallback(b, fallback, func(r rune) ([]byte, bool) {
+		if c.controls && r >= 0x80 && r <= 0x9F {
+			return []byte{byte(r)}, true
+		}
+		if r == utf8.RuneError {
+			return nil, false
+		}
+		x, ok := c.codePage.EncodeRune(r)
+		return []byte{x}, ok
+	})
 }
 
 // Decode decodes b using the charmap.
+// Character code tables keep the TTI control codes (0x80..0x9F) as is.
 func (c *Charmap) Decode(b []byte) ([]byte, error) {
-	return c.codePage.NewDecoder().Bytes(b)
+	if !c.controls {
+		return c.codePage.NewDecoder().Bytes(b)
+	}
+	var dst []byte
+	start := 0
+	for i, x := range b {
+		if x < 0x80 || x > 0x9F {
+			continue
+		}
+		dec, err := c.codePage.NewDecoder().Bytes(b[start:i])
+		if err != nil {
+			return nil, err
+		}
+		dst = append(append(dst, dec...), x)
+		start = i + 1
+	}
+	dec, err := c.codePage.NewDecoder().Bytes(b[start:])
+	if err != nil {
+		return nil, err
+	}
+	return append(dst, dec...), nil
+}
+
+// UTF8 implements the UTF-8 encoding of the non-standard UTF-8 character
+// code table, where the TTI control codes (0x80..0x9F) are single bytes
+// between UTF-8 encoded characters.
+// It implements the TextDecoder and TextEncoder interfaces.
+var UTF8 = utf8Table{}
+
+type utf8Table struct{}
+
+// utf8Table implements TextDecoder and FallbackEncoder.
+var _ TextDecoder = (*utf8Table)(nil)
+var _ FallbackEncoder = (*utf8Table)(nil)
+
+// Encode encodes a UTF-8 byte slice, writing the runes U+0080..U+009F as
+// TTI control codes.
+func (e *utf8Table) Encode(b []byte) ([]byte, error) {
+	dst, _, err := e.EncodeFallback(b, FallbackReplace)
+	return dst, err
+}
+
+// EncodeFallback encodes a UTF-8 byte slice, writing the runes
+// U+0080..U+009F as TTI control codes. Only invalid UTF-8 sequences can not
+// be represented.
+func (e *utf8Table) EncodeFallback(b []byte, fallback Fallback) ([]byte, []Unmappable, error) {
+	return encodeFallback(b, fallback, func(r rune) ([]byte, bool) {
+		if r >= 0x80 && r <= 0x9F {
+			return []byte{byte(r)}, true
+		}
+		if r == utf8.RuneError {
+			return nil, false
+		}
+		return []byte(string(r)), true
+	})
+}
+
+// Decode decodes b, keeping the bytes 0x80..0x9F which do not belong to
+// UTF-8 encoded characters as is, as the TTI control codes of Charmap.
+func (e *utf8Table) Decode(b []byte) ([]byte, error) {
+	var dst []byte
+	for i := 0; i < len(b); {
+		r, n := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError && n == 1 && b[i] >= 0x80 && b[i] <= 0x9F {
+			dst = append(dst, b[i])
+		} else {
+			dst = utf8.AppendRune(dst, r)
+		}
+		i += n
+	}
+	return dst, nil
 }
@@ -0,0 +1,181 @@
+package stl
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestUTF8Table(t *testing.T) {
+	enc := CharacterCodeTableEncoders[CharacterCodeTableUTF8]
+	dec := CharacterCodeTableDecoders[CharacterCodeTableUTF8]
+
+	text := "\u0084Привет\u008a日本\u0085"
+	expected := []byte("\x84Привет\x8a日本\x85")
+	b, err := enc.Encode([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, expected) {
+		t.Errorf("expected %q but got %q", expected, b)
+	}
+
+	b, err = dec.Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, expected) {
+		t.Errorf("expected %q but got %q", expected, b)
+	}
+}
+
+func newTestGSIBytes(t *testing.T, cpn CodePageNumber, opt []byte) []byte {
+	t.Helper()
+	gsi := NewGSIBlock()
+	gsi.SetDefaults(25, DisplayStandardCodeLevel1Teletext)
+	var buf bytes.Buffer
+	if err := gsi.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	encodeGSIInt(b[0:3], int(cpn))
+	copy(b[16:48], bytes.Repeat([]byte{' '}, 32))
+	copy(b[16:48], opt)
+	return b
+}
+
+func TestDecodeGSINonStandardCodePages(t *testing.T) {
+	tests := []struct {
+		cpn      CodePageNumber
+		opt      []byte
+		expected string
+	}{
+		{CodePageNumberWindowsLatin1, []byte("\x93Caf\xe9\x94 \x80"), "“Café” €"},
+		{CodePageNumberCyrillic, []byte("\x8f\xe0\xa8\xa2\xa5\xe2"), "Привет"},
+	}
+	for _, test := range tests {
+		gsi := NewGSIBlock()
+		warns, err := gsi.Decode(bytes.NewReader(newTestGSIBytes(t, test.cpn, test.opt)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(warns) != 0 {
+			t.Errorf("CPN %d: expected no warnings but got %v", test.cpn, warns)
+		}
+		if gsi.OPT != test.expected {
+			t.Errorf("CPN %d: expected OPT %q but got %q", test.cpn, test.expected, gsi.OPT)
+		}
+
+		warns, err = gsi.Validate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, w := range warns {
+			var vErr *ValidateError
+			if errors.Is(w, ErrUnsupportedCPN) || (errors.As(w, &vErr) && vErr.IsFatal()) {
+				t.Errorf("CPN %d: expected non-fatal warnings but got %v", test.cpn, w)
+			}
+		}
+		if !containsErr(warns, ErrNonStandardCPN) {
+			t.Errorf("CPN %d: expected %q warning but got %v", test.cpn, ErrNonStandardCPN, warns)
+		}
+	}
+}
+
+func TestDecodeGSIUnknownCodePage(t *testing.T) {
+	gsi := NewGSIBlock()
+	warns, err := gsi.Decode(bytes.NewReader(newTestGSIBytes(t, 437+1, []byte("Programme"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) != 1 || !errors.Is(warns[0], ErrUnsupportedGSICodePage) {
+		t.Fatalf("expected one %q warning but got %v", ErrUnsupportedGSICodePage, warns)
+	}
+	if gsi.OPT != "Programme" {
+		t.Errorf("expected OPT decoded with the Multilingual code page but got %q", gsi.OPT)
+	}
+}
+
+func TestRegisterCharacterCodeTable(t *testing.T) {
+	const cct CharacterCodeTable = 0x05
+	defer func() {
+		delete(CharacterCodeTableEncoders, cct)
+		delete(CharacterCodeTableDecoders, cct)
+		delete(cctStringMap, cct)
+	}()
+
+	tti := NewTTIBlock()
+	if err := tti.SetText("ĄĘ", cct); err == nil {
+		t.Error("expected error for unregistered CCT")
+	}
+
+	latin2 := NewCharmap(charmap.ISO8859_2, true)
+	RegisterCharacterCodeTable(cct, "Latin-2", latin2, latin2)
+	if cct.String() != "Latin-2" {
+		t.Errorf("expected name Latin-2 but got %q", cct.String())
+	}
+	if err := tti.SetText("\u0084ĄĘ", cct); err != nil {
+		t.Fatal(err)
+	}
+	if tti.TF != "\x84\xa1\xca" {
+		t.Errorf("expected TF %q but got %q", "\x84\xa1\xca", tti.TF)
+	}
+	if text, err := tti.Text(cct); err != nil || text != "\x84ĄĘ" {
+		t.Errorf("expected text %q but got %q, %v", "\x84ĄĘ", text, err)
+	}
+
+	gsi := NewGSIBlock()
+	gsi.SetDefaults(25, DisplayStandardCodeLevel1Teletext)
+	gsi.CCT = cct
+	warns, _ := gsi.Validate()
+	if containsErr(warns, ErrUnsupportedCCT) || !containsErr(warns, ErrNonStandardCCT) {
+		t.Errorf("expected %q warning but got %v", ErrNonStandardCCT, warns)
+	}
+}
+
+func containsErr(errs []error, target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNonStandardTablesRoundTrip(t *testing.T) {
+	f := newTestDiskFile(t, 1, -1)
+	f.GSI.CPN = CodePageNumberWindowsLatin1
+	f.GSI.CCT = CharacterCodeTableUTF8
+	f.GSI.OPT = "“Café” €"
+	text := "\x0b\x0bПривет\u008a\x0b\x0b\u0080日本語\u0081"
+	if err := f.TTI[0].SetText(text, f.GSI.CCT); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got := NewFile()
+	if _, err := got.Decode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got.GSI.CPN != CodePageNumberWindowsLatin1 || got.GSI.CCT != CharacterCodeTableUTF8 {
+		t.Errorf("expected CPN %s and CCT %s but got %s and %s", CodePageNumberWindowsLatin1, CharacterCodeTableUTF8, got.GSI.CPN, got.GSI.CCT)
+	}
+	if got.GSI.OPT != f.GSI.OPT {
+		t.Errorf("expected OPT %q but got %q", f.GSI.OPT, got.GSI.OPT)
+	}
+	if got.TTI[0].TF != f.TTI[0].TF {
+		t.Errorf("expected TF %q but got %q", f.TTI[0].TF, got.TTI[0].TF)
+	}
+	rows, err := got.TTI[0].Rows(got.GSI.CCT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].String() != "Привет" || rows[1].String() != "日本語" {
+		t.Errorf("expected rows %q and %q but got %q", "Привет", "日本語", rows)
+	}
+}
@@ -0,0 +1,195 @@
+package stl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Fallback is the policy of text encoders for the runes which can not be
+// represented in their encoding.
+type Fallback int
+
+const (
+	FallbackReplace       Fallback = iota // Replace the rune by '?'
+	FallbackError                         // Fail with an UnmappableError
+	FallbackTransliterate                 // Transliterate the rune, replace it by '?' if not possible
+)
+
+// String returns the name of the fallback.
+func (f Fallback) String() string {
+	switch f {
+	case FallbackReplace:
+		return "replace"
+	case FallbackError:
+		return "error"
+	case FallbackTransliterate:
+		return "transliterate"
+	}
+	return "<invalid>"
+}
+
+// Transliterations are the replacements tried by FallbackTransliterate for
+// runes which can not be represented. Runes without replacement, or whose
+// replacement can not be represented either, are decomposed and stripped of
+// their diacritics, e.g. "ő" gives "o".
+var Transliterations = map[rune]string{
+	'‐': "-",   // hyphen
+	'‑': "-",   // non-breaking hyphen
+	'‒': "-",   // figure dash
+	'–': "-",   // en dash
+	'—': "-",   // em dash
+	'―': "-",   // horizontal bar
+	'‘': "'",   // left single quotation mark
+	'’': "'",   // right single quotation mark
+	'‚': ",",   // single low-9 quotation mark
+	'‛': "'",   // single high-reversed-9 quotation mark
+	'“': "\"",  // left double quotation mark
+	'”': "\"",  // right double quotation mark
+	'„': "\"",  // double low-9 quotation mark
+	'‟': "\"",  // double high-reversed-9 quotation mark
+	'…': "...", // horizontal ellipsis
+	'‹': "<",   // single left-pointing angle quotation mark
+	'›': ">",   // single right-pointing angle quotation mark
+	'«': "\"",  // left-pointing double angle quotation mark
+	'»': "\"",  // right-pointing double angle quotation mark
+	'•': "-",   // bullet
+	' ': " ",   // no-break space
+	' ': " ",   // thin space
+	' ': " ",   // narrow no-break space
+	'♪': "#",   // eighth note
+	'♫': "#",   // beamed eighth notes
+	'Æ': "AE",  // latin capital letter ae
+	'æ': "ae",  // latin small letter ae
+	'Œ': "OE",  // latin capital ligature oe
+	'œ': "oe",  // latin small ligature oe
+	'ß': "ss",  // latin small letter sharp s
+	'Ø': "O",   // latin capital letter o with stroke
+	'ø': "o",   // latin small letter o with stroke
+	'Ł': "L",   // latin capital letter l with stroke
+	'ł': "l",   // latin small letter l with stroke
+	'€': "EUR", // euro sign
+}
+
+// ErrUnmappableRune is the error of runes which can not be represented in
+// an encoding.
+var ErrUnmappableRune = errors.New("unmappable rune")
+
+// Unmappable is a rune of a UTF-8 text which can not be represented in an
+// encoding.
+type Unmappable struct {
+	Rune   rune // Rune which can not be represented
+	Offset int  // Byte offset of the rune in the UTF-8 text
+}
+
+// String returns the rune and its offset.
+func (u Unmappable) String() string {
+	return fmt.Sprintf("%U %q at %d", u.Rune, u.Rune, u.Offset)
+}
+
+// UnmappableError is an error carrying the runes of a text which can not be
+// represented in an encoding.
+// It matches ErrUnmappableRune with errors.Is.
+type UnmappableError struct {
+	Runes []Unmappable
+}
+
+// UnmappableError implements error interface.
+var _ error = (*UnmappableError)(nil)
+
+// Error returns the error message.
+func (e *UnmappableError) Error() string {
+	runes := make([]string, len(e.Runes))
+	for i, u := range e.Runes {
+		runes[i] = u.String()
+	}
+	return fmt.Sprintf("%s: %s", ErrUnmappableRune, strings.Join(runes, ", "))
+}
+
+// Is reports whether target is ErrUnmappableRune.
+func (e *UnmappableError) Is(target error) bool {
+	return target == ErrUnmappableRune
+}
+
+// FallbackEncoder is a TextEncoder with a fallback policy for the runes it
+// can not represent.
+type FallbackEncoder interface {
+	TextEncoder
+	// EncodeFallback encodes a UTF-8 byte slice to a X-encoded byte slice.
+	// The fallback is applied to the runes which can not be represented,
+	// which are returned.
+	EncodeFallback(b []byte, fallback Fallback) ([]byte, []Unmappable, error)
+}
+
+// encodeFallback encodes the runes of src with encodeRune, which returns
+// false for runes which can not be represented, and applies the fallback
+// to them.
+func encodeFallback(src []byte, fallback Fallback, encodeRune func(r rune) ([]byte, bool)) ([]byte, []Unmappable, error) {
+	var dst []byte
+	var unmappables []Unmappable
+	for i := 0; i < len(src); {
+		r, n := utf8.DecodeRune(src[i:])
+		if b, ok := encodeRune(r); ok {
+			dst = append(dst, b...)
+			i += n
+			continue
+		}
+
+		unmappables = append(unmappables, Unmappable{Rune: r, Offset: i})
+		i += n
+		switch fallback {
+		case FallbackError:
+			continue
+		case FallbackTransliterate:
+			if b, ok := transliterate(r, encodeRune); ok {
+				dst = append(dst, b...)
+				continue
+			}
+		}
+		dst = append(dst, '?')
+	}
+
+	if fallback == FallbackError && len(unmappables) > 0 {
+		return nil, unmappables, &UnmappableError{Runes: unmappables}
+	}
+	return dst, unmappables, nil
+}
+
+// transliterate encodes the replacement of r from Transliterations, or r
+// stripped of its diacritics.
+func transliterate(r rune, encodeRune func(r rune) ([]byte, bool)) ([]byte, bool) {
+	if s, ok := Transliterations[r]; ok {
+		if b, ok := encodeString(s, encodeRune); ok {
+			return b, true
+		}
+	}
+
+	var base []rune
+	for _, d := range norm.NFKD.String(string(r)) {
+		if !unicode.Is(unicode.Mn, d) {
+			base = append(base, d)
+		}
+	}
+	if len(base) == 0 || (len(base) == 1 && base[0] == r) {
+		return nil, false
+	}
+	return encodeString(string(base), encodeRune)
+}
+
+// encodeString encodes all the runes of s, false if one can not be
+// represented.
+func encodeString(s string, encodeRune func(r rune) ([]byte, bool)) ([]byte, bool) {
+	var dst []byte
+	for _, r := range s {
+		b, ok := encodeRune(r)
+		if !ok {
+			return nil, false
+		}
+		dst = append(dst, b...)
+	}
+	return dst, true
+}
@@ -0,0 +1,113 @@
+package stl
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type encodeFallbackTest struct {
+	v           string
+	cct         CharacterCodeTable
+	fallback    Fallback
+	expected    []byte
+	unmappables []Unmappable
+	err         error
+}
+
+var encodeFallbackTests = []encodeFallbackTest{
+	{"a—b", CharacterCodeTableLatin, FallbackReplace, []byte("a?b"), []Unmappable{{'—', 1}}, nil},
+	{"a—b", CharacterCodeTableLatin, FallbackTransliterate, []byte("a-b"), []Unmappable{{'—', 1}}, nil},
+	{"a—b", CharacterCodeTableLatin, FallbackError, nil, []Unmappable{{'—', 1}}, ErrUnmappableRune},
+	{"ő", CharacterCodeTableLatin, FallbackError, []byte{0xCD, 'o'}, nil, nil},
+	{"wait…", CharacterCodeTableLatin, FallbackTransliterate, []byte("wait..."), []Unmappable{{'…', 4}}, nil},
+	{"ạ\U0001F600", CharacterCodeTableLatin, FallbackTransliterate, []byte("a?"), []Unmappable{{'ạ', 0}, {'\U0001F600', 3}}, nil},
+	{"“ő”", CharacterCodeTableLatinCyrillic, FallbackTransliterate, []byte("\"o\""), []Unmappable{{'“', 0}, {'ő', 3}, {'”', 5}}, nil},
+	{"д\u008aé", CharacterCodeTableLatinCyrillic, FallbackReplace, []byte{0xD4, 0x8A, '?'}, []Unmappable{{'é', 4}}, nil},
+	{"é", CharacterCodeTableLatinCyrillic, FallbackError, nil, []Unmappable{{'é', 0}}, ErrUnmappableRune},
+}
+
+func TestEncodeFallback(t *testing.T) {
+	for _, test := range encodeFallbackTests {
+		enc := CharacterCodeTableEncoders[test.cct].(FallbackEncoder)
+		b, unmappables, err := enc.EncodeFallback([]byte(test.v), test.fallback)
+		if !errors.Is(err, test.err) {
+			t.Errorf("EncodeFallback(%q, %s) error = %v, want %v", test.v, test.fallback, err, test.err)
+		}
+		if !bytes.Equal(b, test.expected) {
+			t.Errorf("EncodeFallback(%q, %s) = %q, want %q", test.v, test.fallback, b, test.expected)
+		}
+		if !reflect.DeepEqual(unmappables, test.unmappables) {
+			t.Errorf("EncodeFallback(%q, %s) unmappables = %v, want %v", test.v, test.fallback, unmappables, test.unmappables)
+		}
+	}
+}
+
+func TestCharmapEncodeError(t *testing.T) {
+	_, err := CharacterCodeTableEncoders[CharacterCodeTableLatinGreek].Encode([]byte("αβжx"))
+	var uErr *UnmappableError
+	if !errors.As(err, &uErr) {
+		t.Fatalf("expected UnmappableError but got %v", err)
+	}
+	if want := []Unmappable{{'ж', 4}}; !reflect.DeepEqual(uErr.Runes, want) {
+		t.Errorf("expected %v but got %v", want, uErr.Runes)
+	}
+}
+
+func TestGSIEncodeFallback(t *testing.T) {
+	gsi := NewGSIBlock()
+	gsi.SetDefaults(25, DisplayStandardCodeLevel1Teletext)
+	gsi.OPT = "Łódź — part 1"
+
+	var buf bytes.Buffer
+	if err := gsi.Encode(&buf); !errors.Is(err, ErrInvalidGSIStringValue) {
+		t.Errorf("expected %q error but got %v", ErrInvalidGSIStringValue, err)
+	}
+
+	buf.Reset()
+	warns, err := gsi.EncodeFallback(&buf, FallbackTransliterate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) != 1 || !errors.Is(warns[0], ErrUnmappableRune) {
+		t.Fatalf("expected one %q warning but got %v", ErrUnmappableRune, warns)
+	}
+	if opt := buf.Bytes()[16:48]; !bytes.Equal(opt, []byte("L\xa2dz - part 1                   ")) {
+		t.Errorf("expected transliterated OPT but got %q", opt)
+	}
+}
+
+func TestSetTextFallback(t *testing.T) {
+	tti := NewTTIBlock()
+	unmappables, err := tti.SetTextFallback("It’s 5€", CharacterCodeTableLatin, FallbackTransliterate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Unmappable{{'€', 8}}; !reflect.DeepEqual(unmappables, want) {
+		t.Errorf("expected %v but got %v", want, unmappables)
+	}
+	if want := "It\xb9s 5EUR"; tti.TF != want {
+		t.Errorf("expected %q but got %q", want, tti.TF)
+	}
+}
+
+func TestSetTextUnmappable(t *testing.T) {
+	tti := NewTTIBlock()
+	tti.TF = "before"
+	err := tti.SetText("5€ ♥", CharacterCodeTableLatin)
+	var unmappableErr *UnmappableError
+	if !errors.As(err, &unmappableErr) || !errors.Is(err, ErrUnmappableRune) {
+		t.Fatalf("expected an unmappable rune error but got %v", err)
+	}
+	if want := []Unmappable{{'€', 1}, {'♥', 5}}; !reflect.DeepEqual(unmappableErr.Runes, want) {
+		t.Errorf("expected %v but got %v", want, unmappableErr.Runes)
+	}
+	if want := "before"; tti.TF != want {
+		t.Errorf("expected Text Field %q left unchanged but got %q", want, tti.TF)
+	}
+
+	if err := tti.SetText("5 ♪", CharacterCodeTableLatin); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
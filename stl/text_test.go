@@ -0,0 +1,84 @@
+package stl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+type rowsTest struct {
+	tf     string
+	output []TextRow
+}
+
+var italic = TextStyle{Italic: true, Foreground: TeletextColorWhite, Background: TeletextColorBlack}
+var yellowDoubleHeightBox = TextStyle{Boxing: true, DoubleHeight: true, Foreground: TeletextColorYellow, Background: TeletextColorBlack}
+
+var rowsTests = []rowsTest{
+	{"abc", []TextRow{{{Text: "abc", Style: DefaultTextStyle}}}},
+	{"ab\x8Acd", []TextRow{{{Text: "ab", Style: DefaultTextStyle}}, {{Text: "cd", Style: DefaultTextStyle, Column: 0}}}},
+	{"a\x80b\x8Ac\x81d", []TextRow{
+		{{Text: "a", Style: DefaultTextStyle}, {Text: "b", Style: italic, Column: 1}},
+		{{Text: "c", Style: italic}, {Text: "d", Style: DefaultTextStyle, Column: 1}},
+	}},
+	{"\x0D\x03\x0B\x0Bab\x0A\x0A\x8A\x8A\x0Dc", []TextRow{
+		{{Text: "ab", Style: yellowDoubleHeightBox, Column: 4}},
+		nil,
+		{{Text: "c", Style: TextStyle{DoubleHeight: true, Foreground: TeletextColorWhite, Background: TeletextColorBlack}, Column: 1}},
+	}},
+	{"\xC2e\x8F\x8F", []TextRow{{{Text: "é", Style: DefaultTextStyle}}}},
+}
+
+func TestTTIRows(t *testing.T) {
+	for _, test := range rowsTests {
+		tti := NewTTIBlock()
+		tti.TF = test.tf
+		rows, err := tti.Rows(CharacterCodeTableLatin)
+		if err != nil {
+			t.Errorf("Rows(%q) unexpected error: %s", test.tf, err)
+			continue
+		}
+		if !reflect.DeepEqual(rows, test.output) {
+			t.Errorf("Rows(%q) = %+v, want %+v", test.tf, rows, test.output)
+		}
+	}
+}
+
+func TestTTIRowsUTF8(t *testing.T) {
+	// 'Ё' is encoded across the end of the first Text Field, and the
+	// letters of "Привет" hold the bytes 0x80..0x9F of control codes
+	first := "a" + strings.Repeat("ж", 55) + "Ё"
+	tti := NewTTIBlock()
+	if err := tti.SetText(first+"\u008a\u0080Привет\u0081 мир", CharacterCodeTableUTF8); err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFile()
+	f.GSI = NewGSIBlock()
+	f.GSI.SetDefaults(25, DisplayStandardCodeLevel1Teletext)
+	f.GSI.CCT = CharacterCodeTableUTF8
+	f.TTI = tti.ExtensionBlocks()
+	if len(f.TTI) != 2 {
+		t.Fatalf("expected 2 extension blocks but got %d", len(f.TTI))
+	}
+	if !utf8.ValidString(f.TTI[0].TF) || f.TTI[0].TF != first[:len(first)-len("Ё")] {
+		t.Errorf("expected the first block to end before 'Ё' but got %q", f.TTI[0].TF)
+	}
+
+	subs := f.Subtitles()
+	if len(subs) != 1 {
+		t.Fatalf("expected 1 subtitle but got %d", len(subs))
+	}
+	rows, err := subs[0].Rows(f.GSI.CCT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TextRow{
+		{{Text: first, Style: DefaultTextStyle}},
+		{{Text: "Привет", Style: italic}, {Text: " мир", Style: DefaultTextStyle, Column: 6}},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got %+v, want %+v", rows, want)
+	}
+}
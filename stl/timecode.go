@@ -18,6 +18,21 @@ func (t Timecode) String() string {
 	return fmt.Sprintf("%02d:%02d:%02d:%02d", t.Hours, t.Minutes, t.Seconds, t.Frames)
 }
 
+// MarshalText returns the string representation of the timecode.
+func (t Timecode) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText parses a timecode in its string representation.
+func (t *Timecode) UnmarshalText(b []byte) error {
+	var tc Timecode
+	if _, err := fmt.Sscanf(string(b), "%d:%d:%d:%d", &tc.Hours, &tc.Minutes, &tc.Seconds, &tc.Frames); err != nil {
+		return fmt.Errorf("invalid timecode %q: %w", b, err)
+	}
+	*t = tc
+	return nil
+}
+
 // ToFrames returns the total number of frames.
 func (t Timecode) ToFrames(framerate uint) int {
 	return t.Hours*3600*int(framerate) + t.Minutes*60*int(framerate) + t.Seconds*int(framerate) + t.Frames
@@ -74,3 +89,24 @@ func (t Timecode) Validate(framerate uint) error {
 	}
 	return nil
 }
+
+// Drop frame timecodes (29.97 fps) skip frame numbers 0 and 1 of each
+// minute, except every tenth minute.
+
+// ToDropFrames returns the total number of frames of a 29.97 fps drop frame
+// timecode.
+func (t Timecode) ToDropFrames() int {
+	minutes := t.Hours*60 + t.Minutes
+	return t.ToFrames(30) - 2*(minutes-minutes/10)
+}
+
+// TimecodeFromDropFrames returns a 29.97 fps drop frame timecode from the
+// given number of frames.
+func TimecodeFromDropFrames(frames int) Timecode {
+	d, m := frames/17982, frames%17982
+	frames += 18 * d
+	if m >= 2 {
+		frames += 2 * ((m - 2) / 1798)
+	}
+	return TimecodeFromFrames(frames, 30)
+}
@@ -43,9 +43,18 @@ func (tti *TTIBlock) Text(cct CharacterCodeTable) (string, error) {
 }
 
 // SetText sets the Text Field (TF) from the UTF-8 encoded text.
+// The Text Field is left unchanged if the text holds runes which can not be
+// represented in the character code table, returned as an UnmappableError
+// by a FallbackEncoder. SetTextFallback encodes them with a fallback.
 func (tti *TTIBlock) SetText(text string, cct CharacterCodeTable) error {
 	if enc, ok := CharacterCodeTableEncoders[cct]; ok {
-		b, err := enc.Encode([]byte(text))
+		var b []byte
+		var err error
+		if fEnc, ok := enc.(FallbackEncoder); ok {
+			b, _, err = fEnc.EncodeFallback([]byte(text), FallbackError)
+		} else {
+			b, err = enc.Encode([]byte(text))
+		}
 		if err != nil {
 			return err
 		}
@@ -55,6 +64,27 @@ func (tti *TTIBlock) SetText(text string, cct CharacterCodeTable) error {
 	return fmt.Errorf("unsupported character code table %d", cct)
 }
 
+// SetTextFallback sets the Text Field (TF) from the UTF-8 encoded text,
+// applying the fallback to the runes which can not be represented in the
+// character code table. These runes are returned with their offset in text.
+// Encoders which are not FallbackEncoder are used as with SetText.
+func (tti *TTIBlock) SetTextFallback(text string, cct CharacterCodeTable, fallback Fallback) ([]Unmappable, error) {
+	enc, ok := CharacterCodeTableEncoders[cct]
+	if !ok {
+		return nil, fmt.Errorf("unsupported character code table %d", cct)
+	}
+	fEnc, ok := enc.(FallbackEncoder)
+	if !ok {
+		return nil, tti.SetText(text, cct)
+	}
+	b, unmappables, err := fEnc.EncodeFallback([]byte(text), fallback)
+	if err != nil {
+		return unmappables, err
+	}
+	tti.TF = string(b)
+	return unmappables, nil
+}
+
 // Reset resets the TTI block to its default values.
 func (tti *TTIBlock) Reset() {
 	tti.SGN = -1
@@ -69,7 +69,7 @@ func encodeTTIInt(b []byte, v int) {
 	} else {
 		binary.LittleEndian.PutUint64(c, uint64(v))
 	}
-	copy(b, c[8-len(b):])
+	copy(b, c[:len(b)])
 }
 
 func decodeTTIString(b []byte, v *string) {
@@ -0,0 +1,56 @@
+package stl
+
+import (
+	"bytes"
+	"testing"
+)
+
+type encodeTTIIntTest struct {
+	length int
+	input  int
+	output []byte
+}
+
+var encodeTTIIntTests = []encodeTTIIntTest{
+	{1, 0, []byte{0x00}},
+	{1, 0xFF, []byte{0xFF}},
+	{2, 0x1234, []byte{0x34, 0x12}},
+	{2, -1, []byte{0x00, 0x00}},
+}
+
+func TestEncodeTTIInt(t *testing.T) {
+	for _, test := range encodeTTIIntTests {
+		b := make([]byte, test.length)
+		encodeTTIInt(b, test.input)
+		if !bytes.Equal(b, test.output) {
+			t.Errorf("expected % X but got % X", test.output, b)
+		}
+
+		var v int
+		decodeTTIInt(b, &v)
+		if test.input >= 0 && v != test.input {
+			t.Errorf("expected %d but got %d", test.input, v)
+		}
+	}
+}
+
+func TestEncodeTTIBlockNumbers(t *testing.T) {
+	tti := NewTTIBlock()
+	tti.SGN, tti.SN, tti.EBN = 1, 0x0203, EBNLastBlock
+
+	var buf bytes.Buffer
+	if err := tti.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if b := buf.Bytes()[:3]; !bytes.Equal(b, []byte{0x01, 0x03, 0x02}) {
+		t.Errorf("expected SGN and SN 01 03 02 but got % X", b)
+	}
+
+	decoded := NewTTIBlock()
+	if err := decoded.Decode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.SGN != tti.SGN || decoded.SN != tti.SN {
+		t.Errorf("expected SGN %d SN %d but got SGN %d SN %d", tti.SGN, tti.SN, decoded.SGN, decoded.SN)
+	}
+}
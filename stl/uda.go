@@ -0,0 +1,124 @@
+package stl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// UDASize is the size in bytes of the User-Defined Area (UDA) of the GSI
+// block.
+const UDASize = 576
+
+var (
+	ErrUnknownUDAProfile = errors.New("unknown UDA profile")
+	ErrNoUDAProfile      = errors.New("no UDA profile matches the UDA")
+	ErrUDATooLong        = errors.New("UDA too long")
+)
+
+// UDAField is a named value of the data of a User-Defined Area (UDA).
+type UDAField struct {
+	Name  string
+	Value string
+}
+
+// UDAData is the data of a User-Defined Area (UDA) decoded by a profile,
+// typically a struct of the profile package.
+type UDAData interface {
+	// Fields returns the values of the data, in the order of the area.
+	Fields() []UDAField
+	// Encode returns the User-Defined Area of the data, at most UDASize
+	// bytes.
+	Encode() ([]byte, error)
+}
+
+// UDAProfile decodes and encodes the structured data some broadcasters
+// store in the User-Defined Area (UDA) of the GSI block.
+// No profile is included in this module: profiles of broadcaster layouts
+// (e.g. ARD or ZDF) are registered by their own packages. Without a
+// registered profile, the UDA is kept as raw bytes.
+type UDAProfile interface {
+	// Name returns the name of the profile, e.g. "zdf".
+	Name() string
+	// Match reports whether uda holds data of the profile.
+	Match(uda []byte) bool
+	// Decode decodes the data of uda.
+	Decode(uda []byte) (UDAData, error)
+	// FromFields returns the data of the fields returned by the Fields
+	// method of its data, to read edited data, e.g. from STLXML documents.
+	FromFields(fields []UDAField) (UDAData, error)
+}
+
+var (
+	udaProfilesMu sync.RWMutex
+	udaProfiles   []UDAProfile
+)
+
+// RegisterUDAProfile makes a UDA profile available by its name and to
+// DecodeUDA, after the profiles already registered. Profile packages
+// register themselves in their init function.
+// RegisterUDAProfile panics if the name is empty or already registered.
+func RegisterUDAProfile(p UDAProfile) {
+	udaProfilesMu.Lock()
+	defer udaProfilesMu.Unlock()
+	if p.Name() == "" {
+		panic("stl: RegisterUDAProfile with an empty profile name")
+	}
+	for _, registered := range udaProfiles {
+		if registered.Name() == p.Name() {
+			panic("stl: RegisterUDAProfile called twice for profile " + p.Name())
+		}
+	}
+	udaProfiles = append(udaProfiles, p)
+}
+
+// LookupUDAProfile returns the registered UDA profile of the name.
+func LookupUDAProfile(name string) (UDAProfile, error) {
+	udaProfilesMu.RLock()
+	defer udaProfilesMu.RUnlock()
+	for _, p := range udaProfiles {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownUDAProfile, name)
+}
+
+// UDAProfiles returns the registered UDA profiles, in order of
+// registration.
+func UDAProfiles() []UDAProfile {
+	udaProfilesMu.RLock()
+	defer udaProfilesMu.RUnlock()
+	return append([]UDAProfile(nil), udaProfiles...)
+}
+
+// DecodeUDA decodes the User-Defined Area (UDA) with the first registered
+// profile matching it. ErrNoUDAProfile is returned if none does.
+func (gsi *GSIBlock) DecodeUDA() (UDAProfile, UDAData, error) {
+	for _, p := range UDAProfiles() {
+		if !p.Match(gsi.UDA) {
+			continue
+		}
+		data, err := p.Decode(gsi.UDA)
+		if err != nil {
+			return p, nil, fmt.Errorf("UDA profile %s: %w", p.Name(), err)
+		}
+		return p, data, nil
+	}
+	return nil, nil, ErrNoUDAProfile
+}
+
+// SetUDA sets the User-Defined Area (UDA) to the encoding of data.
+// Trailing spaces, padding the area when encoded, are trimmed.
+func (gsi *GSIBlock) SetUDA(data UDAData) error {
+	uda, err := data.Encode()
+	if err != nil {
+		return err
+	}
+	if len(uda) > UDASize {
+		return fmt.Errorf("%w: %d bytes, must be at most %d", ErrUDATooLong, len(uda), UDASize)
+	}
+	gsi.UDA = bytes.TrimRight(uda, " ")
+	return nil
+}
@@ -0,0 +1,138 @@
+package stl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// auditUDA is the data of the audit UDA profile used in tests: a magic
+// number followed by the name of the checker and the check status.
+type auditUDA struct {
+	Checker string
+	Passed  bool
+}
+
+func (a auditUDA) Fields() []UDAField {
+	return []UDAField{{"Checker", a.Checker}, {"Passed", fmt.Sprint(a.Passed)}}
+}
+
+func (a auditUDA) Encode() ([]byte, error) {
+	if len(a.Checker) > 32 {
+		return nil, fmt.Errorf("checker %q longer than 32", a.Checker)
+	}
+	status := "N"
+	if a.Passed {
+		status = "Y"
+	}
+	return []byte(fmt.Sprintf("AUD1%-32s%s", a.Checker, status)), nil
+}
+
+type auditProfile struct{}
+
+func (auditProfile) Name() string { return "audit" }
+
+func (auditProfile) Match(uda []byte) bool { return bytes.HasPrefix(uda, []byte("AUD1")) }
+
+func (auditProfile) Decode(uda []byte) (UDAData, error) {
+	if len(uda) < 37 {
+		return nil, errors.New("truncated audit data")
+	}
+	return auditUDA{Checker: strings.TrimRight(string(uda[4:36]), " "), Passed: uda[36] == 'Y'}, nil
+}
+
+func (auditProfile) FromFields(fields []UDAField) (UDAData, error) {
+	var a auditUDA
+	for _, f := range fields {
+		switch f.Name {
+		case "Checker":
+			a.Checker = f.Value
+		case "Passed":
+			a.Passed = f.Value == "true"
+		default:
+			return nil, fmt.Errorf("unknown field %s", f.Name)
+		}
+	}
+	return a, nil
+}
+
+func init() {
+	RegisterUDAProfile(auditProfile{})
+}
+
+func TestDecodeGSIKeepsUDA(t *testing.T) {
+	gsi := NewGSIBlock()
+	gsi.SetDefaults(25, DisplayStandardCodeLevel1Teletext)
+	gsi.UDA = []byte("Broadcaster data\x00\x01")
+
+	var buf bytes.Buffer
+	if err := gsi.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded := NewGSIBlock()
+	if _, err := decoded.Decode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.UDA, gsi.UDA) {
+		t.Errorf("expected UDA %q but got %q", gsi.UDA, decoded.UDA)
+	}
+}
+
+func TestUDAProfile(t *testing.T) {
+	gsi := NewGSIBlock()
+	gsi.SetDefaults(25, DisplayStandardCodeLevel1Teletext)
+	if _, _, err := gsi.DecodeUDA(); !errors.Is(err, ErrNoUDAProfile) {
+		t.Errorf("expected %q error but got %v", ErrNoUDAProfile, err)
+	}
+
+	if err := gsi.SetUDA(auditUDA{Checker: "QC desk", Passed: true}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "AUD1QC desk                         Y"; string(gsi.UDA) != want {
+		t.Errorf("expected UDA %q but got %q", want, gsi.UDA)
+	}
+	p, data, err := gsi.DecodeUDA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != "audit" {
+		t.Errorf("expected audit profile but got %s", p.Name())
+	}
+	if want := (auditUDA{Checker: "QC desk", Passed: true}); !reflect.DeepEqual(data, want) {
+		t.Errorf("expected %+v but got %+v", want, data)
+	}
+
+	gsi.UDA = []byte("AUD1short")
+	if _, _, err := gsi.DecodeUDA(); err == nil || errors.Is(err, ErrNoUDAProfile) {
+		t.Errorf("expected decoding error but got %v", err)
+	}
+	warns, _ := gsi.Validate()
+	if !strings.Contains(fmt.Sprint(warns), "truncated audit data") {
+		t.Errorf("expected profile warning but got %v", warns)
+	}
+}
+
+func TestSetUDATooLong(t *testing.T) {
+	gsi := NewGSIBlock()
+	gsi.SetDefaults(25, DisplayStandardCodeLevel1Teletext)
+	if err := gsi.SetUDA(auditUDA{Checker: strings.Repeat("x", 33)}); err == nil {
+		t.Error("expected encoding error")
+	}
+	gsi.UDA = bytes.Repeat([]byte("x"), UDASize+1)
+	warns, _ := gsi.Validate()
+	if !containsErr(warns, ErrUDATooLong) {
+		t.Errorf("expected %q warning but got %v", ErrUDATooLong, warns)
+	}
+}
+
+func TestRegisterUDAProfileTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	RegisterUDAProfile(auditProfile{})
+}
@@ -0,0 +1,38 @@
+package stlxml
+
+import (
+	"encoding/xml"
+	"io"
+
+	"github.com/si0ls/subs/doc"
+	"github.com/si0ls/subs/stl"
+)
+
+func init() {
+	doc.Register(doc.Format{
+		Name:       "stlxml",
+		Extensions: []string{".xml"},
+		Decode: doc.DecodeSTL(func(r io.Reader) (*stl.File, []error, error) {
+			x := New()
+			if err := x.Decode(r); err != nil {
+				return nil, nil, err
+			}
+			// the UDA element is kept if its data can not be encoded
+			var warns []error
+			f, err := x.ToSTLWithError(stl.CharacterCodeTable(x.GSI.CCT))
+			if err != nil {
+				warns = append(warns, err)
+			}
+			f.TextOrder = f.DetectTextOrder()
+			return &f, warns, nil
+		}),
+		Encode: doc.EncodeSTL(func(w io.Writer, f *stl.File) ([]error, error) {
+			x := New()
+			x.FromSTL(*f)
+			if _, err := io.WriteString(w, xml.Header); err != nil {
+				return nil, err
+			}
+			return nil, x.Encode(w)
+		}, doc.Options{AutoCCT: true}),
+	})
+}
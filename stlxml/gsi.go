@@ -2,6 +2,7 @@ package stlxml
 
 import (
 	"encoding/xml"
+	"fmt"
 	"time"
 
 	"github.com/si0ls/subs/stl"
@@ -40,6 +41,10 @@ type GSIXML struct {
 	EN      ENXML    `xml:"EN"`  // Editor's Name
 	ECD     ECDXML   `xml:"ECD"` // Editor's Contact
 	UDA     UDAXML   `xml:"UDA"` // User-Defined Area
+
+	// Data of the User-Defined Area decoded by a registered stl.UDAProfile,
+	// encoded in place of UDA when converted to STL.
+	UDAData *UDADataXML `xml:"UDAData,omitempty"`
 }
 
 // FromSTL converts a stl.GSIBlock to a stlxml.GSIXML.
@@ -74,11 +79,25 @@ func (gsiXML *GSIXML) FromSTL(GSIstl stl.GSIBlock) {
 	gsiXML.EN = ENXML(GSIstl.EN)
 	gsiXML.ECD = ECDXML(GSIstl.ECD)
 	gsiXML.UDA = UDAXML(GSIstl.UDA)
+	gsiXML.UDAData = nil
+	if p, data, err := GSIstl.DecodeUDA(); err == nil {
+		gsiXML.UDAData = newUDADataXML(p, data)
+	}
 }
 
 // ToSTL converts a stlxml.GSIXML to a stl.GSIBlock.
+// The UDA is encoded from UDAData, if any. If UDAData can not be read by
+// its profile or encoded in the UDA, the UDA element is kept, see
+// ToSTLWithError.
 func (gsiXML *GSIXML) ToSTL() stl.GSIBlock {
-	return stl.GSIBlock{
+	gsi, _ := gsiXML.ToSTLWithError()
+	return gsi
+}
+
+// ToSTLWithError converts a stlxml.GSIXML to a stl.GSIBlock like ToSTL,
+// and returns the error reading or encoding UDAData along with the block.
+func (gsiXML *GSIXML) ToSTLWithError() (stl.GSIBlock, error) {
+	gsi := stl.GSIBlock{
 		CPN: stl.CodePageNumber(gsiXML.CPN),
 		DFC: stl.DiskFormatCode(gsiXML.DFC),
 		DSC: stl.DisplayStandardCode(gsiXML.DSC),
@@ -110,11 +129,14 @@ func (gsiXML *GSIXML) ToSTL() stl.GSIBlock {
 		ECD: string(gsiXML.ECD),
 		UDA: []byte(gsiXML.UDA),
 	}
-}
-
-// Validate validates the GSO block.
-func (gsi *GSIXML) Validate() []error {
-	panic("not implemented")
+	data, err := gsiXML.UDAData.decode()
+	if err == nil && data != nil {
+		err = gsi.SetUDA(data)
+	}
+	if err != nil {
+		return gsi, fmt.Errorf("UDAData: %w", err)
+	}
+	return gsi, nil
 }
 
 // CPNXML is the XML representation of STL Code Page Number (CPN).
@@ -194,7 +216,7 @@ var _ xml.Marshaler = (*LCXML)(nil)
 
 // UnmarshalXML decodes the XML-encoded data and stores the result in the LCXML pointed to by lc.
 func (lc *LCXML) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
-	return unmarshalXMLByte((*byte)(lc), d, start)
+	return unmarshalXMLHexByte((*byte)(lc), d, start, true)
 }
 
 // MarshalXML returns the XML encoding of lc.
@@ -626,3 +648,47 @@ func (uda *UDAXML) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 func (uda UDAXML) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	return marshalXMLBytes([]byte(uda), e, start)
 }
+
+// UDADataXML is the XML representation of the data of the STL User-Defined
+// Area (UDA) decoded by a stl.UDAProfile.
+type UDADataXML struct {
+	Profile string        `xml:"Profile,attr"` // Name of the profile
+	Fields  []UDAFieldXML `xml:"Field"`        // Fields of the data
+}
+
+// UDAFieldXML is the XML representation of a field of the data of the STL
+// User-Defined Area (UDA).
+type UDAFieldXML struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// newUDADataXML returns the XML representation of the data decoded by p.
+func newUDADataXML(p stl.UDAProfile, data stl.UDAData) *UDADataXML {
+	x := &UDADataXML{Profile: p.Name()}
+	for _, f := range data.Fields() {
+		x.Fields = append(x.Fields, UDAFieldXML{Name: f.Name, Value: f.Value})
+	}
+	return x
+}
+
+// decode returns the data of the fields, read by the profile. It returns
+// nil if uda is nil.
+func (uda *UDADataXML) decode() (stl.UDAData, error) {
+	if uda == nil {
+		return nil, nil
+	}
+	p, err := stl.LookupUDAProfile(uda.Profile)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]stl.UDAField, len(uda.Fields))
+	for i, f := range uda.Fields {
+		fields[i] = stl.UDAField{Name: f.Name, Value: f.Value}
+	}
+	data, err := p.FromFields(fields)
+	if err != nil {
+		return nil, fmt.Errorf("UDA profile %s: %w", p.Name(), err)
+	}
+	return data, nil
+}
@@ -1,6 +1,7 @@
 package stlxml
 
 import (
+	"bytes"
 	"encoding/xml"
 	"io"
 
@@ -12,6 +13,9 @@ type STLXML struct {
 	XMLName xml.Name `xml:"StlXml"`
 	GSI     GSIXML   `xml:"HEAD>GSI"`
 	TTI     []TTIXML `xml:"BODY>TTICONTAINER>TTI"`
+
+	src     []byte // document read by Decode, used for validation
+	decoded []byte // encoding of the document read by Decode, to detect later changes
 }
 
 // New returns a new stlxml.STLXML.
@@ -21,8 +25,20 @@ func New() *STLXML {
 
 // Decode reads and decodes the STLXML file from r.
 func (stlXML *STLXML) Decode(r io.Reader) error {
-	dec := xml.NewDecoder(r)
-	return dec.Decode(stlXML)
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	stlXML.src, stlXML.decoded = nil, nil
+	dec := xml.NewDecoder(bytes.NewReader(src))
+	if err := dec.Decode(stlXML); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := stlXML.Encode(&buf); err == nil {
+		stlXML.src, stlXML.decoded = src, buf.Bytes()
+	}
+	return nil
 }
 
 // Encode encodes and writes the STLXML file to w.
@@ -47,19 +63,24 @@ func (stlXML *STLXML) FromSTL(stlFile stl.File) {
 }
 
 // ToSTL converts a stlxml.STLXML to a stl.File.
+// The UDA element is kept if UDAData can not be converted, see
+// GSIXML.ToSTL.
 func (stlXML *STLXML) ToSTL(cct stl.CharacterCodeTable) stl.File {
+	file, _ := stlXML.ToSTLWithError(cct)
+	return file
+}
+
+// ToSTLWithError converts a stlxml.STLXML to a stl.File like ToSTL, and
+// returns the error converting UDAData along with the file, which is
+// complete.
+func (stlXML *STLXML) ToSTLWithError(cct stl.CharacterCodeTable) (stl.File, error) {
 	var file stl.File
-	gsi := stlXML.GSI.ToSTL()
+	gsi, err := stlXML.GSI.ToSTLWithError()
 	file.GSI = &gsi
 	file.TTI = make([]*stl.TTIBlock, len(stlXML.TTI))
 	for i, ttiXML := range stlXML.TTI {
 		tti := ttiXML.ToSTL(cct)
 		file.TTI[i] = &tti
 	}
-	return file
-}
-
-// Validate validates the STLXML file.
-func (stlXML *STLXML) Validate() []error {
-	panic("not implemented")
+	return file, err
 }
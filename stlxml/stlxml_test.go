@@ -0,0 +1,307 @@
+package stlxml
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/si0ls/subs/doc"
+	"github.com/si0ls/subs/internal/stltest"
+	"github.com/si0ls/subs/stl"
+)
+
+// newTestFile returns a file with all the GSI text fields set, which STLXML
+// documents write with their padding, and empty Text Fields.
+func newTestFile(t *testing.T) *stl.File {
+	f := stltest.File{Subtitles: []stltest.Subtitle{
+		{TCO: stl.Timecode{Seconds: 1}, VP: 20, JC: stl.JustificationCodeCenteredText},
+		{TCI: stl.Timecode{Seconds: 2}, TCO: stl.Timecode{Seconds: 3}, VP: 20, JC: stl.JustificationCodeCenteredText},
+	}}.New(t)
+	f.GSI.LC = stl.LanguageCodeFrench
+	f.GSI.OPT, f.GSI.OET, f.GSI.TPT, f.GSI.TET = "Programme", "Episode", "Programme", "Episode"
+	f.GSI.TN, f.GSI.TCD, f.GSI.SLR = "Translator", "translator@example.com", "SLR"
+	f.GSI.CD = time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	f.GSI.RD = f.GSI.CD
+	f.GSI.CO, f.GSI.PUB, f.GSI.EN, f.GSI.ECD = "FRA", "Publisher", "Editor", "editor@example.com"
+	return f
+}
+
+func encodeTestFile(t *testing.T, f *stl.File) string {
+	t.Helper()
+	x := New()
+	x.FromSTL(*f)
+	var buf bytes.Buffer
+	if err := x.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestValidate(t *testing.T) {
+	src := encodeTestFile(t, newTestFile(t))
+
+	x := New()
+	if err := x.Decode(strings.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+	if errs := x.Validate(); len(errs) != 0 {
+		t.Errorf("expected no errors but got %v", errs)
+	}
+}
+
+func TestValidateLocatesErrors(t *testing.T) {
+	src := encodeTestFile(t, newTestFile(t))
+	src = strings.Replace(src, "<SLR>SLR             </SLR>", "<SLR>SLR12345678901234</SLR>", 1)
+	src = strings.Replace(src, "<TCO>00000300</TCO>", "<TCO>00000100</TCO>", 1)
+	src = strings.Replace(src, "<TF></TF>", "<TF><Blink/></TF>", 1)
+	src = strings.Replace(src, "<MNR>23</MNR>", "", 1)
+
+	x := New()
+	if err := x.Decode(strings.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+	errs := x.Validate()
+
+	tests := []struct {
+		err  error
+		path string
+	}{
+		{ErrValueTooLong, "StlXml/HEAD/GSI/SLR"},
+		{ErrMissingElement, "StlXml/HEAD/GSI"},
+		{ErrUnknownTextFieldElement, "StlXml/BODY/TTICONTAINER/TTI[0]/TF/Blink"},
+		{stl.ErrInvalidTCITCOOrder, "StlXml/BODY/TTICONTAINER/TTI[1]/TCO"},
+	}
+	for _, test := range tests {
+		var found *ValidateError
+		for _, err := range errs {
+			var vErr *ValidateError
+			if errors.Is(err, test.err) && errors.As(err, &vErr) {
+				found = vErr
+				break
+			}
+		}
+		if found == nil {
+			t.Errorf("expected %q error but got %v", test.err, errs)
+			continue
+		}
+		if found.Path() != test.path {
+			t.Errorf("expected %q error at %s but got %s", test.err, test.path, found.Path())
+		}
+		lines := strings.Split(src, "\n")
+		if found.Line() < 1 || found.Line() > len(lines) {
+			t.Errorf("expected %q error line in document but got %d", test.err, found.Line())
+			continue
+		}
+		name := test.path[strings.LastIndex(test.path, "/")+1:]
+		name = strings.TrimSuffix(name, "[1]")
+		if !strings.Contains(lines[found.Line()-1], "<"+name) {
+			t.Errorf("expected %q error line %d to hold %s but got %q", test.err, found.Line(), name, lines[found.Line()-1])
+		}
+	}
+}
+
+func TestValidateWithoutSource(t *testing.T) {
+	x := New()
+	x.FromSTL(*newTestFile(t))
+	x.GSI.OPT = OPTXML(strings.Repeat("x", 33))
+
+	errs := x.Validate()
+	if len(errs) != 1 || !errors.Is(errs[0], ErrValueTooLong) {
+		t.Fatalf("expected one %q error but got %v", ErrValueTooLong, errs)
+	}
+	var vErr *ValidateError
+	if !errors.As(errs[0], &vErr) || vErr.Line() != 0 {
+		t.Errorf("expected no line for document not read with Decode but got %v", errs[0])
+	}
+}
+
+func TestValidateChangedAfterDecode(t *testing.T) {
+	x := New()
+	if err := x.Decode(strings.NewReader(encodeTestFile(t, newTestFile(t)))); err != nil {
+		t.Fatal(err)
+	}
+	x.GSI.OPT = OPTXML(strings.Repeat("x", 50))
+
+	errs := x.Validate()
+	if len(errs) != 1 || !errors.Is(errs[0], ErrValueTooLong) {
+		t.Fatalf("expected one %q error but got %v", ErrValueTooLong, errs)
+	}
+	if path := errs[0].(*ValidateError).Path(); path != "StlXml/HEAD/GSI/OPT" {
+		t.Errorf("expected error at StlXml/HEAD/GSI/OPT but got %s", path)
+	}
+}
+
+func TestValidateTTI(t *testing.T) {
+	f := newTestFile(t)
+	var gsi GSIXML
+	gsi.FromSTL(*f.GSI)
+	var tti TTIXML
+	tti.FromSTL(*f.TTI[0], f.GSI.CCT)
+	tti.VP = 24
+
+	errs := tti.ValidateWithGSI(gsi)
+	if len(errs) != 1 || !errors.Is(errs[0], stl.ErrUnsupportedVPTeletext) {
+		t.Fatalf("expected one %q error but got %v", stl.ErrUnsupportedVPTeletext, errs)
+	}
+	if path := errs[0].(*ValidateError).Path(); path != "TTI/VP" {
+		t.Errorf("expected error at TTI/VP but got %s", path)
+	}
+
+	errs = tti.Validate()
+	if len(errs) != 1 || !errors.Is(errs[0], stl.ErrUnsupportedVPTeletext) {
+		t.Errorf("expected one %q error with the default GSI block but got %v", stl.ErrUnsupportedVPTeletext, errs)
+	}
+}
+
+// noteUDA is the data of the note UDA profile used in tests: "NOTE:"
+// followed by the note.
+type noteUDA string
+
+func (n noteUDA) Fields() []stl.UDAField { return []stl.UDAField{{Name: "Note", Value: string(n)}} }
+
+func (n noteUDA) Encode() ([]byte, error) { return []byte("NOTE:" + n), nil }
+
+type noteProfile struct{}
+
+func (noteProfile) Name() string { return "note" }
+
+func (noteProfile) Match(uda []byte) bool { return strings.HasPrefix(string(uda), "NOTE:") }
+
+func (noteProfile) Decode(uda []byte) (stl.UDAData, error) {
+	return noteUDA(strings.TrimPrefix(string(uda), "NOTE:")), nil
+}
+
+func (noteProfile) FromFields(fields []stl.UDAField) (stl.UDAData, error) {
+	if len(fields) != 1 || fields[0].Name != "Note" {
+		return nil, errors.New("expected a Note field")
+	}
+	return noteUDA(fields[0].Value), nil
+}
+
+func init() {
+	stl.RegisterUDAProfile(noteProfile{})
+}
+
+func TestUDAData(t *testing.T) {
+	f := newTestFile(t)
+	f.GSI.UDA = []byte("NOTE:checked")
+	src := encodeTestFile(t, f)
+	if !strings.Contains(src, `<UDAData Profile="note">`) || !strings.Contains(src, `<Field Name="Note">checked</Field>`) {
+		t.Fatalf("expected UDA data in document but got %s", src)
+	}
+
+	src = strings.Replace(src, ">checked<", ">approved<", 1)
+	x := New()
+	if err := x.Decode(strings.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+	if errs := x.Validate(); len(errs) != 0 {
+		t.Errorf("expected no errors but got %v", errs)
+	}
+	if gsi, err := x.GSI.ToSTLWithError(); err != nil || string(gsi.UDA) != "NOTE:approved" {
+		t.Errorf("expected UDA encoded from its data but got %q (%v)", gsi.UDA, err)
+	}
+
+	x.GSI.UDAData.Fields[0].Value = strings.Repeat("x", stl.UDASize)
+	if gsi, err := x.GSI.ToSTLWithError(); !errors.Is(err, stl.ErrUDATooLong) || string(gsi.UDA) != "NOTE:checked" {
+		t.Errorf("expected %q error and UDA element kept but got %q (%v)", stl.ErrUDATooLong, gsi.UDA, err)
+	}
+	if gsi := x.GSI.ToSTL(); string(gsi.UDA) != "NOTE:checked" {
+		t.Errorf("expected UDA element kept but got %q", gsi.UDA)
+	}
+
+	src = strings.Replace(src, `Profile="note"`, `Profile="unknown"`, 1)
+	x = New()
+	if err := x.Decode(strings.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+	errs := x.Validate()
+	if len(errs) != 1 || !errors.Is(errs[0], stl.ErrUnknownUDAProfile) {
+		t.Fatalf("expected one %q error but got %v", stl.ErrUnknownUDAProfile, errs)
+	}
+	if path := errs[0].(*ValidateError).Path(); path != "StlXml/HEAD/GSI/UDAData" {
+		t.Errorf("expected error at StlXml/HEAD/GSI/UDAData but got %s", path)
+	}
+	gsi, err := x.GSI.ToSTLWithError()
+	if !errors.Is(err, stl.ErrUnknownUDAProfile) {
+		t.Errorf("expected %q error but got %v", stl.ErrUnknownUDAProfile, err)
+	}
+	if string(gsi.UDA) != "NOTE:checked" {
+		t.Errorf("expected UDA element kept but got %q", gsi.UDA)
+	}
+
+	format, err := doc.Lookup("stlxml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, warns, err := format.Decode(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) != 1 || !errors.Is(warns[0], stl.ErrUnknownUDAProfile) {
+		t.Errorf("expected one %q warning but got %v", stl.ErrUnknownUDAProfile, warns)
+	}
+}
+
+func TestUDADataBinaryRoundTrip(t *testing.T) {
+	f := newTestFile(t)
+	f.GSI.UDA = []byte("NOTE:checked")
+	var bin bytes.Buffer
+	if err := f.Encode(&bin); err != nil {
+		t.Fatal(err)
+	}
+
+	// STL file to STLXML document, through the note profile
+	decoded := stl.NewFile()
+	if _, err := decoded.Decode(&bin); err != nil {
+		t.Fatal(err)
+	}
+	src := encodeTestFile(t, decoded)
+	if !strings.Contains(src, `<Field Name="Note">checked</Field>`) {
+		t.Fatalf("expected UDA data in document but got %s", src)
+	}
+
+	// edited STLXML document back to STL file
+	x := New()
+	if err := x.Decode(strings.NewReader(strings.Replace(src, ">checked<", ">approved<", 1))); err != nil {
+		t.Fatal(err)
+	}
+	edited, err := x.ToSTLWithError(stl.CharacterCodeTable(x.GSI.CCT))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin.Reset()
+	if err := edited.Encode(&bin); err != nil {
+		t.Fatal(err)
+	}
+	decoded = stl.NewFile()
+	if _, err := decoded.Decode(&bin); err != nil {
+		t.Fatal(err)
+	}
+	p, data, err := decoded.GSI.DecodeUDA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != "note" || data != noteUDA("approved") {
+		t.Errorf("expected note %q but got %s %q", "approved", p.Name(), data)
+	}
+}
+
+func TestLanguageCodeHex(t *testing.T) {
+	f := newTestFile(t)
+	f.GSI.LC = stl.LanguageCodeWallon
+	src := encodeTestFile(t, f)
+	if !strings.Contains(src, "<LC>2B</LC>") {
+		t.Fatalf("expected LC 2B in %s", src)
+	}
+
+	x := New()
+	if err := x.Decode(strings.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+	if lc := stl.LanguageCode(x.GSI.LC); lc != f.GSI.LC {
+		t.Errorf("expected LC %02X but got %02X", byte(f.GSI.LC), byte(lc))
+	}
+}
@@ -61,11 +61,6 @@ func (ttiXML TTIXML) ToSTL(cct stl.CharacterCodeTable) stl.TTIBlock {
 	}
 }
 
-// Validate validates the TTI block.
-func (tti *TTIXML) Validate() []error {
-	panic("not implemented")
-}
-
 // SGNXML is the XML representation of STL Subtitle Group Number (SGN).
 type SGNXML int
 
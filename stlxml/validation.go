@@ -0,0 +1,507 @@
+package stlxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/si0ls/subs/stl"
+)
+
+var (
+	ErrMissingElement          = errors.New("missing element")
+	ErrUnknownElement          = errors.New("unknown element")
+	ErrValueTooLong            = errors.New("value too long")
+	ErrInvalidIntValue         = errors.New("invalid int value")
+	ErrInvalidHexValue         = errors.New("invalid hex value")
+	ErrInvalidDateValue        = errors.New("invalid date value")
+	ErrInvalidTimecodeValue    = errors.New("invalid timecode value")
+	ErrUnknownTextFieldElement = errors.New("unknown text field element")
+	ErrMalformedDocument       = errors.New("malformed document")
+)
+
+// ValidateError is an error located in the STLXML document.
+// It carries the path of the concerned element and its line number.
+// ValidateError implements the error and Unwrap interfaces.
+type ValidateError struct {
+	error
+	path string
+	line int
+}
+
+// ValidateError implements error interface.
+var _ error = (*ValidateError)(nil)
+
+func validateErr(err error, path string, line int) error {
+	if err == nil {
+		return nil
+	}
+	return &ValidateError{error: err, path: path, line: line}
+}
+
+// Error returns the error message.
+func (e *ValidateError) Error() string {
+	if e.line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", e.line, e.path, e.error.Error())
+	}
+	return fmt.Sprintf("%s: %s", e.path, e.error.Error())
+}
+
+// Unwrap returns the underlying error.
+func (e *ValidateError) Unwrap() error {
+	return e.error
+}
+
+// Path returns the path of the concerned element, e.g.
+// "StlXml/BODY/TTICONTAINER/TTI[2]/TCI".
+func (e *ValidateError) Path() string {
+	return e.path
+}
+
+// Line returns the line number of the concerned element.
+// It is 0 if the document was not read with Decode.
+func (e *ValidateError) Line() int {
+	return e.line
+}
+
+const (
+	stlXMLPath       = "StlXml"
+	gsiXMLPath       = stlXMLPath + "/HEAD/GSI"
+	ttiContainerPath = stlXMLPath + "/BODY/TTICONTAINER"
+)
+
+// ttiXMLPath returns the path of the i-th TTI element of the document.
+func ttiXMLPath(i int) string {
+	return fmt.Sprintf("%s/TTI[%d]", ttiContainerPath, i)
+}
+
+// Validate validates the STLXML file.
+// The document is first checked against the STLXML schema (required
+// elements, value formats and lengths), then converted and validated as a
+// STL file. Errors are located by element path, and by line number if the
+// document was read with Decode and not changed since.
+func (stlXML *STLXML) Validate() []error {
+	var buf bytes.Buffer
+	if err := stlXML.Encode(&buf); err != nil {
+		return []error{validateErr(err, stlXMLPath, 0)}
+	}
+	src, located := stlXML.src, true
+	if src == nil || !bytes.Equal(buf.Bytes(), stlXML.decoded) {
+		src, located = buf.Bytes(), false
+	}
+	d, err := scanDocument(src, located)
+	if err != nil {
+		return []error{validateErr(fmt.Errorf("%w: %s", ErrMalformedDocument, err), stlXMLPath, 0)}
+	}
+
+	var errs []error
+	for _, path := range []string{gsiXMLPath, ttiContainerPath} {
+		if d.elements[path] == nil {
+			errs = append(errs, validateErr(ErrMissingElement, path, d.line(stlXMLPath)))
+		}
+	}
+	errs = append(errs, d.validateFields(gsiXMLPath, gsiFieldSchemas, gsiOptionalElements...)...)
+	if err := validateUDAData(stlXML.GSI.UDAData); err != nil {
+		path := gsiXMLPath + "/UDAData"
+		errs = append(errs, validateErr(err, path, d.line(path)))
+	}
+	for i := range stlXML.TTI {
+		errs = append(errs, d.validateFields(ttiXMLPath(i), ttiFieldSchemas)...)
+		errs = append(errs, d.validateTextField(ttiXMLPath(i)+"/TF")...)
+	}
+
+	f := stlXML.ToSTL(stl.CharacterCodeTable(stlXML.GSI.CCT))
+	warns, err := f.Validate()
+	for _, w := range appendNonNil(warns, err) {
+		// The text field padding is only known in binary files.
+		if errors.Is(w, stl.ErrLastEBNNotTerminatedBySpace) {
+			continue
+		}
+		path := locateSTLErr(w, gsiXMLPath, ttiXMLPath)
+		errs = append(errs, validateErr(w, path, d.line(path)))
+	}
+	return errs
+}
+
+// Validate validates the GSI block.
+// Errors are located by element path relative to the GSI element.
+func (gsi *GSIXML) Validate() []error {
+	var buf bytes.Buffer
+	if err := xml.NewEncoder(&buf).Encode(gsi); err != nil {
+		return []error{validateErr(err, "GSI", 0)}
+	}
+	d, err := scanDocument(buf.Bytes(), false)
+	if err != nil {
+		return []error{validateErr(fmt.Errorf("%w: %s", ErrMalformedDocument, err), "GSI", 0)}
+	}
+	errs := d.validateFields("GSI", gsiFieldSchemas, gsiOptionalElements...)
+	if err := validateUDAData(gsi.UDAData); err != nil {
+		errs = append(errs, validateErr(err, "GSI/UDAData", 0))
+	}
+
+	gsiSTL := gsi.ToSTL()
+	warns, err := gsiSTL.Validate()
+	for _, w := range appendNonNil(warns, err) {
+		errs = append(errs, validateErr(w, locateSTLErr(w, "GSI", nil), 0))
+	}
+	return errs
+}
+
+// Validate validates the TTI block in the context of a 25 fps teletext file
+// with the Latin character code table, see ValidateWithGSI.
+// Errors are located by element path relative to the TTI element.
+func (tti *TTIXML) Validate() []error {
+	gsi := stl.NewGSIBlock()
+	gsi.SetDefaults(25, stl.DisplayStandardCodeLevel1Teletext)
+	return tti.validate(*gsi)
+}
+
+// ValidateWithGSI validates the TTI block in the context of the GSI block
+// of its file, which gives the framerate, display standard, maximum number
+// of rows and character code table of the block.
+// Errors are located by element path relative to the TTI element.
+func (tti *TTIXML) ValidateWithGSI(gsi GSIXML) []error {
+	return tti.validate(gsi.ToSTL())
+}
+
+// validate validates the TTI block in the context of the GSI block gsi.
+func (tti *TTIXML) validate(gsi stl.GSIBlock) []error {
+	var buf bytes.Buffer
+	if err := xml.NewEncoder(&buf).Encode(tti); err != nil {
+		return []error{validateErr(err, "TTI", 0)}
+	}
+	d, err := scanDocument(buf.Bytes(), false)
+	if err != nil {
+		return []error{validateErr(fmt.Errorf("%w: %s", ErrMalformedDocument, err), "TTI", 0)}
+	}
+	errs := d.validateFields("TTI", ttiFieldSchemas)
+	errs = append(errs, d.validateTextField("TTI/TF")...)
+
+	ttiSTL := tti.ToSTL(gsi.CCT)
+	warns, err := ttiSTL.Validate(gsi.Framerate(), gsi.DSC, gsi.MNR)
+	for _, w := range appendNonNil(warns, err) {
+		// The text field padding is only known in binary files.
+		if errors.Is(w, stl.ErrLastEBNNotTerminatedBySpace) {
+			continue
+		}
+		errs = append(errs, validateErr(w, locateSTLErr(w, "", func(int) string { return "TTI" }), 0))
+	}
+	return errs
+}
+
+// locateSTLErr returns the path of the element concerned by an error of the
+// stl validators. Errors on no particular field are located on the root.
+func locateSTLErr(err error, gsiPath string, ttiPath func(int) string) string {
+	var gsiErr *stl.GSIError
+	if errors.As(err, &gsiErr) && gsiPath != "" {
+		return gsiPath + "/" + string(gsiErr.Field())
+	}
+	var ttiErr *stl.TTIError
+	if errors.As(err, &ttiErr) && ttiPath != nil {
+		return ttiPath(ttiErr.BlockNumber()) + "/" + string(ttiErr.Field())
+	}
+	switch {
+	case gsiPath != "" && ttiPath != nil:
+		return stlXMLPath
+	case gsiPath != "":
+		return gsiPath
+	default:
+		return ttiPath(0)
+	}
+}
+
+// appendNonNil appends err to errs if err is not nil.
+func appendNonNil(errs []error, err error) []error {
+	if err != nil {
+		return append(errs, err)
+	}
+	return errs
+}
+
+// fieldKind is the kind of value of a STLXML field.
+type fieldKind int
+
+const (
+	fieldKindString fieldKind = iota
+	fieldKindInt
+	fieldKindHex
+	fieldKindDate
+	fieldKindTimecode
+	fieldKindText
+)
+
+// fieldSchema describes the value of a STLXML field.
+// Length is the maximum length of the value, 0 if unbounded.
+type fieldSchema struct {
+	name   string
+	kind   fieldKind
+	length int
+}
+
+// gsiFieldSchemas describes the GSI fields, with the lengths used by their
+// MarshalXML methods, which are those of the binary GSI block.
+var gsiFieldSchemas = []fieldSchema{
+	{"CPN", fieldKindInt, 3},
+	{"DFC", fieldKindString, 8},
+	{"DSC", fieldKindInt, 1},
+	{"CCT", fieldKindInt, 2},
+	{"LC", fieldKindHex, 2},
+	{"OPT", fieldKindString, 32},
+	{"OET", fieldKindString, 32},
+	{"TPT", fieldKindString, 32},
+	{"TET", fieldKindString, 32},
+	{"TN", fieldKindString, 32},
+	{"TCD", fieldKindString, 32},
+	{"SLR", fieldKindString, 16},
+	{"CD", fieldKindDate, 6},
+	{"RD", fieldKindDate, 6},
+	{"RN", fieldKindInt, 2},
+	{"TNB", fieldKindInt, 5},
+	{"TNS", fieldKindInt, 5},
+	{"TNG", fieldKindInt, 3},
+	{"MNC", fieldKindInt, 2},
+	{"MNR", fieldKindInt, 2},
+	{"TCS", fieldKindInt, 1},
+	{"TCP", fieldKindTimecode, 8},
+	{"TCF", fieldKindTimecode, 8},
+	{"TND", fieldKindInt, 1},
+	{"DSN", fieldKindInt, 1},
+	{"CO", fieldKindString, 3},
+	{"PUB", fieldKindString, 32},
+	{"EN", fieldKindString, 32},
+	{"ECD", fieldKindString, 32},
+	{"UDA", fieldKindString, 576},
+}
+
+// ttiFieldSchemas describes the TTI fields, with the lengths of the largest
+// values of the binary TTI block.
+var ttiFieldSchemas = []fieldSchema{
+	{"SGN", fieldKindInt, 3},
+	{"SN", fieldKindInt, 5},
+	{"EBN", fieldKindHex, 2},
+	{"CS", fieldKindHex, 2},
+	{"TCI", fieldKindTimecode, 8},
+	{"TCO", fieldKindTimecode, 8},
+	{"VP", fieldKindInt, 2},
+	{"JC", fieldKindHex, 2},
+	{"CF", fieldKindHex, 2},
+	{"TF", fieldKindText, 0},
+}
+
+// gsiOptionalElements are the children of the GSI element which are not
+// fields of the GSI block.
+var gsiOptionalElements = []string{"UDAData"}
+
+// validateUDAData validates the data of the UDA, which must be readable by
+// its profile and fit in the UDA.
+func validateUDAData(uda *UDADataXML) error {
+	data, err := uda.decode()
+	if err != nil || data == nil {
+		return err
+	}
+	var gsi stl.GSIBlock
+	return gsi.SetUDA(data)
+}
+
+// validateFields validates the children of the element at path against the
+// schemas, the optional elements being allowed. A missing element at path
+// is reported by the caller.
+func (d *document) validateFields(path string, schemas []fieldSchema, optional ...string) []error {
+	parent := d.elements[path]
+	if parent == nil {
+		return nil
+	}
+
+	var errs []error
+	known := make(map[string]bool, len(schemas)+len(optional))
+	for _, name := range optional {
+		known[name] = true
+	}
+	for _, s := range schemas {
+		known[s.name] = true
+		p := path + "/" + s.name
+		e := d.elements[p]
+		if e == nil {
+			errs = append(errs, validateErr(fmt.Errorf("%w: %s", ErrMissingElement, s.name), path, d.line(path)))
+			continue
+		}
+		errs = appendNonNil(errs, validateErr(s.validate(e.text), p, d.line(p)))
+	}
+	for _, name := range parent.children {
+		if !known[name] {
+			p := path + "/" + name
+			errs = append(errs, validateErr(ErrUnknownElement, p, d.line(p)))
+		}
+	}
+	return errs
+}
+
+// validateTextField validates the control code elements of the TF element
+// at path.
+func (d *document) validateTextField(path string) []error {
+	tf := d.elements[path]
+	if tf == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, name := range tf.children {
+		if name == "space" || textFieldXMLTags[name] {
+			continue
+		}
+		p := path + "/" + name
+		errs = append(errs, validateErr(fmt.Errorf("%w: %s", ErrUnknownTextFieldElement, name), p, d.line(p)))
+	}
+	return errs
+}
+
+// textFieldXMLTags is the set of control code elements of the TF element.
+var textFieldXMLTags = func() map[string]bool {
+	tags := make(map[string]bool, len(stlControlCodeXmlTag))
+	for _, tag := range stlControlCodeXmlTag {
+		tags[tag] = true
+	}
+	return tags
+}()
+
+// validate validates a value of the field, as read from the document.
+func (s fieldSchema) validate(v string) error {
+	if s.length > 0 && utf8.RuneCountInString(v) > s.length {
+		return fmt.Errorf("%w: %q is longer than %d", ErrValueTooLong, v, s.length)
+	}
+
+	// Blank values are written for unset numbers, dates and timecodes.
+	t := strings.TrimSpace(v)
+	if t == "" {
+		return nil
+	}
+	switch s.kind {
+	case fieldKindInt:
+		if !isDigits(t) {
+			return fmt.Errorf("%w: %q", ErrInvalidIntValue, v)
+		}
+	case fieldKindHex:
+		if !isHexDigits(t) {
+			return fmt.Errorf("%w: %q", ErrInvalidHexValue, v)
+		}
+	case fieldKindDate:
+		if _, err := time.Parse("060102", t); err != nil || len(t) != 6 {
+			return fmt.Errorf("%w: %q must be YYMMDD", ErrInvalidDateValue, v)
+		}
+	case fieldKindTimecode:
+		if len(t) != 8 || !isDigits(t) {
+			return fmt.Errorf("%w: %q must be HHMMSSFF", ErrInvalidTimecodeValue, v)
+		}
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isHexDigits(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') && (r < 'A' || r > 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// document is the index of the elements of a STLXML document by path, as
+// read by scanDocument.
+type document struct {
+	elements map[string]*element
+	located  bool
+}
+
+// element is an element of a STLXML document.
+type element struct {
+	line     int      // line of the start tag
+	text     string   // character data directly within the element
+	children []string // names of the child elements, in order
+}
+
+// scanDocument indexes the elements of src by path. The elements named TTI,
+// but the root, are indexed by their position among their siblings, e.g.
+// "TTI[2]".
+// Line numbers are only kept if located is true.
+func scanDocument(src []byte, located bool) (*document, error) {
+	d := &document{elements: map[string]*element{}, located: located}
+	dec := xml.NewDecoder(bytes.NewReader(src))
+
+	var stack []string
+	var open []*element
+	var ttiCount []int
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF && len(stack) == 0 {
+				return d, nil
+			}
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			if len(open) > 0 {
+				parent := open[len(open)-1]
+				parent.children = append(parent.children, name)
+				if name == "TTI" {
+					name = fmt.Sprintf("TTI[%d]", ttiCount[len(ttiCount)-1])
+					ttiCount[len(ttiCount)-1]++
+				}
+			}
+			line, _ := dec.InputPos()
+			e := &element{line: line}
+			stack = append(stack, name)
+			open = append(open, e)
+			ttiCount = append(ttiCount, 0)
+			path := strings.Join(stack, "/")
+			if _, exists := d.elements[path]; !exists {
+				d.elements[path] = e
+			}
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			open = open[:len(open)-1]
+			ttiCount = ttiCount[:len(ttiCount)-1]
+		case xml.CharData:
+			if len(open) > 0 {
+				open[len(open)-1].text += string(t)
+			}
+		}
+	}
+}
+
+// line returns the line number of the element at path, or of its closest
+// ancestor found in the document. It is 0 if lines are not kept.
+func (d *document) line(path string) int {
+	if !d.located {
+		return 0
+	}
+	for path != "" {
+		if e := d.elements[path]; e != nil {
+			return e.line
+		}
+		i := strings.LastIndex(path, "/")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return 0
+}
@@ -0,0 +1,24 @@
+package subviewer
+
+import (
+	"io"
+
+	"github.com/si0ls/subs/cue"
+	"github.com/si0ls/subs/doc"
+)
+
+func init() {
+	doc.Register(doc.Format{
+		Name:       "subviewer",
+		Extensions: []string{".sub"},
+		Encode:     EncodeDocument,
+	})
+}
+
+// EncodeDocument writes d as a SubViewer 2.0 file to w, as Encode does with
+// the cues of the document, with its title, translator and episode title
+// as title, author and comment of the header.
+func EncodeDocument(w io.Writer, d *doc.Document) ([]error, error) {
+	m := d.Metadata
+	return encode(w, m.Title, m.Translator, m.EpisodeTitle, cue.FromDocument(d), nil)
+}
@@ -0,0 +1,86 @@
+// Package subviewer exports STL files as SubViewer 2.0 subtitle files.
+//
+// A SubViewer file starts with an information header, followed by one
+// block per subtitle, separated by empty lines: the start and end times of
+// the subtitle, as HH:MM:SS.cc separated by a comma, followed by the lines
+// of the subtitle on a single line, separated by [br].
+//
+//	00:00:01.20,00:00:03.00
+//	First line[br]second line
+package subviewer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/si0ls/subs/cue"
+	"github.com/si0ls/subs/stl"
+)
+
+// LineBreak separates the lines of a subtitle.
+const LineBreak = "[br]"
+
+var header = template.Must(template.New("header").Parse(`[INFORMATION]
+[TITLE]{{.Title}}
+[AUTHOR]{{.Author}}
+[SOURCE]
+[PRG]
+[FILEPATH]
+[DELAY]0
+[CD TRACK]0
+[COMMENT]{{.Comment}}
+[END INFORMATION]
+[SUBTITLE]
+[COLF]&HFFFFFF,[STYLE]no,[SIZE]18,[FONT]Arial
+`))
+
+// Encode writes f as a SubViewer 2.0 file to w, with the Translated
+// Program Title (TPT), Translator's Name (TN) and Translated Episode Title
+// (TET) of the GSI block as title, author and comment of the header.
+//
+// Times and lines are those of the cues of the subtitles, times being
+// rounded to the centisecond. SubViewer files have no styling: italics are
+// removed and returned as warnings, with the subtitles which can not be
+// decoded.
+func Encode(w io.Writer, f *stl.File) ([]error, error) {
+	cues, warns, err := cue.FromSTL(f)
+	if err != nil {
+		return warns, err
+	}
+	return encode(w, f.GSI.TPT, f.GSI.TN, f.GSI.TET, cues, warns)
+}
+
+// encode writes the cues as a SubViewer 2.0 file to w, with the title,
+// author and comment of the header, returning the warnings with the
+// styles removed.
+func encode(w io.Writer, title, author, comment string, cues []cue.Cue, warns []error) ([]error, error) {
+	bw := bufio.NewWriter(w)
+	if err := header.Execute(bw, struct{ Title, Author, Comment string }{
+		Title:   title,
+		Author:  author,
+		Comment: comment,
+	}); err != nil {
+		return warns, err
+	}
+	for _, c := range cues {
+		if c.Italic() {
+			warns = append(warns, c.Errorf("italics not supported, removed"))
+		}
+		lines := make([]string, len(c.Lines))
+		for i, l := range c.Lines {
+			lines[i] = l.String()
+		}
+		fmt.Fprintf(bw, "%s,%s\n%s\n\n", formatTime(c.Start), formatTime(c.End), strings.Join(lines, LineBreak))
+	}
+	return warns, bw.Flush()
+}
+
+// formatTime formats a duration as HH:MM:SS.cc.
+func formatTime(d time.Duration) string {
+	cs := d.Round(10*time.Millisecond).Milliseconds() / 10
+	return fmt.Sprintf("%02d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
@@ -0,0 +1,34 @@
+package subviewer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/si0ls/subs/internal/stltest"
+	"github.com/si0ls/subs/stl"
+)
+
+func TestEncode(t *testing.T) {
+	f := stltest.File{Framerate: 30, Subtitles: []stltest.Subtitle{
+		{TCI: stl.Timecode{Seconds: 1, Frames: 1}, TCO: stl.Timecode{Minutes: 1, Seconds: 3, Frames: 2},
+			Text: "\x0b\x0bFirst line\u008a\x0b\x0b\u0080second\u0081 line"},
+	}}.New(t)
+	f.GSI.TPT = "Title"
+
+	var buf bytes.Buffer
+	warns, err := Encode(&buf, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) != 1 {
+		t.Errorf("got %d warnings, want 1: %v", len(warns), warns)
+	}
+	if !strings.HasPrefix(buf.String(), "[INFORMATION]\n[TITLE]Title\n") {
+		t.Errorf("got header\n%s", buf.String())
+	}
+	// 1.033 s and 63.067 s
+	if want := "\n00:00:01.03,00:01:03.07\nFirst line[br]second line\n\n"; !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("got\n%s\nwant suffix\n%s", buf.String(), want)
+	}
+}
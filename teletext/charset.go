@@ -0,0 +1,122 @@
+package teletext
+
+import (
+	"unicode"
+
+	"github.com/si0ls/subs/stl"
+	"golang.org/x/text/unicode/norm"
+)
+
+// National option character subsets (ETS 300 706 table 36).
+const (
+	NationalOptionEnglish           = 0
+	NationalOptionGerman            = 1
+	NationalOptionSwedish           = 2 // Swedish, Finnish, Hungarian
+	NationalOptionItalian           = 3
+	NationalOptionFrench            = 4
+	NationalOptionPortugueseSpanish = 5
+	NationalOptionCzechSlovak       = 6
+)
+
+// nationalPositions are the G0 character positions replaced by the national
+// option subsets.
+var nationalPositions = [13]byte{0x23, 0x24, 0x40, 0x5B, 0x5C, 0x5D, 0x5E, 0x5F, 0x60, 0x7B, 0x7C, 0x7D, 0x7E}
+
+var nationalOptions = map[int][13]rune{
+	NationalOptionEnglish:           {'£', '$', '@', '←', '½', '→', '↑', '#', '–', '¼', '‖', '¾', '÷'},
+	NationalOptionGerman:            {'#', '$', '§', 'Ä', 'Ö', 'Ü', '^', '_', '°', 'ä', 'ö', 'ü', 'ß'},
+	NationalOptionSwedish:           {'#', '¤', 'É', 'Ä', 'Ö', 'Å', 'Ü', '_', 'é', 'ä', 'ö', 'å', 'ü'},
+	NationalOptionItalian:           {'£', '$', 'é', '°', 'ç', '→', '↑', '#', 'ù', 'à', 'ò', 'è', 'ì'},
+	NationalOptionFrench:            {'é', 'ï', 'à', 'ë', 'ê', 'ù', 'î', '#', 'è', 'â', 'ô', 'û', 'ç'},
+	NationalOptionPortugueseSpanish: {'ç', '$', '¡', 'á', 'é', 'í', 'ó', 'ú', '¿', 'ü', 'ñ', 'è', 'à'},
+	NationalOptionCzechSlovak:       {'#', 'ů', 'č', 'ť', 'ž', 'ý', 'í', 'ř', 'é', 'á', 'ě', 'ú', 'š'},
+}
+
+// NationalOption returns the national option character subset best suited
+// to the language.
+func NationalOption(lc stl.LanguageCode) int {
+	switch lc {
+	case stl.LanguageCodeGerman:
+		return NationalOptionGerman
+	case stl.LanguageCodeSwedish, stl.LanguageCodeFinnish, stl.LanguageCodeHungarian:
+		return NationalOptionSwedish
+	case stl.LanguageCodeItalian:
+		return NationalOptionItalian
+	case stl.LanguageCodeFrench:
+		return NationalOptionFrench
+	case stl.LanguageCodePortugese, stl.LanguageCodeSpanish:
+		return NationalOptionPortugueseSpanish
+	case stl.LanguageCodeCzech, stl.LanguageCodeSlovak:
+		return NationalOptionCzechSlovak
+	}
+	return NationalOptionEnglish
+}
+
+// languageCode returns the language code matching the national option
+// subset, the reverse of NationalOption.
+func languageCode(option int) stl.LanguageCode {
+	switch option {
+	case NationalOptionEnglish:
+		return stl.LanguageCodeEnglish
+	case NationalOptionGerman:
+		return stl.LanguageCodeGerman
+	case NationalOptionSwedish:
+		return stl.LanguageCodeSwedish
+	case NationalOptionItalian:
+		return stl.LanguageCodeItalian
+	case NationalOptionFrench:
+		return stl.LanguageCodeFrench
+	case NationalOptionPortugueseSpanish:
+		return stl.LanguageCodeSpanish
+	case NationalOptionCzechSlovak:
+		return stl.LanguageCodeCzech
+	}
+	return stl.LanguageCodeUnknown
+}
+
+// charset is the G0 Latin character set with a national option subset.
+type charset struct {
+	decode [128]rune
+	encode map[rune]byte
+}
+
+func newCharset(option int) *charset {
+	cs := &charset{encode: make(map[rune]byte)}
+	for c := 0x20; c < 0x7F; c++ {
+		cs.decode[c] = rune(c)
+	}
+	cs.decode[0x7F] = '■'
+	subset, ok := nationalOptions[option]
+	if !ok {
+		subset = nationalOptions[NationalOptionEnglish]
+	}
+	for i, pos := range nationalPositions {
+		cs.decode[pos] = subset[i]
+	}
+	for c := 0x20; c < 0x80; c++ {
+		cs.encode[cs.decode[c]] = byte(c)
+	}
+	return cs
+}
+
+// encodeRune returns the character code of r. Characters missing from the
+// character set are replaced by their base letter (without diacritical
+// mark) if available, ok is then false.
+func (cs *charset) encodeRune(r rune) (c byte, ok bool) {
+	if c, ok := cs.encode[r]; ok {
+		return c, true
+	}
+	for _, b := range norm.NFD.String(string(r)) {
+		if unicode.Is(unicode.Mn, b) {
+			continue
+		}
+		if c, ok := cs.encode[b]; ok {
+			return c, false
+		}
+	}
+	return '?', false
+}
+
+func (cs *charset) decodeByte(c byte) rune {
+	return cs.decode[c&0x7F]
+}
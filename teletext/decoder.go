@@ -0,0 +1,183 @@
+package teletext
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/si0ls/subs/stl"
+)
+
+// Decode reads a raw T42 stream from r and recovers the subtitles sent on
+// the page given by the options as a Teletext STL file.
+//
+// A subtitle is displayed from the frame of its page header to the frame of
+// the next header of the page. Rows are kept as transmitted, including
+// spacing attributes, with the first row as Vertical Position (VP).
+// Packets with uncorrectable errors are skipped and returned as warnings.
+func Decode(r io.Reader, opts Options) (*stl.File, []error, error) {
+	if err := opts.setDefaults(); err != nil {
+		return nil, nil, err
+	}
+	start := stl.Timecode{}
+	if opts.Start != nil {
+		start = *opts.Start
+	}
+
+	d := &decoder{
+		opts:       opts,
+		startFrame: start.ToFrames(opts.Framerate),
+		receiving:  make(map[int]bool),
+		option:     -1,
+	}
+
+	var p Packet
+	var n int
+	for ; ; n++ {
+		if _, err := io.ReadFull(r, p[:]); err == io.EOF {
+			break
+		} else if errors.Is(err, io.ErrUnexpectedEOF) {
+			d.warns = append(d.warns, fmt.Errorf("packet %d: truncated packet", n))
+			break
+		} else if err != nil {
+			return nil, d.warns, err
+		}
+		d.packet(n, p)
+	}
+	d.close(n / opts.LinesPerFrame)
+
+	f := stl.NewFile()
+	f.GSI = stl.NewGSIBlock()
+	f.GSI.SetDefaults(opts.Framerate, stl.DisplayStandardCodeLevel1Teletext)
+	f.GSI.TCP = start
+	if d.option >= 0 {
+		f.GSI.LC = languageCode(d.option)
+	}
+	for _, tti := range d.subtitles {
+		f.TTI = append(f.TTI, tti.ExtensionBlocks()...)
+	}
+	f.UpdateCounters()
+
+	return f, d.warns, nil
+}
+
+type decoder struct {
+	opts       Options
+	startFrame int
+	warns      []error
+
+	receiving map[int]bool   // whether the current page of each magazine is the subtitle page
+	rows      map[int][]byte // rows of the page being displayed
+	in        int            // frame of the page being displayed
+	charset   *charset       // character set of the page being displayed
+	option    int            // national option of the first page, -1 if none
+	subtitles []*stl.TTIBlock
+}
+
+func (d *decoder) packet(n int, p Packet) {
+	frame := n / d.opts.LinesPerFrame
+	magazine, row, err := p.Address()
+	if err != nil {
+		d.warns = append(d.warns, fmt.Errorf("packet %d: %w", n, err))
+		return
+	}
+
+	switch {
+	case row == 0:
+		h, err := p.Header()
+		if err != nil {
+			d.warns = append(d.warns, fmt.Errorf("packet %d: %w", n, err))
+			return
+		}
+		if h.Control&ControlMagazineSerial != 0 {
+			// in serial mode a header terminates the pages of all magazines
+			d.receiving = make(map[int]bool)
+		}
+		d.receiving[magazine] = magazine == d.opts.magazine() && h.Page == d.opts.Page&0xFF
+		if !d.receiving[magazine] {
+			return
+		}
+		rows := d.rows
+		d.close(frame)
+		d.rows = make(map[int][]byte)
+		if h.Control&ControlErasePage == 0 {
+			for number, data := range rows {
+				d.rows[number] = data
+			}
+		}
+		d.in = frame
+		d.charset = newCharset(h.NationalOption)
+		if d.option < 0 {
+			d.option = h.NationalOption
+		}
+	case row >= 1 && row <= 23:
+		if !d.receiving[magazine] {
+			return
+		}
+		data, errs := p.Data()
+		if errs > 0 {
+			d.warns = append(d.warns, fmt.Errorf("packet %d: %d parity errors on row %d", n, errs, row))
+		}
+		d.rows[row] = data
+	}
+}
+
+// close ends the display of the current page at frame, adding it to the
+// subtitles if it has displayable rows.
+func (d *decoder) close(frame int) {
+	rows := d.rows
+	d.rows = nil
+	if len(rows) == 0 || frame <= d.in {
+		return
+	}
+
+	first, last := 24, 0
+	for number, data := range rows {
+		if isSpaces(data) {
+			continue
+		}
+		if number < first {
+			first = number
+		}
+		if number > last {
+			last = number
+		}
+	}
+	if first > last {
+		return
+	}
+
+	var text []rune
+	for number := first; number <= last; number++ {
+		if number > first {
+			text = append(text, rune(stl.ControlCodeLineBreak))
+		}
+		for _, c := range trimSpaces(rows[number], false) {
+			if c < 0x20 {
+				text = append(text, rune(c))
+			} else {
+				text = append(text, d.charset.decodeByte(c))
+			}
+		}
+	}
+
+	tti := stl.NewTTIBlock()
+	tti.SGN = 0
+	tti.SN = len(d.subtitles)
+	tti.EBN = stl.EBNLastBlock
+	tti.CS = stl.CumulativeStatusNone
+	tti.TCI = stl.TimecodeFromFrames(d.startFrame+d.in, d.opts.Framerate)
+	tti.TCO = stl.TimecodeFromFrames(d.startFrame+frame, d.opts.Framerate)
+	tti.VP = first
+	tti.JC = stl.JustificationCodeUnchangedPresentation
+	tti.CF = stl.CommentFlagSubtitleData
+	unmappables, err := tti.SetTextFallback(string(text), stl.CharacterCodeTableLatin, stl.FallbackReplace)
+	if err != nil {
+		d.warns = append(d.warns, fmt.Errorf("subtitle %s: %w", tti.TCI, err))
+		return
+	}
+	if len(unmappables) > 0 {
+		d.warns = append(d.warns, fmt.Errorf("subtitle %s: %w, fallback %s", tti.TCI, &stl.UnmappableError{Runes: unmappables}, stl.FallbackReplace))
+	}
+	d.subtitles = append(d.subtitles, tti)
+}
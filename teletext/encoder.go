@@ -0,0 +1,378 @@
+// Package teletext converts STL files to and from Teletext (ETS 300 706)
+// subtitle pages carried as a raw T42 stream: a sequence of 42 bytes
+// packets, a fixed number of packets per video frame.
+package teletext
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/si0ls/subs/stl"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Options configures Teletext encoding and decoding.
+type Options struct {
+	Page          int           // Page number with the magazine as first digit (default 0x888)
+	LinesPerFrame int           // Number of packets per video frame (default 16)
+	Start         *stl.Timecode // Timecode of the first frame of the stream (default GSI Time Code: Start-of-Programme (TCP) when encoding, 00:00:00:00 when decoding)
+	Framerate     uint          // Frame rate of the stream when decoding (default 25), the GSI frame rate is used when encoding
+}
+
+var (
+	ErrNilGSI               = errors.New("nil GSI block")
+	ErrUnsupportedFramerate = errors.New("unsupported framerate")
+	ErrInvalidPage          = errors.New("invalid page number")
+)
+
+func (opts *Options) setDefaults() error {
+	if opts.Page == 0 {
+		opts.Page = 0x888
+	}
+	if opts.Page < 0x100 || opts.Page > 0x8FF {
+		return fmt.Errorf("%w: %X", ErrInvalidPage, opts.Page)
+	}
+	if opts.LinesPerFrame <= 0 {
+		opts.LinesPerFrame = 16
+	}
+	if opts.Framerate == 0 {
+		opts.Framerate = 25
+	}
+	return nil
+}
+
+func (opts Options) magazine() int {
+	return opts.Page >> 8
+}
+
+// Frame is a video frame carrying Teletext packets.
+type Frame struct {
+	Index    int          // Frame number from the start of the stream
+	Timecode stl.Timecode // Timecode of the frame
+	Packets  []Packet     // Packets carried by the frame, at most LinesPerFrame
+}
+
+// Frames returns the video frames carrying the subtitles of f, in order.
+// Frames without packets are omitted.
+//
+// Each subtitle is sent at its Time Code In (TCI) as a page header with the
+// erase page control bit set followed by its rows, and erased at its Time
+// Code Out (TCO) by an empty page unless the next subtitle replaces it on
+// that frame. Cumulative subtitles are added to the page without erasing
+// it. Each page transmission is terminated by a filler page header (page
+// FF of the same magazine). Packets which do not fit in a frame are delayed
+// to the following frames.
+//
+// Subtitles are placed on the row given by their Vertical Position (VP) for
+// Teletext files and scaled to rows 1..23 otherwise, with rows boxed and
+// justified as specified by their Justification Code (JC). Open subtitling
+// control codes are dropped and characters missing from the G0 Latin
+// national option subset chosen from the GSI Language Code (LC) are
+// replaced, such losses are returned as warnings.
+func Frames(f *stl.File, opts Options) ([]Frame, []error, error) {
+	if f.GSI == nil {
+		return nil, nil, ErrNilGSI
+	}
+	if err := opts.setDefaults(); err != nil {
+		return nil, nil, err
+	}
+	framerate := f.GSI.Framerate()
+	if framerate == 0 {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedFramerate, f.GSI.DFC)
+	}
+	start := f.GSI.TCP
+	if opts.Start != nil {
+		start = *opts.Start
+	}
+	startFrame := start.ToFrames(framerate)
+
+	e := &encoder{
+		gsi:       f.GSI,
+		textOrder: f.TextOrder,
+		magazine:  opts.magazine(),
+		pageNum:   opts.Page & 0xFF,
+		option:    NationalOption(f.GSI.LC),
+	}
+	e.charset = newCharset(e.option)
+
+	var subtitles []*stl.TTIBlock
+	for _, tti := range f.Subtitles() {
+		if tti.CF == stl.CommentFlagTranslatorComments {
+			continue
+		}
+		subtitles = append(subtitles, tti)
+	}
+	sort.SliceStable(subtitles, func(i, j int) bool {
+		return subtitles[i].TCI.ToFrames(framerate) < subtitles[j].TCI.ToFrames(framerate)
+	})
+
+	var warns []error
+	scheduled := make(map[int][]Packet)
+	for i, tti := range subtitles {
+		in := tti.TCI.ToFrames(framerate) - startFrame
+		out := tti.TCO.ToFrames(framerate) - startFrame
+		if in < 0 {
+			warns = append(warns, fmt.Errorf("subtitle %d/%d: Time Code In %s before stream start %s, skipped", tti.SGN, tti.SN, tti.TCI, start))
+			continue
+		}
+
+		rows, rowWarns := e.rows(tti)
+		for _, w := range rowWarns {
+			warns = append(warns, fmt.Errorf("subtitle %d/%d: %w", tti.SGN, tti.SN, w))
+		}
+		erase := tti.CS != stl.CumulativeStatusIntermediate && tti.CS != stl.CumulativeStatusLast
+		scheduled[in] = append(scheduled[in], e.transmission(erase, rows)...)
+
+		if out <= in {
+			continue
+		}
+		if i+1 < len(subtitles) {
+			next := subtitles[i+1]
+			nextIn := next.TCI.ToFrames(framerate) - startFrame
+			if nextIn <= out || next.CS == stl.CumulativeStatusIntermediate || next.CS == stl.CumulativeStatusLast {
+				continue
+			}
+		}
+		scheduled[out] = append(scheduled[out], e.transmission(true, nil)...)
+	}
+
+	indexes := make([]int, 0, len(scheduled))
+	for index := range scheduled {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	var frames []Frame
+	var queue []Packet
+	for i := 0; i < len(indexes) || len(queue) > 0; {
+		var index int
+		if len(queue) > 0 {
+			index = frames[len(frames)-1].Index + 1
+		} else {
+			index = indexes[i]
+		}
+		for ; i < len(indexes) && indexes[i] <= index; i++ {
+			queue = append(queue, scheduled[indexes[i]]...)
+		}
+		n := opts.LinesPerFrame
+		if n > len(queue) {
+			n = len(queue)
+		}
+		frames = append(frames, Frame{
+			Index:    index,
+			Timecode: stl.TimecodeFromFrames(startFrame+index, framerate),
+			Packets:  queue[:n:n],
+		})
+		queue = queue[n:]
+	}
+
+	return frames, warns, nil
+}
+
+// Encode writes the subtitles of f as a raw T42 stream to w, from the
+// stream start to the last frame carrying subtitle packets.
+// Each frame is padded to LinesPerFrame packets with filler page headers.
+// See Frames for the subtitle encoding.
+func Encode(w io.Writer, f *stl.File, opts Options) ([]error, error) {
+	if err := opts.setDefaults(); err != nil {
+		return nil, err
+	}
+	frames, warns, err := Frames(f, opts)
+	if err != nil {
+		return warns, err
+	}
+
+	filler := fillerHeader(opts.magazine())
+	var index int
+	for _, frame := range frames {
+		for ; index < frame.Index; index++ {
+			for i := 0; i < opts.LinesPerFrame; i++ {
+				if _, err := w.Write(filler[:]); err != nil {
+					return warns, err
+				}
+			}
+		}
+		for i := 0; i < opts.LinesPerFrame; i++ {
+			p := filler
+			if i < len(frame.Packets) {
+				p = frame.Packets[i]
+			}
+			if _, err := w.Write(p[:]); err != nil {
+				return warns, err
+			}
+		}
+		index++
+	}
+	return warns, nil
+}
+
+func fillerHeader(magazine int) Packet {
+	return Header{
+		Magazine: magazine,
+		Page:     0xFF,
+		Control:  ControlSuppressHeader | ControlInhibitDisplay,
+	}.Packet()
+}
+
+// pageRow is a row of a Teletext page.
+type pageRow struct {
+	number int
+	data   []byte
+}
+
+type encoder struct {
+	gsi       *stl.GSIBlock
+	textOrder stl.TextOrder
+	magazine  int
+	pageNum   int
+	option    int
+	charset   *charset
+}
+
+// transmission returns the packets of a page transmission: page header, rows and
+// terminating filler header.
+func (e *encoder) transmission(erase bool, rows []pageRow) []Packet {
+	h := Header{
+		Magazine:       e.magazine,
+		Page:           e.pageNum,
+		Control:        ControlSubtitle | ControlSuppressHeader,
+		NationalOption: e.option,
+	}
+	if erase {
+		h.Control |= ControlErasePage
+	}
+	packets := []Packet{h.Packet()}
+	for _, row := range rows {
+		packets = append(packets, RowPacket(e.magazine, row.number, row.data))
+	}
+	return append(packets, fillerHeader(e.magazine))
+}
+
+// rows converts the Text Field (TF) of tti to Teletext page rows, written
+// from left to right: right-to-left text is put in visual order.
+func (e *encoder) rows(tti *stl.TTIBlock) ([]pageRow, []error) {
+	var warns []error
+	dec, ok := stl.CharacterCodeTableDecoders[e.gsi.CCT]
+	if !ok {
+		return nil, []error{fmt.Errorf("unsupported character code table %d", e.gsi.CCT)}
+	}
+
+	b, err := dec.Decode([]byte(tti.TF))
+	if err != nil {
+		return nil, []error{err}
+	}
+	b = []byte(stl.ConvertTextOrder(string(b), e.textOrder, stl.TextOrderVisual))
+	// decoders keep the control codes as single bytes, which are not
+	// valid UTF-8, the bytes of multi-byte characters (UTF-8 tables) are
+	// not taken for them
+	var texts []string
+	var text []byte
+	for i := 0; i < len(b); {
+		r, n := utf8.DecodeRune(b[i:])
+		switch {
+		case b[i] == byte(stl.ControlCodeLineBreak) && r == utf8.RuneError && n == 1:
+			texts = append(texts, string(text))
+			text = nil
+		case b[i] >= 0x80 && b[i] <= 0x9F && r == utf8.RuneError && n == 1:
+			// open subtitling control codes
+		default:
+			text = append(text, b[i:i+n]...)
+		}
+		i += n
+	}
+	texts = append(texts, string(text))
+
+	var lines [][]byte
+	for _, text := range texts {
+		var line []byte
+		for _, r := range norm.NFC.String(text) {
+			if r < 0x20 {
+				line = append(line, byte(r))
+				continue
+			}
+			c, ok := e.charset.encodeRune(r)
+			if !ok {
+				warns = append(warns, fmt.Errorf("character %q not available in Teletext national option %d, replaced by %q", r, e.option, e.charset.decodeByte(c)))
+			}
+			line = append(line, c)
+		}
+		lines = append(lines, e.justify(line, tti.JC))
+	}
+
+	first := tti.VP
+	if e.gsi.DSC != stl.DisplayStandardCodeLevel1Teletext && e.gsi.DSC != stl.DisplayStandardCodeLevel2Teletext && e.gsi.MNR > 0 {
+		first = tti.VP * 23 / e.gsi.MNR
+	}
+	if first <= 0 || first+len(lines)-1 > 23 {
+		first = 23 - len(lines) + 1
+	}
+	if first < 1 {
+		warns = append(warns, fmt.Errorf("%d rows do not fit in a Teletext page, truncated", len(lines)))
+		lines = lines[1-first:]
+		first = 1
+	}
+
+	var rows []pageRow
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		if len(line) > 40 {
+			warns = append(warns, fmt.Errorf("row %d longer than 40 characters, truncated", first+i))
+			line = line[:40]
+		}
+		rows = append(rows, pageRow{number: first + i, data: line})
+	}
+	return rows, warns
+}
+
+// justify boxes the row if it has no start box and aligns it on the 40
+// columns of the page according to jc.
+func (e *encoder) justify(line []byte, jc stl.JustificationCode) []byte {
+	line = trimSpaces(line, jc != stl.JustificationCodeUnchangedPresentation)
+	if len(line) == 0 || isSpaces(line) {
+		return nil
+	}
+	if !strings.ContainsRune(string(line), rune(stl.TeletextControlCodeStartBox)) {
+		line = append([]byte{byte(stl.TeletextControlCodeStartBox), byte(stl.TeletextControlCodeStartBox)}, line...)
+	}
+
+	var pad int
+	switch jc {
+	case stl.JustificationCodeCenteredText:
+		pad = (40 - len(line)) / 2
+	case stl.JustificationCodeRightJustifiedText:
+		pad = 40 - len(line)
+	}
+	if pad > 0 {
+		line = append([]byte(strings.Repeat(" ", pad)), line...)
+	}
+	return line
+}
+
+// trimSpaces removes trailing spaces of the row, and leading spaces if
+// leading is set.
+func trimSpaces(line []byte, leading bool) []byte {
+	for len(line) > 0 && line[len(line)-1] == ' ' {
+		line = line[:len(line)-1]
+	}
+	for leading && len(line) > 0 && line[0] == ' ' {
+		line = line[1:]
+	}
+	return line
+}
+
+// isSpaces reports whether the row only holds spaces and spacing
+// attributes, which display nothing.
+func isSpaces(line []byte) bool {
+	for _, c := range line {
+		if c > ' ' {
+			return false
+		}
+	}
+	return true
+}
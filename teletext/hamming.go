@@ -0,0 +1,74 @@
+package teletext
+
+import "errors"
+
+// ErrHamming is returned when a Hamming 8/4 protected byte has more than one
+// bit error and cannot be corrected.
+var ErrHamming = errors.New("uncorrectable Hamming 8/4 error")
+
+// ErrParity is returned when an odd parity protected byte has a parity
+// error.
+var ErrParity = errors.New("odd parity error")
+
+// hamming84 is the Hamming 8/4 encoding of the nibbles 0x0..0xF
+// (ETS 300 706 section 8.2), bits in transmission order (LSB first).
+var hamming84 = [16]byte{
+	0x15, 0x02, 0x49, 0x5E, 0x64, 0x73, 0x38, 0x2F,
+	0xD0, 0xC7, 0x8C, 0x9B, 0xA1, 0xB6, 0xFD, 0xEA,
+}
+
+// unhamming84 maps each byte to the nibble of the nearest codeword, or -1
+// when the byte has more than one bit error.
+var unhamming84 [256]int8
+
+func init() {
+	for b := 0; b < 256; b++ {
+		unhamming84[b] = -1
+		for n, c := range hamming84 {
+			if d := bitCount(byte(b) ^ c); d <= 1 {
+				unhamming84[b] = int8(n)
+				break
+			}
+		}
+	}
+}
+
+// Hamming84 returns the Hamming 8/4 encoding of the low nibble of n.
+func Hamming84(n byte) byte {
+	return hamming84[n&0x0F]
+}
+
+// UnHamming84 decodes a Hamming 8/4 protected byte, correcting single bit
+// errors.
+func UnHamming84(b byte) (byte, error) {
+	n := unhamming84[b]
+	if n < 0 {
+		return 0, ErrHamming
+	}
+	return byte(n), nil
+}
+
+// Parity returns c (7 bits) with its bit 8 set to give odd parity.
+func Parity(c byte) byte {
+	c &= 0x7F
+	if bitCount(c)%2 == 0 {
+		c |= 0x80
+	}
+	return c
+}
+
+// UnParity returns the 7 bits value of an odd parity protected byte.
+func UnParity(b byte) (byte, error) {
+	if bitCount(b)%2 == 0 {
+		return b & 0x7F, ErrParity
+	}
+	return b & 0x7F, nil
+}
+
+func bitCount(b byte) int {
+	var n int
+	for ; b != 0; b &= b - 1 {
+		n++
+	}
+	return n
+}
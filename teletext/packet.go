@@ -0,0 +1,173 @@
+package teletext
+
+import "fmt"
+
+// PacketSize is the size in bytes of a T42 packet: the 2 bytes Magazine and
+// Row Address Group (MRAG) followed by 40 data bytes, without the clock
+// run-in and framing code.
+const PacketSize = 42
+
+// Packet is a Teletext packet in T42 format.
+type Packet [PacketSize]byte
+
+// Control is the set of page header control bits C4..C11.
+type Control uint16
+
+const (
+	ControlErasePage           Control = 1 << iota // C4: erase page
+	ControlNewsflash                               // C5: newsflash
+	ControlSubtitle                                // C6: subtitle
+	ControlSuppressHeader                          // C7: suppress header
+	ControlUpdate                                  // C8: update indicator
+	ControlInterruptedSequence                     // C9: interrupted sequence
+	ControlInhibitDisplay                          // C10: inhibit display
+	ControlMagazineSerial                          // C11: magazine serial
+)
+
+// Header is a page header (packet X/0).
+type Header struct {
+	Magazine       int     // Magazine number (1..8)
+	Page           int     // Page number within the magazine (0x00..0xFF, 0xFF is a filler page)
+	Subcode        int     // Page subcode (S1..S4, 13 bits)
+	Control        Control // Control bits C4..C11
+	NationalOption int     // National option character subset (C12..C14)
+	Text           string  // Header text, displayed in columns 8..39 unless suppressed
+}
+
+// Packet returns the X/0 packet of the header.
+// Header text is truncated or padded with spaces to 32 characters.
+func (h Header) Packet() Packet {
+	var p Packet
+	p.setAddress(h.Magazine, 0)
+	s := h.Subcode
+	c := h.Control
+	bit := func(c Control, f Control, shift uint) byte {
+		if c&f != 0 {
+			return 1 << shift
+		}
+		return 0
+	}
+	data := [8]byte{
+		byte(h.Page & 0x0F),
+		byte(h.Page >> 4 & 0x0F),
+		byte(s & 0x0F),
+		byte(s>>4&0x07) | bit(c, ControlErasePage, 3),
+		byte(s >> 7 & 0x0F),
+		byte(s>>11&0x03) | bit(c, ControlNewsflash, 2) | bit(c, ControlSubtitle, 3),
+		bit(c, ControlSuppressHeader, 0) | bit(c, ControlUpdate, 1) | bit(c, ControlInterruptedSequence, 2) | bit(c, ControlInhibitDisplay, 3),
+		bit(c, ControlMagazineSerial, 0) | byte(h.NationalOption&0x07)<<1,
+	}
+	for i, n := range data {
+		p[2+i] = Hamming84(n)
+	}
+	for i := 0; i < 32; i++ {
+		c := byte(' ')
+		if i < len(h.Text) {
+			c = h.Text[i]
+		}
+		p[10+i] = Parity(c)
+	}
+	return p
+}
+
+// RowPacket returns the X/row packet (row 1..23 for page content) of the
+// magazine carrying data, odd parity encoded.
+// Data is truncated or padded with spaces to 40 characters.
+func RowPacket(magazine, row int, data []byte) Packet {
+	var p Packet
+	p.setAddress(magazine, row)
+	for i := 0; i < 40; i++ {
+		c := byte(' ')
+		if i < len(data) {
+			c = data[i]
+		}
+		p[2+i] = Parity(c)
+	}
+	return p
+}
+
+func (p *Packet) setAddress(magazine, row int) {
+	p[0] = Hamming84(byte(magazine&0x07) | byte(row&0x01)<<3)
+	p[1] = Hamming84(byte(row >> 1))
+}
+
+// Address returns the magazine (1..8) and row (0..31) numbers of the packet.
+func (p Packet) Address() (magazine, row int, err error) {
+	b0, err := UnHamming84(p[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("packet address: %w", err)
+	}
+	b1, err := UnHamming84(p[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("packet address: %w", err)
+	}
+	magazine = int(b0 & 0x07)
+	if magazine == 0 {
+		magazine = 8
+	}
+	return magazine, int(b0>>3) | int(b1)<<1, nil
+}
+
+// Header decodes the packet as a page header (packet X/0).
+// Parity errors in the header text are replaced by spaces.
+func (p Packet) Header() (Header, error) {
+	var h Header
+	magazine, row, err := p.Address()
+	if err != nil {
+		return h, err
+	}
+	if row != 0 {
+		return h, fmt.Errorf("packet X/%d is not a page header", row)
+	}
+	var data [8]byte
+	for i := range data {
+		if data[i], err = UnHamming84(p[2+i]); err != nil {
+			return h, fmt.Errorf("page header byte %d: %w", i, err)
+		}
+	}
+	h.Magazine = magazine
+	h.Page = int(data[0]) | int(data[1])<<4
+	h.Subcode = int(data[2]) | int(data[3]&0x07)<<4 | int(data[4])<<7 | int(data[5]&0x03)<<11
+	flags := []struct {
+		b, mask byte
+		f       Control
+	}{
+		{data[3], 0x08, ControlErasePage},
+		{data[5], 0x04, ControlNewsflash},
+		{data[5], 0x08, ControlSubtitle},
+		{data[6], 0x01, ControlSuppressHeader},
+		{data[6], 0x02, ControlUpdate},
+		{data[6], 0x04, ControlInterruptedSequence},
+		{data[6], 0x08, ControlInhibitDisplay},
+		{data[7], 0x01, ControlMagazineSerial},
+	}
+	for _, flag := range flags {
+		if flag.b&flag.mask != 0 {
+			h.Control |= flag.f
+		}
+	}
+	h.NationalOption = int(data[7] >> 1)
+	text, _ := unParityBytes(p[10:])
+	h.Text = string(text)
+	return h, nil
+}
+
+// Data returns the 40 data bytes of the packet with parity removed.
+// Bytes with a parity error are replaced by spaces, the number of errors is
+// returned.
+func (p Packet) Data() ([]byte, int) {
+	return unParityBytes(p[2:])
+}
+
+func unParityBytes(b []byte) ([]byte, int) {
+	data := make([]byte, len(b))
+	var errs int
+	for i, c := range b {
+		var err error
+		if data[i], err = UnParity(c); err != nil {
+			data[i] = ' '
+			errs++
+		}
+	}
+	return data, errs
+}
@@ -0,0 +1,171 @@
+package teletext
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/si0ls/subs/internal/stltest"
+	"github.com/si0ls/subs/stl"
+)
+
+func TestHamming84(t *testing.T) {
+	for n := byte(0); n < 16; n++ {
+		b := Hamming84(n)
+		if got, err := UnHamming84(b); err != nil || got != n {
+			t.Errorf("UnHamming84(%#02x) = %d, %v; want %d", b, got, err, n)
+		}
+		for bit := 0; bit < 8; bit++ {
+			if got, err := UnHamming84(b ^ 1<<bit); err != nil || got != n {
+				t.Errorf("UnHamming84(%#02x) with bit %d error = %d, %v; want %d", b, bit, got, err, n)
+			}
+		}
+		if _, err := UnHamming84(b ^ 0x03); err != ErrHamming {
+			t.Errorf("UnHamming84(%#02x) with 2 bit errors: expected ErrHamming, got %v", b, err)
+		}
+	}
+}
+
+func TestHeader(t *testing.T) {
+	h := Header{
+		Magazine:       8,
+		Page:           0x88,
+		Subcode:        0x1234,
+		Control:        ControlErasePage | ControlSubtitle | ControlSuppressHeader,
+		NationalOption: NationalOptionFrench,
+		Text:           pad32("subs"),
+	}
+	p := h.Packet()
+	if p[0] != 0x15 || p[1] != 0x15 {
+		t.Errorf("unexpected MRAG %#02x %#02x", p[0], p[1])
+	}
+	got, err := p.Header()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != h {
+		t.Errorf("expected header %+v, got %+v", h, got)
+	}
+}
+
+func pad32(s string) string {
+	return s + string(bytes.Repeat([]byte{' '}, 32-len(s)))
+}
+
+func TestEncodeDecode(t *testing.T) {
+	// double height with a national character, a subtitle starting when the
+	// previous one ends and a gap of two seconds
+	f := stltest.File{Subtitles: []stltest.Subtitle{
+		{TCI: stl.Timecode{Hours: 10, Seconds: 1}, TCO: stl.Timecode{Hours: 10, Seconds: 2, Frames: 12}, VP: 20,
+			Text: "\x0d\x0b\x0bL'été\u008a\u008a\x0d\x0b\x0b\x03où"},
+		{TCI: stl.Timecode{Hours: 10, Seconds: 2, Frames: 12}, TCO: stl.Timecode{Hours: 10, Seconds: 3}, VP: 21,
+			Text: "\x0b\x0bsuite"},
+		{TCI: stl.Timecode{Hours: 10, Seconds: 5}, TCO: stl.Timecode{Hours: 10, Seconds: 6}, VP: 22,
+			Text: "\x0b\x0bfin"},
+	}}.New(t)
+	f.GSI.LC = stl.LanguageCodeFrench
+	f.GSI.TCP = stl.Timecode{Hours: 10}
+
+	var buf bytes.Buffer
+	opts := Options{LinesPerFrame: 4}
+	warns, err := Encode(&buf, f, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) > 0 {
+		t.Errorf("unexpected warnings: %v", warns)
+	}
+	if want := (6*25 + 1) * 4 * PacketSize; buf.Len() != want {
+		t.Errorf("expected %d bytes, got %d", want, buf.Len())
+	}
+
+	start := f.GSI.TCP
+	opts.Start = &start
+	got, warns, err := Decode(&buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) > 0 {
+		t.Errorf("unexpected warnings: %v", warns)
+	}
+	if got.GSI.LC != stl.LanguageCodeFrench {
+		t.Errorf("expected language %s, got %s", stl.LanguageCodeFrench, got.GSI.LC)
+	}
+	if len(got.TTI) != len(f.TTI) {
+		t.Fatalf("expected %d subtitles, got %d", len(f.TTI), len(got.TTI))
+	}
+	for i, tti := range got.TTI {
+		want := f.TTI[i]
+		if tti.TF != want.TF {
+			t.Errorf("subtitle %d: expected TF %q, got %q", i, want.TF, tti.TF)
+		}
+		if tti.TCI != want.TCI || tti.TCO != want.TCO {
+			t.Errorf("subtitle %d: expected %s-%s, got %s-%s", i, want.TCI, want.TCO, tti.TCI, tti.TCO)
+		}
+		if tti.VP != want.VP {
+			t.Errorf("subtitle %d: expected VP %d, got %d", i, want.VP, tti.VP)
+		}
+	}
+}
+
+func TestEncodeUTF8(t *testing.T) {
+	cct := stl.CharacterCodeTableUTF8
+	// 'Ü' and 'ß' are encoded with the bytes 0x9C and 0x9F of control codes
+	text := "\x0b\x0bÜber\u008a\x0b\x0bGröße"
+	f := stltest.File{CCT: cct, Subtitles: []stltest.Subtitle{
+		{TCI: stl.Timecode{Seconds: 1}, TCO: stl.Timecode{Seconds: 2}, VP: 20, Text: text},
+	}}.New(t)
+	f.GSI.LC = stl.LanguageCodeGerman
+
+	var buf bytes.Buffer
+	opts := Options{LinesPerFrame: 4}
+	warns, err := Encode(&buf, f, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) > 0 {
+		t.Errorf("unexpected warnings: %v", warns)
+	}
+	start := f.GSI.TCP
+	opts.Start = &start
+	got, _, err := Decode(&buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.TTI) != 1 {
+		t.Fatalf("expected 1 subtitle, got %d", len(got.TTI))
+	}
+	want := stl.NewTTIBlock()
+	if err := want.SetText(text, stl.CharacterCodeTableLatin); err != nil {
+		t.Fatal(err)
+	}
+	if got.TTI[0].TF != want.TF {
+		t.Errorf("expected TF %q, got %q", want.TF, got.TTI[0].TF)
+	}
+}
+
+func TestEncodeTextOrder(t *testing.T) {
+	for _, order := range []stl.TextOrder{stl.TextOrderLogical, stl.TextOrderVisual} {
+		f := stltest.File{CCT: stl.CharacterCodeTableLatinHebrew, TextOrder: order, Subtitles: []stltest.Subtitle{
+			{TCI: stl.Timecode{Seconds: 1}, TCO: stl.Timecode{Seconds: 2}, VP: 20, Text: "\x0b\x0bשלום Next"},
+		}}.New(t)
+
+		var buf bytes.Buffer
+		opts := Options{LinesPerFrame: 4}
+		if _, err := Encode(&buf, f, opts); err != nil {
+			t.Fatal(err)
+		}
+		start := f.GSI.TCP
+		opts.Start = &start
+		got, _, err := Decode(&buf, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.TTI) != 1 {
+			t.Fatalf("%s: expected 1 subtitle, got %d", order, len(got.TTI))
+		}
+		// Teletext rows are written from left to right, in visual order
+		if want := "\x0b\x0bNext ????"; got.TTI[0].TF != want {
+			t.Errorf("%s: expected TF %q, got %q", order, want, got.TTI[0].TF)
+		}
+	}
+}